@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// sutta is one sutta of an edition: its title and the paragraphs after it,
+// as passageText gives them, with the words of each for matching.
+type sutta struct {
+	title string
+	paras []string
+	bags  []map[string]int
+}
+
+// suttaTitleRe matches a paragraph that is a sutta title, like
+// "1. brahmajālasuttaṃ" or "(3) mahāli suttaṃ", numbers and closing
+// punctuation removed; the end line "brahmajālasuttaṃ niṭṭhitaṃ" is not one.
+var suttaTitleRe = regexp.MustCompile(`^[0-9().\s]*((?:\pL+ ){0,3}\pL*sutta(?:nta)?[ṃ]?)[.:]?$`)
+
+// suttaTitle returns the title the paragraph text is, or "".
+func suttaTitle(text string) string {
+	if m := suttaTitleRe.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// readSuttas reads the mūla files of c by book and splits each book into
+// its suttas, each starting at its title. The files of a book are read in
+// natural order, so dn-2 comes before dn-10; what comes before the first
+// title, and the files of no book, are left out.
+func readSuttas(ctx context.Context, c corpora.Corpus, books map[string]bool) (map[string][]sutta, error) {
+	files, err := c.Files()
+	if err != nil {
+		return nil, err
+	}
+	files = slices.DeleteFunc(files, func(path string) bool {
+		b := corpora.BookOf(c, path)
+		return corpora.LayerOf(c, path) != corpora.Mula || b == corpora.Other || books != nil && !books[b]
+	})
+	slices.SortStableFunc(files, naturalCompare)
+
+	byBook := make(map[string][]sutta)
+	prog := tools.NewProgress(c.Name(), len(files))
+	defer prog.Finish()
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		book := corpora.BookOf(c, path)
+		err := c.ScanText(path, func(line string) error {
+			text := passageText(c, line)
+			if text == "" {
+				return nil
+			}
+			if title := suttaTitle(text); title != "" {
+				byBook[book] = append(byBook[book], sutta{title: title})
+				return nil
+			}
+			list := byBook[book]
+			if len(list) == 0 {
+				return nil
+			}
+			s := &list[len(list)-1]
+			s.paras = append(s.paras, text)
+			s.bags = append(s.bags, wordBag(text))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		prog.Add(1)
+	}
+	return byBook, nil
+}
+
+// parseBookList parses a comma-separated list of the book keys readSuttas
+// takes, nil for all books when s is empty.
+func parseBookList(s string) (map[string]bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	books := make(map[string]bool)
+	for _, b := range strings.Split(s, ",") {
+		b = strings.TrimSpace(b)
+		if !slices.Contains(corpora.Books, b) || b == corpora.Other {
+			return nil, fmt.Errorf("unknown book %q (have %s)", b, strings.Join(corpora.Books[:len(corpora.Books)-1], ", "))
+		}
+		books[b] = true
+	}
+	return books, nil
+}
+
+// naturalCompare orders paths with their digit runs compared as numbers.
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da != "" && db != "" {
+			x, _ := strconv.Atoi(da)
+			y, _ := strconv.Atoi(db)
+			if x != y {
+				return x - y
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) - len(b)
+}
+
+// leadingDigits returns the ASCII digits s starts with.
+func leadingDigits(s string) string {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	return s[:end]
+}
+
+// wordBag counts the Pāḷi words of text.
+func wordBag(text string) map[string]int {
+	bag := make(map[string]int)
+	for _, s := range pali.WordSpans(text) {
+		bag[text[s[0]:s[1]]]++
+	}
+	return bag
+}
+
+// titleBag counts the letter pairs of a title, without its spaces and the
+// last niggahīta, so "mahālisuttaṃ" and "mahāli suttaṃ" are alike.
+func titleBag(title string) map[string]int {
+	r := []rune(strings.TrimSuffix(strings.ReplaceAll(title, " ", ""), "ṃ"))
+	bag := make(map[string]int)
+	for i := 0; i+1 < len(r); i++ {
+		bag[string(r[i:i+2])]++
+	}
+	return bag
+}
+
+// dice is the Dice coefficient of two bags: twice the items they share
+// over the items of both, from 0 to 1.
+func dice(a, b map[string]int) float64 {
+	shared, size := 0, 0
+	for w, n := range a {
+		shared += min(n, b[w])
+		size += n
+	}
+	for _, n := range b {
+		size += n
+	}
+	if size == 0 {
+		return 0
+	}
+	return 2 * float64(shared) / float64(size)
+}
+
+// alignBand is the least distance from the diagonal, in items, that
+// alignPairs looks at.
+const alignBand = 20
+
+// alignedPair pairs item i of one sequence with item j of another.
+type alignedPair struct {
+	i, j int
+	sim  float64
+}
+
+// alignPairs aligns two sequences of n and m items in order: it pairs item
+// i of the first with item j of the second when sim(i, j) is at least
+// minSim, so that the total similarity of the pairs is largest and no two
+// pairs cross. Only the pairs near the diagonal are tried, within
+// alignBand items plus the difference of the lengths, which keeps long
+// books affordable; editions of the same text do not stray further.
+func alignPairs(n, m int, sim func(i, j int) float64, minSim float64) []alignedPair {
+	if n == 0 || m == 0 {
+		return nil
+	}
+	band := alignBand + max(n-m, m-n)
+	width := 2*band + 1
+	lo := func(i int) int { return i*m/n - band } // first column of row i
+	// score and move of cell (i, j), row by row within the band
+	score := make([]float64, (n+1)*width)
+	move := make([]byte, (n+1)*width)
+	const (
+		skipA byte = iota + 1
+		skipB
+		match
+	)
+	at := func(i, j int) (float64, bool) {
+		k := j - lo(i)
+		if j < 0 || k < 0 || k >= width {
+			return math.Inf(-1), false
+		}
+		return score[i*width+k], true
+	}
+	for i := 0; i <= n; i++ {
+		for k := 0; k < width; k++ {
+			j := lo(i) + k
+			cell := i*width + k
+			if j < 0 || j > m {
+				score[cell] = math.Inf(-1)
+				continue
+			}
+			if i == 0 || j == 0 {
+				continue
+			}
+			best, how := math.Inf(-1), byte(0)
+			if s, ok := at(i-1, j); ok && s > best {
+				best, how = s, skipA
+			}
+			if s, ok := at(i, j-1); ok && s > best {
+				best, how = s, skipB
+			}
+			if s, ok := at(i-1, j-1); ok {
+				if x := sim(i-1, j-1); x >= minSim && s+x >= best {
+					best, how = s+x, match
+				}
+			}
+			score[cell], move[cell] = best, how
+		}
+	}
+
+	var pairs []alignedPair
+	for i, j := n, m; i > 0 && j > 0; {
+		switch move[i*width+j-lo(i)] {
+		case match:
+			pairs = append(pairs, alignedPair{i - 1, j - 1, sim(i-1, j-1)})
+			i, j = i-1, j-1
+		case skipA:
+			i--
+		case skipB:
+			j--
+		default:
+			// off the band: nothing more can be paired
+			i, j = 0, 0
+		}
+	}
+	slices.Reverse(pairs)
+	return pairs
+}
+
+// alignTable pairs the suttas of books a and b of two editions, book by
+// book in corpora.Books order, then the paragraphs of each pair of
+// suttas, and returns the table of the paragraph pairs of names nameA and
+// nameB with the number of suttas and paragraphs paired.
+func alignTable(nameA, nameB string, a, b map[string][]sutta, minSim float64) (t table, suttas, paras int) {
+	t = table{columns: []string{"book", "sutta", "title_" + nameA, "title_" + nameB, "paragraph_" + nameA, "paragraph_" + nameB, "similarity", "text_" + nameA, "text_" + nameB}}
+	for _, book := range corpora.Books {
+		sa, sb := a[book], b[book]
+		alignSuttas(sa, sb, minSim, func(i, j int, pairs []alignedPair) {
+			x, y := sa[i], sb[j]
+			suttas++
+			for _, pp := range pairs {
+				paras++
+				t.rows = append(t.rows, []any{book, i + 1, x.title, y.title, pp.i + 1, pp.j + 1, math.Round(pp.sim*1e4) / 1e4, x.paras[pp.i], y.paras[pp.j]})
+			}
+		})
+	}
+	return t, suttas, paras
+}
+
+// alignSuttas pairs the suttas of one book of two editions, a and b, and
+// calls fn with the numbers of each pair of suttas, in order, and the
+// pairs of their paragraphs, at least minSim alike.
+func alignSuttas(a, b []sutta, minSim float64, fn func(i, j int, paras []alignedPair)) {
+	ta := make([]map[string]int, len(a))
+	for i, s := range a {
+		ta[i] = titleBag(s.title)
+	}
+	tb := make([]map[string]int, len(b))
+	for j, s := range b {
+		tb[j] = titleBag(s.title)
+	}
+	// the titles decide which suttas pair, their numbers in the book
+	// being kept in order by the alignment
+	for _, sp := range alignPairs(len(a), len(b), func(i, j int) float64 { return dice(ta[i], tb[j]) }, alignTitleSim) {
+		x, y := a[sp.i], b[sp.j]
+		fn(sp.i, sp.j, alignPairs(len(x.paras), len(y.paras), func(i, j int) float64 { return dice(x.bags[i], y.bags[j]) }, minSim))
+	}
+}
+
+// alignTitleSim is the least similarity of the titles of two suttas
+// paired.
+const alignTitleSim = 0.6
+
+// runAlign implements the align subcommand: it pairs the paragraphs of the
+// same suttas in parallel editions.
+func runAlign(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("align", flag.ExitOnError)
+	commandUsage(fs, "Pairs the suttas of parallel editions by title and number, then their paragraphs by the words they share, and writes the aligned paragraph pairs.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora; the first is aligned with each of the others")
+	bookList := fs.String("books", "", "comma-separated book keys to align, e.g. dn,mn (default: all)")
+	minSim := fs.Float64("min-similarity", 0.5, "least share of words two paragraphs must have in common to pair (Dice, 0–1)")
+	variants := fs.Bool("variants", false, "also count the words the editions spell differently at the same position of the paired paragraphs")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("aligning parallel editions")
+	tic := tools.Tic()
+	list, err := selectCorpora(*names)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if len(list) < 2 {
+		tools.Errorf("align needs two corpora or more, got %q", *names)
+		return
+	}
+	books, err := parseBookList(*bookList)
+	if err != nil {
+		tools.Errorf("-books: %v", err)
+		return
+	}
+
+	suttas := make([]map[string][]sutta, len(list))
+	for i, c := range list {
+		if suttas[i], err = readSuttas(ctx, c, books); err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			return
+		}
+		n := 0
+		for _, s := range suttas[i] {
+			n += len(s)
+		}
+		tools.Infof("%s: %d suttas in %d books", c.Name(), n, len(suttas[i]))
+	}
+	pivot := list[0].Name()
+	for i, c := range list[1:] {
+		t, nSuttas, nParas := alignTable(pivot, c.Name(), suttas[0], suttas[i+1], *minSim)
+		name := fmt.Sprintf("align_%s_%s", pivot, c.Name())
+		if err := sink.Write(name, t); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+		tools.Infof("%s: %d suttas and %d paragraphs paired", name, nSuttas, nParas)
+	}
+	if *variants {
+		names := make([]string, len(list))
+		for i, c := range list {
+			names[i] = c.Name()
+		}
+		t := variantTable(names, suttas, *minSim)
+		name := "variants_" + strings.Join(names, "_")
+		if err := sink.Write(name, t); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+		n := 0
+		for _, row := range t.rows {
+			n += row[0].(int)
+		}
+		tools.Infof("%s: %d spellings at %d positions", name, len(t.rows), n)
+	}
+	tic.Toc()
+}
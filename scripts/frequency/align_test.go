@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestAlignPairs(t *testing.T) {
+	// b is a with an item left out and one added, far enough apart to
+	// need the band of a long sequence
+	var a, b []string
+	for i := range 300 {
+		a = append(a, fmt.Sprint("p", i))
+		if i == 40 {
+			continue
+		}
+		b = append(b, fmt.Sprint("p", i))
+		if i == 250 {
+			b = append(b, "extra")
+		}
+	}
+	same := func(i, j int) float64 {
+		if a[i] == b[j] {
+			return 1
+		}
+		return 0
+	}
+	pairs := alignPairs(len(a), len(b), same, 0.5)
+	if len(pairs) != 299 {
+		t.Fatalf("%d pairs, want 299", len(pairs))
+	}
+	for _, p := range pairs {
+		if a[p.i] != b[p.j] {
+			t.Fatalf("paired %s with %s", a[p.i], b[p.j])
+		}
+	}
+	if alignPairs(0, len(b), same, 0.5) != nil {
+		t.Errorf("pairs with an empty side")
+	}
+}
+
+func TestSuttaTitle(t *testing.T) {
+	var got []string
+	for _, text := range []string{"1. brahmajālasuttaṃ", "(3) mahāli suttaṃ", "brahmajālasuttaṃ niṭṭhitaṃ", "evaṃ me sutaṃ", "10. mahāsatipaṭṭhānasuttanta"} {
+		got = append(got, suttaTitle(text))
+	}
+	if want := []string{"brahmajālasuttaṃ", "mahāli suttaṃ", "", "", "mahāsatipaṭṭhānasuttanta"}; !slices.Equal(got, want) {
+		t.Errorf("titles %q, want %q", got, want)
+	}
+}
+
+func TestVariantTable(t *testing.T) {
+	edition := func(paras ...string) map[string][]sutta {
+		s := sutta{title: "brahmajālasuttaṃ"}
+		for _, p := range paras {
+			s.paras = append(s.paras, p)
+			s.bags = append(s.bags, wordBag(p))
+		}
+		return map[string][]sutta{"dn": {s}}
+	}
+	cst := edition("evaṃ me sutaṃ ekaṃ samayaṃ bhagavā antarā ca rājagahaṃ antarā ca nāḷandaṃ", "tatra kho bhagavā bhikkhū āmantesi")
+	bjt := edition("evaṃ me sutaṃ ekaṃ samayaṃ bhagavā antarā ca rājagahaṃ antarā ca nālandaṃ", "tatra kho bhagavā bhikkhū āmantesi")
+	sya := edition("evamme sutaṃ ekaṃ samayaṃ bhagavā antarā ca rājagahaṃ antarā ca nāḷandaṃ", "tatra kho bhagavā bhikkhu āmantesi")
+
+	tab := variantTable([]string{"cst", "bjt", "sya"}, []map[string][]sutta{cst, bjt, sya}, 0.5)
+	if want := []string{"count", "form_cst", "form_bjt", "form_sya", "example"}; !slices.Equal(tab.columns, want) {
+		t.Errorf("columns %q, want %q", tab.columns, want)
+	}
+	var got []string
+	for _, row := range tab.rows {
+		got = append(got, fmt.Sprintf("%v", row))
+	}
+	// evamme pairs with evaṃ, leaving me without a form in sya, which is
+	// no variant
+	want := []string{"[1 bhikkhū bhikkhū bhikkhu dn 1.2]", "[1 evaṃ evaṃ evamme dn 1.1]", "[1 nāḷandaṃ nālandaṃ nāḷandaṃ dn 1.1]"}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("variants\n got %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"dpd/go_modules/frequency/anki"
+	"dpd/go_modules/tools"
+)
+
+// vocabularyModel is the note type of the vocabulary deck. Its name and
+// field order must not change, or importing a newer deck adds a second
+// note type instead of updating the notes.
+var vocabularyModel = anki.Model{
+	ID:     anki.ID("palifreq vocabulary"),
+	Name:   "Pāḷi vocabulary (palifreq)",
+	Fields: []string{"Word", "Gloss", "Rank", "Example"},
+	Front:  `<div class="word">{{Word}}</div>`,
+	Back: `{{FrontSide}}<hr id=answer><div class="gloss">{{Gloss}}</div>` +
+		`{{#Example}}<div class="example">{{Example}}</div>{{/Example}}<div class="rank">#{{Rank}}</div>`,
+	CSS: `.card { font-family: sans-serif; font-size: 20px; text-align: center; }
+.word { font-size: 32px; }
+.example { margin-top: 1em; font-style: italic; }
+.rank { margin-top: 1em; font-size: 14px; color: #888; }`,
+}
+
+// example is a keyword-in-context snippet of the sentences table.
+type example struct {
+	left, form, right string
+}
+
+// html renders the snippet with its keyword in bold.
+func (e example) html() string {
+	return strings.TrimSpace(html.EscapeString(e.left) + " <b>" + html.EscapeString(e.form) + "</b> " + html.EscapeString(e.right))
+}
+
+// loadExamples reads the first snippet of each headword and of each word
+// from the sentences table that concordance wrote into the database at
+// path.
+func loadExamples(path string) (byHeadword map[int]example, byWord map[string]example, err error) {
+	db, err := sql.Open("sqlite", tools.SQLiteURI(path, "mode=ro"))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT word, COALESCE(headword_id, 0), form, left_context, right_context FROM sentences ORDER BY id`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading sentences: %w", err)
+	}
+	defer rows.Close()
+	byHeadword = make(map[int]example)
+	byWord = make(map[string]example)
+	for rows.Next() {
+		var (
+			word string
+			id   int
+			e    example
+		)
+		if err := rows.Scan(&word, &id, &e.form, &e.left, &e.right); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := byHeadword[id]; id != 0 && !ok {
+			byHeadword[id] = e
+		}
+		if _, ok := byWord[word]; id == 0 && !ok {
+			byWord[word] = e
+		}
+	}
+	return byHeadword, byWord, rows.Err()
+}
+
+// vocabularyDeck makes a deck of the study list t, one note per headword
+// in rank order, with the example snippet of the headword, or else of its
+// lemma without the homonym number, when there is one. Notes are tagged
+// with the part of speech, proper nouns also with proper_noun and number
+// words with number.
+func vocabularyDeck(name string, t table, byHeadword map[int]example, byWord map[string]example) *anki.Deck {
+	d := &anki.Deck{
+		ID:          anki.ID("palifreq deck " + name),
+		Name:        name,
+		Description: "The most frequent Pāḷi headwords of the Tipiṭaka with their DPD meanings, by palifreq.",
+		Model:       vocabularyModel,
+	}
+	for _, row := range t.rows {
+		rank, id, lemma, pos, meaning, name := row[0].(int), row[1].(int), row[2].(string), row[3].(string), row[5].(string), row[7].(int)
+		number := row[8].(string)
+		e, ok := byHeadword[id]
+		if !ok {
+			e, ok = byWord[lemmaWord(lemma)]
+		}
+		ex := ""
+		if ok {
+			ex = e.html()
+		}
+		tags := []string{strings.ReplaceAll(pos, " ", "_")}
+		if name == 1 {
+			tags = append(tags, "proper_noun")
+		}
+		if number != "" {
+			tags = append(tags, "number")
+		}
+		d.Notes = append(d.Notes, anki.Note{
+			// the DPD headword id keeps the note across rebuilds
+			GUID:   "dpd-" + strconv.Itoa(id),
+			Fields: []string{html.EscapeString(lemma), html.EscapeString(meaning), strconv.Itoa(rank), ex},
+			Tags:   tags,
+		})
+	}
+	return d
+}
+
+// runAnki implements the anki subcommand.
+func runAnki(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("anki", flag.ExitOnError)
+	commandUsage(fs, "Writes the most frequent DPD headwords with their meanings, ranks and example sentences as an Anki package (.apkg).")
+	top := fs.Int("top", 1000, "number of headwords in the deck")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora whose counts of the last run rank the headwords")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database")
+	sentences := fs.String("sentences", "", "SQLite database whose sentences table (see concordance) gives the examples (default: no examples)")
+	exclude := fs.String("exclude", "", "file of words whose headwords to leave out, one per line (see stopwords)")
+	dropNames := fs.Bool("drop-names", false, "leave out proper nouns (names of people, places and texts) instead of tagging them")
+	dropNumbers := fs.Bool("drop-numbers", false, "leave out number words (DPD cardinals and ordinals) instead of tagging them")
+	deckName := fs.String("deck", "Pāḷi vocabulary", "name of the Anki deck; imports update the deck of the same name")
+	out := fs.String("out", filepath.Join(freqDir, "pali_vocabulary.apkg"), "package to write")
+	fs.Parse(args)
+
+	tools.PTitle("saving the Anki deck")
+	tic := tools.Tic()
+
+	t, err := loadStudyTable(strings.Split(*names, ","), *dpdPath, *top, *exclude, *dropNames, *dropNumbers)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	var (
+		byHeadword map[int]example
+		byWord     map[string]example
+	)
+	if *sentences != "" {
+		if byHeadword, byWord, err = loadExamples(*sentences); err != nil {
+			tools.Errorf("%s: %v", *sentences, err)
+			return
+		}
+	}
+	d := vocabularyDeck(*deckName, t, byHeadword, byWord)
+	if err := d.WriteApkg(*out); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	withExample := 0
+	for _, n := range d.Notes {
+		if n.Fields[3] != "" {
+			withExample++
+		}
+	}
+	tools.Infof("%d notes, %d with an example, in %s", len(d.Notes), withExample, *out)
+
+	tic.Toc()
+}
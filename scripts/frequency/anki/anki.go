@@ -0,0 +1,320 @@
+// Package anki writes Anki decks as .apkg packages, ready for File →
+// Import: a zip of the collection.anki2 SQLite database (schema 11, which
+// every Anki since 2.1 imports) and an empty media list. Notes carry
+// stable GUIDs, so importing a newer package updates the notes of an older
+// one instead of adding duplicates, and keeps their review history.
+package anki
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"dpd/go_modules/tools"
+)
+
+// Model is a note type: its fields and the one card template built from
+// them. Front and Back are Anki templates such as "{{Word}}" and
+// "{{FrontSide}}<hr id=answer>{{Gloss}}".
+type Model struct {
+	ID     int64
+	Name   string
+	Fields []string
+	Front  string
+	Back   string
+	CSS    string
+}
+
+// Note is one note of a deck, with a value per model field in order.
+// Fields are HTML.
+type Note struct {
+	GUID   string
+	Fields []string
+	Tags   []string
+}
+
+// Deck is a named deck of notes of one model. Cards are introduced in
+// the order of Notes.
+type Deck struct {
+	ID          int64
+	Name        string
+	Description string
+	Model       Model
+	Notes       []Note
+}
+
+// ID derives a stable Anki id from a name, so a deck or model keeps its
+// id from one package to the next. Ids are positive and below 2⁵³.
+func ID(name string) int64 {
+	h := fnv.New64a()
+	io.WriteString(h, name)
+	return int64(h.Sum64()>>12) + 1
+}
+
+const schema = `
+CREATE TABLE col (
+	id     INTEGER PRIMARY KEY,
+	crt    INTEGER NOT NULL,
+	mod    INTEGER NOT NULL,
+	scm    INTEGER NOT NULL,
+	ver    INTEGER NOT NULL,
+	dty    INTEGER NOT NULL,
+	usn    INTEGER NOT NULL,
+	ls     INTEGER NOT NULL,
+	conf   TEXT    NOT NULL,
+	models TEXT    NOT NULL,
+	decks  TEXT    NOT NULL,
+	dconf  TEXT    NOT NULL,
+	tags   TEXT    NOT NULL
+);
+CREATE TABLE notes (
+	id    INTEGER PRIMARY KEY,
+	guid  TEXT    NOT NULL,
+	mid   INTEGER NOT NULL,
+	mod   INTEGER NOT NULL,
+	usn   INTEGER NOT NULL,
+	tags  TEXT    NOT NULL,
+	flds  TEXT    NOT NULL,
+	sfld  INTEGER NOT NULL,
+	csum  INTEGER NOT NULL,
+	flags INTEGER NOT NULL,
+	data  TEXT    NOT NULL
+);
+CREATE TABLE cards (
+	id     INTEGER PRIMARY KEY,
+	nid    INTEGER NOT NULL,
+	did    INTEGER NOT NULL,
+	ord    INTEGER NOT NULL,
+	mod    INTEGER NOT NULL,
+	usn    INTEGER NOT NULL,
+	type   INTEGER NOT NULL,
+	queue  INTEGER NOT NULL,
+	due    INTEGER NOT NULL,
+	ivl    INTEGER NOT NULL,
+	factor INTEGER NOT NULL,
+	reps   INTEGER NOT NULL,
+	lapses INTEGER NOT NULL,
+	left   INTEGER NOT NULL,
+	odue   INTEGER NOT NULL,
+	odid   INTEGER NOT NULL,
+	flags  INTEGER NOT NULL,
+	data   TEXT    NOT NULL
+);
+CREATE TABLE revlog (
+	id      INTEGER PRIMARY KEY,
+	cid     INTEGER NOT NULL,
+	usn     INTEGER NOT NULL,
+	ease    INTEGER NOT NULL,
+	ivl     INTEGER NOT NULL,
+	lastIvl INTEGER NOT NULL,
+	factor  INTEGER NOT NULL,
+	time    INTEGER NOT NULL,
+	type    INTEGER NOT NULL
+);
+CREATE TABLE graves (
+	usn  INTEGER NOT NULL,
+	oid  INTEGER NOT NULL,
+	type INTEGER NOT NULL
+);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+// WriteApkg writes d as an .apkg package to path, replacing it
+// atomically.
+func (d *Deck) WriteApkg(path string) error {
+	tmp, err := os.MkdirTemp("", "apkg-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	collection := filepath.Join(tmp, "collection.anki2")
+	if err := d.writeCollection(collection, time.Now()); err != nil {
+		return err
+	}
+
+	f, err := tools.CreateAtomic(path, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	zw := zip.NewWriter(f)
+	if err := addFile(zw, "collection.anki2", collection); err != nil {
+		return err
+	}
+	w, err := zw.Create("media")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "{}"); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+func addFile(zw *zip.Writer, name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, src)
+	return err
+}
+
+// writeCollection creates the collection database of d at path.
+func (d *Deck) writeCollection(path string, now time.Time) error {
+	for i, n := range d.Notes {
+		if len(n.Fields) != len(d.Model.Fields) {
+			return fmt.Errorf("note %d has %d fields, model %s has %d", i+1, len(n.Fields), d.Model.Name, len(d.Model.Fields))
+		}
+	}
+	db, err := sql.Open("sqlite", tools.SQLiteURI(path, ""))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	conf, models, decks, dconf, err := d.colJSON(now)
+	if err != nil {
+		return err
+	}
+	sec, ms := now.Unix(), now.UnixMilli()
+	if _, err := tx.Exec(`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		sec, ms, ms, conf, models, decks, dconf); err != nil {
+		return err
+	}
+
+	note, err := tx.Prepare(`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`)
+	if err != nil {
+		return err
+	}
+	defer note.Close()
+	card, err := tx.Prepare(`INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`)
+	if err != nil {
+		return err
+	}
+	defer card.Close()
+	// note and card ids only need to be unique within the package; Anki
+	// matches imported notes by GUID
+	for i, n := range d.Notes {
+		id := ms + int64(i)
+		tags := ""
+		if len(n.Tags) > 0 {
+			tags = " " + strings.Join(n.Tags, " ") + " "
+		}
+		sortField := stripHTML(n.Fields[0])
+		if _, err := note.Exec(id, n.GUID, d.Model.ID, sec, tags, strings.Join(n.Fields, "\x1f"), sortField, checksum(sortField)); err != nil {
+			return err
+		}
+		if _, err := card.Exec(id, id, d.ID, sec, i+1); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// colJSON returns the JSON columns of the col row: the collection
+// configuration, the model, the deck next to Anki's Default deck, and the
+// default deck options.
+func (d *Deck) colJSON(now time.Time) (conf, models, decks, dconf string, err error) {
+	sec := now.Unix()
+	fields := make([]map[string]any, len(d.Model.Fields))
+	for i, name := range d.Model.Fields {
+		fields[i] = map[string]any{"name": name, "ord": i, "font": "Arial", "size": 20, "media": []any{}, "rtl": false, "sticky": false}
+	}
+	model := map[string]any{
+		"id": d.Model.ID, "name": d.Model.Name, "type": 0, "mod": sec, "usn": -1,
+		"sortf": 0, "did": d.ID, "tags": []any{}, "vers": []any{},
+		"flds": fields,
+		"tmpls": []map[string]any{{
+			"name": "Card 1", "ord": 0, "qfmt": d.Model.Front, "afmt": d.Model.Back,
+			"bqfmt": "", "bafmt": "", "did": nil,
+		}},
+		"req":       []any{[]any{0, "any", []int{0}}},
+		"css":       d.Model.CSS,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+	}
+	deck := func(id int64, name, desc string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "desc": desc, "mod": sec, "usn": -1, "conf": 1, "dyn": 0,
+			"collapsed": false, "browserCollapsed": false, "extendNew": 0, "extendRev": 50,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+	options := map[string]any{
+		"id": 1, "name": "Default", "mod": 0, "usn": 0, "dyn": false, "autoplay": true, "replayq": true, "timer": 0, "maxTaken": 60,
+		"new":   map[string]any{"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500, "order": 1, "perDay": 20, "bury": true, "separate": true},
+		"rev":   map[string]any{"perDay": 100, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500, "minSpace": 1, "bury": true},
+		"lapse": map[string]any{"delays": []int{10}, "leechAction": 0, "leechFails": 8, "minInt": 1, "mult": 0},
+	}
+	collection := map[string]any{
+		"activeDecks": []int64{d.ID}, "curDeck": d.ID, "curModel": strconv.FormatInt(d.Model.ID, 10),
+		"nextPos": len(d.Notes) + 1, "newSpread": 0, "collapseTime": 1200, "timeLim": 0,
+		"estTimes": true, "dueCounts": true, "sortType": "noteFld", "sortBackwards": false, "addToCur": true,
+	}
+	id := func(n int64) string { return strconv.FormatInt(n, 10) }
+	parts := []any{
+		collection,
+		map[string]any{id(d.Model.ID): model},
+		map[string]any{"1": deck(1, "Default", ""), id(d.ID): deck(d.ID, d.Name, d.Description)},
+		map[string]any{"1": options},
+	}
+	out := make([]string, len(parts))
+	for i, p := range parts {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		out[i] = string(data)
+	}
+	return out[0], out[1], out[2], out[3], nil
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// stripHTML is the plain text of a field, as Anki sorts and checksums it.
+func stripHTML(field string) string {
+	return htmlTag.ReplaceAllString(field, "")
+}
+
+// checksum is Anki's note checksum: the first 32 bits of the SHA-1 of the
+// sort field.
+func checksum(field string) int64 {
+	sum := sha1.Sum([]byte(field))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
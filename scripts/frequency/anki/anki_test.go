@@ -0,0 +1,95 @@
+package anki
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestWriteApkg(t *testing.T) {
+	model := Model{ID: ID("model"), Name: "test", Fields: []string{"Word", "Gloss"}, Front: "{{Word}}", Back: "{{Gloss}}"}
+	d := &Deck{ID: ID("deck"), Name: "Pāḷi", Model: model, Notes: []Note{
+		{GUID: "dpd-1", Fields: []string{"<b>dhamma</b>", "nature"}, Tags: []string{"masc"}},
+		{GUID: "dpd-2", Fields: []string{"buddha", "awakened"}},
+	}}
+	dir := t.TempDir()
+	// the collection is built in a temporary directory whose name is a
+	// fragment and a query if left unescaped
+	tmp := filepath.Join(dir, "tmp#1?x")
+	if err := os.Mkdir(tmp, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TMPDIR", tmp)
+	path := filepath.Join(dir, "deck.apkg")
+	if err := d.WriteApkg(path); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+	if string(files["media"]) != "{}" {
+		t.Errorf("media %q", files["media"])
+	}
+	collection := filepath.Join(dir, "collection.anki2")
+	if err := os.WriteFile(collection, files["collection.anki2"], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var models, decks string
+	if err := db.QueryRow(`SELECT models, decks FROM col`).Scan(&models, &decks); err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]struct{ Name string }
+	var ds map[string]struct{ Name string }
+	if err := json.Unmarshal([]byte(models), &m); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(decks), &ds); err != nil {
+		t.Fatal(err)
+	}
+	if m[strconv.FormatInt(model.ID, 10)].Name != "test" || ds[strconv.FormatInt(d.ID, 10)].Name != "Pāḷi" {
+		t.Errorf("models %s, decks %s", models, decks)
+	}
+
+	var sfld, tags string
+	if err := db.QueryRow(`SELECT sfld, tags FROM notes WHERE guid = 'dpd-1'`).Scan(&sfld, &tags); err != nil {
+		t.Fatal(err)
+	}
+	if sfld != "dhamma" || tags != " masc " {
+		t.Errorf("sort field %q, tags %q", sfld, tags)
+	}
+	var cards, due int
+	if err := db.QueryRow(`SELECT COUNT(*), MAX(due) FROM cards WHERE did = ?`, d.ID).Scan(&cards, &due); err != nil {
+		t.Fatal(err)
+	}
+	if cards != 2 || due != 2 {
+		t.Errorf("%d cards, last due %d", cards, due)
+	}
+
+	d.Notes[0].Fields = d.Notes[0].Fields[:1]
+	if err := d.WriteApkg(path); err == nil {
+		t.Error("a note missing a field was written")
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/tools"
+)
+
+// bundleManifest describes a release bundle, in its manifest.json, so an
+// app release can pin the data build it ships and check its files.
+type bundleManifest struct {
+	Version    string   `json:"version"`
+	Created    string   `json:"created"` // RFC 3339, UTC
+	DPDRelease string   `json:"dpd_release,omitempty"`
+	DPDSchema  int      `json:"dpd_schema"`
+	Corpora    []string `json:"corpora"`
+	// the normalizer steps the corpora were counted with, in order
+	Normalizer []string         `json:"normalizer"`
+	Artifacts  []bundleArtifact `json:"artifacts"`
+}
+
+// bundleArtifact is one gzip-compressed file of a bundle.
+type bundleArtifact struct {
+	Name             string `json:"name"` // of the output before compression, e.g. cst_freq.tsv
+	File             string `json:"file"` // in the bundle, e.g. cst_freq.tsv.gz
+	Kind             string `json:"kind"` // see bundleInput
+	Bytes            int64  `json:"bytes"`
+	SHA256           string `json:"sha256"` // of the uncompressed contents
+	CompressedBytes  int64  `json:"compressed_bytes"`
+	CompressedSHA256 string `json:"compressed_sha256"`
+}
+
+// bundleInput is an output of the last run to bundle. kind is one of
+// frequency, lemma_frequency, wordlist, heatmap, master, summary and
+// sentence_bank.
+type bundleInput struct {
+	kind, path string
+}
+
+// bundleInputs lists the outputs the app reads of the corpora names from
+// the output directory, and the sentence bank at sentenceBank, written
+// since the time since. The tables and word lists of each corpus are
+// required, so a corpus the run skipped is not bundled with the outputs of
+// an older one; a heatmap is bundled for the corpora that have one.
+func bundleInputs(names []string, sentenceBank string, since time.Time) ([]bundleInput, error) {
+	var inputs []bundleInput
+	var missing []string
+	need := func(kind, path string) {
+		if !bundled(path, since) {
+			missing = append(missing, path)
+			return
+		}
+		inputs = append(inputs, bundleInput{kind, path})
+	}
+	for _, name := range names {
+		need("frequency", filepath.Join(freqDir, name+"_freq.tsv"))
+		need("lemma_frequency", filepath.Join(freqDir, name+"_lemma_freq.tsv"))
+		need("wordlist", filepath.Join(freqDir, name+"_wordlist.json"))
+		if path := filepath.Join(freqDir, name+"_heatmap.json"); bundled(path, since) {
+			inputs = append(inputs, bundleInput{"heatmap", path})
+		}
+	}
+	need("master", filepath.Join(freqDir, "master_freq.tsv"))
+	need("summary", filepath.Join(freqDir, "corpus_summary.tsv"))
+	need("sentence_bank", sentenceBank)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing from the run or older than it: %s", strings.Join(missing, ", "))
+	}
+	return inputs, nil
+}
+
+// bundled reports whether the run left the file path, modified since the
+// time since.
+func bundled(path string, since time.Time) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.ModTime().Before(since)
+}
+
+// writeBundle compresses inputs into dir, which must not exist yet, and
+// writes m, with the artifacts added, as dir/manifest.json.
+func writeBundle(dir string, m bundleManifest, inputs []bundleInput) (bundleManifest, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return m, err
+	}
+	for _, in := range inputs {
+		a, err := gzipArtifact(dir, in)
+		if err != nil {
+			return m, err
+		}
+		m.Artifacts = append(m.Artifacts, a)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return m, err
+	}
+	return m, tools.WriteFileAtomic(filepath.Join(dir, "manifest.json"), append(data, '\n'), 0o644)
+}
+
+// gzipArtifact writes in, compressed, to dir/<name>.gz and returns its
+// entry of the manifest.
+func gzipArtifact(dir string, in bundleInput) (bundleArtifact, error) {
+	src, err := os.Open(in.path)
+	if err != nil {
+		return bundleArtifact{}, err
+	}
+	defer src.Close()
+	name := filepath.Base(in.path)
+	a := bundleArtifact{Name: name, File: name + ".gz", Kind: in.kind}
+	f, err := tools.CreateAtomic(filepath.Join(dir, a.File), 0o644)
+	if err != nil {
+		return a, err
+	}
+	defer f.Abort()
+
+	// sha256 of both sides, counted as they stream through
+	plain, packed := sha256.New(), sha256.New()
+	out := &countingWriter{w: io.MultiWriter(f, packed)}
+	zw, err := gzip.NewWriterLevel(out, gzip.BestCompression)
+	if err != nil {
+		return a, err
+	}
+	if a.Bytes, err = io.Copy(io.MultiWriter(zw, plain), src); err != nil {
+		return a, fmt.Errorf("%s: %w", in.path, err)
+	}
+	if err := zw.Close(); err != nil {
+		return a, err
+	}
+	if err := f.Commit(); err != nil {
+		return a, err
+	}
+	a.SHA256 = hex.EncodeToString(plain.Sum(nil))
+	a.CompressedBytes = out.n
+	a.CompressedSHA256 = hex.EncodeToString(packed.Sum(nil))
+	return a, nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// runBundle implements the bundle subcommand: it runs freq, heatmap and
+// sentence-bank, then assembles their outputs the app reads into one
+// directory per version, for app releases to pin.
+func runBundle(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("bundle", flag.ExitOnError)
+	commandUsage(fs, "Runs freq, heatmap and sentence-bank and assembles the frequency tables, word lists, heatmaps and sentence bank the app reads into <out>/<version>: gzip-compressed files and a manifest.json of their sizes and checksums.")
+	out := fs.String("out", filepath.Join(freqDir, "bundles"), "directory to write the bundle directory into")
+	version := fs.String("version", time.Now().UTC().Format("2006.01.02"), "version of the data build, the name of its directory")
+	names := fs.String("corpora", strings.Join(masterCorpora, ","), "comma-separated corpora to count and bundle")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database used for the lemmas and the sentence bank")
+	jobs := fs.Int("jobs", 0, "number of files counted concurrently (0: one per CPU)")
+	force := fs.Bool("force", false, "replace an existing bundle of the same version")
+	fs.Parse(args)
+
+	tools.PTitle("building the release bundle " + *version)
+	tic := tools.Tic()
+	if *version == "" || strings.ContainsAny(*version, `/\`) || strings.HasPrefix(*version, ".") {
+		tools.Errorf("-version %q: want a name for a directory, e.g. 2025.05.01", *version)
+		return
+	}
+	list, err := selectCorpora(*names)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	dir := filepath.Join(*out, *version)
+	if _, err := os.Stat(dir); err == nil && !*force {
+		tools.Errorf("%s exists; give another -version or -force to replace it", dir)
+		return
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		tools.Errorf("%v", err)
+		return
+	}
+	db, err := dpd.Open(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	m := bundleManifest{Version: *version, DPDRelease: db.Release(), DPDSchema: db.Schema()}
+	for _, s := range cfg.tokenizer().Normalizer.Steps() {
+		m.Normalizer = append(m.Normalizer, s.String())
+	}
+	db.Close()
+	var sectioned []string
+	for _, c := range list {
+		m.Corpora = append(m.Corpora, c.Name())
+		if _, ok := c.(corpora.SectionTagger); ok {
+			sectioned = append(sectioned, c.Name())
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "palifreq-bundle-")
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer os.RemoveAll(tmp)
+	sentenceBank := filepath.Join(tmp, "sentence_bank.db")
+
+	// each step logs its own errors; any of them stops the bundle
+	step := func(name string, run func(context.Context, []string), args ...string) bool {
+		before := tools.Errors()
+		run(ctx, args)
+		if ctx.Err() != nil {
+			return false
+		}
+		if tools.Errors() > before {
+			tools.Errorf("bundle: %s failed; no bundle written", name)
+			return false
+		}
+		return true
+	}
+	corpusList := strings.Join(m.Corpora, ",")
+	// file times may be coarser than the clock
+	started := time.Now().Truncate(time.Second)
+	freqArgs := []string{"-corpora", corpusList, "-lemmas", "-dpd", *dpdPath, "-output-format", "tsv", "-sink", "file"}
+	if *jobs > 0 {
+		freqArgs = append(freqArgs, "-jobs", fmt.Sprint(*jobs))
+	}
+	if !step("freq", runFreq, freqArgs...) {
+		return
+	}
+	if len(sectioned) > 0 && !step("heatmap", runHeatmap, "-corpora", strings.Join(sectioned, ",")) {
+		return
+	}
+	if !step("sentence-bank", runSentenceBank, "-db", sentenceBank, "-corpora", corpusList, "-dpd", *dpdPath) {
+		return
+	}
+
+	tools.PTitle("assembling " + dir)
+	inputs, err := bundleInputs(m.Corpora, sentenceBank, started)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	// assembled next to its place and renamed into it, so a bundle
+	// directory is never seen half written
+	staging := filepath.Join(*out, "."+*version+".tmp")
+	if err := os.RemoveAll(staging); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	m.Created = time.Now().UTC().Format(time.RFC3339)
+	if m, err = writeBundle(staging, m, inputs); err != nil {
+		os.RemoveAll(staging)
+		tools.Errorf("%v", err)
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	var packed int64
+	for _, a := range m.Artifacts {
+		packed += a.CompressedBytes
+	}
+	tools.Infof("%s: %d files, %s compressed", dir, len(m.Artifacts), byteSize(packed))
+	tic.Toc()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBundle(t *testing.T) {
+	freqDir = t.TempDir()
+	started := time.Now().Add(-time.Minute)
+	files := map[string]string{
+		"cst_freq.tsv":       "word\tcount\nevaṃ\t3\n",
+		"cst_lemma_freq.tsv": "headword_id\tlemma\tcount\n1\tevaṃ 1\t3\n",
+		"cst_wordlist.json":  "[\n  \"evaṃ\"\n]\n",
+		"cst_heatmap.json":   "{}\n",
+		"master_freq.tsv":    "word\trank\nevaṃ\t1\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(freqDir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bank := filepath.Join(t.TempDir(), "sentence_bank.db")
+	if err := os.WriteFile(bank, []byte("not really sqlite"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bundleInputs([]string{"cst"}, bank, started); err == nil || !strings.Contains(err.Error(), "corpus_summary.tsv") {
+		t.Fatalf("without the summary: %v", err)
+	}
+	files["corpus_summary.tsv"] = "corpus\twords\ncst\t1\n"
+	if err := os.WriteFile(filepath.Join(freqDir, "corpus_summary.tsv"), []byte(files["corpus_summary.tsv"]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files["sentence_bank.db"] = "not really sqlite"
+	// a table of an older run, as of a corpus the run skipped
+	old := filepath.Join(freqDir, "cst_freq.tsv")
+	if err := os.Chtimes(old, started.Add(-time.Hour), started.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bundleInputs([]string{"cst"}, bank, started); err == nil || !strings.Contains(err.Error(), "cst_freq.tsv") {
+		t.Fatalf("with an old table: %v", err)
+	}
+	if err := os.Chtimes(old, time.Now(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := bundleInputs([]string{"cst"}, bank, started)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "2025.05.01")
+	if _, err := writeBundle(dir, bundleManifest{Version: "2025.05.01", Corpora: []string{"cst"}}, inputs); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m bundleManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != "2025.05.01" || len(m.Artifacts) != len(files) {
+		t.Fatalf("manifest %+v", m)
+	}
+	for _, a := range m.Artifacts {
+		packed, err := os.ReadFile(filepath.Join(dir, a.File))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum := sha256.Sum256(packed); hex.EncodeToString(sum[:]) != a.CompressedSHA256 || int64(len(packed)) != a.CompressedBytes {
+			t.Errorf("%s: compressed checksum or size differs from the manifest", a.File)
+		}
+		zr, err := gzip.NewReader(strings.NewReader(string(packed)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		plain, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(plain) != files[a.Name] {
+			t.Errorf("%s unpacks to %q, want %q", a.File, plain, files[a.Name])
+		}
+		if sum := sha256.Sum256(plain); hex.EncodeToString(sum[:]) != a.SHA256 || int64(len(plain)) != a.Bytes {
+			t.Errorf("%s: checksum or size differs from the manifest", a.Name)
+		}
+	}
+	if m.Artifacts[3].Kind != "heatmap" {
+		t.Errorf("artifact kinds %+v", m.Artifacts)
+	}
+}
+
+func TestGzipArtifactAbort(t *testing.T) {
+	// a directory opens but does not read
+	dir := t.TempDir()
+	if _, err := gzipArtifact(dir, bundleInput{"frequency", t.TempDir()}); err == nil {
+		t.Fatal("gzipped a directory")
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, "*")); len(left) > 0 {
+		t.Errorf("left %v", left)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// charsetWord is a word of a file with characters that do not belong in
+// Roman Pāḷi.
+type charsetWord struct {
+	word    string
+	count   int
+	illegal []rune // in order of first occurrence
+	fix     string // "" when none is found
+	certain bool
+}
+
+// fileCharset is what the charset check finds in one file.
+type fileCharset struct {
+	path  string
+	words []charsetWord // most frequent first
+}
+
+// checkCharset reads the words of one file as the corpus gives its text,
+// composed to NFC, and keeps those with a character IsPaliRune rejects,
+// punctuation around them trimmed, with the fix SuggestSpelling finds.
+func checkCharset(c corpora.Corpus, path string) (fileCharset, error) {
+	byWord := make(map[string]*charsetWord)
+	err := c.ScanText(path, func(line string) error {
+		for _, w := range strings.Fields(pali.Normalize(line)) {
+			w = strings.TrimFunc(w, unicode.IsPunct)
+			if w == "" {
+				continue
+			}
+			if cw := byWord[w]; cw != nil {
+				cw.count++
+				continue
+			}
+			var illegal []rune
+			for _, r := range w {
+				if !pali.IsPaliRune(r) && !slices.Contains(illegal, r) {
+					illegal = append(illegal, r)
+				}
+			}
+			if illegal == nil {
+				continue
+			}
+			cw := &charsetWord{word: w, count: 1, illegal: illegal}
+			cw.fix, cw.certain = pali.SuggestSpelling(w)
+			byWord[w] = cw
+		}
+		return nil
+	})
+	fc := fileCharset{path: path}
+	for _, w := range slices.Sorted(maps.Keys(byWord)) {
+		fc.words = append(fc.words, *byWord[w])
+	}
+	sort.SliceStable(fc.words, func(i, j int) bool { return fc.words[i].count > fc.words[j].count })
+	return fc, err
+}
+
+// charsetTables gives the words of files outside the Pāḷi character set,
+// by file, and the summary of each file with such words.
+func charsetTables(files []fileCharset) (words, summary table) {
+	words = table{columns: []string{"file", "word", "count", "characters", "fix", "certain"}}
+	summary = table{columns: []string{"file", "words", "tokens", "certain_tokens"}}
+	for _, fc := range files {
+		if len(fc.words) == 0 {
+			continue
+		}
+		tokens, certain := 0, 0
+		for _, cw := range fc.words {
+			codes := make([]string, len(cw.illegal))
+			for i, r := range cw.illegal {
+				codes[i] = fmt.Sprintf("%U", r)
+			}
+			words.rows = append(words.rows, []any{fc.path, cw.word, cw.count, strings.Join(codes, " "), cw.fix, cw.certain})
+			tokens += cw.count
+			if cw.certain {
+				certain += cw.count
+			}
+		}
+		summary.rows = append(summary.rows, []any{fc.path, len(fc.words), tokens, certain})
+	}
+	return words, summary
+}
+
+// runCharset implements the charset subcommand: every word of the corpora
+// checked against the Roman Pāḷi character set, with the violations by
+// file and fixes where one is found.
+func runCharset(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("charset", flag.ExitOnError)
+	commandUsage(fs, "Checks every word of the corpora against the Roman Pāḷi character set and lists those outside it by file, with a fix where one is found, such as ā for the mojibake Ä plus U+0081.")
+	list := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to check")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "number of files checked concurrently")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("checking the character set of the corpora")
+	tic := tools.Tic()
+	selected, err := selectCorpora(*list)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	for _, c := range selected {
+		paths, err := c.Files()
+		if err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			continue
+		}
+		var files []fileCharset
+		prog := tools.NewProgress(c.Name(), len(paths))
+		err = scanInOrder(ctx, paths, *jobs, func(path string) (fileCharset, error) {
+			return checkCharset(c, path)
+		}, func(fc fileCharset) error {
+			prog.Add(1)
+			files = append(files, fc)
+			return nil
+		})
+		if err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			return
+		}
+		prog.Finish()
+
+		words, summary := charsetTables(files)
+		for _, out := range []struct {
+			name string
+			t    table
+		}{
+			{c.Name() + "_charset", words},
+			{c.Name() + "_charset_files", summary},
+		} {
+			if err := sink.Write(out.name, out.t); err != nil {
+				tools.Errorf("%v", err)
+				return
+			}
+		}
+		tokens, certain := 0, 0
+		for _, row := range summary.rows {
+			tokens += row[2].(int)
+			certain += row[3].(int)
+		}
+		tools.Infof("%s: %d words outside the Pāḷi character set in %d of %d files, %d tokens, %d of them with a certain fix (-auto-repair)", c.Name(), len(words.rows), len(summary.rows), len(paths), tokens, certain)
+	}
+	tic.Toc()
+}
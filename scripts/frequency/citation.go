@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"dpd/go_modules/frequency/corpora"
+)
+
+// citedBooks are the books whose suttas are numbered straight through, as
+// SuttaCentral numbers them: dn1–dn34 and mn1–mn152. The suttas of SN and
+// AN are numbered within saṃyuttas and nipātas, with ranges for the
+// peyyāla suttas, which the editions do not mark alike.
+var citedBooks = map[string]bool{corpora.DN: true, corpora.MN: true}
+
+// vaggaTitleRe matches the heading of a chapter of suttas, like
+// "2. sīhanādavaggo", which belongs to no sutta.
+var vaggaTitleRe = regexp.MustCompile(`^[0-9().\s]*(?:\pL+ ){0,3}\pL*vaggo[.:]?$`)
+
+// citeStart is where the numbering stands when a file begins: suttas of
+// its book before it, and paragraphs of the last of them.
+type citeStart struct {
+	sutta, para int
+}
+
+// citations numbers the suttas of the DN and MN mūla files of a corpus, so
+// the paragraphs read from them are cited with SuttaCentral ids: mn10:5 is
+// the fifth paragraph after the title of MN 10, mn10:0 the title.
+type citations struct {
+	starts map[string]citeStart // by path
+}
+
+// loadCitations counts the sutta titles of the DN and MN mūla files of c,
+// read by book in natural order like readSuttas, so each file can be
+// cited on its own, in whichever order files are read.
+func loadCitations(ctx context.Context, c corpora.Corpus) (*citations, error) {
+	files, err := c.Files()
+	if err != nil {
+		return nil, err
+	}
+	files = slices.DeleteFunc(files, func(path string) bool {
+		return corpora.LayerOf(c, path) != corpora.Mula || !citedBooks[corpora.BookOf(c, path)]
+	})
+	slices.SortStableFunc(files, naturalCompare)
+
+	cs := &citations{starts: make(map[string]citeStart, len(files))}
+	at := make(map[string]*fileCiter)
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		book := corpora.BookOf(c, path)
+		fc := at[book]
+		if fc == nil {
+			fc = &fileCiter{book: book}
+			at[book] = fc
+		}
+		cs.starts[path] = citeStart{fc.sutta, fc.para}
+		err := c.ScanText(path, func(line string) error {
+			fc.next(passageText(c, line))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return cs, nil
+}
+
+// file returns the citer of the paragraphs of path, nil when path is not
+// a DN or MN mūla file.
+func (cs *citations) file(c corpora.Corpus, path string) *fileCiter {
+	start, ok := cs.starts[path]
+	if !ok {
+		return nil
+	}
+	return &fileCiter{book: corpora.BookOf(c, path), sutta: start.sutta, para: start.para}
+}
+
+// fileCiter cites the paragraphs of one file, in order.
+type fileCiter struct {
+	book        string
+	sutta, para int
+}
+
+// next returns the SuttaCentral id of the next paragraph of the file, text
+// as passageText gives it, and "" for a blank paragraph, a chapter heading,
+// what comes before the first sutta and every paragraph of a nil citer.
+// The paragraphs are counted as the edition breaks them, which is close
+// to, but not always, how SuttaCentral numbers its sections.
+func (fc *fileCiter) next(text string) string {
+	if fc == nil || text == "" || vaggaTitleRe.MatchString(text) {
+		return ""
+	}
+	if suttaTitle(text) != "" {
+		fc.sutta++
+		fc.para = 0
+	} else if fc.sutta == 0 {
+		return ""
+	} else {
+		fc.para++
+	}
+	return fmt.Sprintf("%s%d:%d", fc.book, fc.sutta, fc.para)
+}
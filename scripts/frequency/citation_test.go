@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dpd/go_modules/frequency/corpora"
+)
+
+func TestCitations(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"dn-1.txt":  "namo tassa bhagavato\n1. brahmajālasuttaṃ\nevaṃ me sutaṃ\nparibbājakakathā\n\n2. sāmaññaphalasuttaṃ\nevaṃ me sutaṃ\n",
+		"dn-2.txt":  "tena kho pana samayena\n1. ambaṭṭhavaggo\n3. ambaṭṭhasuttaṃ\nevaṃ me sutaṃ\n",
+		"dn-10.txt": "4. soṇadaṇḍasuttaṃ\nevaṃ me sutaṃ\n",
+		"sn-1.txt":  "1. oghataraṇasuttaṃ\nevaṃ me sutaṃ\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := corpora.NewBjt(dir)
+	cs, err := loadCitations(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// dn-10 follows dn-2, though it sorts before it, and the first
+	// paragraph of dn-2 continues the last sutta of dn-1
+	for _, tt := range []struct {
+		file string
+		want []string
+	}{
+		{"dn-10.txt", []string{"dn4:0", "dn4:1"}},
+		{"dn-1.txt", []string{"", "dn1:0", "dn1:1", "dn1:2", "", "dn2:0", "dn2:1"}},
+		{"dn-2.txt", []string{"dn2:2", "", "dn3:0", "dn3:1"}},
+		{"sn-1.txt", []string{"", ""}},
+	} {
+		path := filepath.ToSlash(filepath.Join(dir, tt.file))
+		cite := cs.file(c, path)
+		var got []string
+		err := c.ScanText(path, func(line string) error {
+			got = append(got, cite.next(passageText(c, line)))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ids %q, want %q", tt.file, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// collocationTokenizer keeps the elision markers, which end a window, and
+// drops the other non-words.
+var collocationTokenizer = pali.Tokenizer{KeepEditorial: true}
+
+// scanCollocations adds the words of the files of c to co, paragraph by
+// paragraph, each split at its elisions, so a window only holds words
+// printed together.
+func scanCollocations(ctx context.Context, c corpora.Corpus, co *freq.Collocations) error {
+	files, err := c.Files()
+	if err != nil {
+		return err
+	}
+	prog := tools.NewProgress(c.Name(), len(files))
+	defer prog.Finish()
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := c.ScanText(path, func(line string) error {
+			tokens := collocationTokenizer.Tokenize(c.Normalize(line))
+			start := 0
+			for i, w := range tokens {
+				if w == "pe" {
+					co.Add(tokens[start:i])
+					start = i + 1
+				}
+			}
+			co.Add(tokens[start:])
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		prog.Add(1)
+	}
+	return nil
+}
+
+// collocationTable is the table of columns headword_id, lemma, collocate,
+// count, collocate_count, pmi and llr: the first perHeadword collocates of
+// each headword of heads, in their order.
+func collocationTable(heads []freq.LemmaCount, collocates map[int][]freq.Collocate, perHeadword int, excluded map[string]bool) table {
+	t := table{columns: []string{"headword_id", "lemma", "collocate", "count", "collocate_count", "pmi", "llr"}}
+	for _, lc := range heads {
+		n := 0
+		for _, co := range collocates[lc.Headword.ID] {
+			if n == perHeadword {
+				break
+			}
+			if excluded[co.Word] {
+				continue
+			}
+			t.rows = append(t.rows, []any{lc.Headword.ID, lc.Headword.Lemma1, co.Word, co.Count, co.WordCount, co.PMI, co.LogLikelihood})
+			n++
+		}
+	}
+	return t
+}
+
+// runCollocations implements the collocations subcommand: the words most
+// strongly associated with each of the top headwords.
+func runCollocations(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("collocations", flag.ExitOnError)
+	commandUsage(fs, "Writes, for each of the top DPD headwords, the words found most often near its forms compared with chance, by log-likelihood or PMI, into <corpus>_collocations.")
+	names := fs.String("corpora", "cst", "comma-separated corpora to read, one table each")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database")
+	top := fs.Int("top", 1000, "number of headwords, by their counts of the last run")
+	window := fs.Int("window", 4, "words on each side of a form that count as near it")
+	perHeadword := fs.Int("per-headword", 20, "collocates written per headword")
+	minCount := fs.Int("min-count", 5, "times a word must be near a headword to be its collocate")
+	rank := fs.String("rank", "llr", "measure to rank the collocates by: llr (log-likelihood) or pmi")
+	exclude := fs.String("exclude", "", "file of words to leave out as collocates, one per line (see stopwords); they are still counted")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("saving collocations")
+	tic := tools.Tic()
+	if *window < 1 {
+		tools.Errorf("-window %d: want 1 or more", *window)
+		return
+	}
+	if *rank != "llr" && *rank != "pmi" {
+		tools.Errorf("-rank %q: want llr or pmi", *rank)
+		return
+	}
+	list, err := selectCorpora(*names)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	excluded, err := loadExclusions(*exclude)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	lem, err := freq.LoadLemmatizer(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	total, err := corpusTotals(strings.Split(*names, ","))
+	if err != nil {
+		tools.Errorf("%v (count the corpora first)", err)
+		return
+	}
+	heads := lem.Counts(total)
+	if len(heads) > *top {
+		heads = heads[:*top]
+	}
+	nodes := make(map[string][]int)
+	for form, kws := range headwordForms(lem, heads) {
+		for _, k := range kws {
+			nodes[form] = append(nodes[form], k.headwordID)
+		}
+	}
+	tools.Infof("%d headwords, %d forms, window of %d", len(heads), len(nodes), *window)
+
+	for _, c := range list {
+		co := freq.NewCollocations(*window, nodes)
+		if err := scanCollocations(ctx, c, co); err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			return
+		}
+		collocates := co.Collocates(*minCount, *rank == "pmi")
+		t := collocationTable(heads, collocates, *perHeadword, excluded)
+		if err := sink.Write(c.Name()+"_collocations", t); err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			return
+		}
+		tools.Infof("%s: %d words, %d collocates of %d headwords", c.Name(), co.Tokens, len(t.rows), len(collocates))
+	}
+	tic.Toc()
+}
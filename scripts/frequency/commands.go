@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/translit"
+	"dpd/go_modules/tools"
+)
+
+// runFreq implements the freq subcommand.
+func runFreq(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("freq", flag.ExitOnError)
+	commandUsage(fs, "Counts the corpora and writes frequency tables, word lists and the master list to "+freqDir+".")
+	pf := addPipelineFlags(fs)
+	lemmas := fs.Bool("lemmas", false, "also aggregate counts by DPD headword")
+	split := fs.Bool("split", false, "also write tables with sandhi and compounds split by DPD's deconstructor")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database used by -lemmas, -split, -drop-names and -drop-numbers")
+	sf := addSinkFlags(fs, "tsv")
+	ngrams := fs.String("ngrams", "", "comma-separated n-gram sizes to count, e.g. 2,3")
+	ngramMin := fs.Int("ngram-min-count", 2, "minimum count for an n-gram to be written")
+	weights := make(map[string]*float64, len(masterCorpora))
+	for _, name := range masterCorpora {
+		weights[name] = fs.Float64("weight-"+name, 1, "weight of "+name+" in the master list")
+	}
+	weightCustom := fs.Float64("weight-custom", 1, "weight of each -custom-corpus in the master list")
+	masterTop := fs.Int("master-top", 0, "number of words in the master list (0: all)")
+	verse := fs.Bool("verse", false, "also write verse and prose tables for the corpora marking verse (CST, VRI, BJT)")
+	genres := fs.Bool("genres", false, "also write a frequency table per piṭaka and per genre (narrative, doctrinal, verse, vinaya_rule), and the tags of each file")
+	dedup := fs.Bool("dedup", false, "also write a frequency table with the paragraphs repeating an earlier one (peyyāla expansions) weighed down")
+	dedupThreshold := fs.Float64("dedup-threshold", freq.DefaultDedup.Threshold, "share of word shingles a paragraph must share with an earlier one to count as its repeat (0-1)")
+	dedupDamping := fs.Float64("dedup-damping", freq.DefaultDedup.Damping, "weight of a repeated paragraph in the -dedup table: 0 counts a block once, 1 every time")
+	exclude := fs.String("exclude", "", "file of words to leave out of the word lists, one per line (see stopwords)")
+	dropNames := fs.Bool("drop-names", false, "leave the forms of DPD proper nouns out of the word lists")
+	dropNumbers := fs.Bool("drop-numbers", false, "leave numerals and the forms of DPD number words out of the word lists; the tables keep them")
+	provenance := fs.Int("provenance", 0, "also write a table of the first `K` locations (file, line, column) of every word; bypasses the cache")
+	fs.Parse(args)
+
+	p, list, err := pf.pipeline(ctx)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	// stops the profiles on the early returns; finish stops them itself
+	defer p.prof.stop()
+	if p.ngramSizes, err = parseNgramSizes(*ngrams); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	p.ngramMin = *ngramMin
+	p.verse = *verse
+	p.genres = *genres
+	if *provenance < 0 {
+		tools.Errorf("-provenance %d: want 0 or more", *provenance)
+		return
+	}
+	p.provenance = *provenance
+	if *dedup {
+		if *dedupThreshold <= 0 || *dedupThreshold > 1 {
+			tools.Errorf("-dedup-threshold %v: want more than 0 and at most 1", *dedupThreshold)
+			return
+		}
+		if *dedupDamping < 0 || *dedupDamping > 1 {
+			tools.Errorf("-dedup-damping %v: want 0 to 1", *dedupDamping)
+			return
+		}
+		p.dedup = &freq.Dedup{Threshold: *dedupThreshold, Damping: *dedupDamping, MinWords: freq.DefaultDedup.MinWords}
+	}
+	if *pf.dryRun {
+		plan := runPlan{
+			outputs: func(c corpora.Corpus, label string, books []string) []string {
+				out := []string{sf.planned(label + "_freq"), plannedFile(filepath.Join(freqDir, label+"_wordlist.json")), sf.planned(label + "_coverage"), sf.planned(label + "_qa")}
+				if p.provenance > 0 {
+					out = append(out, sf.planned(label+"_provenance"))
+				}
+				for _, b := range books {
+					out = append(out, sf.planned("books/"+label+"_"+b+"_freq"))
+				}
+				if p.genres {
+					for _, pk := range corpora.Pitakas {
+						out = append(out, sf.planned("pitakas/"+label+"_"+pk+"_freq"))
+					}
+					for _, g := range corpora.Genres {
+						out = append(out, sf.planned("genres/"+label+"_"+g+"_freq"))
+					}
+					out = append(out, sf.planned(label+"_file_tags"))
+				}
+				if *dropNumbers {
+					out = append(out, sf.planned(label+"_numbers"))
+				}
+				if p.dedup != nil {
+					out = append(out, sf.planned(label+"_dedup_freq"))
+				}
+				if p.cleaningReport {
+					out = append(out, sf.planned(label+"_cleaning"))
+				}
+				if p.variants != nil {
+					out = append(out, sf.planned(label+"_variants"))
+				}
+				if *split {
+					out = append(out, sf.planned(label+"_split_freq"))
+				}
+				for _, n := range p.ngramSizes {
+					out = append(out, sf.planned(fmt.Sprintf("%s_%dgram_freq", label, n)))
+				}
+				if *lemmas {
+					out = append(out, sf.planned(label+"_lemma_freq"))
+				}
+				if p.verse && corpora.TellsVerse(c) {
+					out = append(out, sf.planned(label+"_verse_freq"), sf.planned(label+"_prose_freq"))
+				}
+				return out
+			},
+			final: []string{sf.planned(p.layered("master") + "_freq"), sf.planned(p.layered("corpus_summary"))},
+		}
+		if *lemmas || *split {
+			plan.needs = append(plan.needs, prerequisite{*dpdPath, "-lemmas and -split"})
+		}
+		if *exclude != "" {
+			plan.needs = append(plan.needs, prerequisite{*exclude, "-exclude"})
+		}
+		if *dropNames {
+			plan.needs = append(plan.needs, prerequisite{*dpdPath, "-drop-names"})
+		}
+		if *dropNumbers {
+			plan.needs = append(plan.needs, prerequisite{*dpdPath, "-drop-numbers"})
+		}
+		p.dryRun(list, plan)
+		return
+	}
+
+	if p.sink, err = sf.open(); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	p.files = true
+
+	tools.PTitle("saving frequency files and word lists")
+	tic := tools.Tic()
+
+	if p.exclude, err = loadExclusions(*exclude); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if *dropNames {
+		if p.exclude, err = excludeNames(p.exclude, *dpdPath); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+	}
+	if *dropNumbers {
+		if p.numbers, err = loadNumberForms(*dpdPath); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+	}
+
+	if *lemmas {
+		if p.lem, err = freq.LoadLemmatizer(*dpdPath); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+	}
+	if *split {
+		if p.split, err = loadSplitter(*dpdPath); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+	}
+
+	totals := p.runAll(list)
+
+	w := make(map[string]float64, len(weights))
+	for name, v := range weights {
+		w[name] = *v
+	}
+	for _, name := range customCorpora {
+		w[name] = *weightCustom
+	}
+	// the corpora an interrupt left out would be missing from it
+	if ctx.Err() == nil {
+		stop := stageWrite.Start()
+		if err := saveMasterList(p.sink, p.layered("master"), totals, w, *masterTop); err != nil {
+			tools.Errorf("master list: %v", err)
+		}
+		if err := saveSummary(p.sink, p.layered("corpus_summary"), list, totals); err != nil {
+			tools.Errorf("corpus summary: %v", err)
+		}
+		stop()
+	}
+
+	tic.Toc()
+	p.finish()
+}
+
+// runWordlist implements the wordlist subcommand.
+func runWordlist(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("wordlist", flag.ExitOnError)
+	commandUsage(fs, "Counts the corpora and writes only <corpus>_wordlist.json to "+freqDir+".")
+	pf := addPipelineFlags(fs)
+	exclude := fs.String("exclude", "", "file of words to leave out of the word lists, one per line (see stopwords)")
+	dropNames := fs.Bool("drop-names", false, "leave the forms of DPD proper nouns out of the word lists")
+	dropNumbers := fs.Bool("drop-numbers", false, "leave numerals and the forms of DPD number words out of the word lists; the tables keep them")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database used by -drop-names and -drop-numbers")
+	fs.Parse(args)
+
+	tools.PTitle("saving word lists")
+	tic := tools.Tic()
+
+	p, list, err := pf.pipeline(ctx)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer p.prof.stop()
+	if *pf.dryRun {
+		plan := runPlan{outputs: func(_ corpora.Corpus, label string, _ []string) []string {
+			return []string{plannedFile(filepath.Join(freqDir, label+"_wordlist.json"))}
+		}}
+		if *exclude != "" {
+			plan.needs = append(plan.needs, prerequisite{*exclude, "-exclude"})
+		}
+		if *dropNames {
+			plan.needs = append(plan.needs, prerequisite{*dpdPath, "-drop-names"})
+		}
+		if *dropNumbers {
+			plan.needs = append(plan.needs, prerequisite{*dpdPath, "-drop-numbers"})
+		}
+		p.dryRun(list, plan)
+		return
+	}
+	if p.exclude, err = loadExclusions(*exclude); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if *dropNames {
+		if p.exclude, err = excludeNames(p.exclude, *dpdPath); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+	}
+	if *dropNumbers {
+		if p.numbers, err = loadNumberForms(*dpdPath); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+	}
+	for name, counts := range p.runAll(list) {
+		stop := stageWrite.Start()
+		path := filepath.Join(freqDir, p.layered(name)+"_wordlist.json")
+		if err := saveWordlist(path, freq.Sorted(counts), p.unlisted); err != nil {
+			tools.Errorf("%s: %v", name, err)
+		} else {
+			p.meta.add(path)
+		}
+		stop()
+	}
+
+	tic.Toc()
+	p.finish()
+}
+
+// runExport implements the export subcommand.
+func runExport(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	commandUsage(fs, "Counts the corpora and writes the word_frequency tables into a SQLite database.")
+	pf := addPipelineFlags(fs)
+	dbPath := fs.String("db", "", "SQLite database to write into, e.g. pali.db (required)")
+	index := fs.Bool("index", false, "also write a word_citation index (word, source file, count)")
+	lemmas := fs.Bool("lemmas", false, "also write lemma_frequency, counts aggregated by DPD headword")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database used by -lemmas")
+	verse := fs.Bool("verse", false, "also write word_frequency rows of the verse and the prose, as corpora <corpus>_verse and <corpus>_prose")
+	romanization := fs.String("romanization", "iast", "romanization of the words and lemmas written: iast, iso15919 (ṁ) or velthuis (ASCII, e.g. aa and .m)")
+	batch := fs.Int("batch", export.BatchRows, "rows inserted by each statement into -db; 4 to 64 write fastest")
+	fs.Parse(args)
+
+	tools.PTitle("exporting frequencies to " + *dbPath)
+	tic := tools.Tic()
+
+	if *dbPath == "" {
+		tools.Errorf("export needs -db")
+		return
+	}
+	if *batch < 1 {
+		tools.Errorf("-batch %d: want 1 or more", *batch)
+		return
+	}
+	export.BatchRows = *batch
+	p, list, err := pf.pipeline(ctx)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer p.prof.stop()
+	p.index = *index
+	p.verse = *verse
+	if p.roman, err = translit.ParseRomanization(*romanization); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if *pf.dryRun {
+		tables := "word_frequency, word_frequency_book"
+		if *lemmas {
+			tables += ", lemma_frequency"
+		}
+		if *index {
+			tables += ", word_citation"
+		}
+		plan := runPlan{
+			outputs: func(c corpora.Corpus, label string, _ []string) []string {
+				out := []string{"rows of " + label + " in " + tables}
+				if p.verse && corpora.TellsVerse(c) {
+					out = append(out, "rows of "+label+"_verse and "+label+"_prose in word_frequency")
+				}
+				return out
+			},
+			final: []string{plannedFile(*dbPath)},
+		}
+		if *lemmas {
+			plan.needs = append(plan.needs, prerequisite{*dpdPath, "-lemmas"})
+		}
+		p.dryRun(list, plan)
+		return
+	}
+	if *lemmas {
+		if p.lem, err = freq.LoadLemmatizer(*dpdPath); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+	}
+	if p.db, err = export.Open(*dbPath); err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+	p.dbPath = *dbPath
+	if err := export.DeferIndexes(p.db); err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+
+	p.runAll(list)
+
+	tic.Toc()
+	p.finish()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// wordSite is a word's total count in a corpus and the first file, in
+// file order, where it occurs.
+type wordSite struct {
+	count int
+	file  string
+}
+
+// loadFileCounts reads the file cache left by the last counting run of a
+// corpus and returns its word counts by file path.
+func loadFileCounts(corpus string) (map[string]map[string]int, error) {
+	counts, err := freq.ReadCache(filepath.Join(freqDir, ".cache", corpus+".gob"))
+	if err == nil {
+		useCorpus(corpus)
+	}
+	return counts, err
+}
+
+// loadWordSites reads the file cache of a corpus and indexes its words.
+func loadWordSites(corpus string) (map[string]wordSite, error) {
+	counts, err := loadFileCounts(corpus)
+	if err != nil {
+		return nil, err
+	}
+	files := slices.Sorted(maps.Keys(counts))
+
+	sites := make(map[string]wordSite)
+	for _, file := range files {
+		for w, n := range counts[file] {
+			s := sites[w]
+			if s.file == "" {
+				s.file = file
+			}
+			s.count += n
+			sites[w] = s
+		}
+	}
+	return sites, nil
+}
+
+// runCompare implements the compare subcommand: it lists the words each
+// corpus has that none of the others has, from the counts of the last run.
+func runCompare(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	commandUsage(fs, "Lists the words found in only one of the corpora, from the counts of the last run.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to compare")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+	tools.PTitle("comparing corpus vocabularies")
+
+	list := strings.Split(*names, ",")
+	sites := make(map[string]map[string]wordSite, len(list))
+	for _, name := range list {
+		s, err := loadWordSites(name)
+		if err != nil {
+			tools.Errorf("%s: %v (count it first)", name, err)
+			return
+		}
+		sites[name] = s
+	}
+	warnMixedChains(list)
+
+	t := table{columns: []string{"corpus", "word", "count", "example_file"}}
+	for _, name := range list {
+		type row struct {
+			word string
+			site wordSite
+		}
+		var unique []row
+	words:
+		for w, s := range sites[name] {
+			for _, other := range list {
+				if other == name {
+					continue
+				}
+				if _, ok := sites[other][w]; ok {
+					continue words
+				}
+			}
+			unique = append(unique, row{w, s})
+		}
+		sort.Slice(unique, func(i, j int) bool {
+			if unique[i].site.count != unique[j].site.count {
+				return unique[i].site.count > unique[j].site.count
+			}
+			return tools.ComparePali(unique[i].word, unique[j].word) < 0
+		})
+		for _, u := range unique {
+			t.rows = append(t.rows, []any{name, u.word, u.site.count, u.site.file})
+		}
+		tools.Infof("%s: %d of %d words found in no other corpus", name, len(unique), len(sites[name]))
+	}
+	if err := sink.Write("compare_unique", t); err != nil {
+		tools.Errorf("%v", err)
+	}
+}
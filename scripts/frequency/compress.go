@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compression is how the file sink compresses tables, named by the
+// extension it adds after the format's: cst_freq.tsv.gz, cst_freq.tsv.zst.
+type compression string
+
+const (
+	compressNone compression = ""
+	compressGzip compression = "gz"
+	compressZstd compression = "zst"
+)
+
+// parseCompression reads the -compress flag: none, gzip or zstd, or the
+// extensions gz and zst.
+func parseCompression(s string) (compression, error) {
+	switch strings.ToLower(s) {
+	case "", "none":
+		return compressNone, nil
+	case "gzip", "gz":
+		return compressGzip, nil
+	case "zstd", "zst":
+		return compressZstd, nil
+	}
+	return "", fmt.Errorf("unknown compression %q (want none, gzip or zstd)", s)
+}
+
+// ext is the extension c adds to a file name, "" for none.
+func (c compression) ext() string {
+	if c == compressNone {
+		return ""
+	}
+	return "." + string(c)
+}
+
+// splitCompression returns path less the extension of a compression, and
+// that compression.
+func splitCompression(path string) (string, compression) {
+	for _, c := range []compression{compressGzip, compressZstd} {
+		if base, ok := strings.CutSuffix(path, c.ext()); ok {
+			return base, c
+		}
+	}
+	return path, compressNone
+}
+
+// compressWriter returns a writer compressing into w with c. Closing it
+// ends the compressed stream but leaves w open.
+func compressWriter(w io.Writer, c compression) (io.WriteCloser, error) {
+	switch c {
+	case compressGzip:
+		return gzip.NewWriter(w), nil
+	case compressZstd:
+		return zstd.NewWriter(w)
+	}
+	return nopWriteCloser{w}, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// magic numbers of the compressed streams
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// openDecompressed opens path for reading, decompressing it when it is
+// gzip or zstd, as its first bytes tell whatever its name, so every reader
+// of tables and word files takes compressed files as they are.
+func openDecompressed(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	head, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return readCloser{zr, func() error { zr.Close(); return f.Close() }}, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return readCloser{zr, func() error { zr.Close(); return f.Close() }}, nil
+	}
+	return readCloser{br, f.Close}, nil
+}
+
+// readCloser reads from one reader and closes with a function of its own.
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error { return r.close() }
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"flag"
+	"runtime"
+	"strings"
+	"sync"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// keyword is one entry of the concordance word list: a surface form, or a
+// DPD headword when headwordID is not zero.
+type keyword struct {
+	word       string
+	headwordID int
+}
+
+// concordancer collects keyword-in-context snippets.
+type concordancer struct {
+	tok     pali.Tokenizer
+	context int                  // words kept on each side of the keyword
+	max     int                  // snippets kept per keyword
+	forms   map[string][]keyword // surface form → keywords it matches
+	jobs    int                  // files scanned at once
+	found   map[keyword]int      // snippets stored so far
+	full    sync.Map             // keywords with max snippets, skipped by the scans
+	cites   *citations           // of the corpus being read
+}
+
+// snippet is a keyword-in-context snippet of a file.
+type snippet struct {
+	k              keyword
+	form, citation string
+	left, right    string
+}
+
+// fileSnippets is the snippets a scan found in one file.
+type fileSnippets struct {
+	source   string
+	snippets []snippet
+}
+
+// snippetKey is what makes a row of sentences unique.
+type snippetKey struct {
+	word, left, form, right string
+}
+
+// readKeywords reads a word list with one form per line, ignoring blank
+// lines and lines starting with #.
+func readKeywords(path string) ([]string, error) {
+	f, err := openDecompressed(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var words []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, pali.Normalize(strings.ToLower(line)))
+		}
+	}
+	return words, sc.Err()
+}
+
+// corpusTotals sums the counts of the last run over the given corpora.
+func corpusTotals(names []string) (map[string]int, error) {
+	total := make(map[string]int)
+	for _, name := range names {
+		sites, err := loadWordSites(name)
+		if err != nil {
+			return nil, err
+		}
+		for w, s := range sites {
+			total[w] += s.count
+		}
+	}
+	return total, nil
+}
+
+// topWords returns the n most frequent words of total.
+func topWords(total map[string]int, n int) []string {
+	list := freq.Sorted(total)
+	if len(list) > n {
+		list = list[:n]
+	}
+	words := make([]string, len(list))
+	for i, wc := range list {
+		words[i] = wc.Word
+	}
+	return words
+}
+
+// headwordForms maps every form of the headwords of heads that DPD knows
+// to their keywords, for matching.
+func headwordForms(lem *freq.Lemmatizer, heads []freq.LemmaCount) map[string][]keyword {
+	chosen := make(map[int]bool, len(heads))
+	for _, lc := range heads {
+		chosen[lc.Headword.ID] = true
+	}
+	forms := make(map[string][]keyword)
+	for form, ids := range lem.Lookup {
+		for _, id := range ids {
+			if chosen[id] {
+				forms[form] = append(forms[form], keyword{lem.Headwords[id].Lemma1, id})
+			}
+		}
+	}
+	return forms
+}
+
+// scanFile returns the snippets of one file. Snippets stay within a
+// paragraph, so they never join unrelated passages, and are cited by its
+// SuttaCentral id in DN and MN. Keywords with all their snippets stored
+// are skipped; add applies the limit to the others.
+func (cn *concordancer) scanFile(c corpora.Corpus, path string) (fileSnippets, error) {
+	fsn := fileSnippets{source: export.SourceID(corpora.RootOf(c), path)}
+	cite := cn.cites.file(c, path)
+	err := c.ScanText(path, func(line string) error {
+		citation := ""
+		if cite != nil {
+			citation = cite.next(passageText(c, line))
+		}
+		tokens := cn.tok.Tokenize(c.Normalize(line))
+		for i, form := range tokens {
+			for _, k := range cn.forms[form] {
+				if _, full := cn.full.Load(k); full {
+					continue
+				}
+				left := strings.Join(tokens[max(i-cn.context, 0):i], " ")
+				right := strings.Join(tokens[i+1:min(i+1+cn.context, len(tokens))], " ")
+				fsn.snippets = append(fsn.snippets, snippet{k, form, citation, left, right})
+			}
+		}
+		return nil
+	})
+	return fsn, err
+}
+
+// add stores the snippets of one file, in order, for the keywords with
+// fewer than max snippets. Snippets already stored, e.g. from another
+// edition, are left out and do not count towards the limit.
+func (cn *concordancer) add(ins *export.Inserter, seen map[snippetKey]bool, corpus string, fsn fileSnippets) error {
+	for _, s := range fsn.snippets {
+		key := snippetKey{s.k.word, s.left, s.form, s.right}
+		if cn.found[s.k] >= cn.max || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if cn.found[s.k]++; cn.found[s.k] == cn.max {
+			cn.full.Store(s.k, true)
+		}
+		var hw any
+		if s.k.headwordID != 0 {
+			hw = s.k.headwordID
+		}
+		if err := ins.Add(s.k.word, hw, s.form, corpus, fsn.source, s.citation, s.left, s.right); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runConcordance implements the concordance subcommand: it stores
+// keyword-in-context snippets for a word list into the sentences table.
+func runConcordance(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("concordance", flag.ExitOnError)
+	commandUsage(fs, "Stores keyword-in-context snippets for a word list in the sentences table.")
+	dbPath := fs.String("db", "", "SQLite database to write the sentences table into (required)")
+	wordsPath := fs.String("words", "", "word list, one form per line (default: the -top most frequent words or lemmas)")
+	top := fs.Int("top", 2000, "number of keywords taken from the last counting run when -words is not given")
+	lemmas := fs.Bool("lemmas", false, "use the -top DPD headwords as keywords and match all their forms")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database used by -lemmas")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to search, in order of preference")
+	contextWords := fs.Int("context", 5, "words of context on each side of the keyword")
+	perWord := fs.Int("max-per-word", 20, "maximum snippets stored per keyword")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "number of files scanned concurrently")
+	fs.Parse(args)
+
+	tools.PTitle("extracting keyword-in-context snippets")
+	tic := tools.Tic()
+	if *dbPath == "" {
+		tools.Errorf("concordance needs -db")
+		return
+	}
+
+	list, err := selectCorpora(*names)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+
+	cn := &concordancer{tok: cfg.tokenizer(), context: *contextWords, max: *perWord, jobs: *jobs, forms: make(map[string][]keyword), found: make(map[keyword]int)}
+	if *lemmas {
+		lem, err := freq.LoadLemmatizer(*dpdPath)
+		if err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+		total, err := corpusTotals(strings.Split(*names, ","))
+		if err != nil {
+			tools.Errorf("%v (count the corpora first)", err)
+			return
+		}
+		heads := lem.Counts(total)
+		if len(heads) > *top {
+			heads = heads[:*top]
+		}
+		cn.forms = headwordForms(lem, heads)
+		tools.Infof("%d headwords, %d forms", len(heads), len(cn.forms))
+	} else {
+		var words []string
+		if *wordsPath != "" {
+			words, err = readKeywords(*wordsPath)
+		} else {
+			var total map[string]int
+			if total, err = corpusTotals(strings.Split(*names, ",")); err == nil {
+				words = topWords(total, *top)
+			}
+		}
+		if err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+		for _, w := range words {
+			cn.forms[w] = []keyword{{word: w}}
+		}
+		tools.Infof("%d keywords", len(words))
+	}
+
+	db, err := export.Open(*dbPath)
+	if err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+	if err := cn.run(ctx, db, list); err != nil {
+		export.Close(db)
+		tools.Errorf("%v", err)
+		return
+	}
+	if err := export.Close(db); err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+	tools.Infof("%d keywords have snippets", len(cn.found))
+	tic.Toc()
+}
+
+// run rebuilds the sentences table from the corpora in list, scanning the
+// files of each -jobs at a time but storing their snippets in file order,
+// so the table does not depend on -jobs. When ctx is done it stops, and
+// the table keeps its previous rows.
+func (cn *concordancer) run(ctx context.Context, db *sql.DB, list []corpora.Corpus) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM sentences`); err != nil {
+		return err
+	}
+	ins := export.NewInserter(tx, `
+		INSERT INTO sentences (word, headword_id, form, corpus, source, citation, left_context, right_context)
+		VALUES`, ``, 8)
+	seen := make(map[snippetKey]bool)
+
+	for _, c := range list {
+		files, err := c.Files()
+		if err != nil {
+			return err
+		}
+		if cn.cites, err = loadCitations(ctx, c); err != nil {
+			return err
+		}
+		prog := tools.NewProgress(c.Name(), len(files))
+		err = scanInOrder(ctx, files, cn.jobs, func(path string) (fileSnippets, error) {
+			return cn.scanFile(c, path)
+		}, func(fsn fileSnippets) error {
+			prog.Add(1)
+			return cn.add(ins, seen, c.Name(), fsn)
+		})
+		if err != nil {
+			return err
+		}
+		prog.Finish()
+	}
+	if err := ins.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
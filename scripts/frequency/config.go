@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"dpd/go_modules/frequency/pali"
+)
+
+// defaultConfigPath is read when present; PALIFREQ_CONFIG names another
+// file, which must then exist.
+const defaultConfigPath = "palifreq.toml"
+
+// config is the contents of palifreq.toml. Paths are relative to the
+// directory palifreq runs in, normally the dpd-db root.
+type config struct {
+	OutputDir string            `toml:"output_dir"`
+	Corpora   map[string]string `toml:"corpora"` // input directory by corpus name
+	Normalize normalizeConfig   `toml:"normalize"`
+	// directory of cleaning rule files, <corpus>.toml, each replacing the
+	// rules shipped for that corpus; "" keeps them all
+	CleaningDir string `toml:"cleaning_dir"`
+	// the DPD release dpd.db must be, e.g. "v0.3.20250501"; "" takes any
+	// release of a schema palifreq reads
+	DPDRelease string `toml:"dpd_release"`
+	// spelling variant rules for -variants; when given they replace
+	// pali.DefaultVariants
+	Variants []pali.VariantRule `toml:"variants"`
+	// archive to fetch by corpus name; a table here replaces the default
+	// source of that corpus as a whole
+	Download map[string]downloadSource `toml:"download"`
+	Sink     sinkConfig                `toml:"sink"`
+}
+
+// sinkConfig configures the output sinks chosen with -sink.
+type sinkConfig struct {
+	HTTP httpSinkConfig `toml:"http"`
+}
+
+// httpSinkConfig is the endpoint of -sink http. Header values may name
+// environment variables, e.g. "Bearer ${PALIFREQ_TOKEN}", so secrets stay
+// out of the file.
+type httpSinkConfig struct {
+	URL       string            `toml:"url"`
+	Headers   map[string]string `toml:"headers"`
+	Retries   int               `toml:"retries"`    // after the first attempt
+	RetryWait string            `toml:"retry_wait"` // before the first retry, doubling after each
+	Timeout   string            `toml:"timeout"`    // per request
+}
+
+// normalizeConfig holds the tokenizer options; flags of the same name
+// still override them.
+type normalizeConfig struct {
+	KeepDandas    bool `toml:"keep_dandas"`
+	KeepParanums  bool `toml:"keep_paranums"`
+	KeepDigits    bool `toml:"keep_digits"`
+	KeepEditorial bool `toml:"keep_editorial"`
+	KeepForeign   bool `toml:"keep_foreign"`
+	// the normalizer steps, in order, e.g. ["nfc", "unify-niggahita",
+	// "lowercase"]; empty for pali.DefaultSteps
+	Chain []string `toml:"chain"`
+}
+
+func defaultConfig() config {
+	return config{
+		OutputDir: "shared_data/frequency",
+		Corpora: map[string]string{
+			"cst":      "resources/dpd_submodules/cst/romn",
+			"bjt":      "resources/dpd_submodules/bjt/public/static/roman_txt",
+			"sya":      "resources/syāmaraṭṭha_1927",
+			"vri":      "resources/tipitaka.org/romn/cscd",
+			"sya_thai": "resources/syāmaraṭṭha_1927_thai",
+			"bjt_sinh": "resources/dpd_submodules/bjt/public/static/text",
+			"cst_mymr": "resources/tipitaka.org/mymr/cscd",
+			"cst_deva": "resources/tipitaka.org/deva/cscd",
+			"khmer":    "resources/khmer_tipitaka",
+		},
+		Normalize: normalizeConfig{KeepEditorial: pali.Default.KeepEditorial},
+		Download:  maps.Clone(defaultSources),
+		Sink:      sinkConfig{HTTP: httpSinkConfig{Retries: 3, RetryWait: "1s", Timeout: "5m"}},
+	}
+}
+
+// loadConfig returns the defaults, overlaid with the config file and then
+// with the environment:
+//
+//	PALIFREQ_OUTPUT_DIR        output_dir
+//	PALIFREQ_DPD_RELEASE       dpd_release
+//	PALIFREQ_CORPUS_<NAME>     corpora.<name>, e.g. PALIFREQ_CORPUS_SYA_THAI
+//	PALIFREQ_KEEP_<OPTION>     normalize.keep_<option>, e.g. PALIFREQ_KEEP_DIGITS=1
+//	PALIFREQ_NORMALIZE         normalize.chain, comma-separated
+func loadConfig() (config, error) {
+	cfg := defaultConfig()
+	path, explicit := os.LookupEnv("PALIFREQ_CONFIG")
+	if !explicit {
+		path = defaultConfigPath
+	}
+	// keys missing from the file, including corpora, keep their defaults
+	if _, err := toml.DecodeFile(path, &cfg); err != nil && (explicit || !errors.Is(err, fs.ErrNotExist)) {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if v := os.Getenv("PALIFREQ_OUTPUT_DIR"); v != "" {
+		cfg.OutputDir = v
+	}
+	if v := os.Getenv("PALIFREQ_DPD_RELEASE"); v != "" {
+		cfg.DPDRelease = v
+	}
+	for name := range cfg.Corpora {
+		if v := os.Getenv("PALIFREQ_CORPUS_" + strings.ToUpper(name)); v != "" {
+			cfg.Corpora[name] = v
+		}
+	}
+	for env, opt := range map[string]*bool{
+		"PALIFREQ_KEEP_DANDAS":    &cfg.Normalize.KeepDandas,
+		"PALIFREQ_KEEP_PARANUMS":  &cfg.Normalize.KeepParanums,
+		"PALIFREQ_KEEP_DIGITS":    &cfg.Normalize.KeepDigits,
+		"PALIFREQ_KEEP_EDITORIAL": &cfg.Normalize.KeepEditorial,
+		"PALIFREQ_KEEP_FOREIGN":   &cfg.Normalize.KeepForeign,
+	} {
+		v, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("%s: %w", env, err)
+		}
+		*opt = b
+	}
+	if v := os.Getenv("PALIFREQ_NORMALIZE"); v != "" {
+		cfg.Normalize.Chain = strings.Split(v, ",")
+	}
+	if _, err := cfg.normalizer(); err != nil {
+		return cfg, fmt.Errorf("normalize.chain: %w", err)
+	}
+	return cfg, nil
+}
+
+// normalizer returns the chain of the normalize options.
+func (c config) normalizer() (pali.Normalizer, error) {
+	return pali.ParseNormalizer(strings.Join(c.Normalize.Chain, ","))
+}
+
+// tokenizer returns the tokenizer the normalize options describe. The
+// chain was checked by loadConfig.
+func (c config) tokenizer() pali.Tokenizer {
+	nz, _ := c.normalizer()
+	return pali.Tokenizer{
+		Normalizer:           nz,
+		KeepDandas:           c.Normalize.KeepDandas,
+		KeepParagraphNumbers: c.Normalize.KeepParanums,
+		KeepDigits:           c.Normalize.KeepDigits,
+		KeepEditorial:        c.Normalize.KeepEditorial,
+		KeepForeign:          c.Normalize.KeepForeign,
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"runtime"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// foreignSpan is a span of a line of a file that Classify finds in a
+// language other than Pāḷi.
+type foreignSpan struct {
+	line     int
+	language pali.Language
+	words    int
+	text     string
+}
+
+// fileContamination is what the contamination check finds in one file.
+type fileContamination struct {
+	path  string
+	words int // all the words of the file
+	spans []foreignSpan
+}
+
+// checkContamination reads the lines of one file of c as passageText gives
+// them, normalized as the default tokenizer does, and keeps the spans
+// Classify finds in Sanskrit or English, which counting drops unless
+// -keep-foreign is given.
+func checkContamination(c corpora.Corpus, path string) (fileContamination, error) {
+	fc := fileContamination{path: path}
+	line := 0
+	err := c.ScanText(path, func(l string) error {
+		line++
+		text := pali.Default.Normalizer.Normalize(passageText(c, l))
+		fc.words += len(pali.WordSpans(text))
+		for _, s := range pali.Classify(text) {
+			fc.spans = append(fc.spans, foreignSpan{line, s.Language, s.Words, text[s.Start:s.End]})
+		}
+		return nil
+	})
+	return fc, err
+}
+
+// contaminationTables gives the foreign spans of the files, in file and
+// line order, and the summary of each file with such spans: the words of
+// each language and their share of the words of the file.
+func contaminationTables(files []fileContamination) (spans, summary table) {
+	spans = table{columns: []string{"file", "line", "language", "words", "text"}}
+	summary = table{columns: []string{"file", "words", "spans", "sanskrit_words", "english_words", "share"}}
+	for _, fc := range files {
+		if len(fc.spans) == 0 {
+			continue
+		}
+		byLanguage := make(map[pali.Language]int)
+		for _, s := range fc.spans {
+			spans.rows = append(spans.rows, []any{fc.path, s.line, s.language.String(), s.words, s.text})
+			byLanguage[s.language] += s.words
+		}
+		foreign := byLanguage[pali.Sanskrit] + byLanguage[pali.English]
+		summary.rows = append(summary.rows, []any{fc.path, fc.words, len(fc.spans), byLanguage[pali.Sanskrit], byLanguage[pali.English], float64(foreign) / float64(max(fc.words, 1))})
+	}
+	return spans, summary
+}
+
+// runContamination implements the contamination subcommand: the Sanskrit
+// quotations and English editorial notes of the corpora, by file, so they
+// can be fixed in the sources.
+func runContamination(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("contamination", flag.ExitOnError)
+	commandUsage(fs, "Lists by file the spans of the corpora a character trigram classifier finds in Sanskrit or English, such as quotations and editorial notes in the commentaries, which counting drops unless -keep-foreign is given.")
+	list := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to check")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "number of files checked concurrently")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("checking the corpora for Sanskrit and English")
+	tic := tools.Tic()
+	selected, err := selectCorpora(*list)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	for _, c := range selected {
+		paths, err := c.Files()
+		if err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			continue
+		}
+		var files []fileContamination
+		prog := tools.NewProgress(c.Name(), len(paths))
+		err = scanInOrder(ctx, paths, *jobs, func(path string) (fileContamination, error) {
+			return checkContamination(c, path)
+		}, func(fc fileContamination) error {
+			prog.Add(1)
+			files = append(files, fc)
+			return nil
+		})
+		if err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			return
+		}
+		prog.Finish()
+
+		spans, summary := contaminationTables(files)
+		for _, out := range []struct {
+			name string
+			t    table
+		}{
+			{c.Name() + "_contamination", spans},
+			{c.Name() + "_contamination_files", summary},
+		} {
+			if err := sink.Write(out.name, out.t); err != nil {
+				tools.Errorf("%v", err)
+				return
+			}
+		}
+		words := 0
+		for _, row := range spans.rows {
+			words += row[3].(int)
+		}
+		tools.Infof("%s: %d foreign spans of %d words in %d of %d files", c.Name(), len(spans.rows), words, len(summary.rows), len(files))
+	}
+	tic.Toc()
+}
@@ -0,0 +1,88 @@
+package corpora
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"dpd/go_modules/frequency/translit"
+)
+
+// Bjt is the Buddha Jayanti Tripiṭaka in Roman script.
+type Bjt struct {
+	dirCorpus
+}
+
+// NewBjt returns the BJT corpus rooted at dir, a tree of romanized .txt files.
+func NewBjt(dir string) *Bjt {
+	return &Bjt{newDirCorpus("bjt", dir, ".txt")}
+}
+
+// Normalize removes page references like [PTS Page 001] and [\q 1/] by the
+// rules of cleaning/bjt.toml.
+func (b *Bjt) Normalize(text string) string {
+	return strings.ToLower(b.clean(text))
+}
+
+// BjtSinhala is the Buddha Jayanti Tripiṭaka in its original Sinhala
+// script, transliterated to Roman on reading, so the BJT counts need not
+// rely on third-party romanizations.
+type BjtSinhala struct {
+	dirCorpus
+}
+
+// NewBjtSinhala returns the Sinhala-script BJT corpus rooted at dir, the
+// tipitaka.lk JSON books (public/static/text/*.json).
+func NewBjtSinhala(dir string) *BjtSinhala {
+	return &BjtSinhala{newDirCorpus("bjt_sinh", dir, ".json")}
+}
+
+// bjtBookFile is the layout of a tipitaka.lk JSON book: pages with a Pāḷi side
+// and a Sinhala translation side, of which only the Pāḷi is read. Each
+// entry has a type such as "paragraph", "heading" or "gatha".
+type bjtBookFile struct {
+	Pages []struct {
+		Pali struct {
+			Entries []struct {
+				Text string `json:"text"`
+				Type string `json:"type"`
+			} `json:"entries"`
+		} `json:"pali"`
+	} `json:"pages"`
+}
+
+// ScanText decodes the whole JSON book before calling fn; the books are
+// split into volumes small enough for that.
+func (b *BjtSinhala) ScanText(path string, fn func(line string) error) error {
+	return b.ScanPassages(path, func(line string, _ bool) error { return fn(line) })
+}
+
+// ScanPassages takes the entries of type gatha for verse.
+func (b *BjtSinhala) ScanPassages(path string, fn func(line string, verse bool) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var book bjtBookFile
+	if err := json.NewDecoder(f).Decode(&book); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, page := range book.Pages {
+		for _, e := range page.Pali.Entries {
+			if err := fn(translit.Sinhala(e.Text), e.Type == "gatha"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Normalize removes footnote references like {1} and bold markers by the
+// rules of cleaning/bjt_sinh.toml.
+func (b *BjtSinhala) Normalize(text string) string {
+	return strings.ToLower(b.clean(text))
+}
+
+func (b *BjtSinhala) Book(path string) string { return bjtBook(path) }
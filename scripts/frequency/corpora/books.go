@@ -0,0 +1,116 @@
+package corpora
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Book keys used for per-book frequency tables.
+const (
+	DN         = "dn"
+	MN         = "mn"
+	SN         = "sn"
+	AN         = "an"
+	KN         = "kn"
+	Vinaya     = "vin"
+	Abhidhamma = "abh"
+	Other      = "other"
+)
+
+// Books lists the book keys in canonical order.
+var Books = []string{Vinaya, DN, MN, SN, AN, KN, Abhidhamma, Other}
+
+// BookTagger is implemented by corpora whose file names tell which book a
+// file belongs to.
+type BookTagger interface {
+	Book(path string) string
+}
+
+// BookOf returns the book of path in c, or Other when c cannot tell.
+func BookOf(c Corpus, path string) string {
+	if t, ok := c.(BookTagger); ok {
+		if b := t.Book(path); b != "" {
+			return b
+		}
+	}
+	return Other
+}
+
+// cstBook tags CST/VRI file names: s01.. to s05.. are the five nikāyas,
+// vin.. the Vinaya, abh.. the Abhidhamma and e.. the añña texts.
+func cstBook(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasPrefix(name, "s01"):
+		return DN
+	case strings.HasPrefix(name, "s02"):
+		return MN
+	case strings.HasPrefix(name, "s03"):
+		return SN
+	case strings.HasPrefix(name, "s04"):
+		return AN
+	case strings.HasPrefix(name, "s05"):
+		return KN
+	case strings.HasPrefix(name, "vin"):
+		return Vinaya
+	case strings.HasPrefix(name, "abh"):
+		return Abhidhamma
+	}
+	return Other
+}
+
+// bjtBook tags BJT file names, which start with the book abbreviation:
+// dn-1.txt, mn-1-1.txt, vp-mv.txt (Vinaya piṭaka), ap-dhs.txt (Abhidhamma).
+func bjtBook(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	prefix, _, _ := strings.Cut(name, "-")
+	switch prefix {
+	case "dn", "mn", "sn", "an", "kn":
+		return prefix
+	case "vp":
+		return Vinaya
+	case "ap":
+		return Abhidhamma
+	}
+	return Other
+}
+
+// syaBook tags the Siam edition by volume number, the leading digits of the
+// file name: 1–8 Vinaya, 9–11 DN, 12–14 MN, 15–19 SN, 20–24 AN, 25–33 KN and
+// 34–45 Abhidhamma.
+func syaBook(path string) string {
+	name := filepath.Base(path)
+	end := 0
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+	vol, err := strconv.Atoi(name[:end])
+	if err != nil {
+		return Other
+	}
+	switch {
+	case vol < 1:
+		return Other
+	case vol <= 8:
+		return Vinaya
+	case vol <= 11:
+		return DN
+	case vol <= 14:
+		return MN
+	case vol <= 19:
+		return SN
+	case vol <= 24:
+		return AN
+	case vol <= 33:
+		return KN
+	case vol <= 45:
+		return Abhidhamma
+	}
+	return Other
+}
+
+func (c *Cst) Book(path string) string { return cstBook(path) }
+func (v *Vri) Book(path string) string { return cstBook(path) }
+func (b *Bjt) Book(path string) string { return bjtBook(path) }
+func (s *Sya) Book(path string) string { return syaBook(path) }
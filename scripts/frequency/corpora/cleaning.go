@@ -0,0 +1,171 @@
+package corpora
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/BurntSushi/toml"
+)
+
+// CleaningRule is one step of cleaning the text of an edition before it is
+// tokenized, such as removing page references or footnote numbers.
+type CleaningRule struct {
+	Name    string `toml:"name"`    // short key used in reports, e.g. "footnotes"
+	Pattern string `toml:"pattern"` // regular expression matched within a line
+	Replace string `toml:"replace"` // replacement; $1 refers to a group of Pattern
+}
+
+// cleaningFile is the layout of a rule file: the rules in the order they
+// are applied, each a [[rule]] table.
+type cleaningFile struct {
+	Rules []CleaningRule `toml:"rule"`
+}
+
+// shippedCleaning holds the rule files of the editions, cleaning/<name>.toml,
+// used unless the cleaning directory of palifreq.toml has its own.
+//
+//go:embed cleaning/*.toml
+var shippedCleaning embed.FS
+
+// Cleaner applies cleaning rules, in order, to the lines of an edition and
+// counts how often each matched. It is safe for concurrent use.
+type Cleaner struct {
+	rules []CleaningRule
+	res   []*regexp.Regexp
+	fired []atomic.Int64
+}
+
+// NewCleaner compiles rules.
+func NewCleaner(rules []CleaningRule) (*Cleaner, error) {
+	c := &Cleaner{rules: rules, fired: make([]atomic.Int64, len(rules))}
+	for _, r := range rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("cleaning rule %q: %w", r.Name, err)
+		}
+		c.res = append(c.res, re)
+	}
+	return c, nil
+}
+
+// parseCleaningRules parses the rule file read from path.
+func parseCleaningRules(data []byte, path string) ([]CleaningRule, error) {
+	var f cleaningFile
+	if _, err := toml.Decode(string(data), &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, r := range f.Rules {
+		if r.Name == "" || r.Pattern == "" {
+			return nil, fmt.Errorf("%s: rule %d needs a name and a pattern", path, i+1)
+		}
+	}
+	return f.Rules, nil
+}
+
+// LoadCleaner returns the cleaner of the edition name: from dir/<name>.toml
+// when dir is set and has that file, or else from the rules shipped with
+// the edition. An edition with neither cleans nothing. It also returns the
+// file the rules came from, or "" for none.
+func LoadCleaner(name, dir string) (*Cleaner, string, error) {
+	var path string
+	var data []byte
+	err := fs.ErrNotExist
+	if dir != "" {
+		path = filepath.Join(dir, name+".toml")
+		data, err = os.ReadFile(path)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		path = "cleaning/" + name + ".toml"
+		if data, err = shippedCleaning.ReadFile(path); errors.Is(err, fs.ErrNotExist) {
+			c, _ := NewCleaner(nil)
+			return c, "", nil
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	rules, err := parseCleaningRules(data, path)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := NewCleaner(rules)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	return c, path, nil
+}
+
+// shippedCleaner returns the cleaner of the rules shipped with the edition
+// name. They are part of the program, so an error in them panics.
+func shippedCleaner(name string) *Cleaner {
+	c, _, err := LoadCleaner(name, "")
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Clean applies the rules to text. A nil Cleaner returns text unchanged.
+func (c *Cleaner) Clean(text string) string {
+	if c == nil {
+		return text
+	}
+	for i, re := range c.res {
+		matches := re.FindAllStringSubmatchIndex(text, -1)
+		if matches == nil {
+			continue
+		}
+		c.fired[i].Add(int64(len(matches)))
+		var b []byte
+		last := 0
+		for _, m := range matches {
+			b = append(b, text[last:m[0]]...)
+			b = re.ExpandString(b, c.rules[i].Replace, text, m)
+			last = m[1]
+		}
+		text = string(append(b, text[last:]...))
+	}
+	return text
+}
+
+// Rules returns the rules in the order they are applied.
+func (c *Cleaner) Rules() []CleaningRule {
+	if c == nil {
+		return nil
+	}
+	return c.rules
+}
+
+// Fired returns how many matches each rule has replaced so far, in the
+// order of Rules.
+func (c *Cleaner) Fired() []int64 {
+	if c == nil {
+		return nil
+	}
+	n := make([]int64, len(c.fired))
+	for i := range c.fired {
+		n[i] = c.fired[i].Load()
+	}
+	return n
+}
+
+// Cleaned is implemented by the corpora whose cleaning rules can be read and
+// replaced, as every edition of this package does.
+type Cleaned interface {
+	Cleaner() *Cleaner
+	SetCleaner(c *Cleaner)
+}
+
+// CleanerOf returns the cleaner of c, or nil when it has none.
+func CleanerOf(c Corpus) *Cleaner {
+	if cc, ok := c.(Cleaned); ok {
+		return cc.Cleaner()
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Package corpora describes the Pāḷi text editions the frequency pipeline
+// counts. Each edition implements Corpus; adding another edition means
+// writing one implementation and registering it.
+package corpora
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"dpd/go_modules/frequency/cstxml"
+	"dpd/go_modules/tools"
+)
+
+// Corpus is one edition of the texts.
+type Corpus interface {
+	// Name is the short lowercase key used in output file names, e.g. "cst".
+	Name() string
+	// Files lists the source files to count, in a stable order.
+	Files() ([]string, error)
+	// ScanText calls fn with each line (paragraph) of the running text of
+	// one file, in order, without holding the whole file in memory. It
+	// stops at the first error fn returns.
+	ScanText(path string, fn func(line string) error) error
+	// Normalize applies the edition's cleaning rules to a line from ScanText.
+	Normalize(text string) string
+}
+
+// Rooted is implemented by corpora stored under one directory.
+type Rooted interface {
+	Root() string
+}
+
+// RootOf returns the directory c is stored under, or "" when it has none.
+func RootOf(c Corpus) string {
+	if r, ok := c.(Rooted); ok {
+		return r.Root()
+	}
+	return ""
+}
+
+var registry []Corpus
+
+// Register adds c to the corpora returned by Registered.
+// Registering a second corpus with the same name replaces the first.
+func Register(c Corpus) {
+	for i, r := range registry {
+		if r.Name() == c.Name() {
+			registry[i] = c
+			return
+		}
+	}
+	registry = append(registry, c)
+}
+
+// Registered returns the registered corpora in registration order.
+func Registered() []Corpus {
+	return append([]Corpus(nil), registry...)
+}
+
+// Get returns the registered corpus with the given name.
+func Get(name string) (Corpus, bool) {
+	for _, c := range registry {
+		if c.Name() == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// dirCorpus is the common base of corpora stored as a directory tree of
+// files with one extension.
+type dirCorpus struct {
+	name    string
+	dir     string
+	ext     string
+	cleaner *Cleaner
+}
+
+// newDirCorpus returns the base of the edition name, with the cleaning
+// rules shipped for it.
+func newDirCorpus(name, dir, ext string) dirCorpus {
+	return dirCorpus{name: name, dir: dir, ext: ext, cleaner: shippedCleaner(name)}
+}
+
+func (d dirCorpus) Name() string { return d.name }
+
+func (d dirCorpus) Root() string { return d.dir }
+
+func (d dirCorpus) Cleaner() *Cleaner { return d.cleaner }
+
+func (d *dirCorpus) SetCleaner(c *Cleaner) { d.cleaner = c }
+
+// clean applies the cleaning rules of the edition to text.
+func (d dirCorpus) clean(text string) string { return d.cleaner.Clean(text) }
+
+// Files walks the corpus directory and returns all files with the corpus
+// extension, in any case, sorted by path. The paths are slash-separated on
+// every platform, which the os functions take as they are, so the caches
+// and the file columns of the outputs read the same wherever the corpus
+// was counted.
+func (d dirCorpus) Files() ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(d.dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !e.IsDir() && HasExt(e.Name(), d.ext) {
+			paths = append(paths, filepath.ToSlash(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// HasExt reports whether the file name ends in the extension ext, in any
+// case: copies of the editions made on Windows or macOS may name their
+// files .XML or .Txt.
+func HasExt(name, ext string) bool {
+	return len(name) >= len(ext) && strings.EqualFold(name[len(name)-len(ext):], ext)
+}
+
+// maxLine bounds the length of one line of a text file.
+const maxLine = 16 << 20
+
+// ScanText reads the file as UTF-8, without its byte order mark, or as
+// UTF-16 when its mark says so. Bytes that are not valid UTF-8 are read as
+// Windows-1252, with a warning of the file, or fail it under
+// StrictEncoding.
+func (d dirCorpus) ScanText(path string, fn func(line string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(cstxml.NewReader(f))
+	sc.Buffer(make([]byte, 64<<10), maxLine)
+	var enc encodingReport
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if StrictEncoding && !utf8.ValidString(line) {
+			return fmt.Errorf("%s: line %d: not valid UTF-8 (-strict-encoding)", path, n)
+		}
+		if err := fn(enc.fix(line, n)); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if enc.lines > 0 {
+		tools.Warnf("%s: %d bytes on %d lines not valid UTF-8, read as Windows-1252 (first on line %d)", path, enc.bad, enc.lines, enc.first)
+	}
+	if enc.boms > 0 {
+		tools.Warnf("%s: %d byte order marks within the text, removed", path, enc.boms)
+	}
+	return nil
+}
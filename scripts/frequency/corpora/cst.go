@@ -0,0 +1,82 @@
+package corpora
+
+import (
+	"strings"
+
+	"dpd/go_modules/frequency/cstxml"
+	"dpd/go_modules/frequency/translit"
+)
+
+// Cst is the Chaṭṭha Saṅgāyana edition, read straight from its XML sources.
+type Cst struct {
+	dirCorpus
+}
+
+// NewCst returns the CST corpus rooted at dir, a directory of CST4 .xml
+// files such as the romn folder of the tipitaka.org release.
+func NewCst(dir string) *Cst {
+	return &Cst{newDirCorpus("cst", dir, ".xml")}
+}
+
+func (c *Cst) ScanText(path string, fn func(line string) error) error {
+	return cstxml.ScanFile(path, func(p cstxml.Paragraph) error { return fn(p.Text) })
+}
+
+func (c *Cst) Normalize(text string) string {
+	return strings.ToLower(c.clean(text))
+}
+
+// CstMyanmar is the Chaṭṭha Saṅgāyana edition in Myanmar script, the
+// script it was recited and first printed in, transliterated to Roman on
+// reading. Counting it next to Cst cross-checks the Roman conversion of
+// the CST sources.
+type CstMyanmar struct {
+	dirCorpus
+}
+
+// NewCstMyanmar returns the Myanmar-script CST corpus rooted at dir, a
+// directory of .xml books such as https://tipitaka.org/mymr/cscd/. File
+// names follow the Roman release, so books, layers and sections are
+// tagged the same way.
+func NewCstMyanmar(dir string) *CstMyanmar {
+	return &CstMyanmar{newDirCorpus("cst_mymr", dir, ".xml")}
+}
+
+func (c *CstMyanmar) ScanText(path string, fn func(line string) error) error {
+	return cstxml.ScanFile(path, func(p cstxml.Paragraph) error { return fn(translit.Myanmar(p.Text)) })
+}
+
+func (c *CstMyanmar) Normalize(text string) string {
+	return strings.ToLower(c.clean(text))
+}
+
+func (c *CstMyanmar) Book(path string) string    { return cstBook(path) }
+func (c *CstMyanmar) Layer(path string) string   { return FileLayer(path) }
+func (c *CstMyanmar) Section(path string) string { return cstSection(path) }
+
+// CstDevanagari is the Chaṭṭha Saṅgāyana edition in Devanagari script,
+// transliterated to Roman on reading. Like CstMyanmar it cross-checks the
+// Roman conversion of the CST sources, and stands in for Roman files that
+// are corrupt.
+type CstDevanagari struct {
+	dirCorpus
+}
+
+// NewCstDevanagari returns the Devanagari-script CST corpus rooted at dir,
+// a directory of .xml books such as https://tipitaka.org/deva/cscd/, named
+// like the Roman release.
+func NewCstDevanagari(dir string) *CstDevanagari {
+	return &CstDevanagari{newDirCorpus("cst_deva", dir, ".xml")}
+}
+
+func (c *CstDevanagari) ScanText(path string, fn func(line string) error) error {
+	return cstxml.ScanFile(path, func(p cstxml.Paragraph) error { return fn(translit.Devanagari(p.Text)) })
+}
+
+func (c *CstDevanagari) Normalize(text string) string {
+	return strings.ToLower(c.clean(text))
+}
+
+func (c *CstDevanagari) Book(path string) string    { return cstBook(path) }
+func (c *CstDevanagari) Layer(path string) string   { return FileLayer(path) }
+func (c *CstDevanagari) Section(path string) string { return cstSection(path) }
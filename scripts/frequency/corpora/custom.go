@@ -0,0 +1,39 @@
+package corpora
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Custom is a corpus of the user's own: a tree of UTF-8 .txt files of
+// Roman Pāḷi, such as a chanting book or a graded reader, counted like the
+// editions so its vocabulary can be compared with theirs. Each line of a
+// file is a paragraph; the files count towards the Other book.
+type Custom struct {
+	dirCorpus
+}
+
+// customName is what a custom corpus may be called: a lowercase key fit
+// for the output file names.
+var customName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// NewCustom returns the custom corpus name rooted at dir. The name must be
+// a lowercase key of letters, digits and underscores, and no registered
+// corpus may have it already.
+func NewCustom(name, dir string) (*Custom, error) {
+	if !customName.MatchString(name) {
+		return nil, fmt.Errorf("custom corpus name %q: want lowercase letters, digits and underscores", name)
+	}
+	if _, ok := Get(name); ok {
+		return nil, fmt.Errorf("custom corpus name %q is taken by another corpus", name)
+	}
+	return &Custom{newDirCorpus(name, dir, ".txt")}, nil
+}
+
+func (c *Custom) Normalize(text string) string {
+	return strings.ToLower(c.clean(text))
+}
+
+// A custom corpus is taken as canonical text: the layer filters keep it.
+func (c *Custom) Layer(string) string { return Mula }
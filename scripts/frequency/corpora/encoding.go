@@ -0,0 +1,72 @@
+package corpora
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// StrictEncoding, when set, makes a line of a text file that is not valid
+// UTF-8 an error of the file, instead of reading its stray bytes as
+// Windows-1252. palifreq sets it from -strict-encoding.
+var StrictEncoding bool
+
+// cp1252 are the characters Windows-1252 gives the bytes 0x80 to 0x9f;
+// the five bytes it leaves undefined read as the C1 controls of Latin-1,
+// which the other bytes follow.
+var cp1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// transcode returns line with every byte that is not part of valid UTF-8
+// read as Windows-1252, the encoding a stretch of a file saved by a Latin-1
+// editor most likely has, and the number of those bytes. The valid UTF-8
+// around them is kept.
+func transcode(line string) (string, int) {
+	var b strings.Builder
+	b.Grow(len(line) + len(line)/2)
+	bad := 0
+	for i := 0; i < len(line); {
+		r, n := utf8.DecodeRuneInString(line[i:])
+		if r == utf8.RuneError && n == 1 {
+			bad++
+			if c := line[i]; c >= 0x80 && c < 0xa0 {
+				b.WriteRune(cp1252[c-0x80])
+			} else {
+				b.WriteRune(rune(c))
+			}
+			i++
+			continue
+		}
+		b.WriteString(line[i : i+n])
+		i += n
+	}
+	return b.String(), bad
+}
+
+// encodingReport gathers what reading a text file had to repair, for the
+// warning of the file.
+type encodingReport struct {
+	bad, lines int // bytes not valid UTF-8, and the lines holding them
+	first      int // line number of the first of them
+	boms       int // byte order marks within the text
+}
+
+// fix returns line, line number n of its file, in valid UTF-8 without
+// byte order marks, recording what it changed.
+func (e *encodingReport) fix(line string, n int) string {
+	if !utf8.ValidString(line) {
+		var bad int
+		line, bad = transcode(line)
+		if e.lines == 0 {
+			e.first = n
+		}
+		e.bad += bad
+		e.lines++
+	}
+	if strings.ContainsRune(line, '\uFEFF') {
+		e.boms += strings.Count(line, "\uFEFF")
+		line = strings.ReplaceAll(line, "\uFEFF", "")
+	}
+	return line
+}
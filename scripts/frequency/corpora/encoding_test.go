@@ -0,0 +1,51 @@
+package corpora
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestScanTextEncoding(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	scan := func(path string) ([]string, error) {
+		var lines []string
+		err := NewSya(dir).ScanText(path, func(line string) error {
+			lines = append(lines, line)
+			return nil
+		})
+		return lines, err
+	}
+
+	// a BOM, a stray BOM where two files were joined and a line saved as
+	// Windows-1252, its ā lost but its quotes and é kept
+	mixed := write("mixed.txt", []byte("\xef\xbb\xbfevaṃ me sutaṃ\nekaṃ \uFEFFsamayaṃ\n\x93bhagav\xe9\x94 \x85\n"))
+	want := []string{"evaṃ me sutaṃ", "ekaṃ samayaṃ", "“bhagavé” …"}
+	if got, err := scan(mixed); err != nil || !slices.Equal(got, want) {
+		t.Errorf("ScanText = %q, %v; want %q", got, err, want)
+	}
+
+	// UTF-16 with its byte order mark
+	utf16 := []byte{0xff, 0xfe}
+	for _, r := range "evaṃ\nme" {
+		utf16 = append(utf16, byte(r), byte(r>>8))
+	}
+	if got, err := scan(write("utf16.txt", utf16)); err != nil || !slices.Equal(got, []string{"evaṃ", "me"}) {
+		t.Errorf("ScanText of UTF-16 = %q, %v", got, err)
+	}
+
+	StrictEncoding = true
+	defer func() { StrictEncoding = false }()
+	if _, err := scan(mixed); err == nil || !strings.Contains(err.Error(), "line 3: not valid UTF-8") {
+		t.Errorf("ScanText under StrictEncoding: %v, want line 3 not valid UTF-8", err)
+	}
+}
@@ -0,0 +1,79 @@
+package corpora
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Piṭaka keys used for per-piṭaka frequency tables.
+const (
+	PitakaVinaya     = "vinaya"
+	PitakaSutta      = "sutta"
+	PitakaAbhidhamma = "abhidhamma"
+)
+
+// Pitakas lists the piṭaka keys in canonical order.
+var Pitakas = []string{PitakaVinaya, PitakaSutta, PitakaAbhidhamma}
+
+// PitakaOf returns the piṭaka of path in c, from its book, or "" for the
+// files of no book.
+func PitakaOf(c Corpus, path string) string {
+	switch BookOf(c, path) {
+	case Vinaya:
+		return PitakaVinaya
+	case DN, MN, SN, AN, KN:
+		return PitakaSutta
+	case Abhidhamma:
+		return PitakaAbhidhamma
+	}
+	return ""
+}
+
+// Genre keys used for per-genre frequency tables.
+const (
+	Narrative  = "narrative"
+	Doctrinal  = "doctrinal"
+	Verse      = "verse"
+	VinayaRule = "vinaya_rule"
+)
+
+// Genres lists the genre keys in the order of their tables.
+var Genres = []string{Narrative, Doctrinal, Verse, VinayaRule}
+
+// genreTable is the mapping of sections and books to genres, maintained in
+// genres.tsv.
+//
+//go:embed genres.tsv
+var genreTable string
+
+// genres maps the keys of genreTable to their genre.
+var genres = parseGenres(genreTable)
+
+// parseGenres parses the lines "key<TAB>genre<TAB>contents" of a genre
+// table, skipping blank lines and # comments. The table is part of the
+// program, so an error in it panics.
+func parseGenres(table string) map[string]string {
+	m := make(map[string]string)
+	for i, line := range strings.Split(table, "\n") {
+		if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		if len(f) < 2 || !slices.Contains(Genres, f[1]) {
+			panic(fmt.Sprintf("genres.tsv:%d: want key, genre (one of %s) and contents", i+1, strings.Join(Genres, ", ")))
+		}
+		m[f[0]] = f[1]
+	}
+	return m
+}
+
+// GenreOf returns the genre of path in c: that of its section, else that
+// of its book, or "" when genres.tsv has neither.
+func GenreOf(c Corpus, path string) string {
+	if g, ok := genres[SectionOf(c, path)]; ok {
+		return g
+	}
+	return genres[BookOf(c, path)]
+}
@@ -0,0 +1,34 @@
+package corpora
+
+import (
+	"strings"
+
+	"dpd/go_modules/frequency/translit"
+)
+
+// Khmer is the Cambodian edition (Phnom Penh, 1931–1969) in Khmer script,
+// transliterated to Roman on reading, so the Southeast Asian editions can be
+// compared with one another and with CST.
+type Khmer struct {
+	dirCorpus
+}
+
+// NewKhmer returns the Khmer corpus rooted at dir, a tree of UTF-8 .txt
+// files holding the Pāḷi text only; the Khmer translation printed facing
+// it in the edition must be left out, or its words are counted as Pāḷi.
+// File names do not follow a shared scheme, so every file counts towards
+// the Other book.
+func NewKhmer(dir string) *Khmer {
+	return &Khmer{newDirCorpus("khmer", dir, ".txt")}
+}
+
+func (k *Khmer) ScanText(path string, fn func(line string) error) error {
+	return k.dirCorpus.ScanText(path, func(line string) error { return fn(translit.Khmer(line)) })
+}
+
+func (k *Khmer) Normalize(text string) string {
+	return strings.ToLower(k.clean(text))
+}
+
+// The edition holds the canonical texts only.
+func (k *Khmer) Layer(string) string { return Mula }
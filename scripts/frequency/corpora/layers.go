@@ -0,0 +1,53 @@
+package corpora
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Layers of the CST/VRI file naming scheme, taken from the second
+// extension: s0101m.mul.xml, s0101a.att.xml, s0101t.tik.xml, e0101n.nrf.xml.
+const (
+	Mula       = "mul"
+	Atthakatha = "att"
+	Tika       = "tik"
+	Anna       = "nrf"
+)
+
+// Layers lists the layer keys in canonical order.
+var Layers = []string{Mula, Atthakatha, Tika, Anna}
+
+// FileLayer returns the layer of a CST/VRI file name, in any case, or ""
+// when the name does not follow the scheme.
+func FileLayer(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	switch layer := strings.TrimPrefix(filepath.Ext(base), "."); layer {
+	case Mula, Atthakatha, Tika, Anna:
+		return layer
+	}
+	return ""
+}
+
+// LayerTagger is implemented by corpora that can tell the layer of a file.
+type LayerTagger interface {
+	Layer(path string) string
+}
+
+// LayerOf returns the layer of path in c, or "" when c cannot tell.
+func LayerOf(c Corpus, path string) string {
+	if t, ok := c.(LayerTagger); ok {
+		return t.Layer(path)
+	}
+	return ""
+}
+
+func (c *Cst) Layer(path string) string { return FileLayer(path) }
+func (v *Vri) Layer(path string) string { return FileLayer(path) }
+
+// The BJT and SYA editions hold the canonical texts only.
+
+func (b *Bjt) Layer(string) string        { return Mula }
+func (b *BjtSinhala) Layer(string) string { return Mula }
+func (s *Sya) Layer(string) string        { return Mula }
+func (s *SyaThai) Layer(string) string    { return Mula }
@@ -0,0 +1,37 @@
+package corpora
+
+import (
+	"dpd/go_modules/frequency/cstxml"
+	"dpd/go_modules/frequency/translit"
+)
+
+// ParagraphScanner is implemented by corpora read from CST XML, whose
+// paragraphs keep their rend and number, the structural markup of the
+// books, nikāya, vagga and sutta headings included.
+type ParagraphScanner interface {
+	// ScanParagraphs is ScanText with the markup of each paragraph, its
+	// text in Roman script.
+	ScanParagraphs(path string, fn func(cstxml.Paragraph) error) error
+}
+
+func (c *Cst) ScanParagraphs(path string, fn func(cstxml.Paragraph) error) error {
+	return cstxml.ScanFile(path, fn)
+}
+
+func (c *CstMyanmar) ScanParagraphs(path string, fn func(cstxml.Paragraph) error) error {
+	return cstxml.ScanFile(path, func(p cstxml.Paragraph) error {
+		p.Text = translit.Myanmar(p.Text)
+		return fn(p)
+	})
+}
+
+func (c *CstDevanagari) ScanParagraphs(path string, fn func(cstxml.Paragraph) error) error {
+	return cstxml.ScanFile(path, func(p cstxml.Paragraph) error {
+		p.Text = translit.Devanagari(p.Text)
+		return fn(p)
+	})
+}
+
+func (v *Vri) ScanParagraphs(path string, fn func(cstxml.Paragraph) error) error {
+	return cstxml.ScanFile(path, fn)
+}
@@ -0,0 +1,112 @@
+package corpora
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// The paths below are built with filepath, so the tests hold with the
+// separators of any platform.
+
+func TestFilesPaths(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		filepath.Join("romn", "s0101m.mul.xml"),
+		filepath.Join("romn", "S0102M.MUL.XML"),
+		filepath.Join("romn", "extra", "vin01m.mul.Xml"),
+		filepath.Join("romn", "notes.txt"),
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := NewCst(dir).Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, name := range names[:3] {
+		want = append(want, filepath.ToSlash(filepath.Join(dir, name)))
+	}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("Files() = %q, want %q", got, want)
+	}
+	for _, path := range got {
+		if strings.Contains(path, `\`) {
+			t.Errorf("%s is not slash-separated", path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("the path Files gave does not open: %v", err)
+		}
+	}
+}
+
+func TestFileNamesAnyCase(t *testing.T) {
+	upper := filepath.Join("romn", "S0102M.MUL.XML")
+	if got := FileLayer(upper); got != Mula {
+		t.Errorf("FileLayer(%s) = %q, want %q", upper, got, Mula)
+	}
+	c := NewCst("romn")
+	if got := BookOf(c, upper); got != DN {
+		t.Errorf("BookOf(%s) = %q, want %q", upper, got, DN)
+	}
+	if got := BookOf(NewBjt("txt"), filepath.Join("txt", "MN-1-1.TXT")); got != MN {
+		t.Errorf("BookOf(MN-1-1.TXT) = %q, want %q", got, MN)
+	}
+	for _, tc := range []struct {
+		name, ext string
+		want      bool
+	}{
+		{"s0101m.mul.xml", ".xml", true},
+		{"S0101M.MUL.XML", ".xml", true},
+		{"dn-1.Txt", ".txt", true},
+		{"dn-1.txt.bak", ".txt", false},
+		{"xml", ".xml", false},
+	} {
+		if got := HasExt(tc.name, tc.ext); got != tc.want {
+			t.Errorf("HasExt(%q, %q) = %v, want %v", tc.name, tc.ext, got, tc.want)
+		}
+	}
+}
+
+func TestCustomFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", filepath.Join("part", "A.TXT"), "notes.md"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("Buddhaṃ saraṇaṃ gacchāmi\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := NewCustom("reader_1", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.ToSlash(filepath.Join(dir, "b.txt")), filepath.ToSlash(filepath.Join(dir, "part", "A.TXT"))}
+	if !slices.Equal(got, want) {
+		t.Errorf("Files() = %v, want %v", got, want)
+	}
+	if got := c.Normalize("Buddhaṃ"); got != "buddhaṃ" || BookOf(c, want[0]) != Other || LayerOf(c, want[0]) != Mula {
+		t.Errorf("Normalize = %q, book %s, layer %s", got, BookOf(c, want[0]), LayerOf(c, want[0]))
+	}
+	for _, name := range []string{"Reader", "1st", "my-reader", ""} {
+		if _, err := NewCustom(name, dir); err == nil {
+			t.Errorf("NewCustom(%q) took the name", name)
+		}
+	}
+}
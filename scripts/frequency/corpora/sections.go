@@ -0,0 +1,119 @@
+package corpora
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Sections is the fixed grid of Tipiṭaka sections used for frequency
+// heatmaps, in canonical order: the five Vinaya books, the volumes of the
+// four main nikāyas (the nipātas for AN), the Khuddaka books in CST order
+// and the seven Abhidhamma books.
+var Sections = sectionGrid()
+
+func sectionGrid() []string {
+	var grid []string
+	for _, g := range []struct {
+		prefix string
+		n      int
+	}{{"V", 5}, {"D", 3}, {"M", 3}, {"S", 5}, {"A", 11}, {"K", 19}, {"Abh", 7}} {
+		for i := 1; i <= g.n; i++ {
+			grid = append(grid, fmt.Sprintf("%s%d", g.prefix, i))
+		}
+	}
+	return grid
+}
+
+// SectionTagger is implemented by corpora whose file names tell which
+// heatmap section a file belongs to.
+type SectionTagger interface {
+	Section(path string) string
+}
+
+// SectionOf returns the section of path in c, or "" when the file lies
+// outside the grid or c cannot tell.
+func SectionOf(c Corpus, path string) string {
+	if t, ok := c.(SectionTagger); ok {
+		return t.Section(path)
+	}
+	return ""
+}
+
+// cstSection tags CST/VRI file names. Vinaya: vin01 is the Pārājika and
+// vin02m1–m4 the Pācittiya, Mahāvagga, Cūḷavagga and Parivāra. Suttas:
+// s<nikāya><volume>, e.g. s0203 is MN 3 and s0411 the AN Ekādasakanipāta.
+// Abhidhamma: abh01 Dhammasaṅgaṇī, abh02 Vibhaṅga, then abh03m1–m11 the
+// Dhātukathā, Puggalapaññatti, Kathāvatthu, three Yamaka and five Paṭṭhāna
+// volumes, which merge into Abh6 and Abh7.
+func cstSection(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasPrefix(name, "vin01"):
+		return "V1"
+	case strings.HasPrefix(name, "vin02"):
+		if n := leadingNumber(name[len("vin02")+1:]); n >= 1 && n <= 4 {
+			return "V" + strconv.Itoa(n+1)
+		}
+	case strings.HasPrefix(name, "abh01"):
+		return "Abh1"
+	case strings.HasPrefix(name, "abh02"):
+		return "Abh2"
+	case strings.HasPrefix(name, "abh03"):
+		switch n := leadingNumber(name[len("abh03")+1:]); {
+		case n >= 1 && n <= 3:
+			return "Abh" + strconv.Itoa(n+2)
+		case n >= 4 && n <= 6:
+			return "Abh6"
+		case n >= 7:
+			return "Abh7"
+		}
+	case len(name) >= 5 && name[0] == 's':
+		nikaya, err1 := strconv.Atoi(name[1:3])
+		vol, err2 := strconv.Atoi(name[3:5])
+		if err1 == nil && err2 == nil && nikaya >= 1 && nikaya <= 5 {
+			return inGrid("DMSAK"[nikaya-1:nikaya] + strconv.Itoa(vol))
+		}
+	}
+	return ""
+}
+
+// bjtSection tags the BJT volumes of the four main nikāyas, whose file
+// names carry the volume after the book: dn-2.txt, mn-1-1.txt, an-11.txt.
+// The Vinaya, Khuddaka and Abhidhamma files are split differently from
+// CST and stay outside the grid.
+func bjtSection(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	book, rest, _ := strings.Cut(name, "-")
+	switch book {
+	case "dn", "mn", "sn", "an":
+		if n := leadingNumber(rest); n > 0 {
+			return inGrid(strings.ToUpper(book[:1]) + strconv.Itoa(n))
+		}
+	}
+	return ""
+}
+
+// leadingNumber parses the digits at the start of s, or returns 0.
+func leadingNumber(s string) int {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
+
+// inGrid returns s when it is one of Sections, else "".
+func inGrid(s string) string {
+	if slices.Contains(Sections, s) {
+		return s
+	}
+	return ""
+}
+
+func (c *Cst) Section(path string) string { return cstSection(path) }
+func (v *Vri) Section(path string) string { return cstSection(path) }
+func (b *Bjt) Section(path string) string { return bjtSection(path) }
@@ -0,0 +1,46 @@
+package corpora
+
+import (
+	"strings"
+
+	"dpd/go_modules/frequency/translit"
+)
+
+// Sya is the Syāmaraṭṭha (Siam) 1927 edition in Roman script.
+type Sya struct {
+	dirCorpus
+}
+
+// NewSya returns the SYA corpus rooted at dir, a tree of romanized .txt files.
+func NewSya(dir string) *Sya {
+	return &Sya{newDirCorpus("sya", dir, ".txt")}
+}
+
+// Normalize removes bracketed page and volume references by the rules of
+// cleaning/sya.toml.
+func (s *Sya) Normalize(text string) string {
+	return strings.ToLower(s.clean(text))
+}
+
+// SyaThai is the Syāmaraṭṭha edition in its original Thai script,
+// transliterated to Roman on reading. Counting it next to Sya checks the
+// third-party romanization against the source.
+type SyaThai struct {
+	dirCorpus
+}
+
+// NewSyaThai returns the Thai-script SYA corpus rooted at dir, a tree of
+// UTF-8 .txt files.
+func NewSyaThai(dir string) *SyaThai {
+	return &SyaThai{newDirCorpus("sya_thai", dir, ".txt")}
+}
+
+func (s *SyaThai) ScanText(path string, fn func(line string) error) error {
+	return s.dirCorpus.ScanText(path, func(line string) error { return fn(translit.Thai(line)) })
+}
+
+func (s *SyaThai) Normalize(text string) string {
+	return strings.ToLower(s.clean(text))
+}
+
+func (s *SyaThai) Book(path string) string { return syaBook(path) }
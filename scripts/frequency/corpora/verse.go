@@ -0,0 +1,59 @@
+package corpora
+
+import (
+	"strings"
+	"unicode"
+
+	"dpd/go_modules/frequency/cstxml"
+	"dpd/go_modules/frequency/translit"
+)
+
+// PassageScanner is implemented by corpora whose sources tell verse
+// (gāthā) from prose.
+type PassageScanner interface {
+	// ScanPassages is ScanText with each line marked as verse or not.
+	ScanPassages(path string, fn func(line string, verse bool) error) error
+}
+
+// TellsVerse reports whether c can tell verse from prose.
+func TellsVerse(c Corpus) bool {
+	_, ok := c.(PassageScanner)
+	return ok
+}
+
+// ScanPassages calls fn with each line of path in c marked as verse or
+// prose. Corpora that cannot tell them apart give only prose.
+func ScanPassages(c Corpus, path string, fn func(line string, verse bool) error) error {
+	if s, ok := c.(PassageScanner); ok {
+		return s.ScanPassages(path, fn)
+	}
+	return c.ScanText(path, func(line string) error { return fn(line, false) })
+}
+
+// The CST and VRI files mark each line of a verse with a gatha rend.
+
+func (c *Cst) ScanPassages(path string, fn func(line string, verse bool) error) error {
+	return cstxml.ScanFile(path, func(p cstxml.Paragraph) error { return fn(p.Text, p.Verse()) })
+}
+
+func (c *CstMyanmar) ScanPassages(path string, fn func(line string, verse bool) error) error {
+	return cstxml.ScanFile(path, func(p cstxml.Paragraph) error { return fn(translit.Myanmar(p.Text), p.Verse()) })
+}
+
+func (c *CstDevanagari) ScanPassages(path string, fn func(line string, verse bool) error) error {
+	return cstxml.ScanFile(path, func(p cstxml.Paragraph) error { return fn(translit.Devanagari(p.Text), p.Verse()) })
+}
+
+func (v *Vri) ScanPassages(path string, fn func(line string, verse bool) error) error {
+	return cstxml.ScanFile(path, func(p cstxml.Paragraph) error { return fn(p.Text, p.Verse()) })
+}
+
+// ScanPassages takes the indented lines of the romanized BJT for verse:
+// the edition sets its gāthās indented, one pāda pair per line, and prose
+// flush left.
+func (b *Bjt) ScanPassages(path string, fn func(line string, verse bool) error) error {
+	return b.dirCorpus.ScanText(path, func(line string) error {
+		text := strings.TrimLeftFunc(line, unicode.IsSpace)
+		return fn(line, text != "" && len(text) < len(line))
+	})
+}
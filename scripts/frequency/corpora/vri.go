@@ -0,0 +1,28 @@
+package corpora
+
+import (
+	"strings"
+
+	"dpd/go_modules/frequency/cstxml"
+)
+
+// Vri is the Vipassana Research Institute Roman-script edition published at
+// tipitaka.org (romn/cscd). Unlike the CST submodule it is taken with all of
+// its layers: mūla, aṭṭhakathā, ṭīkā and the añña texts.
+type Vri struct {
+	dirCorpus
+}
+
+// NewVri returns the VRI corpus rooted at dir, a mirror of
+// https://tipitaka.org/romn/cscd/ holding the .xml books.
+func NewVri(dir string) *Vri {
+	return &Vri{newDirCorpus("vri", dir, ".xml")}
+}
+
+func (v *Vri) ScanText(path string, fn func(line string) error) error {
+	return cstxml.ScanFile(path, func(p cstxml.Paragraph) error { return fn(p.Text) })
+}
+
+func (v *Vri) Normalize(text string) string {
+	return strings.ToLower(v.clean(text))
+}
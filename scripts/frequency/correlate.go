@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// allBooks is the book column of the correlations of whole corpora.
+const allBooks = "all"
+
+// correlationTable is the table of columns corpus_a, corpus_b, book,
+// words, shared, spearman and kendall of every pair of corpora of names,
+// in their order: over the whole corpora, then per book both have, with
+// the counts of each corpus by book in books.
+func correlationTable(names []string, books map[string]freq.Books, top int) (table, []string) {
+	t := table{columns: []string{"corpus_a", "corpus_b", "book", "words", "shared", "spearman", "kendall"}}
+	var notes []string
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			all := freq.Correlate(books[a].Total(), books[b].Total(), top)
+			t.rows = append(t.rows, []any{a, b, allBooks, all.Words, all.Shared, all.Spearman, all.Kendall})
+			lowest, low := "", 2.0
+			for _, book := range corpora.Books {
+				ca, okA := books[a][book]
+				cb, okB := books[b][book]
+				if !okA || !okB {
+					continue
+				}
+				r := freq.Correlate(ca, cb, top)
+				t.rows = append(t.rows, []any{a, b, book, r.Words, r.Shared, r.Spearman, r.Kendall})
+				if r.Spearman < low {
+					lowest, low = book, r.Spearman
+				}
+			}
+			note := fmt.Sprintf("%s ~ %s: ρ %.4f, τ %.4f over %d words", a, b, all.Spearman, all.Kendall, all.Words)
+			if lowest != "" {
+				note += fmt.Sprintf("; lowest in %s, ρ %.4f", lowest, low)
+			}
+			notes = append(notes, note)
+		}
+	}
+	return t, notes
+}
+
+// runCorrelate implements the correlate subcommand: how alike the word
+// frequencies of the editions rank, from the counts of the last run.
+func runCorrelate(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("correlate", flag.ExitOnError)
+	commandUsage(fs, "Writes the Spearman and Kendall rank correlations of the word frequencies of every pair of corpora, overall and per book, from the counts of the last run. Low values point at conversion problems or editorial differences.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to correlate")
+	top := fs.Int("top", 1000, "correlate the union of the N most frequent words of each side (0: all words)")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("correlating word ranks")
+	tic := tools.Tic()
+	list := strings.Split(*names, ",")
+	if len(list) < 2 {
+		tools.Errorf("-corpora %s: want two corpora or more", *names)
+		return
+	}
+	books := make(map[string]freq.Books, len(list))
+	for _, name := range list {
+		c, ok := corpora.Get(name)
+		if !ok {
+			tools.Errorf("unknown corpus %q (have %s)", name, strings.Join(corpusNames(), ", "))
+			return
+		}
+		files, err := loadFileCounts(name)
+		if err != nil {
+			tools.Errorf("%s: %v (count it first)", name, err)
+			return
+		}
+		books[name] = make(freq.Books)
+		for path, counts := range files {
+			books[name].Add(corpora.BookOf(c, path), counts)
+		}
+	}
+	t, notes := correlationTable(list, books, *top)
+	for _, note := range notes {
+		tools.Infof("%s", note)
+	}
+	if err := sink.Write("rank_correlation", t); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	tic.Toc()
+}
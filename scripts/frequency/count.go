@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// countCorpus counts c with freq.Count under the settings of p, with the
+// file cache and the checkpoint of its label. p.sem bounds the files in
+// flight at once across all corpora of the run.
+func (p *pipeline) countCorpus(c corpora.Corpus) (*freq.Table, error) {
+	t, err := freq.Count(p.ctx, c, freq.Options{
+		Tokenizer:  p.tok,
+		Layers:     p.layers,
+		Filter:     p.filter,
+		Variants:   p.variants,
+		Ngrams:     p.ngramSizes,
+		Limit:      p.sem,
+		Cache:      p.loadCache(c),
+		Checkpoint: p.checkpoint(c),
+		Verse:      p.verse,
+		Force:      p.force,
+		Progress:   p.prog,
+		Dedup:      p.dedup,
+		Provenance: p.provenance,
+
+		ExcludeSuspect: p.excludeSuspect,
+	})
+	if err == nil {
+		useCorpus(p.label(c))
+	}
+	return t, p.corpusError(c, err)
+}
+
+// checkpoint returns the checkpoint of c, read when resuming, or nil when
+// checkpoints are off. finish removes it once the whole run succeeded.
+func (p *pipeline) checkpoint(c corpora.Corpus) *freq.Checkpoint {
+	if p.checkpointEvery <= 0 {
+		return nil
+	}
+	ck := freq.LoadCheckpoint(filepath.Join(freqDir, ".checkpoint", p.label(c)+".gob"), p.checkpointEvery, p.resume)
+	p.checkpointsMu.Lock()
+	defer p.checkpointsMu.Unlock()
+	p.checkpoints = append(p.checkpoints, ck)
+	return ck
+}
+
+// corpusFiles lists the files of c to count: those of the selected layers
+// that pass the filter. A missing or empty corpus is a skipError.
+func (p *pipeline) corpusFiles(c corpora.Corpus) ([]string, error) {
+	files, err := freq.Files(c, p.layers)
+	if err == nil {
+		files, err = p.filter.Apply(c, files)
+	}
+	return files, p.corpusError(c, err)
+}
+
+// corpusError turns the errors of a corpus without input into skipErrors
+// saying how to get it.
+func (p *pipeline) corpusError(c corpora.Corpus, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return skipError(fmt.Sprintf("%s not found; %s", cfg.Corpora[c.Name()], fetchHint(c.Name())))
+	case errors.Is(err, freq.ErrNoFiles):
+		return skipError(fmt.Sprintf("no source files in %s; %s", cfg.Corpora[c.Name()], fetchHint(c.Name())))
+	case errors.Is(err, freq.ErrNoLayerFiles):
+		return skipError("no files of layers " + p.layerKey)
+	case errors.Is(err, freq.ErrNoFilterFiles):
+		return skipError("no files pass the filter " + p.filter.String())
+	}
+	return err
+}
+
+// loadCache reads the file cache of c for the tokenizer settings of p and
+// the cleaning rules of c.
+func (p *pipeline) loadCache(c corpora.Corpus) *freq.Cache {
+	return freq.LoadCache(filepath.Join(freqDir, ".cache", p.label(c)+".gob"), p.tok, corpora.CleanerOf(c).Rules())
+}
+
+// stageWrite times the writing of outputs, reported by -timings with the
+// counting stages of package freq.
+var stageWrite = tools.Stage("write")
+
+// skipError reports a corpus that was not counted because its input is
+// missing, as opposed to one that failed.
+type skipError string
+
+func (e skipError) Error() string { return "skipped — " + string(e) }
+
+// fetchHint tells how to get the sources of a corpus.
+func fetchHint(name string) string {
+	if _, ok := cfg.Download[name]; ok {
+		return "run palifreq download -corpora " + name
+	}
+	return "fetch them or set corpora." + name + " in palifreq.toml"
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// stdinFreqTable is the <name>_freq table of count-stdin: the top words of
+// counts, all when top is 0, with their rank in the ranked list, 0 for a
+// word the list lacks.
+func stdinFreqTable(counts map[string]int, rank map[string]int, top int) table {
+	list := freq.Sorted(counts)
+	if top > 0 && len(list) > top {
+		list = list[:top]
+	}
+	total := freq.TokenTotal(counts)
+	t := table{columns: []string{"rank", "word", "count", "per_million", "list_rank"}}
+	for i, wc := range list {
+		t.rows = append(t.rows, []any{i + 1, wc.Word, wc.Count, freq.PerMillion(wc.Count, total), rank[wc.Word]})
+	}
+	return t
+}
+
+// runCountStdin implements the count-stdin subcommand: a quick count of
+// the text piped in, such as a handout, without a corpus directory.
+func runCountStdin(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("count-stdin", flag.ExitOnError)
+	commandUsage(fs, "Counts the words of the Roman Pāḷi text read from standard input, as the counting commands tokenize, and prints their frequency table and its coverage by the top words of a frequency-ranked list, the master list by default.")
+	list := fs.String("list", "master", "frequency-ranked list the text is measured against, as coverage -list takes it")
+	name := fs.String("name", "stdin", "name of the output tables")
+	top := fs.Int("top", 50, "number of words in the frequency table (0: all)")
+	pointsFlag := fs.String("n", "500,1000,2000,5000", "comma-separated list sizes to report")
+	sf := addSinkFlags(fs, "tsv")
+	// a quick look prints its tables, unless told to keep them
+	fs.Lookup("sink").DefValue = "stdout"
+	fs.Set("sink", "stdout")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("counting standard input")
+	tic := tools.Tic()
+	points, err := parsePoints(*pointsFlag)
+	if err != nil {
+		tools.Errorf("-n: %v", err)
+		return
+	}
+	counts, err := freq.CountText(os.Stdin, cfg.tokenizer())
+	if err != nil {
+		tools.Errorf("standard input: %v", err)
+		return
+	}
+	total := freq.TokenTotal(counts)
+	if total == 0 {
+		tools.Errorf("standard input has no words")
+		return
+	}
+	label := sqlName(*name)
+
+	// without the list, the counts alone
+	var rank map[string]int
+	path, err := rankedListPath(*list)
+	if err == nil {
+		var words []string
+		if words, err = readRankedList(path); err == nil {
+			rank = listRanks(words)
+			tools.Infof("%s: %d tokens, %d distinct words, against the %d words of %s", label, total, len(counts), len(words), path)
+		}
+	}
+	if err != nil {
+		tools.Warnf("%v; no coverage", err)
+		tools.Infof("%s: %d tokens, %d distinct words", label, total, len(counts))
+	}
+
+	if err := sink.Write(label+"_freq", stdinFreqTable(counts, rank, *top)); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if rank != nil {
+		t := textCoverageTable(counts, rank, points)
+		for _, row := range t.rows {
+			tools.Infof("top %d: %.1f%% of the tokens, %.1f%% of the words", row[0], 100*row[2].(float64), 100*row[4].(float64))
+		}
+		if err := sink.Write("coverage_"+label, t); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+	}
+	tic.Toc()
+}
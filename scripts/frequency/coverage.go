@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// coveragePoints are the vocabulary sizes the coverage table reports.
+var coveragePoints = []int{10, 100, 200, 500, 1000, 2000, 3000, 5000, 10000, 20000, 50000, 100000}
+
+// summaryPoints are the coverage points of the corpus summary.
+var summaryPoints = []int{100, 1000, 10000}
+
+// share is n as a fraction of total, rounded to four decimals.
+func share(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)*1e4/float64(total)) / 1e4
+}
+
+// covered is the number of tokens of the top words of list, ranked by
+// descending count.
+func covered(list []freq.WordCount, top int) int {
+	n := 0
+	for _, wc := range list[:min(top, len(list))] {
+		n += wc.Count
+	}
+	return n
+}
+
+// coverageTable is the coverage curve of list, ranked by descending count:
+// for each of coveragePoints below the number of types, and for all of
+// them, the tokens the top words make up and their share of the corpus.
+func coverageTable(list []freq.WordCount) table {
+	var points []int
+	for _, top := range coveragePoints {
+		if top < len(list) {
+			points = append(points, top)
+		}
+	}
+	points = append(points, len(list))
+
+	total := covered(list, len(list))
+	t := table{columns: []string{"top", "tokens", "coverage"}}
+	sum, done := 0, 0
+	for _, top := range points {
+		for _, wc := range list[done:top] {
+			sum += wc.Count
+		}
+		done = top
+		t.rows = append(t.rows, []any{top, sum, share(sum, total)})
+	}
+	return t
+}
+
+// saveCoverage writes the table <name>_coverage to s and logs the tokens
+// of name, its type/token ratio and the share of the top 1000 words.
+func saveCoverage(s Sink, name string, list []freq.WordCount) error {
+	tokens := covered(list, len(list))
+	tools.Infof("%s: %d tokens, type/token ratio %.4f; the top 1000 words cover %.1f%% of them",
+		name, tokens, share(len(list), tokens), 100*share(covered(list, 1000), tokens))
+	return s.Write(name+"_coverage", coverageTable(list))
+}
+
+// saveSummary writes the table <name> to s: one row per corpus of list
+// found in counts, in order, with its tokens, types, type/token ratio and
+// the coverage of the summaryPoints top words.
+func saveSummary(s Sink, name string, list []corpora.Corpus, counts map[string]map[string]int) error {
+	t := table{columns: []string{"corpus", "tokens", "types", "type_token_ratio"}}
+	for _, top := range summaryPoints {
+		t.columns = append(t.columns, fmt.Sprintf("top_%d", top))
+	}
+	for _, c := range list {
+		m, ok := counts[c.Name()]
+		if !ok {
+			continue
+		}
+		ranked := freq.Sorted(m)
+		tokens := covered(ranked, len(ranked))
+		row := []any{c.Name(), tokens, len(ranked), share(len(ranked), tokens)}
+		for _, top := range summaryPoints {
+			row = append(row, share(covered(ranked, top), tokens))
+		}
+		t.rows = append(t.rows, row)
+	}
+	return s.Write(name, t)
+}
+
+// textCoveragePoints are the list sizes the coverage command reports by
+// default.
+var textCoveragePoints = []int{500, 1000, 2000, 5000}
+
+// readRankedList reads a frequency-ranked word list, most frequent first:
+// a JSON array of words like <corpus>_wordlist.json, a text file of one
+// word per line, or the word column of a table like <corpus>_freq or the
+// master list, in the order of its rows.
+func readRankedList(path string) ([]string, error) {
+	plain, _ := splitCompression(path)
+	ext := strings.ToLower(filepath.Ext(plain))
+	f, err := openDecompressed(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var words []string
+	switch ext {
+	case ".txt":
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if w := strings.TrimSpace(sc.Text()); w != "" && !strings.HasPrefix(w, "#") {
+				words = append(words, w)
+			}
+		}
+		err = sc.Err()
+	case ".tsv", ".csv":
+		cr := csv.NewReader(bufio.NewReader(f))
+		if ext == ".tsv" {
+			cr.Comma = '\t'
+		}
+		var header []string
+		if header, err = cr.Read(); err != nil {
+			break
+		}
+		col := slices.Index(header, "word")
+		if col < 0 {
+			return nil, fmt.Errorf("%s: no word column", path)
+		}
+		for {
+			rec, err2 := cr.Read()
+			if err2 == io.EOF {
+				break
+			}
+			if err2 != nil {
+				err = err2
+				break
+			}
+			words = append(words, rec[col])
+		}
+	case ".json", ".jsonl":
+		dec := json.NewDecoder(bufio.NewReader(f))
+		if ext == ".json" {
+			if _, err = dec.Token(); err != nil { // [
+				break
+			}
+		}
+		for dec.More() {
+			var v any
+			if err = dec.Decode(&v); err != nil {
+				break
+			}
+			switch v := v.(type) {
+			case string:
+				words = append(words, v)
+			case map[string]any:
+				w, ok := v["word"].(string)
+				if !ok {
+					return nil, fmt.Errorf("%s: a row without a word", path)
+				}
+				words = append(words, w)
+			default:
+				return nil, fmt.Errorf("%s: want words or rows of a table", path)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s: want a .json, .jsonl, .tsv, .csv or .txt list", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return words, nil
+}
+
+// rankedListPath resolves the -list of the coverage command: a file, or
+// the name of a table of the output directory like master or cst_freq, or
+// of a corpus, whose word list is taken.
+func rankedListPath(list string) (string, error) {
+	if _, err := os.Stat(list); err == nil {
+		return list, nil
+	}
+	wordlist := filepath.Join(freqDir, list+"_wordlist.json")
+	if _, err := os.Stat(wordlist); err == nil {
+		return wordlist, nil
+	}
+	tables, err := countTables(freqDir)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range []string{list, list + "_freq"} {
+		if path, ok := tables[name]; ok {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("-list %s: no such file, word list or table in %s", list, freqDir)
+}
+
+// listRanks ranks the words of a ranked list from 1, a word listed twice
+// at its first place.
+func listRanks(words []string) map[string]int {
+	rank := make(map[string]int, len(words))
+	for i, w := range words {
+		if _, ok := rank[w]; !ok {
+			rank[w] = i + 1
+		}
+	}
+	return rank
+}
+
+// countText counts the tokens of the .txt file at path, or of every .txt
+// file under the directory path, as the counting commands tokenize.
+func countText(path string) (map[string]int, error) {
+	tok := cfg.tokenizer()
+	counts := make(map[string]int)
+	read := func(path string) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		c, err := freq.CountText(f, tok)
+		for w, n := range c {
+			counts[w] += n
+		}
+		return err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return counts, read(path)
+	}
+	err = filepath.WalkDir(path, func(p string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() || !corpora.HasExt(e.Name(), ".txt") {
+			return err
+		}
+		return read(p)
+	})
+	return counts, err
+}
+
+// textCoverageTable is the table of columns top, tokens, coverage, types
+// and type_coverage: for each list size of points, the tokens of counts
+// whose words are among the first top words of rank, and the share they
+// and their distinct words make up.
+func textCoverageTable(counts map[string]int, rank map[string]int, points []int) table {
+	total := freq.TokenTotal(counts)
+	t := table{columns: []string{"top", "tokens", "coverage", "types", "type_coverage"}}
+	for _, top := range points {
+		tokens, types := 0, 0
+		for w, n := range counts {
+			if r, ok := rank[w]; ok && r <= top {
+				tokens += n
+				types++
+			}
+		}
+		t.rows = append(t.rows, []any{top, tokens, share(tokens, total), types, share(types, len(counts))})
+	}
+	return t
+}
+
+// uncoveredTable is the table of columns word, count and rank of the words
+// of counts outside the first top words of rank, most frequent first; the
+// rank is 0 for a word the list lacks.
+func uncoveredTable(counts map[string]int, rank map[string]int, top int) table {
+	var list []freq.WordCount
+	for w, n := range counts {
+		if r, ok := rank[w]; !ok || r > top {
+			list = append(list, freq.WordCount{Word: w, Count: n})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return tools.ComparePali(list[i].Word, list[j].Word) < 0
+	})
+	t := table{columns: []string{"word", "count", "rank"}}
+	for _, wc := range list {
+		t.rows = append(t.rows, []any{wc.Word, wc.Count, rank[wc.Word]})
+	}
+	return t
+}
+
+// parsePoints parses a comma-separated list of positive sizes, ascending.
+func parsePoints(s string) ([]int, error) {
+	var points []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("%q: want a positive number", f)
+		}
+		points = append(points, n)
+	}
+	slices.Sort(points)
+	return slices.Compact(points), nil
+}
+
+// runCoverage implements the coverage subcommand: how much of a text the
+// top words of a frequency-ranked list cover.
+func runCoverage(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("coverage", flag.ExitOnError)
+	commandUsage(fs, "Reports the share of the tokens of a target text, or of the files of a counted corpus, that the top N words of a frequency-ranked list cover, and the words they leave uncovered, into coverage_<name> and uncovered_<name>.")
+	list := fs.String("list", "master", "frequency-ranked list: a .json word list, .txt file of one word per line or .tsv/.csv/.json table with a word column, or the name of a corpus or table of the output directory")
+	text := fs.String("text", "", "target: a UTF-8 .txt file, or a directory of them")
+	corpus := fs.String("corpus", "", "target: a corpus, from the counts of the last run, instead of -text")
+	match := fs.String("files", "", "with -corpus, only the files whose path contains this, e.g. s0101m for the first suttas of the Dīgha")
+	name := fs.String("name", "", "name of the output tables (default: the file name of -text, or the corpus)")
+	pointsFlag := fs.String("n", "500,1000,2000,5000", "comma-separated list sizes to report")
+	uncoveredAt := fs.Int("uncovered-at", 0, "list the words outside the top N (default: the largest of -n)")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("measuring text coverage")
+	tic := tools.Tic()
+	points, err := parsePoints(*pointsFlag)
+	if err != nil {
+		tools.Errorf("-n: %v", err)
+		return
+	}
+	if *uncoveredAt <= 0 {
+		*uncoveredAt = points[len(points)-1]
+	}
+	path, err := rankedListPath(*list)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	words, err := readRankedList(path)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	rank := listRanks(words)
+
+	var counts map[string]int
+	label := *name
+	switch {
+	case (*text == "") == (*corpus == ""):
+		tools.Errorf("coverage needs one of -text and -corpus")
+		return
+	case *text != "":
+		if counts, err = countText(*text); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+		if label == "" {
+			label = tableName(filepath.Base(filepath.Clean(*text)))
+		}
+	default:
+		files, err := loadFileCounts(*corpus)
+		if err != nil {
+			tools.Errorf("%s: %v (count it first)", *corpus, err)
+			return
+		}
+		counts = make(map[string]int)
+		for path, c := range files {
+			if strings.Contains(path, *match) {
+				for w, n := range c {
+					counts[w] += n
+				}
+			}
+		}
+		if label == "" {
+			label = *corpus
+			if *match != "" {
+				label += "_" + *match
+			}
+		}
+	}
+	label = sqlName(label)
+	total := freq.TokenTotal(counts)
+	if total == 0 {
+		tools.Errorf("the target has no words")
+		return
+	}
+
+	t := textCoverageTable(counts, rank, points)
+	tools.Infof("%s: %d tokens, %d distinct words, against the %d words of %s", label, total, len(counts), len(words), path)
+	for _, row := range t.rows {
+		tools.Infof("top %d: %.1f%% of the tokens, %.1f%% of the words", row[0], 100*row[2].(float64), 100*row[4].(float64))
+	}
+	if err := sink.Write("coverage_"+label, t); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if err := sink.Write("uncovered_"+label, uncoveredTable(counts, rank, *uncoveredAt)); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	tic.Toc()
+}
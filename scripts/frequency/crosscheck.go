@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// fileDiff is how the counts of one file differ between two editions of
+// the same text in different scripts.
+type fileDiff struct {
+	file             string
+	tokensA, tokensB int
+	forms            int      // forms counted differently
+	examples         []string // the largest differences, as "form a→b"
+}
+
+// byBase keys per-file counts by file name, which the script editions of
+// one release share.
+func byBase(files map[string]map[string]int) map[string]map[string]int {
+	m := make(map[string]map[string]int, len(files))
+	for path, counts := range files {
+		m[filepath.Base(path)] = counts
+	}
+	return m
+}
+
+// diffFile compares the counts of one file in two editions and keeps up
+// to top examples, largest difference first.
+func diffFile(file string, a, b map[string]int, top int) fileDiff {
+	d := fileDiff{file: file, tokensA: freq.TokenTotal(a), tokensB: freq.TokenTotal(b)}
+	var deltas []wordDelta
+	for w, n := range a {
+		if b[w] != n {
+			deltas = append(deltas, wordDelta{Word: w, Old: n, New: b[w], Delta: b[w] - n})
+		}
+	}
+	for w, n := range b {
+		if _, ok := a[w]; !ok {
+			deltas = append(deltas, wordDelta{Word: w, New: n, Delta: n})
+		}
+	}
+	d.forms = len(deltas)
+	sort.Slice(deltas, func(i, j int) bool {
+		x, y := abs(deltas[i].Delta), abs(deltas[j].Delta)
+		if x != y {
+			return x > y
+		}
+		return tools.ComparePali(deltas[i].Word, deltas[j].Word) < 0
+	})
+	for _, wd := range deltas[:min(top, len(deltas))] {
+		d.examples = append(d.examples, fmt.Sprintf("%s %d→%d", wd.Word, wd.Old, wd.New))
+	}
+	return d
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// crossCheck compares two script editions file by file. It returns the
+// files whose counts differ, most differing forms first, and the files
+// only one edition has, compared against an empty file.
+func crossCheck(a, b map[string]map[string]int, top int) (diffs, onlyA, onlyB []fileDiff) {
+	a, b = byBase(a), byBase(b)
+	for file, ca := range a {
+		cb, ok := b[file]
+		if !ok {
+			onlyA = append(onlyA, diffFile(file, ca, nil, top))
+			continue
+		}
+		if d := diffFile(file, ca, cb, top); d.forms > 0 {
+			diffs = append(diffs, d)
+		}
+	}
+	for file, cb := range b {
+		if _, ok := a[file]; !ok {
+			onlyB = append(onlyB, diffFile(file, nil, cb, top))
+		}
+	}
+	for _, list := range [][]fileDiff{diffs, onlyA, onlyB} {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].forms != list[j].forms {
+				return list[i].forms > list[j].forms
+			}
+			return list[i].file < list[j].file
+		})
+	}
+	return diffs, onlyA, onlyB
+}
+
+// crossCheckTable is the report of crossCheck for the editions nameA and
+// nameB: the files only one edition has, then the differing files, with
+// the tokens of each edition, the number of differing forms and examples.
+func crossCheckTable(nameA, nameB string, diffs, onlyA, onlyB []fileDiff) table {
+	t := table{columns: []string{"file", nameA + "_tokens", nameB + "_tokens", "differing_forms", "examples"}}
+	add := func(d fileDiff, note string) {
+		examples := strings.Join(d.examples, "; ")
+		if note != "" {
+			examples = note + ": " + examples
+		}
+		t.rows = append(t.rows, []any{d.file, d.tokensA, d.tokensB, d.forms, examples})
+	}
+	for _, d := range onlyA {
+		add(d, "missing in "+nameB)
+	}
+	for _, d := range onlyB {
+		add(d, "missing in "+nameA)
+	}
+	for _, d := range diffs {
+		add(d, "")
+	}
+	return t
+}
+
+// runCrossCheck implements the crosscheck subcommand: it compares two
+// script editions of one text, such as the Roman and Devanagari CST,
+// file by file, from the counts of the last run.
+func runCrossCheck(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("crosscheck", flag.ExitOnError)
+	commandUsage(fs, "Compares two script editions of the same text file by file, from the counts of the last run, and lists the files whose counts differ.")
+	names := fs.String("corpora", "cst,cst_deva", "the two corpora to compare, e.g. cst,cst_mymr")
+	top := fs.Int("examples", 5, "differing forms shown per file")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	list := strings.Split(*names, ",")
+	if len(list) != 2 {
+		tools.Errorf("crosscheck compares two corpora, not %d", len(list))
+		return
+	}
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("cross-checking " + list[0] + " against " + list[1])
+	tic := tools.Tic()
+
+	var files [2]map[string]map[string]int
+	for i, name := range list {
+		if files[i], err = loadFileCounts(name); err != nil {
+			tools.Errorf("%s: %v (count it first)", name, err)
+			return
+		}
+	}
+	warnMixedChains(list)
+	diffs, onlyA, onlyB := crossCheck(files[0], files[1], *top)
+	t := crossCheckTable(list[0], list[1], diffs, onlyA, onlyB)
+	if err := sink.Write("crosscheck_"+list[0]+"_"+list[1], t); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	paired := len(files[0]) - len(onlyA)
+	tools.Infof("%d of %d files in both differ; %d only in %s, %d only in %s",
+		len(diffs), paired, len(onlyA), list[0], len(onlyB), list[1])
+
+	tic.Toc()
+}
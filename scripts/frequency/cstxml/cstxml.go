@@ -0,0 +1,283 @@
+// Package cstxml reads the Chaṭṭha Saṅgāyana (CST4) XML sources from
+// tipitaka.org and extracts their running text.
+//
+// The files come as UTF-16 (the original CST4 release) or UTF-8 (later
+// mirrors), in both the romn and deva directories. Paragraphs are <p>
+// elements carrying a rend attribute (bodytext, gatha1, centre, ...) and an
+// optional paragraph number; <note> holds variant readings and <pb> marks
+// page breaks, neither of which belongs in the counted text.
+package cstxml
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Paragraph is one block of text from a CST file.
+type Paragraph struct {
+	Rend string // rend attribute, e.g. "bodytext", "gatha1", "chapter"
+	N    string // paragraph number, empty when the element has none
+	Text string
+}
+
+// Verse reports whether p is a line of verse: the gatha1, gatha2, gatha3
+// and gathalast paragraphs making up a gāthā.
+func (p Paragraph) Verse() bool { return strings.HasPrefix(p.Rend, "gatha") }
+
+// Decode converts raw file bytes to a UTF-8 string, honouring a UTF-16 or
+// UTF-8 byte order mark and falling back to UTF-16LE detection for files
+// whose BOM was lost.
+func Decode(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], false)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], true)
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		data = data[3:]
+	case len(data) >= 2 && data[0] == '<' && data[1] == 0:
+		return decodeUTF16(data, false)
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("not valid UTF-8 or UTF-16")
+	}
+	return string(data), nil
+}
+
+// NewReader returns a reader of the UTF-8 text of r, decoding UTF-16 on
+// the fly by the same rules as Decode.
+func NewReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(3)
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Reader(br)
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder().Reader(br)
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		br.Discard(3)
+	case len(head) >= 2 && head[0] == '<' && head[1] == 0:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder().Reader(br)
+	}
+	return br
+}
+
+func decodeUTF16(data []byte, bigEndian bool) (string, error) {
+	if len(data)%2 != 0 {
+		return "", fmt.Errorf("odd byte count in UTF-16 data")
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// skipped elements contribute no text
+var skipped = map[string]bool{
+	"note":      true,
+	"pb":        true,
+	"teiHeader": true,
+}
+
+// skippedHi are <hi rend=...> values that only carry numbering
+var skippedHi = map[string]bool{
+	"paranum": true,
+	"dot":     true,
+}
+
+// Parse extracts the paragraphs of a decoded CST XML document.
+// Headings (<head>) are returned as paragraphs with their rend value.
+func Parse(text string) ([]Paragraph, error) {
+	var paras []Paragraph
+	err := scan(strings.NewReader(text), func(p Paragraph) error {
+		paras = append(paras, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paras, nil
+}
+
+// Scan reads a CST XML document of any supported encoding from r and calls
+// fn with each paragraph as soon as it ends, so only one paragraph is held
+// in memory at a time. It stops at the first error fn returns.
+func Scan(r io.Reader, fn func(Paragraph) error) error {
+	return scan(NewReader(r), fn)
+}
+
+func scan(r io.Reader, fn func(Paragraph) error) error {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	// the text is already UTF-8, whatever the declaration says
+	d.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+
+	var (
+		cur   *Paragraph
+		buf   strings.Builder
+		skip  int // depth inside skipped elements
+		depth int // depth inside the current paragraph
+	)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			if skip > 0 || skipped[name] || (name == "hi" && skippedHi[attr(t, "rend")]) {
+				skip++
+				continue
+			}
+			if cur != nil {
+				depth++
+				continue
+			}
+			if name == "p" || name == "head" {
+				cur = &Paragraph{Rend: attr(t, "rend"), N: attr(t, "n")}
+				buf.Reset()
+				depth = 0
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if cur == nil {
+				continue
+			}
+			if depth > 0 {
+				depth--
+				continue
+			}
+			cur.Text = strings.Join(strings.Fields(buf.String()), " ")
+			if cur.Text != "" {
+				if err := fn(*cur); err != nil {
+					return err
+				}
+			}
+			cur = nil
+		case xml.CharData:
+			if cur != nil && skip == 0 {
+				buf.Write(t)
+			}
+		}
+	}
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// ReadFile decodes and parses a single CST XML file.
+func ReadFile(path string) ([]Paragraph, error) {
+	var paras []Paragraph
+	err := ScanFile(path, func(p Paragraph) error {
+		paras = append(paras, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paras, nil
+}
+
+// ScanFile calls fn with each paragraph of a CST XML file, as Scan does.
+func ScanFile(path string, fn func(Paragraph) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := Scan(f, fn); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Text joins paragraphs into plain text, one paragraph per line.
+func Text(paras []Paragraph) string {
+	var b strings.Builder
+	for _, p := range paras {
+		b.WriteString(p.Text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// ConvertDir converts every .xml file in src, the extension in any case,
+// into a UTF-8 .txt file of the same base name in dst and returns the
+// number of files written. Files whose txt counterpart is newer than the
+// source are left alone.
+func ConvertDir(src, dst string) (int, error) {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return 0, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".xml") {
+			paths = append(paths, filepath.Join(src, e.Name()))
+		}
+	}
+	if len(paths) == 0 {
+		return 0, fmt.Errorf("no xml files in %s", src)
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, path := range paths {
+		base := filepath.Base(path)
+		out := filepath.Join(dst, strings.TrimSuffix(base, filepath.Ext(base))+".txt")
+		if upToDate(path, out) {
+			continue
+		}
+		paras, err := ReadFile(path)
+		if err != nil {
+			return n, err
+		}
+		if err := os.WriteFile(out, []byte(Text(paras)), 0o644); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func upToDate(src, dst string) bool {
+	si, err := os.Stat(src)
+	if err != nil {
+		return false
+	}
+	di, err := os.Stat(dst)
+	if err != nil {
+		return false
+	}
+	return !di.ModTime().Before(si.ModTime())
+}
@@ -0,0 +1,138 @@
+package cstxml
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		xml  string
+		want []Paragraph
+	}{
+		{"roman", `<?xml version="1.0" encoding="UTF-16"?>
+<TEI.2><teiHeader><title>ignored</title></teiHeader><text><body>
+<p rend="nikaya">Dīghanikāyo</p>
+<head rend="chapter">1. Brahmajālasuttaṃ</head>
+<p rend="bodytext" n="1"><hi rend="paranum">1</hi><hi rend="dot">.</hi> Evaṃ me sutaṃ – ekaṃ samayaṃ
+bhagavā <pb ed="M" n="1.0001" />antarā ca rājagahaṃ<note>rājagahañca (syā.)</note> ca nāḷandaṃ.</p>
+<p rend="gatha1">Yo dhammaṃ <hi rend="bold">desesi</hi>,</p>
+<p rend="gathalast">anukampāya pāṇinaṃ.</p>
+</body></text></TEI.2>`, []Paragraph{
+			{"nikaya", "", "Dīghanikāyo"},
+			{"chapter", "", "1. Brahmajālasuttaṃ"},
+			{"bodytext", "1", "Evaṃ me sutaṃ – ekaṃ samayaṃ bhagavā antarā ca rājagahaṃ ca nāḷandaṃ."},
+			{"gatha1", "", "Yo dhammaṃ desesi,"},
+			{"gathalast", "", "anukampāya pāṇinaṃ."},
+		}},
+		{"devanagari", `<?xml version="1.0" encoding="UTF-8"?>
+<TEI.2><text><body>
+<p rend="book">सीलक्खन्धवग्गपाळि</p>
+<p rend="bodytext" n="2"><hi rend="paranum">२</hi><hi rend="dot">.</hi> एवं मे सुतं<pb ed="V" n="1.0001" /> – एकं समयं भगवा।</p>
+<p rend="centre"><note>(सी॰)</note></p>
+</body></text></TEI.2>`, []Paragraph{
+			{"book", "", "सीलक्खन्धवग्गपाळि"},
+			{"bodytext", "2", "एवं मे सुतं – एकं समयं भगवा।"},
+		}},
+		// a paragraph within another is part of its text, and the
+		// skipped elements nest in both
+		{"nested", `<body>
+<p rend="bodytext" n="3">tatra <p rend="hangnum">kho</p> bhagavā <note>a <note>b</note> c</note>āmantesi</p>
+<p rend="centre">&amp; <hi rend="paranum"><hi rend="bold">4</hi></hi>iti</p>
+</body>`, []Paragraph{
+			{"bodytext", "3", "tatra kho bhagavā āmantesi"},
+			{"centre", "", "& iti"},
+		}},
+	} {
+		got, err := Parse(tc.xml)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: Parse =\n%q\nwant\n%q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestVerse(t *testing.T) {
+	for rend, want := range map[string]bool{"gatha1": true, "gatha3": true, "gathalast": true, "bodytext": false, "centre": false} {
+		if got := (Paragraph{Rend: rend}).Verse(); got != want {
+			t.Errorf("%s: Verse = %v", rend, got)
+		}
+	}
+}
+
+// utf16Bytes encodes s as UTF-16 with a byte order mark, or without one
+// when bom is false.
+func utf16Bytes(s string, bigEndian, bom bool) []byte {
+	var b bytes.Buffer
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	for _, u := range units {
+		if bigEndian {
+			b.Write([]byte{byte(u >> 8), byte(u)})
+		} else {
+			b.Write([]byte{byte(u), byte(u >> 8)})
+		}
+	}
+	return b.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	const doc = `<p rend="bodytext">evaṃ me sutaṃ एवं</p>`
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"utf-8", []byte(doc)},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, doc...)},
+		{"utf-16le", utf16Bytes(doc, false, true)},
+		{"utf-16be", utf16Bytes(doc, true, true)},
+		{"utf-16le without bom", utf16Bytes(doc, false, false)},
+	} {
+		got, err := Decode(tc.data)
+		if err != nil || got != doc {
+			t.Errorf("%s: Decode = %q, %v", tc.name, got, err)
+		}
+		var paras []Paragraph
+		err = Scan(bytes.NewReader(tc.data), func(p Paragraph) error {
+			paras = append(paras, p)
+			return nil
+		})
+		if err != nil || len(paras) != 1 || paras[0].Text != "evaṃ me sutaṃ एवं" {
+			t.Errorf("%s: Scan = %q, %v", tc.name, paras, err)
+		}
+	}
+	if _, err := Decode([]byte{'a', 0xFF, 'b'}); err == nil {
+		t.Error("Decode of invalid UTF-8: no error")
+	}
+	if _, err := Decode([]byte{0xFF, 0xFE, '<'}); err == nil {
+		t.Error("Decode of an odd UTF-16 byte count: no error")
+	}
+}
+
+func TestText(t *testing.T) {
+	paras, err := Parse(`<p rend="subhead">Pāthikasuttaṃ</p><p rend="bodytext">evaṃ me sutaṃ</p>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Text(paras); got != "Pāthikasuttaṃ\nevaṃ me sutaṃ\n" {
+		t.Errorf("Text = %q", got)
+	}
+	errStop := errors.New("stop")
+	if err := Scan(strings.NewReader(`<p rend="a">x</p><p rend="b">y</p>`), func(p Paragraph) error {
+		if p.Rend == "b" {
+			t.Error("Scan went on after fn failed")
+		}
+		return errStop
+	}); err != errStop {
+		t.Errorf("Scan = %v, want the error of fn", err)
+	}
+}
@@ -0,0 +1,62 @@
+package cstxml
+
+import "regexp"
+
+// headingRends are the rend values of the headings below the book: the
+// chapter heads and the titles and subheads within them.
+var headingRends = map[string]bool{
+	"chapter":    true,
+	"title":      true,
+	"subhead":    true,
+	"subsubhead": true,
+}
+
+var (
+	// suttaTitleRe matches a heading that is a sutta title, like
+	// "1. Brahmajālasuttaṃ" or "(3) Mahāli suttaṃ".
+	suttaTitleRe = regexp.MustCompile(`(?i)^[0-9().\s-]*(?:\pL+ ){0,3}\pL*sutta(?:nta)?[ṃṁm]?[.:]?$`)
+	// vaggaTitleRe matches a heading that is a chapter of suttas, like
+	// "2. Sīhanādavaggo".
+	vaggaTitleRe = regexp.MustCompile(`(?i)^[0-9().\s-]*(?:\pL+ ){0,3}\pL*vaggo[.:]?$`)
+)
+
+// Structure is where a paragraph of a CST file stands in its structural
+// markup: the headings above it, outermost first, and the number of the
+// last numbered paragraph. The nikāya and book come from their rend; the
+// headings below are told apart by their text, as the books use the
+// chapter, title and subhead rends for different levels: a chapter head is
+// a sutta in DN, a vagga in MN and a saṃyutta in SN.
+type Structure struct {
+	Nikaya  string // e.g. "Dīghanikāyo"
+	Book    string // e.g. "Sīlakkhandhavaggapāḷi"
+	Chapter string // a chapter head that is neither a vagga nor a sutta, like a saṃyutta
+	Vagga   string // e.g. "1. Mūlapariyāyavaggo"
+	Sutta   string // e.g. "1. Brahmajālasuttaṃ"
+	ParaNum string // the n of the last numbered paragraph, "" before the first
+}
+
+// Next moves s past p, the next paragraph of the file, and reports whether
+// p is a heading of the structure. A heading clears the levels below it.
+func (s *Structure) Next(p Paragraph) bool {
+	heading := true
+	switch {
+	case p.Rend == "nikaya":
+		*s = Structure{Nikaya: p.Text}
+	case p.Rend == "book":
+		*s = Structure{Nikaya: s.Nikaya, Book: p.Text}
+	case !headingRends[p.Rend]:
+		heading = false
+	case suttaTitleRe.MatchString(p.Text):
+		s.Sutta = p.Text
+	case vaggaTitleRe.MatchString(p.Text):
+		s.Vagga, s.Sutta = p.Text, ""
+	case p.Rend == "chapter":
+		s.Chapter, s.Vagga, s.Sutta = p.Text, "", ""
+	default:
+		heading = false
+	}
+	if p.N != "" {
+		s.ParaNum = p.N
+	}
+	return heading
+}
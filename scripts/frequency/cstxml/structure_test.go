@@ -0,0 +1,75 @@
+package cstxml
+
+import "testing"
+
+func TestStructure(t *testing.T) {
+	// the openings of DN 1, MN 1 and SN 1, as Parse returns them
+	paras, err := Parse(`<body>
+<p rend="nikaya">Dīghanikāyo</p>
+<p rend="book">Sīlakkhandhavaggapāḷi</p>
+<head rend="chapter">1. Brahmajālasuttaṃ</head>
+<p rend="subhead">Paribbājakakathā</p>
+<p rend="bodytext" n="1"><hi rend="paranum">1</hi><hi rend="dot">.</hi> Evaṃ me sutaṃ.</p>
+<p rend="bodytext">Atha kho <pb ed="M" n="1.0002" />bhagavā.</p>
+<p rend="nikaya">Majjhimanikāyo</p>
+<p rend="book">Mūlapaṇṇāsapāḷi</p>
+<head rend="chapter">1. Mūlapariyāyavaggo</head>
+<p rend="subhead">1. Mūlapariyāyasuttaṃ</p>
+<p rend="bodytext" n="1">Evaṃ me sutaṃ.</p>
+<p rend="book">Sagāthāvaggasaṃyuttapāḷi</p>
+<head rend="chapter">1. Devatāsaṃyuttaṃ</head>
+<p rend="title">1. Naḷavaggo</p>
+<p rend="subhead">1. Oghataraṇasuttaṃ</p>
+<p rend="bodytext" n="1">Evaṃ me sutaṃ.</p>
+<p rend="gatha1">Cirassaṃ vata passāmi,</p>
+<head rend="chapter">2. Devaputtasaṃyuttaṃ</head>
+<p rend="bodytext" n="82">Evaṃ me sutaṃ.</p>
+</body>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dn := Structure{Nikaya: "Dīghanikāyo", Book: "Sīlakkhandhavaggapāḷi", Sutta: "1. Brahmajālasuttaṃ"}
+	mn := Structure{Nikaya: "Majjhimanikāyo", Book: "Mūlapaṇṇāsapāḷi", Vagga: "1. Mūlapariyāyavaggo", Sutta: "1. Mūlapariyāyasuttaṃ"}
+	sn := Structure{Nikaya: "Majjhimanikāyo", Book: "Sagāthāvaggasaṃyuttapāḷi", Chapter: "1. Devatāsaṃyuttaṃ", Vagga: "1. Naḷavaggo", Sutta: "1. Oghataraṇasuttaṃ"}
+	want := []struct {
+		heading bool
+		s       Structure
+	}{
+		{true, Structure{Nikaya: "Dīghanikāyo"}},
+		{true, Structure{Nikaya: "Dīghanikāyo", Book: "Sīlakkhandhavaggapāḷi"}},
+		{true, dn},
+		{false, dn}, // a subhead that is no vagga or sutta
+		{false, withPara(dn, "1")},
+		{false, withPara(dn, "1")},
+		{true, Structure{Nikaya: "Majjhimanikāyo"}},
+		{true, Structure{Nikaya: "Majjhimanikāyo", Book: "Mūlapaṇṇāsapāḷi"}},
+		{true, Structure{Nikaya: "Majjhimanikāyo", Book: "Mūlapaṇṇāsapāḷi", Vagga: "1. Mūlapariyāyavaggo"}},
+		{true, mn},
+		{false, withPara(mn, "1")},
+		// a book clears the levels below the nikāya, whose head the file
+		// of the next book need not repeat
+		{true, Structure{Nikaya: "Majjhimanikāyo", Book: "Sagāthāvaggasaṃyuttapāḷi"}},
+		{true, Structure{Nikaya: "Majjhimanikāyo", Book: "Sagāthāvaggasaṃyuttapāḷi", Chapter: "1. Devatāsaṃyuttaṃ"}},
+		{true, Structure{Nikaya: "Majjhimanikāyo", Book: "Sagāthāvaggasaṃyuttapāḷi", Chapter: "1. Devatāsaṃyuttaṃ", Vagga: "1. Naḷavaggo"}},
+		{true, sn},
+		{false, withPara(sn, "1")},
+		{false, withPara(sn, "1")},
+		{true, Structure{Nikaya: "Majjhimanikāyo", Book: "Sagāthāvaggasaṃyuttapāḷi", Chapter: "2. Devaputtasaṃyuttaṃ", ParaNum: "1"}},
+		{false, Structure{Nikaya: "Majjhimanikāyo", Book: "Sagāthāvaggasaṃyuttapāḷi", Chapter: "2. Devaputtasaṃyuttaṃ", ParaNum: "82"}},
+	}
+	if len(paras) != len(want) {
+		t.Fatalf("%d paragraphs, want %d: %q", len(paras), len(want), paras)
+	}
+	var s Structure
+	for i, p := range paras {
+		heading := s.Next(p)
+		if heading != want[i].heading || s != want[i].s {
+			t.Errorf("after %q: %v, %+v; want %v, %+v", p.Text, heading, s, want[i].heading, want[i].s)
+		}
+	}
+}
+
+func withPara(s Structure, n string) Structure {
+	s.ParaNum = n
+	return s
+}
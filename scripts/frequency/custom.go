@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+)
+
+// customCorpora are the names of the corpora -custom-corpus registered, in
+// the order given.
+var customCorpora []string
+
+// addCustomCorpus registers the custom corpus of a -custom-corpus value,
+// [NAME=]DIR, with the cleaning rules of cfg.CleaningDir for NAME if it
+// has some.
+func addCustomCorpus(value string) error {
+	name, dir, ok := strings.Cut(value, "=")
+	if !ok {
+		name, dir = "custom", value
+	}
+	if dir == "" {
+		return fmt.Errorf("%q: want [NAME=]DIR", value)
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	c, err := corpora.NewCustom(name, dir)
+	if err != nil {
+		return err
+	}
+	cleaner, _, err := corpora.LoadCleaner(name, cfg.CleaningDir)
+	if err != nil {
+		return err
+	}
+	c.SetCleaner(cleaner)
+	corpora.Register(c)
+	customCorpora = append(customCorpora, name)
+	return nil
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// wordDelta is one entry whose count differs between two runs; Old or New
+// is 0 for an entry added or removed.
+type wordDelta struct {
+	Word  string `json:"word"`
+	Old   int    `json:"old"`
+	New   int    `json:"new"`
+	Delta int    `json:"delta"`
+}
+
+// tableDiff compares one table of two output sets. The entry lists hold
+// at most the top entries asked for; the counts before them hold all.
+type tableDiff struct {
+	Table     string      `json:"table"`
+	OldTokens int         `json:"old_tokens"`
+	NewTokens int         `json:"new_tokens"`
+	NAdded    int         `json:"n_added"`
+	NRemoved  int         `json:"n_removed"`
+	NChanged  int         `json:"n_changed"`
+	Added     []wordDelta `json:"added"`   // by new count, largest first
+	Removed   []wordDelta `json:"removed"` // by old count, largest first
+	Changed   []wordDelta `json:"changed"` // by size of the change, largest first
+	// how the runs that wrote the tables differ, by their sidecars
+	Meta []string `json:"meta,omitempty"`
+}
+
+// outputDiff compares two output sets, table by table.
+type outputDiff struct {
+	Old     string      `json:"old"`
+	New     string      `json:"new"`
+	OnlyOld []string    `json:"only_old"` // tables missing from the new set
+	OnlyNew []string    `json:"only_new"` // tables missing from the old set
+	Tables  []tableDiff `json:"tables"`
+}
+
+// diffCounts compares the counts of one table in two runs, keeping the top
+// entries of each list (all of them when top is 0).
+func diffCounts(name string, old, new map[string]int, top int) tableDiff {
+	d := tableDiff{
+		Table:     name,
+		OldTokens: freq.TokenTotal(old),
+		NewTokens: freq.TokenTotal(new),
+		Added:     []wordDelta{},
+		Removed:   []wordDelta{},
+		Changed:   []wordDelta{},
+	}
+	for w, n := range new {
+		m, ok := old[w]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, wordDelta{w, 0, n, n})
+		case m != n:
+			d.Changed = append(d.Changed, wordDelta{w, m, n, n - m})
+		}
+	}
+	for w, m := range old {
+		if _, ok := new[w]; !ok {
+			d.Removed = append(d.Removed, wordDelta{w, m, 0, -m})
+		}
+	}
+	d.NAdded, d.NRemoved, d.NChanged = len(d.Added), len(d.Removed), len(d.Changed)
+	d.Added = topDeltas(d.Added, top)
+	d.Removed = topDeltas(d.Removed, top)
+	d.Changed = topDeltas(d.Changed, top)
+	return d
+}
+
+// topDeltas orders list by the size of the change, then by word, and cuts
+// it to top entries unless top is 0.
+func topDeltas(list []wordDelta, top int) []wordDelta {
+	abs := func(n int) int { return max(n, -n) }
+	sort.Slice(list, func(i, j int) bool {
+		if a, b := abs(list[i].Delta), abs(list[j].Delta); a != b {
+			return a > b
+		}
+		return tools.ComparePali(list[i].Word, list[j].Word) < 0
+	})
+	if top > 0 && len(list) > top {
+		list = list[:top]
+	}
+	return list
+}
+
+// countTables finds the frequency tables of an output set: a directory,
+// searched with its books subdirectory, or a single table file. They are
+// returned by name without extension, like the names sinks get, so sets
+// written in different formats still pair up. Of a table written in several
+// formats, the file written last is taken.
+func countTables(root string) (map[string]string, error) {
+	fi, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[string]string)
+	if !fi.IsDir() {
+		tables[tableName(filepath.Base(root))] = root
+		return tables, nil
+	}
+	err = filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() {
+			// the file cache and other hidden directories hold no tables
+			if path != root && strings.HasPrefix(e.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		name := tableName(filepath.ToSlash(rel))
+		if !strings.HasSuffix(name, "_freq") {
+			return nil
+		}
+		plain, _ := splitCompression(path)
+		// Parquet tables are for other tools; diff reads the text formats
+		if f, err := parseOutputFormat(strings.TrimPrefix(filepath.Ext(plain), ".")); err != nil || f == formatParquet {
+			return nil
+		}
+		if prev, ok := tables[name]; ok && !newer(path, prev) {
+			return nil
+		}
+		tables[name] = path
+		return nil
+	})
+	return tables, err
+}
+
+// newer tells whether the file a was modified after the file b.
+func newer(a, b string) bool {
+	fa, errA := os.Stat(a)
+	fb, errB := os.Stat(b)
+	return errA == nil && errB == nil && fa.ModTime().After(fb.ModTime())
+}
+
+// tableName is a table file's path without its extensions, that of its
+// format and that of its compression.
+func tableName(path string) string {
+	path, _ = splitCompression(path)
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// diffOutputs compares the tables of the output sets old and new. Tables
+// without counts, such as the master list, are left out.
+func diffOutputs(old, new string, top int) (outputDiff, error) {
+	d := outputDiff{Old: old, New: new, OnlyOld: []string{}, OnlyNew: []string{}, Tables: []tableDiff{}}
+	oldTables, err := countTables(old)
+	if err != nil {
+		return d, err
+	}
+	newTables, err := countTables(new)
+	if err != nil {
+		return d, err
+	}
+	// two single files are compared whatever their names
+	if len(oldTables) == 1 && len(newTables) == 1 {
+		if fi, err := os.Stat(new); err == nil && !fi.IsDir() {
+			for name := range oldTables {
+				newTables = map[string]string{name: new}
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(oldTables)) {
+		if _, ok := newTables[name]; !ok {
+			d.OnlyOld = append(d.OnlyOld, name)
+			continue
+		}
+		a, err := readCounts(oldTables[name])
+		if errors.Is(err, errNoCounts) {
+			tools.Debugf("%s: %v; skipped", name, err)
+			continue
+		}
+		if err != nil {
+			return d, err
+		}
+		b, err := readCounts(newTables[name])
+		if err != nil {
+			return d, err
+		}
+		td := diffCounts(name, a, b, top)
+		if td.Meta, err = diffMeta(oldTables[name], newTables[name]); err != nil {
+			return d, err
+		}
+		d.Tables = append(d.Tables, td)
+	}
+	for _, name := range slices.Sorted(maps.Keys(newTables)) {
+		if _, ok := oldTables[name]; !ok {
+			d.OnlyNew = append(d.OnlyNew, name)
+		}
+	}
+	return d, nil
+}
+
+// diffMeta compares the sidecars of the tables old and new, when both
+// have one.
+func diffMeta(old, new string) ([]string, error) {
+	a, okA, err := readMeta(old)
+	if err != nil {
+		return nil, err
+	}
+	b, okB, err := readMeta(new)
+	if err != nil || !okA || !okB {
+		return nil, err
+	}
+	return metaChanges(a, b), nil
+}
+
+// writeReport prints d for reading, leaving out the tables that did not
+// change.
+func (d outputDiff) writeReport(w io.Writer) {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", d.Old, d.New)
+	for _, name := range d.OnlyOld {
+		fmt.Fprintf(w, "only in %s: %s\n", d.Old, name)
+	}
+	for _, name := range d.OnlyNew {
+		fmt.Fprintf(w, "only in %s: %s\n", d.New, name)
+	}
+	same := 0
+	for _, t := range d.Tables {
+		if t.NAdded+t.NRemoved+t.NChanged == 0 {
+			same++
+			continue
+		}
+		fmt.Fprintf(w, "\n%s: %d → %d tokens (%+d), %d added, %d removed, %d changed\n",
+			t.Table, t.OldTokens, t.NewTokens, t.NewTokens-t.OldTokens, t.NAdded, t.NRemoved, t.NChanged)
+		for _, m := range t.Meta {
+			fmt.Fprintf(w, "  # %s\n", m)
+		}
+		for _, e := range t.Added {
+			fmt.Fprintf(w, "  + %-24s %d\n", e.Word, e.New)
+		}
+		for _, e := range t.Removed {
+			fmt.Fprintf(w, "  - %-24s %d\n", e.Word, e.Old)
+		}
+		for _, e := range t.Changed {
+			fmt.Fprintf(w, "  ~ %-24s %d → %d (%+d)\n", e.Word, e.Old, e.New, e.Delta)
+		}
+	}
+	fmt.Fprintf(w, "\n%d tables compared, %d unchanged\n", len(d.Tables), same)
+}
+
+// runDiff implements the diff subcommand: it compares the frequency tables
+// of two runs, e.g. a copy of the output directory taken before changing
+// a corpus or a cleaning rule and the output directory after.
+func runDiff(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	commandUsage(fs, "Compares the frequency tables of OLD and NEW, two output sets (directories or single tables) given after the flags, and reports added and removed words and the largest count changes.")
+	top := fs.Int("top", 20, "number of entries listed per table and kind of change (0: all)")
+	asJson := fs.Bool("json", false, "write the report as JSON")
+	exitCode := fs.Bool("exit-code", false, "exit with status 1 when the sets differ")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	d, err := diffOutputs(fs.Arg(0), fs.Arg(1), *top)
+	if err != nil {
+		tools.Errorf("%v", err)
+		os.Exit(1)
+	}
+	if *asJson {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(d); err != nil {
+			tools.Errorf("%v", err)
+			os.Exit(1)
+		}
+	} else {
+		d.writeReport(os.Stdout)
+	}
+
+	if *exitCode && d.differs() {
+		os.Exit(1)
+	}
+}
+
+// differs tells whether any table was added, removed or changed.
+func (d outputDiff) differs() bool {
+	if len(d.OnlyOld) > 0 || len(d.OnlyNew) > 0 {
+		return true
+	}
+	for _, t := range d.Tables {
+		if t.NAdded+t.NRemoved+t.NChanged > 0 {
+			return true
+		}
+	}
+	return false
+}
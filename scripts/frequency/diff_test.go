@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+)
+
+func TestReadCountsFormats(t *testing.T) {
+	want := map[string]int{"ca": 5, "dhamma, vinaya": 2, `"evaṃ"`: 1}
+	tab := freqTable(freq.Sorted(want))
+	dir := t.TempDir()
+	for _, c := range []compression{compressNone, compressGzip, compressZstd} {
+		for _, f := range []outputFormat{formatTsv, formatCsv, formatJson, formatJsonl} {
+			path := filepath.Join(dir, "cst_freq")
+			if err := writeTable(path, f, c, tab); err != nil {
+				t.Fatal(err)
+			}
+			file := path + "." + string(f) + c.ext()
+			got, err := readCounts(file)
+			if err != nil {
+				t.Fatalf("%s: %v", file, err)
+			}
+			if !maps.Equal(got, want) {
+				t.Errorf("%s: read %v, want %v", file, got, want)
+			}
+			tables, err := countTables(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tables["cst_freq"] != file {
+				t.Errorf("countTables(%s) = %v, want it as cst_freq", file, tables)
+			}
+		}
+	}
+}
+
+func TestDiffCounts(t *testing.T) {
+	old := map[string]int{"ca": 10, "vā": 4, "hi": 3, "kho": 2}
+	new := map[string]int{"ca": 12, "vā": 1, "hi": 3, "pana": 5}
+	d := diffCounts("cst_freq", old, new, 1)
+	if d.NAdded != 1 || d.NRemoved != 1 || d.NChanged != 2 {
+		t.Fatalf("%d added, %d removed, %d changed", d.NAdded, d.NRemoved, d.NChanged)
+	}
+	if d.Added[0] != (wordDelta{"pana", 0, 5, 5}) || d.Removed[0] != (wordDelta{"kho", 2, 0, -2}) {
+		t.Errorf("added %v, removed %v", d.Added, d.Removed)
+	}
+	// vā fell by 3, more than ca rose, and only the top change is kept
+	if len(d.Changed) != 1 || d.Changed[0] != (wordDelta{"vā", 4, 1, -3}) {
+		t.Errorf("changed %v", d.Changed)
+	}
+}
+
+func TestParquetTableNotCounted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cst_freq")
+	tab := freqTable(freq.Sorted(map[string]int{"ca": 5, "evaṃ": 1}))
+	if err := writeTable(path, formatParquet, compressZstd, tab); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path + ".parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatalf("%s.parquet is no Parquet file", path)
+	}
+	if _, err := readCounts(path + ".parquet"); err == nil {
+		t.Error("readCounts read a Parquet table")
+	}
+	tables, err := countTables(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 0 {
+		t.Errorf("countTables(%s) = %v, want the Parquet table left out", dir, tables)
+	}
+}
+
+func TestSidecars(t *testing.T) {
+	t.Cleanup(func() { sources.normalizer = nil })
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	run := func(dir string, counts map[string]int, chain string) {
+		t.Helper()
+		nz, err := pali.ParseNormalizer(chain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		useNormalizer(nz)
+		s := fileSink{dir: dir, format: formatTsv, meta: &sidecars{}}
+		if err := s.Write("cst_freq", freqTable(freq.Sorted(counts))); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run(oldDir, map[string]int{"evaṃ": 2}, "")
+	run(newDir, map[string]int{"evaṃ": 2, "evaṁ": 1}, "nfc,lowercase")
+
+	path := filepath.Join(newDir, "cst_freq.tsv")
+	m, ok, err := readMeta(path)
+	if err != nil || !ok {
+		t.Fatalf("no sidecar: %v", err)
+	}
+	if sum, _ := freq.HashFile(path); m.SHA256 != sum || m.Tool != "palifreq" || !slices.Equal(m.Normalizer, []string{"nfc", "lowercase"}) {
+		t.Errorf("sidecar %+v", m)
+	}
+
+	d, err := diffOutputs(oldDir, newDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the sidecars are no tables
+	if len(d.Tables) != 1 || len(d.OnlyNew) != 0 {
+		t.Fatalf("tables %v, only new %v", d.Tables, d.OnlyNew)
+	}
+	want := []string{"normalizer: nfc,unify-niggahita,lowercase → nfc,lowercase"}
+	if got := d.Tables[0].Meta; !slices.Equal(got, want) {
+		t.Errorf("meta changes %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"dpd/go_modules/tools"
+)
+
+// downloadSource is where the sources of a corpus come from: an archive,
+// the SHA-256 it must have, and the directory inside it that holds the
+// corpus files. The archive is URL, or the GitHub archive of Repo at
+// Commit, whose Subdir is then inside the repository.
+type downloadSource struct {
+	URL    string `toml:"url"`
+	Repo   string `toml:"repo"`   // owner/name on GitHub, instead of URL
+	Commit string `toml:"commit"` // commit or branch of Repo
+	SHA256 string `toml:"sha256"` // hex; empty skips verification with a warning
+	Subdir string `toml:"subdir"` // path inside the archive, "/"-separated
+}
+
+// archive returns the URL of the archive of s and the path of the corpus
+// files inside it.
+func (s downloadSource) archive() (url, subdir string) {
+	if s.Repo == "" {
+		return s.URL, s.Subdir
+	}
+	// GitHub names the top directory <name>-<commit>
+	top := path.Base(s.Repo) + "-" + s.Commit
+	return "https://github.com/" + s.Repo + "/archive/" + s.Commit + ".zip", path.Join(top, s.Subdir)
+}
+
+// defaultSources are the upstream repositories of the editions that have
+// one: the CST XML in Roman and Devanagari script and the BJT in Roman
+// and Sinhala script, two corpora to an archive. They track a branch, so
+// their checksum changes with every upstream commit; pin a commit and its
+// sha256 in palifreq.toml to verify it. The Syāmaraṭṭha editions have
+// none: see manualCorpora.
+var defaultSources = map[string]downloadSource{
+	"cst":      {Repo: "VipassanaTech/tipitaka-xml", Commit: "main", Subdir: "romn"},
+	"cst_deva": {Repo: "VipassanaTech/tipitaka-xml", Commit: "main", Subdir: "deva"},
+	"bjt":      {Repo: "pathnirvana/tipitaka.lk", Commit: "master", Subdir: "public/static/roman_txt"},
+	"bjt_sinh": {Repo: "pathnirvana/tipitaka.lk", Commit: "master", Subdir: "public/static/text"},
+}
+
+// manualCorpora are the editions with no built-in source, and why: their
+// text files are copied into the corpus directory by hand, or fetched from
+// a [download.<corpus>] table of palifreq.toml naming a mirror.
+var manualCorpora = map[string]string{
+	"sya":      "the romanized Syāmaraṭṭha text files are in no public repository or release archive to pin",
+	"sya_thai": "the Thai-script Syāmaraṭṭha text files are in no public repository or release archive to pin",
+}
+
+// pendingDownload is a corpus to unpack from an archive fetched for it.
+type pendingDownload struct {
+	name   string
+	src    downloadSource
+	subdir string
+	dest   string
+}
+
+// runDownload implements the download subcommand.
+func runDownload(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("download", flag.ExitOnError)
+	commandUsage(fs, "Downloads corpus archives, verifies their SHA-256 and unpacks them into the corpus directories, fetching an archive once for all the corpora in it. SYA and SYA Thai have no built-in source, their text files being in no public repository or archive to pin; copy them into their directories by hand or add a [download.<corpus>] table.")
+	names := fs.String("corpora", "", "comma-separated corpora to download (default: all with a source)")
+	force := fs.Bool("force", false, "download even when the corpus directory already exists")
+	strict := fs.Bool("require-checksum", false, "refuse sources without a sha256")
+	fs.Parse(args)
+
+	tools.PTitle("downloading corpora")
+	tic := tools.Tic()
+
+	var list []string
+	if *names == "" {
+		for name := range cfg.Download {
+			list = append(list, name)
+		}
+		sort.Strings(list)
+	} else {
+		list = strings.Split(*names, ",")
+	}
+	// the corpora of one archive, such as cst and cst_deva, in the order
+	// of the first of them
+	var urls []string
+	byURL := make(map[string][]pendingDownload)
+	for _, name := range list {
+		src, ok := cfg.Download[name]
+		url, subdir := src.archive()
+		if !ok || url == "" {
+			if why, manual := manualCorpora[name]; manual {
+				tools.Errorf("%s: no download source, as %s; copy its files into %s by hand or add [download.%s] to palifreq.toml", name, why, cfg.Corpora[name], name)
+				continue
+			}
+			tools.Errorf("%s: no download source; add [download.%s] to palifreq.toml", name, name)
+			continue
+		}
+		dest, ok := cfg.Corpora[name]
+		if !ok {
+			tools.Errorf("%s: unknown corpus", name)
+			continue
+		}
+		if _, err := os.Stat(dest); err == nil && !*force {
+			tools.Infof("%s: %s exists, skipping (use -force to replace it)", name, dest)
+			continue
+		}
+		if src.SHA256 == "" {
+			if *strict {
+				tools.Errorf("%s: no sha256 for %s", name, url)
+				continue
+			}
+			tools.Warnf("%s: no sha256 configured, the archive will not be verified", name)
+		}
+		if _, ok := byURL[url]; !ok {
+			urls = append(urls, url)
+		}
+		byURL[url] = append(byURL[url], pendingDownload{name: name, src: src, subdir: subdir, dest: dest})
+	}
+	for _, url := range urls {
+		if ctx.Err() != nil {
+			break
+		}
+		download(ctx, url, byURL[url])
+	}
+
+	tic.Toc()
+}
+
+// download fetches the archive at url once and unpacks the corpora of
+// pending from it, each checked against its own sha256, logging the
+// errors. When ctx is done while fetching, the corpus directories are
+// left as they were.
+func download(ctx context.Context, url string, pending []pendingDownload) {
+	var names []string
+	for _, d := range pending {
+		names = append(names, d.name)
+	}
+	label := strings.Join(names, ",")
+	archive, sum, err := fetch(ctx, label, url)
+	if err != nil {
+		tools.Errorf("%s: %v", label, err)
+		return
+	}
+	defer os.Remove(archive)
+	for _, d := range pending {
+		if d.src.SHA256 == "" {
+			tools.Infof("%s: sha256 %s", d.name, sum)
+		} else if !strings.EqualFold(sum, d.src.SHA256) {
+			tools.Errorf("%s: checksum mismatch for %s: got %s, want %s", d.name, url, sum, d.src.SHA256)
+			continue
+		}
+		n, err := install(archive, url, d.subdir, d.dest)
+		if err != nil {
+			tools.Errorf("%s: %v", d.name, err)
+			continue
+		}
+		tools.Infof("%s: %d files in %s", d.name, n, d.dest)
+	}
+}
+
+// install replaces dest with the files under subdir of the archive
+// fetched from url. It returns the number of files unpacked.
+func install(archive, url, subdir, dest string) (int, error) {
+	// unpack next to dest and swap, so a failed run leaves the old copy
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return 0, err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dest), filepath.Base(dest)+".download-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(tmp)
+	n, err := unpack(archive, subdir, tmp)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("no files under %q in %s", subdir, url)
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		return 0, err
+	}
+	return n, os.Rename(tmp, dest)
+}
+
+// fetch downloads url to a temporary file and returns its path and
+// SHA-256.
+func fetch(ctx context.Context, name, url string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	f, err := os.CreateTemp("", "palifreq-"+name+"-")
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	prog := tools.NewProgress(name+" KiB", int(max(resp.ContentLength, 0)/1024))
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h, kibCounter{prog}), resp.Body)
+	prog.Finish()
+	if err != nil {
+		os.Remove(f.Name())
+		return "", "", err
+	}
+	return f.Name(), hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// kibCounter advances a progress bar by the KiB written through it.
+type kibCounter struct{ p *tools.Progress }
+
+func (k kibCounter) Write(b []byte) (int, error) {
+	k.p.Add(len(b) / 1024)
+	return len(b), nil
+}
+
+// unpack extracts the files under subdir of a zip or gzipped tar archive
+// into dest, keeping their paths relative to subdir.
+func unpack(archive, subdir, dest string) (int, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	magic, _ := bufio.NewReader(f).Peek(4)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	prefix := strings.Trim(subdir, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	switch {
+	case slices.Equal(magic, []byte("PK\x03\x04")):
+		return unpackZip(archive, prefix, dest)
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		return unpackTarGz(f, prefix, dest)
+	}
+	return 0, fmt.Errorf("%s is neither a zip nor a tar.gz archive", archive)
+}
+
+func unpackZip(archive, prefix, dest string) (int, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+	n := 0
+	for _, zf := range zr.File {
+		target, ok := extractPath(zf.Name, prefix, dest)
+		if !ok || zf.FileInfo().IsDir() {
+			continue
+		}
+		r, err := zf.Open()
+		if err != nil {
+			return n, err
+		}
+		err = writeFile(target, r)
+		r.Close()
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func unpackTarGz(r io.Reader, prefix, dest string) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	n := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		target, ok := extractPath(hdr.Name, prefix, dest)
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := writeFile(target, tr); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// extractPath maps an archive entry to its path under dest. It reports
+// false for entries outside prefix and for names that would escape dest.
+func extractPath(name, prefix, dest string) (string, bool) {
+	name = path.Clean("/" + name)[1:]
+	if !strings.HasPrefix(name, prefix) || name == strings.TrimSuffix(prefix, "/") {
+		return "", false
+	}
+	rel := strings.TrimPrefix(name, prefix)
+	if rel == "" || !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", false
+	}
+	return filepath.Join(dest, filepath.FromSlash(rel)), true
+}
+
+func writeFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"dpd/go_modules/tools"
+)
+
+func TestSourceArchive(t *testing.T) {
+	url, subdir := defaultSources["bjt"].archive()
+	if url != "https://github.com/pathnirvana/tipitaka.lk/archive/master.zip" || subdir != "tipitaka.lk-master/public/static/roman_txt" {
+		t.Errorf("bjt archive %s, %s", url, subdir)
+	}
+	src := downloadSource{URL: "https://example.org/sya.tar.gz", Subdir: "sya/txt"}
+	if url, subdir := src.archive(); url != src.URL || subdir != src.Subdir {
+		t.Errorf("sya archive %s, %s", url, subdir)
+	}
+}
+
+func TestDownloadShared(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, text := range map[string]string{
+		"lk-1/roman_txt/dn1.txt": "evaṃ me sutaṃ",
+		"lk-1/text/dn1.json":     "{}",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(text))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	dir := t.TempDir()
+	cfg = defaultConfig()
+	cfg.Corpora = map[string]string{
+		"bjt":      filepath.Join(dir, "roman"),
+		"bjt_sinh": filepath.Join(dir, "sinh"),
+		"cst":      filepath.Join(dir, "cst"),
+	}
+	cfg.Download = map[string]downloadSource{
+		"bjt":      {URL: srv.URL + "/lk.zip", Subdir: "lk-1/roman_txt"},
+		"bjt_sinh": {URL: srv.URL + "/lk.zip", Subdir: "lk-1/text"},
+		"cst":      {URL: srv.URL + "/lk.zip", Subdir: "lk-1/text", SHA256: "00"},
+	}
+
+	before := tools.Errors()
+	runDownload(context.Background(), []string{"-corpora", "bjt,bjt_sinh,cst"})
+	if n := hits.Load(); n != 1 {
+		t.Errorf("%d requests for one archive, want 1", n)
+	}
+	for _, path := range []string{"roman/dn1.txt", "sinh/dn1.json"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+	// the corpus pinned to another checksum is left out alone
+	if tools.Errors() != before+1 {
+		t.Errorf("%d errors, want the checksum mismatch of cst", tools.Errors()-before)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cst")); err == nil {
+		t.Errorf("cst unpacked despite its checksum")
+	}
+}
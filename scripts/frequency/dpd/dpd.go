@@ -0,0 +1,618 @@
+// Package dpd reads the parts of the Digital Pāḷi Dictionary database
+// (dpd.db) the frequency tools need: the inflection lookup table, headword
+// details and inflection templates.
+package dpd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	_ "modernc.org/sqlite"
+
+	"dpd/go_modules/tools"
+)
+
+// DB is an open, read-only dpd.db.
+type DB struct {
+	db      *sql.DB
+	schema  schema
+	release string
+	sql     *strings.Replacer // rewrites the queries for schema
+}
+
+// schema is a layout of dpd.db this package reads. Queries are written for
+// the newest; the table and column names of an older one replace theirs.
+type schema struct {
+	version   int
+	headwords string // the table of headwords
+	lemma     string // its column of the lemma
+	roots     string // the table of roots
+}
+
+// schemas are the layouts read, newest first. Releases before the rename of
+// pali_words to dpd_headwords, of its pali_1 to lemma_1 and of pali_roots
+// to dpd_roots have schema 1.
+var schemas = []schema{
+	{2, "dpd_headwords", "lemma_1", "dpd_roots"},
+	{1, "pali_words", "pali_1", "pali_roots"},
+}
+
+// schemaColumns are the columns read of each table, the table of headwords
+// under its name in the newest schema, besides its lemma.
+var schemaColumns = map[string][]string{
+	"dpd_headwords":        {"id", "pos", "stem", "pattern", "meaning_1", "meaning_2", "construction", "grammar"},
+	"lookup":               {"lookup_key", "headwords", "deconstructor"},
+	"inflection_templates": {"pattern", "data"},
+}
+
+// PinnedRelease, when set, is the only DPD release Open accepts, as the
+// dpd_release_version of the db_info table gives it, e.g. "v0.3.20250501".
+// palifreq sets it from dpd_release in palifreq.toml.
+var PinnedRelease string
+
+// releasesURL is where the DPD releases, dpd.db among their assets, are
+// published.
+const releasesURL = "https://github.com/digitalpalidictionary/dpd-db/releases"
+
+// Open opens the DPD database at path read-only. It fails when the
+// database has a layout this package cannot read, or is not the release
+// PinnedRelease asks for, saying which release to fetch instead. Its
+// errors name path.
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite", tools.SQLiteURI(path, "mode=ro"))
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	d := &DB{db: db}
+	if err := d.check(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+func (d *DB) Close() error { return d.db.Close() }
+
+// Release is the DPD release of the database, e.g. "v0.3.20250501", or ""
+// when it has no db_info table telling it.
+func (d *DB) Release() string { return d.release }
+
+// Schema is the version of the layout of the database, the latest 2.
+func (d *DB) Schema() int { return d.schema.version }
+
+// check reads the release and finds the schema of the database.
+func (d *DB) check() error {
+	tables, err := d.columns()
+	if err != nil {
+		return err
+	}
+	if tables["db_info"]["key"] && tables["db_info"]["value"] {
+		err := d.db.QueryRow(`SELECT value FROM db_info WHERE key = 'dpd_release_version'`).Scan(&d.release)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("reading db_info: %w", err)
+		}
+	}
+	if PinnedRelease != "" && d.release != PinnedRelease {
+		have := "has no release in its db_info table"
+		if d.release != "" {
+			have = "is DPD release " + d.release
+		}
+		return fmt.Errorf("%s, but dpd_release pins %s; fetch the dpd.db of %s from %s, or change dpd_release", have, PinnedRelease, PinnedRelease, releasesURL)
+	}
+	var problems []string
+	for _, s := range schemas {
+		missing := s.missing(tables)
+		if len(missing) == 0 {
+			d.schema = s
+			d.sql = strings.NewReplacer("dpd_headwords", s.headwords, "lemma_1", s.lemma, "dpd_roots", s.roots)
+			return nil
+		}
+		problems = append(problems, fmt.Sprintf("schema %d lacks %s", s.version, strings.Join(missing, ", ")))
+	}
+	release := ""
+	if d.release != "" {
+		release = " (release " + d.release + ")"
+	}
+	suggest := "the latest release"
+	if PinnedRelease != "" {
+		suggest = "release " + PinnedRelease
+	}
+	return fmt.Errorf("not a DPD database of a schema palifreq reads%s: %s; fetch the dpd.db of %s from %s", release, strings.Join(problems, "; "), suggest, releasesURL)
+}
+
+// columns returns the columns of each table of the database.
+func (d *DB) columns() (map[string]map[string]bool, error) {
+	rows, err := d.db.Query(`SELECT m.name, c.name FROM sqlite_master m, pragma_table_info(m.name) c WHERE m.type = 'table'`)
+	if err != nil {
+		return nil, fmt.Errorf("reading the tables: %w", err)
+	}
+	defer rows.Close()
+	tables := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, col string
+		if err := rows.Scan(&table, &col); err != nil {
+			return nil, err
+		}
+		if tables[table] == nil {
+			tables[table] = make(map[string]bool)
+		}
+		tables[table][col] = true
+	}
+	return tables, rows.Err()
+}
+
+// missing lists the tables and columns of s that tables lacks, as
+// table.column, or the table alone when it lacks it all.
+func (s schema) missing(tables map[string]map[string]bool) []string {
+	var missing []string
+	for _, table := range []string{"dpd_headwords", "lookup", "inflection_templates"} {
+		want := schemaColumns[table]
+		if table == "dpd_headwords" {
+			table = s.headwords
+			want = append([]string{s.lemma}, want...)
+		}
+		have, ok := tables[table]
+		if !ok {
+			missing = append(missing, table)
+			continue
+		}
+		for _, col := range want {
+			if !have[col] {
+				missing = append(missing, table+"."+col)
+			}
+		}
+	}
+	return missing
+}
+
+// query runs q, written for the newest schema, on the database.
+func (d *DB) query(q string) (*sql.Rows, error) {
+	return d.db.Query(d.sql.Replace(q))
+}
+
+// Headword is a row of dpd_headwords.
+type Headword struct {
+	ID     int
+	Lemma1 string // e.g. "dhamma 1"
+	Pos    string
+}
+
+// Lookup maps every inflected form to the ids of the headwords it can
+// belong to, from the lookup table's headwords column.
+func (d *DB) Lookup() (map[string][]int, error) {
+	rows, err := d.query(`SELECT lookup_key, headwords FROM lookup WHERE headwords != '' AND headwords != '[]'`)
+	if err != nil {
+		return nil, fmt.Errorf("reading lookup: %w", err)
+	}
+	defer rows.Close()
+
+	lookup := make(map[string][]int)
+	for rows.Next() {
+		var key, list string
+		if err := rows.Scan(&key, &list); err != nil {
+			return nil, err
+		}
+		var ids []int
+		if err := json.Unmarshal([]byte(list), &ids); err != nil {
+			return nil, fmt.Errorf("lookup %q: %w", key, err)
+		}
+		lookup[key] = ids
+	}
+	return lookup, rows.Err()
+}
+
+// Headwords returns all headwords by id.
+func (d *DB) Headwords() (map[int]Headword, error) {
+	rows, err := d.query(`SELECT id, lemma_1, pos FROM dpd_headwords`)
+	if err != nil {
+		return nil, fmt.Errorf("reading dpd_headwords: %w", err)
+	}
+	defer rows.Close()
+
+	heads := make(map[int]Headword)
+	for rows.Next() {
+		var h Headword
+		if err := rows.Scan(&h.ID, &h.Lemma1, &h.Pos); err != nil {
+			return nil, err
+		}
+		heads[h.ID] = h
+	}
+	return heads, rows.Err()
+}
+
+// Deconstructions maps forms to DPD's sandhi and compound splits from the
+// lookup table's deconstructor column, best split first. Each split is a
+// string of parts joined by " + ", e.g. "evaṃ + me".
+func (d *DB) Deconstructions() (map[string][]string, error) {
+	rows, err := d.query(`SELECT lookup_key, deconstructor FROM lookup WHERE deconstructor != '' AND deconstructor != '[]'`)
+	if err != nil {
+		return nil, fmt.Errorf("reading lookup: %w", err)
+	}
+	defer rows.Close()
+
+	splits := make(map[string][]string)
+	for rows.Next() {
+		var key, list string
+		if err := rows.Scan(&key, &list); err != nil {
+			return nil, err
+		}
+		var parts []string
+		if err := json.Unmarshal([]byte(list), &parts); err != nil {
+			return nil, fmt.Errorf("deconstructor %q: %w", key, err)
+		}
+		splits[key] = parts
+	}
+	return splits, rows.Err()
+}
+
+// Inflection is one form generated by a headword's inflection template.
+type Inflection struct {
+	HeadwordID int
+	Pos        string
+	Pattern    string // inflection_templates.pattern, e.g. "a masc"
+	Form       string
+	Ending     string // "-" when the form is the bare stem
+	Grammar    string // e.g. "masc nom sg"
+}
+
+// stemMarkers are the characters DPD adds to stems that never appear in
+// the inflected forms.
+var stemMarkers = strings.NewReplacer("!", "", "*", "")
+
+// Inflections calls fn with every form generated from the stem and
+// inflection template of each headword that has both.
+//
+// A template is a JSON table whose first row holds column headings; every
+// other row starts with its label and continues in pairs of cells, a list
+// of endings followed by the grammar they express.
+func (d *DB) Inflections(fn func(Inflection)) error {
+	rows, err := d.query(`
+		SELECT h.id, h.pos, h.stem, h.pattern, t.data
+		FROM dpd_headwords h JOIN inflection_templates t ON t.pattern = h.pattern
+		WHERE h.stem != '' AND h.stem != '-'`)
+	if err != nil {
+		return fmt.Errorf("reading inflection templates: %w", err)
+	}
+	defer rows.Close()
+
+	// headwords share templates, so each is decoded once
+	templates := make(map[string][][][]string)
+	for rows.Next() {
+		var id int
+		var pos, stem, pattern, data string
+		if err := rows.Scan(&id, &pos, &stem, &pattern, &data); err != nil {
+			return err
+		}
+		table, ok := templates[pattern]
+		if !ok {
+			if err := json.Unmarshal([]byte(data), &table); err != nil {
+				return fmt.Errorf("template %q: %w", pattern, err)
+			}
+			templates[pattern] = table
+		}
+		stem = stemMarkers.Replace(stem)
+		for _, row := range table[min(1, len(table)):] {
+			for i := 1; i+1 < len(row); i += 2 {
+				grammar := strings.Join(row[i+1], " ")
+				for _, ending := range row[i] {
+					if ending == "" {
+						continue
+					}
+					form := stem + ending
+					if ending == "-" {
+						form = stem
+					}
+					fn(Inflection{id, pos, pattern, form, ending, grammar})
+				}
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// Gloss is the dictionary side of a headword: its meaning and how it is
+// built.
+type Gloss struct {
+	Meaning      string // meaning_1, or meaning_2 for entries not yet finalised
+	Construction string // e.g. "√budh + ta"
+}
+
+// Glosses returns the gloss of every headword by id.
+func (d *DB) Glosses() (map[int]Gloss, error) {
+	rows, err := d.query(`
+		SELECT id, COALESCE(NULLIF(meaning_1, ''), meaning_2, ''), COALESCE(construction, '')
+		FROM dpd_headwords`)
+	if err != nil {
+		return nil, fmt.Errorf("reading dpd_headwords: %w", err)
+	}
+	defer rows.Close()
+
+	glosses := make(map[int]Gloss)
+	for rows.Next() {
+		var id int
+		var g Gloss
+		if err := rows.Scan(&id, &g.Meaning, &g.Construction); err != nil {
+			return nil, err
+		}
+		glosses[id] = g
+	}
+	return glosses, rows.Err()
+}
+
+// ProperNouns returns the ids of the headwords that are names of people,
+// places, texts and the like. DPD marks them with "name" in the grammar,
+// as in "masc, name", or starts their meaning with "name of".
+func (d *DB) ProperNouns() (map[int]bool, error) {
+	rows, err := d.query(`
+		SELECT id, COALESCE(grammar, ''), COALESCE(NULLIF(meaning_1, ''), meaning_2, '')
+		FROM dpd_headwords`)
+	if err != nil {
+		return nil, fmt.Errorf("reading dpd_headwords: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		var grammar, meaning string
+		if err := rows.Scan(&id, &grammar, &meaning); err != nil {
+			return nil, err
+		}
+		if isProperNoun(grammar, meaning) {
+			names[id] = true
+		}
+	}
+	return names, rows.Err()
+}
+
+// isProperNoun tells a name by its grammar and meaning.
+func isProperNoun(grammar, meaning string) bool {
+	for _, g := range strings.Split(grammar, ",") {
+		if strings.TrimSpace(g) == "name" {
+			return true
+		}
+	}
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(meaning)), "name of ")
+}
+
+// Name kinds of Names.
+const (
+	Person    = "person"
+	Place     = "place"
+	OtherName = "other"
+)
+
+// placeWords and personWords tell the kind of a name by the first of them
+// its meaning has, as in "name of a village" or "name of a monk".
+var (
+	placeWords = []string{
+		"village", "town", "city", "capital", "country", "kingdom", "region", "district", "province",
+		"river", "lake", "pond", "sea", "ocean", "island", "mountain", "hill", "rock", "cave",
+		"forest", "grove", "wood", "park", "garden", "monastery", "hermitage", "shrine", "place",
+	}
+	personWords = []string{
+		"monk", "nun", "bhikkhu", "bhikkhunī", "elder", "arahant", "buddha", "disciple", "ascetic", "wanderer",
+		"brahman", "brahmin", "king", "queen", "prince", "princess", "minister", "general", "merchant",
+		"householder", "lay follower", "man", "woman", "boy", "girl", "son", "daughter", "wife", "mother", "father",
+		"deva", "god", "goddess", "yakkha", "nāga", "asura", "spirit", "person",
+	}
+)
+
+// Names returns the kind, Person, Place or OtherName, of the headwords
+// that are proper nouns as ProperNouns tells them, by id, from the words
+// of their meaning.
+func (d *DB) Names() (map[int]string, error) {
+	rows, err := d.query(`
+		SELECT id, COALESCE(grammar, ''), COALESCE(NULLIF(meaning_1, ''), meaning_2, '')
+		FROM dpd_headwords`)
+	if err != nil {
+		return nil, fmt.Errorf("reading dpd_headwords: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var grammar, meaning string
+		if err := rows.Scan(&id, &grammar, &meaning); err != nil {
+			return nil, err
+		}
+		if isProperNoun(grammar, meaning) {
+			names[id] = nameKind(meaning)
+		}
+	}
+	return names, rows.Err()
+}
+
+// nameKind tells the kind of a name by the earliest of the place and
+// person words its meaning has as a word of its own.
+func nameKind(meaning string) string {
+	words := strings.FieldsFunc(strings.ToLower(meaning), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	text := " " + strings.Join(words, " ") + " "
+	kind, first := OtherName, len(text)
+	for _, list := range []struct {
+		kind  string
+		words []string
+	}{{Place, placeWords}, {Person, personWords}} {
+		for _, w := range list.words {
+			if i := strings.Index(text, " "+w+" "); i >= 0 && i < first {
+				kind, first = list.kind, i
+			}
+		}
+	}
+	return kind
+}
+
+// Number kinds of Numbers.
+const (
+	Cardinal = "cardinal"
+	Ordinal  = "ordinal"
+)
+
+// Numbers returns the kind, Cardinal or Ordinal, of the headwords that are
+// number words, by id. DPD gives them the parts of speech card and ordin.
+func (d *DB) Numbers() (map[int]string, error) {
+	rows, err := d.query(`SELECT id, pos FROM dpd_headwords WHERE pos IN ('card', 'ordin')`)
+	if err != nil {
+		return nil, fmt.Errorf("reading dpd_headwords: %w", err)
+	}
+	defer rows.Close()
+
+	numbers := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var pos string
+		if err := rows.Scan(&id, &pos); err != nil {
+			return nil, err
+		}
+		numbers[id] = Cardinal
+		if pos == "ordin" {
+			numbers[id] = Ordinal
+		}
+	}
+	return numbers, rows.Err()
+}
+
+// Root is a root DPD derives headwords from, e.g. "√gam", with its
+// meaning, e.g. "going", when the database has one.
+type Root struct {
+	Key     string
+	Meaning string
+}
+
+// Roots returns the root of every headword DPD gives one, by id, from the
+// root_key column of the headwords, with the meanings of the roots table
+// when the database has it. A database without root_key is an error
+// saying so.
+func (d *DB) Roots() (map[int]Root, error) {
+	tables, err := d.columns()
+	if err != nil {
+		return nil, err
+	}
+	if !tables[d.schema.headwords]["root_key"] {
+		return nil, fmt.Errorf("%s has no root_key column; fetch a release with roots from %s", d.schema.headwords, releasesURL)
+	}
+	meanings := make(map[string]string)
+	if tables[d.schema.roots]["root"] && tables[d.schema.roots]["root_meaning"] {
+		rows, err := d.query(`SELECT root, COALESCE(root_meaning, '') FROM dpd_roots`)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", d.schema.roots, err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var root, meaning string
+			if err := rows.Scan(&root, &meaning); err != nil {
+				return nil, err
+			}
+			meanings[root] = meaning
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := d.query(`SELECT id, root_key FROM dpd_headwords WHERE root_key IS NOT NULL AND root_key != ''`)
+	if err != nil {
+		return nil, fmt.Errorf("reading dpd_headwords: %w", err)
+	}
+	defer rows.Close()
+	roots := make(map[int]Root)
+	for rows.Next() {
+		var id int
+		var key string
+		if err := rows.Scan(&id, &key); err != nil {
+			return nil, err
+		}
+		key = strings.TrimSpace(key)
+		roots[id] = Root{key, meanings[key]}
+	}
+	return roots, rows.Err()
+}
+
+// Prefixes returns the prefixes (upasagga) before the root of every
+// headword DPD gives a root family, by id, from the family_root column:
+// "pa ā" for the family "pa ā √gam", "" for the bare root "√gam". A
+// database without family_root is an error saying so.
+func (d *DB) Prefixes() (map[int]string, error) {
+	tables, err := d.columns()
+	if err != nil {
+		return nil, err
+	}
+	if !tables[d.schema.headwords]["family_root"] {
+		return nil, fmt.Errorf("%s has no family_root column; fetch a release with roots from %s", d.schema.headwords, releasesURL)
+	}
+	rows, err := d.query(`SELECT id, family_root FROM dpd_headwords WHERE family_root IS NOT NULL AND family_root != ''`)
+	if err != nil {
+		return nil, fmt.Errorf("reading dpd_headwords: %w", err)
+	}
+	defer rows.Close()
+	prefixes := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var family string
+		if err := rows.Scan(&id, &family); err != nil {
+			return nil, err
+		}
+		fields := strings.Fields(family)
+		i := slices.IndexFunc(fields, func(f string) bool { return strings.HasPrefix(f, "√") })
+		if i < 0 {
+			continue
+		}
+		prefixes[id] = strings.Join(fields[:i], " ")
+	}
+	return prefixes, rows.Err()
+}
+
+// irregularUses is the number of headwords an inflection template must
+// serve to count as a regular declension or conjugation.
+const irregularUses = 3
+
+// Irregular returns the ids of the headwords that inflect irregularly:
+// those whose stem carries the ! DPD marks irregular forms with, as in
+// bhagav!, and those whose template serves fewer than irregularUses
+// headwords. Indeclinables have no template and are not irregular.
+func (d *DB) Irregular() (map[int]bool, error) {
+	rows, err := d.query(`
+		SELECT id, COALESCE(stem, ''), COALESCE(pattern, '')
+		FROM dpd_headwords`)
+	if err != nil {
+		return nil, fmt.Errorf("reading dpd_headwords: %w", err)
+	}
+	defer rows.Close()
+
+	irregular := make(map[int]bool)
+	byPattern := make(map[string][]int)
+	for rows.Next() {
+		var id int
+		var stem, pattern string
+		if err := rows.Scan(&id, &stem, &pattern); err != nil {
+			return nil, err
+		}
+		if pattern == "" || stem == "" || stem == "-" {
+			continue
+		}
+		if strings.Contains(stem, "!") {
+			irregular[id] = true
+		}
+		byPattern[pattern] = append(byPattern[pattern], id)
+	}
+	for _, ids := range byPattern {
+		if len(ids) < irregularUses {
+			for _, id := range ids {
+				irregular[id] = true
+			}
+		}
+	}
+	return irregular, rows.Err()
+}
@@ -0,0 +1,189 @@
+package dpd
+
+import (
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fixture writes a small dpd.db of the given schema, 1 or 2, to a
+// temporary directory, runs the statements of extra on it and returns its
+// path. It has a verb and a noun, each with its template and its forms in
+// lookup, and the roots table of its schema.
+func fixture(t *testing.T, version int, extra ...string) string {
+	t.Helper()
+	headwords, lemma, roots := "dpd_headwords", "lemma_1", "dpd_roots"
+	if version == 1 {
+		headwords, lemma, roots = "pali_words", "pali_1", "pali_roots"
+	}
+	path := filepath.Join(t.TempDir(), "dpd.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	queries := []string{
+		`CREATE TABLE ` + headwords + ` (id INTEGER PRIMARY KEY, ` + lemma + ` TEXT, pos TEXT, stem TEXT, pattern TEXT,
+			meaning_1 TEXT, meaning_2 TEXT, construction TEXT, grammar TEXT, root_key TEXT, family_root TEXT)`,
+		`INSERT INTO ` + headwords + ` VALUES
+			(1, 'gacchati 1', 'pr', 'gacch', 'ati pr', 'goes', '', '√gam + a + ti', 'pr', '√gam', 'ā √gam'),
+			(2, 'dhamma 1', 'masc', 'dhamm', 'a masc', '', 'nature', '√dhar + ma', 'masc', '√dhar', '√dhar')`,
+		`CREATE TABLE lookup (lookup_key TEXT PRIMARY KEY, headwords TEXT, deconstructor TEXT)`,
+		`INSERT INTO lookup VALUES ('gacchati', '[1]', ''), ('dhammo', '[2]', ''), ('dhammoti', '', '["dhammo + iti"]')`,
+		`CREATE TABLE inflection_templates (pattern TEXT PRIMARY KEY, data TEXT)`,
+		`INSERT INTO inflection_templates VALUES
+			('ati pr', '[[[""], ["sg"], [""]], [["3rd"], ["ati"], ["pr", "3rd", "sg"]]]'),
+			('a masc', '[[[""], ["sg"], [""]], [["nom"], ["o"], ["masc", "nom", "sg"]]]')`,
+		`CREATE TABLE ` + roots + ` (root TEXT PRIMARY KEY, root_meaning TEXT)`,
+		`INSERT INTO ` + roots + ` VALUES ('√gam', 'going'), ('√dhar', 'holding')`,
+	}
+	for _, q := range append(queries, extra...) {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	return path
+}
+
+func TestOpenSchemas(t *testing.T) {
+	for _, version := range []int{2, 1} {
+		d, err := Open(fixture(t, version))
+		if err != nil {
+			t.Fatalf("schema %d: %v", version, err)
+		}
+		defer d.Close()
+		if d.Schema() != version || d.Release() != "" {
+			t.Errorf("schema %d: Schema = %d, Release = %q", version, d.Schema(), d.Release())
+		}
+		heads, err := d.Headwords()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if heads[1] != (Headword{1, "gacchati 1", "pr"}) || len(heads) != 2 {
+			t.Errorf("schema %d: Headwords = %v", version, heads)
+		}
+		lookup, err := d.Lookup()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(lookup["dhammo"], []int{2}) || len(lookup) != 2 {
+			t.Errorf("schema %d: Lookup = %v", version, lookup)
+		}
+		var forms []string
+		if err := d.Inflections(func(in Inflection) { forms = append(forms, in.Form+" "+in.Grammar) }); err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(forms)
+		if want := []string{"dhammo masc nom sg", "gacchati pr 3rd sg"}; !slices.Equal(forms, want) {
+			t.Errorf("schema %d: Inflections = %q, want %q", version, forms, want)
+		}
+		glosses, err := d.Glosses()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if glosses[2].Meaning != "nature" {
+			t.Errorf("schema %d: gloss of dhamma 1 %+v, want meaning_2", version, glosses[2])
+		}
+	}
+}
+
+func TestOpenMissing(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		extra []string
+		want  []string
+	}{
+		{"column", []string{`ALTER TABLE lookup DROP COLUMN deconstructor`},
+			[]string{"schema 2 lacks lookup.deconstructor", "schema 1 lacks pali_words, lookup.deconstructor"}},
+		{"table", []string{`DROP TABLE inflection_templates`},
+			[]string{"schema 2 lacks inflection_templates;", "fetch the dpd.db of the latest release from " + releasesURL}},
+		{"lemma", []string{`ALTER TABLE dpd_headwords RENAME COLUMN lemma_1 TO lemma`},
+			[]string{"schema 2 lacks dpd_headwords.lemma_1"}},
+	} {
+		path := fixture(t, 2, tc.extra...)
+		_, err := Open(path)
+		if err == nil {
+			t.Errorf("%s: opened", tc.name)
+			continue
+		}
+		for _, want := range append(tc.want, path+": not a DPD database") {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q lacks %q", tc.name, err, want)
+			}
+		}
+	}
+}
+
+func TestPinnedRelease(t *testing.T) {
+	saved := PinnedRelease
+	t.Cleanup(func() { PinnedRelease = saved })
+	info := []string{
+		`CREATE TABLE db_info (key TEXT PRIMARY KEY, value TEXT)`,
+		`INSERT INTO db_info VALUES ('dpd_release_version', 'v0.3.1')`,
+	}
+
+	PinnedRelease = "v0.3.1"
+	d, err := Open(fixture(t, 2, info...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Release() != "v0.3.1" {
+		t.Errorf("Release = %q", d.Release())
+	}
+	d.Close()
+
+	PinnedRelease = "v0.3.2"
+	for _, tc := range []struct {
+		extra []string
+		want  string
+	}{
+		{info, "is DPD release v0.3.1, but dpd_release pins v0.3.2"},
+		{nil, "has no release in its db_info table, but dpd_release pins v0.3.2"},
+	} {
+		_, err := Open(fixture(t, 2, tc.extra...))
+		if err == nil {
+			t.Errorf("%s: opened", tc.want)
+			continue
+		}
+		for _, want := range []string{tc.want, "fetch the dpd.db of v0.3.2 from " + releasesURL} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q lacks %q", err, want)
+			}
+		}
+	}
+}
+
+func TestRoots(t *testing.T) {
+	for _, version := range []int{2, 1} {
+		d, err := Open(fixture(t, version))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Close()
+		roots, err := d.Roots()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if roots[1] != (Root{"√gam", "going"}) || roots[2] != (Root{"√dhar", "holding"}) {
+			t.Errorf("schema %d: Roots = %v", version, roots)
+		}
+		prefixes, err := d.Prefixes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prefixes[1] != "ā" || prefixes[2] != "" || len(prefixes) != 2 {
+			t.Errorf("schema %d: Prefixes = %v", version, prefixes)
+		}
+	}
+
+	d, err := Open(fixture(t, 2, `ALTER TABLE dpd_headwords DROP COLUMN root_key`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if _, err := d.Roots(); err == nil || !strings.Contains(err.Error(), "dpd_headwords has no root_key column") {
+		t.Errorf("Roots without root_key: %v", err)
+	}
+}
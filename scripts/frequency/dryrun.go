@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/tools"
+)
+
+// runPlan is what -dry-run reports about a run besides the corpora.
+type runPlan struct {
+	// outputs lists what counting one corpus writes, given the corpus, its
+	// label and the books of its files in canonical order
+	outputs func(c corpora.Corpus, label string, books []string) []string
+	final   []string       // written once every corpus is done
+	needs   []prerequisite // files the run cannot do without
+}
+
+// prerequisite is a file a run needs besides the corpora.
+type prerequisite struct {
+	path string
+	why  string // the flag or command needing it
+}
+
+// dryRun reports what counting list would involve, without counting or
+// writing anything: per corpus the files and bytes to read, how many of
+// them the cache holds and the outputs it would write, then the run-wide
+// outputs and the missing prerequisites. Under -strict a skipped corpus
+// or a missing prerequisite exits with status 1.
+func (p *pipeline) dryRun(list []corpora.Corpus, plan runPlan) {
+	tools.PTitle("dry run: nothing is counted or written")
+	problems := 0
+	for _, need := range plan.needs {
+		if _, err := os.Stat(need.path); err != nil {
+			tools.Errorf("%s: missing, needed by %s", need.path, need.why)
+			problems++
+		}
+	}
+
+	var (
+		allFiles int
+		allBytes int64
+	)
+	for _, c := range list {
+		label := p.label(c)
+		files, err := p.corpusFiles(c)
+		if err != nil {
+			tools.Warnf("%s: %v", label, err)
+			problems++
+			continue
+		}
+		var size int64
+		seen := make(map[string]bool)
+		for _, path := range files {
+			if fi, err := os.Stat(path); err == nil {
+				size += fi.Size()
+			}
+			seen[corpora.BookOf(c, path)] = true
+		}
+		var books []string
+		for _, b := range corpora.Books {
+			if seen[b] {
+				books = append(books, b)
+			}
+		}
+		allFiles += len(files)
+		allBytes += size
+
+		cached := ""
+		if !p.force && len(p.ngramSizes) == 0 && !(p.verse && corpora.TellsVerse(c)) && p.provenance == 0 {
+			cached = fmt.Sprintf(", %d in the cache (reused when unchanged)", p.loadCache(c).Cached(files))
+		}
+		fmt.Printf("%s: %d files, %s%s\n", label, len(files), byteSize(size), cached)
+		for _, o := range plan.outputs(c, label, books) {
+			fmt.Printf("  %s\n", o)
+		}
+	}
+	for _, o := range plan.final {
+		fmt.Printf("%s\n", o)
+	}
+	fmt.Printf("%d files, %s in all\n", allFiles, byteSize(allBytes))
+	if problems > 0 && p.strict {
+		os.Exit(1)
+	}
+}
+
+// plannedFile describes an output file: its path and whether it exists.
+func plannedFile(path string) string {
+	if _, err := os.Stat(path); err == nil {
+		return path + " (overwrite)"
+	}
+	return path + " (new)"
+}
+
+// planned describes the output table name as the chosen sink would
+// receive it.
+func (sf *sinkFlags) planned(name string) string {
+	if *sf.sink != "file" {
+		return name + " → " + *sf.sink
+	}
+	format, comp, err := sf.formats()
+	if err != nil {
+		return name + " (" + err.Error() + ")"
+	}
+	return plannedFile(filepath.Join(freqDir, filepath.FromSlash(name)+"."+string(format)+comp.ext()))
+}
+
+// byteSize formats n bytes in binary units.
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// ebtSections are the sections of the Early Buddhist Texts, over which DPD
+// counts the ebt_count of its headwords: the Vinaya without the Parivāra,
+// the four main nikāyas, and the Dhammapada, Udāna, Itivuttaka,
+// Suttanipāta, Theragāthā and Therīgāthā of the Khuddaka.
+var ebtSections = []string{
+	"V1", "V2", "V3", "V4",
+	"D1", "D2", "D3", "M1", "M2", "M3", "S1", "S2", "S3", "S4", "S5",
+	"A1", "A2", "A3", "A4", "A5", "A6", "A7", "A8", "A9", "A10", "A11",
+	"K2", "K3", "K4", "K5", "K8", "K9",
+}
+
+// ebtCounts adds up the per-file counts files of c over the root texts of
+// sections; commentaries, which SectionOf places with their root text, are
+// left out. It returns the counts and the number of files they come from.
+func ebtCounts(c corpora.Corpus, files map[string]map[string]int, sections []string) (map[string]int, int) {
+	counts := make(map[string]int)
+	used := 0
+	for path, fc := range files {
+		if !slices.Contains(sections, corpora.SectionOf(c, path)) || corpora.LayerOf(c, path) != corpora.Mula {
+			continue
+		}
+		used++
+		for w, n := range fc {
+			counts[w] += n
+		}
+	}
+	return counts, used
+}
+
+// ebtCountTable gives the ebt_count of every headword found in the counts,
+// by DPD id, in the columns of DPD's dpd_headwords table.
+func ebtCountTable(lemmas []freq.LemmaCount) table {
+	sort.Slice(lemmas, func(i, j int) bool { return lemmas[i].Headword.ID < lemmas[j].Headword.ID })
+	t := table{columns: []string{"id", "lemma_1", "ebt_count"}}
+	for _, lc := range lemmas {
+		t.rows = append(t.rows, []any{lc.Headword.ID, lc.Headword.Lemma1, lc.Count})
+	}
+	return t
+}
+
+// parseSections reads a comma-separated list of sections of the grid.
+func parseSections(list string) ([]string, error) {
+	var sections []string
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if !slices.Contains(corpora.Sections, s) {
+			return nil, fmt.Errorf("unknown section %q (have %s–%s)", s, corpora.Sections[0], corpora.Sections[len(corpora.Sections)-1])
+		}
+		sections = append(sections, s)
+	}
+	return sections, nil
+}
+
+// runEbt implements the ebt subcommand: the counts of the last run over
+// the Early Buddhist Texts, in the files DPD's frequency build reads, so
+// that DPD can take its ebt_count from them.
+func runEbt(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("ebt", flag.ExitOnError)
+	commandUsage(fs, "Writes the word counts of the Early Buddhist Texts and the ebt_count of each DPD headword in the format of DPD's frequency build, from the counts of the last run.")
+	names := fs.String("corpora", "cst", "comma-separated corpora to count (cst, vri and bjt know their sections)")
+	list := fs.String("sections", strings.Join(ebtSections, ","), "comma-separated sections counted as the Early Buddhist Texts")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database")
+	fs.Parse(args)
+
+	tools.PTitle("saving EBT frequency files for DPD")
+	tic := tools.Tic()
+	sections, err := parseSections(*list)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	lem, err := freq.LoadLemmatizer(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	meta := &sidecars{}
+	sink := fileSink{dir: freqDir, format: formatTsv, meta: meta}
+	for _, name := range strings.Split(*names, ",") {
+		c, ok := corpora.Get(name)
+		if !ok {
+			tools.Errorf("unknown corpus %q", name)
+			continue
+		}
+		if _, ok := c.(corpora.SectionTagger); !ok {
+			tools.Warnf("%s: the edition's files cannot be placed in sections; skipped", name)
+			continue
+		}
+		files, err := loadFileCounts(name)
+		if err != nil {
+			tools.Errorf("%s: %v (count it first)", name, err)
+			continue
+		}
+		counts, used := ebtCounts(c, files, sections)
+		if used == 0 {
+			tools.Warnf("%s: no root text files in the -sections; skipped", name)
+			continue
+		}
+		path := filepath.Join(freqDir, name+"_ebt_freq.json")
+		if err := saveWordCounts(path, counts); err != nil {
+			tools.Errorf("%s: %v", name, err)
+			continue
+		}
+		meta.add(path)
+		lemmas := lem.Counts(counts)
+		if err := sink.Write(name+"_ebt_count", ebtCountTable(lemmas)); err != nil {
+			tools.Errorf("%s: %v", name, err)
+			continue
+		}
+		tools.Infof("%s: %d words, %d tokens from %d files, %d headwords", name, len(counts), freq.TokenTotal(counts), used, len(lemmas))
+	}
+	if err := sink.Close(); err != nil {
+		tools.Errorf("%v", err)
+	}
+	tic.Toc()
+}
+
+// saveWordCounts writes counts to path as a JSON object of each word and
+// its count, the dictionaries DPD's frequency build loads.
+func saveWordCounts(path string, counts map[string]int) error {
+	data, err := json.MarshalIndent(counts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return tools.WriteFileAtomic(path, append(data, '\n'), 0o644)
+}
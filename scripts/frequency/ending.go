@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// analysis is one reading of a surface form: the ending it has under an
+// inflection pattern and the grammar that ending expresses there.
+type analysis struct {
+	pattern string
+	ending  string
+	grammar string
+}
+
+// endingKey is a row of the per-pattern ending table.
+type endingKey struct {
+	pattern, grammar, ending string
+}
+
+// loadAnalyses reads the DPD inflection templates and returns the readings
+// of each form in words, keeping headwords whose pos is in posSet (all of
+// them when posSet is empty). Identical readings of a form that several
+// headwords share are kept once.
+func loadAnalyses(path string, words map[string]bool, posSet map[string]bool) (map[string][]analysis, error) {
+	db, err := dpd.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	forms := make(map[string][]analysis)
+	seen := make(map[string]map[analysis]bool)
+	err = db.Inflections(func(in dpd.Inflection) {
+		if !words[in.Form] || (len(posSet) > 0 && !posSet[in.Pos]) {
+			return
+		}
+		a := analysis{in.Pattern, in.Ending, in.Grammar}
+		if seen[in.Form] == nil {
+			seen[in.Form] = make(map[analysis]bool)
+		}
+		if !seen[in.Form][a] {
+			seen[in.Form][a] = true
+			forms[in.Form] = append(forms[in.Form], a)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return forms, nil
+}
+
+// endingCounts aggregates surface counts by ending, and by pattern, grammar
+// and ending. As with headwords, a form with several readings adds its full
+// count to each distinct one, so the tables overlap. It also returns the
+// number of distinct forms behind each ending.
+func endingCounts(counts map[string]int, forms map[string][]analysis) (map[string]int, map[string]int, map[endingKey]int) {
+	byEnding := make(map[string]int)
+	formsByEnding := make(map[string]int)
+	byPattern := make(map[endingKey]int)
+	for w, n := range counts {
+		endings := make(map[string]bool)
+		for _, a := range forms[w] {
+			endings[a.ending] = true
+			byPattern[endingKey{a.pattern, a.grammar, a.ending}] += n
+		}
+		for e := range endings {
+			byEnding[e] += n
+			formsByEnding[e]++
+		}
+	}
+	return byEnding, formsByEnding, byPattern
+}
+
+// saveEndingFreq writes the tables <name>_ending_freq (columns ending,
+// count, forms, rank, per_million) and <name>_ending_pattern_freq (pattern,
+// grammar, ending, count, rank) to s.
+func saveEndingFreq(s Sink, name string, counts map[string]int, forms map[string][]analysis) error {
+	byEnding, formsByEnding, byPattern := endingCounts(counts, forms)
+	tokens := freq.TokenTotal(counts)
+
+	t := table{columns: []string{"ending", "count", "forms", "rank", "per_million"}}
+	for i, wc := range freq.Sorted(byEnding) {
+		t.rows = append(t.rows, []any{wc.Word, wc.Count, formsByEnding[wc.Word], i + 1, freq.PerMillion(wc.Count, tokens)})
+	}
+	if err := s.Write(name+"_ending_freq", t); err != nil {
+		return err
+	}
+
+	keys := make([]endingKey, 0, len(byPattern))
+	for k := range byPattern {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if byPattern[a] != byPattern[b] {
+			return byPattern[a] > byPattern[b]
+		}
+		if a.pattern != b.pattern {
+			return a.pattern < b.pattern
+		}
+		if a.grammar != b.grammar {
+			return a.grammar < b.grammar
+		}
+		return a.ending < b.ending
+	})
+	t = table{columns: []string{"pattern", "grammar", "ending", "count", "rank"}}
+	for i, k := range keys {
+		t.rows = append(t.rows, []any{k.pattern, k.grammar, k.ending, byPattern[k], i + 1})
+	}
+	return s.Write(name+"_ending_pattern_freq", t)
+}
+
+// runEndings implements the endings subcommand.
+func runEndings(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("endings", flag.ExitOnError)
+	commandUsage(fs, "Tags each counted form with its endings under the DPD inflection templates and writes ending frequency tables.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to analyse, from the counts of the last run")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database with the inflection templates")
+	pos := fs.String("pos", "", "comma-separated parts of speech to keep, e.g. masc,fem,nt (default: all)")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("saving ending frequencies")
+	tic := tools.Tic()
+
+	posSet := make(map[string]bool)
+	if *pos != "" {
+		for _, p := range strings.Split(*pos, ",") {
+			posSet[strings.TrimSpace(p)] = true
+		}
+	}
+
+	list := strings.Split(*names, ",")
+	counts := make(map[string]map[string]int, len(list))
+	words := make(map[string]bool)
+	for _, name := range list {
+		sites, err := loadWordSites(name)
+		if err != nil {
+			tools.Errorf("%s: %v (count it first)", name, err)
+			return
+		}
+		m := make(map[string]int, len(sites))
+		for w, s := range sites {
+			m[w] = s.count
+			words[w] = true
+		}
+		counts[name] = m
+	}
+
+	forms, err := loadAnalyses(*dpdPath, words, posSet)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	tools.Infof("%d of %d forms have an inflection reading", len(forms), len(words))
+	for _, name := range list {
+		if err := saveEndingFreq(sink, name, counts[name], forms); err != nil {
+			tools.Errorf("%s: %v", name, err)
+		}
+	}
+
+	tic.Toc()
+}
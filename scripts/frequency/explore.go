@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/freqdb"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// explorer answers the queries of the explore command from a SQLite
+// database of palifreq's outputs. Each part of an answer comes from its
+// own table and is left out when the database lacks it.
+type explorer struct {
+	db           *sql.DB
+	freq         *freqdb.DB // the frequency tables of db
+	tables       map[string]bool
+	collocations []string // the <corpus>_collocations tables, by name
+	examples     int      // sentences shown per word
+	collocates   int      // collocates shown per headword and corpus
+	tty          bool     // clear the screen before each answer
+}
+
+// exploreHelp is printed for "?" and at the start.
+const exploreHelp = `type a word to see its counts per corpus and book, its top collocates
+and sample sentences; dham* lists the most frequent words starting with
+dham; ? shows this help, q (or end of input) quits`
+
+// newExplorer lists the tables of db the explorer can use.
+func newExplorer(db *sql.DB) (*explorer, error) {
+	// before the query below holds the connection of a database from
+	// export.Open
+	fdb, err := freqdb.New(db)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	e := &explorer{db: db, freq: fdb, tables: make(map[string]bool)}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		e.tables[name] = true
+		if strings.HasSuffix(name, "_collocations") {
+			e.collocations = append(e.collocations, name)
+		}
+	}
+	return e, rows.Err()
+}
+
+// run answers the queries read from r, one per line, on w until r ends,
+// ctx is done or the user quits.
+func (e *explorer) run(ctx context.Context, r io.Reader, w io.Writer) error {
+	fmt.Fprintln(w, exploreHelp)
+	in := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(w, "\nword> ")
+		if !in.Scan() {
+			fmt.Fprintln(w)
+			return in.Err()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		query := strings.TrimSpace(in.Text())
+		if e.tty && query != "" {
+			fmt.Fprint(w, "\033[H\033[2J")
+		}
+		var err error
+		switch query {
+		case "":
+			continue
+		case "q", "quit", "exit":
+			return nil
+		case "?", "help":
+			fmt.Fprintln(w, exploreHelp)
+			continue
+		}
+		word := pali.Normalize(strings.ToLower(query))
+		if prefix, ok := strings.CutSuffix(word, "*"); ok {
+			err = e.search(w, prefix)
+		} else {
+			err = e.lookup(w, word)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// heading prints a section title, in bold on a terminal.
+func (e *explorer) heading(w io.Writer, title string) {
+	if e.tty {
+		title = "\033[1m" + title + "\033[0m"
+	}
+	fmt.Fprintf(w, "\n%s\n", title)
+}
+
+// search lists the 20 most frequent words starting with prefix over all
+// corpora.
+func (e *explorer) search(w io.Writer, prefix string) error {
+	if !e.tables["word_frequency"] {
+		fmt.Fprintln(w, "the database has no word_frequency table (see export)")
+		return nil
+	}
+	list, err := e.freq.Prefix(prefix, 20)
+	if err != nil {
+		return err
+	}
+	e.heading(w, "words starting with "+prefix)
+	for _, wc := range list {
+		fmt.Fprintf(w, "  %-24s %d\n", wc.Word, wc.Count)
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	return nil
+}
+
+// lookup prints what the database knows of word.
+func (e *explorer) lookup(w io.Writer, word string) error {
+	e.heading(w, word)
+	for _, part := range []func(io.Writer, string) error{e.counts, e.books, e.collocatesOf, e.sentences} {
+		if err := part(w, word); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// counts prints the count and rank of word in each corpus.
+func (e *explorer) counts(w io.Writer, word string) error {
+	if !e.tables["word_frequency"] {
+		return nil
+	}
+	list, err := e.freq.Lookup(word)
+	if err != nil {
+		return err
+	}
+	for _, f := range list {
+		fmt.Fprintf(w, "  %-10s %8d  rank %d\n", f.Corpus, f.Count, f.Rank)
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(w, "  in no corpus; try "+word+"*")
+	}
+	return nil
+}
+
+// books prints the counts of word per book of each corpus, in canon order.
+func (e *explorer) books(w io.Writer, word string) error {
+	if !e.tables["word_frequency_book"] {
+		return nil
+	}
+	list, err := e.freq.Books(word)
+	if err != nil || len(list) == 0 {
+		return err
+	}
+	byCorpus := make(map[string]map[string]int)
+	for _, f := range list {
+		if byCorpus[f.Corpus] == nil {
+			byCorpus[f.Corpus] = make(map[string]int)
+		}
+		byCorpus[f.Corpus][f.Book] = f.Count
+	}
+	e.heading(w, "by book")
+	for _, corpus := range slices.Sorted(maps.Keys(byCorpus)) {
+		var parts []string
+		for _, book := range corpora.Books {
+			if n, ok := byCorpus[corpus][book]; ok {
+				parts = append(parts, fmt.Sprintf("%s %d", book, n))
+			}
+		}
+		fmt.Fprintf(w, "  %-10s %s\n", corpus, strings.Join(parts, ", "))
+	}
+	return nil
+}
+
+// headwords returns the IDs of the DPD headwords the sentence tables tie
+// word to, as a form or a lemma.
+func (e *explorer) headwords(word string) ([]int, error) {
+	var queries []string
+	if e.tables["sentence_bank"] {
+		queries = append(queries, `SELECT headword_id FROM sentence_bank WHERE form = ?1 OR lemma = ?1`)
+	}
+	if e.tables["sentences"] {
+		queries = append(queries, `SELECT headword_id FROM sentences WHERE (form = ?1 OR word = ?1) AND headword_id IS NOT NULL`)
+	}
+	if len(queries) == 0 {
+		return nil, nil
+	}
+	rows, err := e.db.Query(`SELECT DISTINCT headword_id FROM (`+strings.Join(queries, ` UNION `)+`) ORDER BY headword_id`, word)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// collocatesOf prints the top collocates of the headwords of word, or of
+// word as a lemma, from each collocations table in the order it ranks
+// them.
+func (e *explorer) collocatesOf(w io.Writer, word string) error {
+	if len(e.collocations) == 0 {
+		return nil
+	}
+	ids, err := e.headwords(word)
+	if err != nil {
+		return err
+	}
+	marks := slices.Repeat([]string{"?"}, len(ids))
+	args := []any{word}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	for _, name := range e.collocations {
+		query := `SELECT lemma, collocate FROM ` + quoteIdent(name) + ` WHERE lemma = ?`
+		if len(ids) > 0 {
+			query += ` OR headword_id IN (` + strings.Join(marks, ", ") + `)`
+		}
+		rows, err := e.db.Query(query+` ORDER BY rowid`, args...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		byLemma := make(map[string][]string)
+		var lemmas []string
+		for rows.Next() {
+			var lemma, collocate string
+			if err := rows.Scan(&lemma, &collocate); err != nil {
+				rows.Close()
+				return err
+			}
+			if _, ok := byLemma[lemma]; !ok {
+				lemmas = append(lemmas, lemma)
+			}
+			if len(byLemma[lemma]) < e.collocates {
+				byLemma[lemma] = append(byLemma[lemma], collocate)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, lemma := range lemmas {
+			e.heading(w, "collocates of "+lemma+" in "+strings.TrimSuffix(name, "_collocations"))
+			fmt.Fprintf(w, "  %s\n", strings.Join(byLemma[lemma], ", "))
+		}
+	}
+	return nil
+}
+
+// sentences prints sample sentences of word with the form marked, from
+// the sentence bank, or else the concordance snippets.
+func (e *explorer) sentences(w io.Writer, word string) error {
+	var query string
+	switch {
+	case e.tables["sentence_bank"]:
+		query = `
+			SELECT substr(sentence, 1, form_start), form, substr(sentence, form_end + 1), source, citation
+			FROM sentence_bank WHERE form = ?1 OR lemma = ?1 ORDER BY id LIMIT ?2`
+	case e.tables["sentences"]:
+		query = `
+			SELECT left_context, form, right_context, source, citation
+			FROM sentences WHERE form = ?1 OR word = ?1 ORDER BY id LIMIT ?2`
+	default:
+		return nil
+	}
+	rows, err := e.db.Query(query, word, e.examples)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	first := true
+	for rows.Next() {
+		var left, form, right, source, citation string
+		if err := rows.Scan(&left, &form, &right, &source, &citation); err != nil {
+			return err
+		}
+		if first {
+			e.heading(w, "sentences")
+			first = false
+		}
+		where := source
+		if citation != "" {
+			where = citation
+		}
+		fmt.Fprintf(w, "  %s[%s]%s\n      %s\n", left, form, right, where)
+	}
+	return rows.Err()
+}
+
+// isTTY reports whether f is a terminal.
+func isTTY(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// runExplore implements the explore subcommand: an interactive look at the
+// counts, collocates and sentences of a database of palifreq's outputs.
+func runExplore(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("explore", flag.ExitOnError)
+	commandUsage(fs, "Looks words up interactively in a SQLite database made by export, concordance, sentence-bank and collocations -sink sqlite:PATH: counts per corpus and book, top collocates and sample sentences.")
+	dbPath := fs.String("db", "", "SQLite database to read (required)")
+	examples := fs.Int("examples", 5, "sample sentences shown per word")
+	collocates := fs.Int("collocates", 10, "collocates shown per headword and corpus")
+	fs.Parse(args)
+
+	if *dbPath == "" {
+		tools.Errorf("explore needs -db")
+		return
+	}
+	if _, err := os.Stat(*dbPath); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	db, err := sql.Open("sqlite", tools.SQLiteURI(*dbPath, "mode=ro"))
+	if err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+	defer db.Close()
+	e, err := newExplorer(db)
+	if err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+	if !e.tables["word_frequency"] {
+		tools.Warnf("%s has no word_frequency table; run export first", *dbPath)
+	}
+	e.examples, e.collocates, e.tty = *examples, *collocates, isTTY(os.Stdout)
+	if err := e.run(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		tools.Errorf("%v", err)
+	}
+}
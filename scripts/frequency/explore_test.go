@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dpd/go_modules/frequency/export"
+)
+
+func TestExplore(t *testing.T) {
+	db, err := export.Open(filepath.Join(t.TempDir(), "explore.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, q := range []string{
+		`INSERT INTO word_frequency VALUES ('dhammaṃ', 'cst', 12, 3), ('dhammaṃ', 'sya', 10, 4), ('dhammo', 'cst', 30, 1)`,
+		`INSERT INTO word_frequency_book VALUES ('dhammaṃ', 'cst', 'sn', 4, 2), ('dhammaṃ', 'cst', 'dn', 8, 1)`,
+		`INSERT INTO sentence_bank (headword_id, lemma, form, sentence, form_start, form_end, corpus, source, book, paragraph, citation)
+			VALUES (7, 'dhamma 1', 'dhammaṃ', 'so dhammaṃ deseti', 3, 10, 'cst', 's0101m.mul.xml', 'dn', 1, 'DN 1')`,
+		`CREATE TABLE cst_collocations (headword_id INTEGER, lemma TEXT, collocate TEXT, count INTEGER, collocate_count INTEGER, pmi REAL, llr REAL)`,
+		`INSERT INTO cst_collocations VALUES (7, 'dhamma 1', 'deseti', 5, 9, 3.2, 40.1), (7, 'dhamma 1', 'vinaya', 4, 20, 2.1, 22.5), (8, 'buddha', 'dhamma', 3, 3, 1, 1)`,
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	e, err := newExplorer(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.examples, e.collocates = 5, 1
+
+	var out strings.Builder
+	if err := e.run(context.Background(), strings.NewReader("Dhammaṃ\ndhamm*\nq\nnot read\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"  cst              12  rank 3\n  sya              10  rank 4\n",
+		"  cst        dn 8, sn 4\n",
+		"collocates of dhamma 1 in cst\n  deseti\n",
+		"  so [dhammaṃ] deseti\n      DN 1\n",
+		"  dhammo                   30\n  dhammaṃ                  22\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "vinaya") || strings.Contains(got, "buddha") || strings.Contains(got, "not read") {
+		t.Errorf("output shows more than asked:\n%s", got)
+	}
+}
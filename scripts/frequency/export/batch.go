@@ -0,0 +1,79 @@
+package export
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// BatchRows is the number of rows an Inserter writes with one statement,
+// 1 for a statement a row. Each row of a multi-row INSERT costs SQLite far
+// less than a statement of its own, but the driver binds the parameters of
+// larger statements more slowly than it saves: BenchmarkBatchRows writes
+// the word_citation rows of a corpus fastest with 4 to 64 rows, 1.5 times
+// as slowly with 1, twice as slowly with 256 and four times as slowly with
+// 1024. An Inserter keeps the value it was made with.
+var BatchRows = 16
+
+// Inserter inserts rows into a table of a transaction BatchRows at a time,
+// with one multi-row INSERT statement prepared once.
+type Inserter struct {
+	tx    *sql.Tx
+	head  string // INSERT … (columns) VALUES
+	tail  string // what follows the rows, e.g. ON CONFLICT …
+	row   string // the placeholders of one row, (?, ?, …)
+	width int
+	batch int
+	full  *sql.Stmt
+	args  []any
+}
+
+// NewInserter returns an Inserter of rows of width values, each statement
+// made of head, the rows and tail.
+func NewInserter(tx *sql.Tx, head, tail string, width int) *Inserter {
+	return &Inserter{
+		tx:    tx,
+		head:  head,
+		tail:  tail,
+		row:   "(" + strings.TrimSuffix(strings.Repeat("?, ", width), ", ") + ")",
+		width: width,
+		batch: max(BatchRows, 1),
+		args:  make([]any, 0, max(BatchRows, 1)*width),
+	}
+}
+
+// statement is the INSERT statement of n rows.
+func (in *Inserter) statement(n int) string {
+	return in.head + " " + strings.TrimSuffix(strings.Repeat(in.row+", ", n), ", ") + " " + in.tail
+}
+
+// Add adds a row of values, writing the batch once it is full.
+func (in *Inserter) Add(values ...any) error {
+	in.args = append(in.args, values...)
+	if len(in.args) < in.batch*in.width {
+		return nil
+	}
+	if in.full == nil {
+		var err error
+		if in.full, err = in.tx.Prepare(in.statement(in.batch)); err != nil {
+			return err
+		}
+	}
+	_, err := in.full.Exec(in.args...)
+	in.args = in.args[:0]
+	return err
+}
+
+// Close writes the rows left and releases the statement.
+func (in *Inserter) Close() error {
+	var err error
+	if n := len(in.args) / in.width; n > 0 {
+		_, err = in.tx.Exec(in.statement(n), in.args...)
+		in.args = in.args[:0]
+	}
+	if in.full != nil {
+		if cerr := in.full.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,445 @@
+// Package export writes the counts of package freq into the SQLite
+// database the app reads: word_frequency, word_frequency_book,
+// lemma_frequency and word_citation. Each writer replaces the rows of one
+// corpus in a transaction of its own, so corpora can be written in any
+// order and rewritten later. It inserts them in the order of the primary
+// key of the table, which those four tables are clustered on.
+package export
+
+import (
+	"database/sql"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	_ "modernc.org/sqlite"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// Schema creates the tables and indexes of the database when missing:
+// those written here and the sentences, sentence_bank, study_list,
+// learning_value and structure tables palifreq's concordance,
+// sentence-bank, study, score and structure commands fill. The frequency
+// and citation tables are WITHOUT ROWID, a single B-tree on their primary
+// key; tables made before keep their rowid and work the same.
+const Schema = `
+CREATE TABLE IF NOT EXISTS word_frequency (
+	word   TEXT    NOT NULL,
+	corpus TEXT    NOT NULL,
+	count  INTEGER NOT NULL,
+	rank   INTEGER NOT NULL,
+	PRIMARY KEY (word, corpus)
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS idx_word_frequency_corpus_rank
+	ON word_frequency (corpus, rank);
+CREATE TABLE IF NOT EXISTS word_frequency_book (
+	word   TEXT    NOT NULL,
+	corpus TEXT    NOT NULL,
+	book   TEXT    NOT NULL,
+	count  INTEGER NOT NULL,
+	rank   INTEGER NOT NULL,
+	PRIMARY KEY (word, corpus, book)
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS idx_word_frequency_book_rank
+	ON word_frequency_book (corpus, book, rank);
+CREATE TABLE IF NOT EXISTS lemma_frequency (
+	headword_id INTEGER NOT NULL,
+	lemma       TEXT    NOT NULL,
+	corpus      TEXT    NOT NULL,
+	count       INTEGER NOT NULL,
+	rank        INTEGER NOT NULL,
+	PRIMARY KEY (headword_id, corpus)
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS idx_lemma_frequency_corpus_rank
+	ON lemma_frequency (corpus, rank);
+CREATE TABLE IF NOT EXISTS word_citation (
+	word   TEXT    NOT NULL,
+	corpus TEXT    NOT NULL,
+	source TEXT    NOT NULL,
+	count  INTEGER NOT NULL,
+	PRIMARY KEY (word, corpus, source)
+) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS sentences (
+	id            INTEGER PRIMARY KEY,
+	word          TEXT    NOT NULL,
+	headword_id   INTEGER,
+	form          TEXT    NOT NULL,
+	corpus        TEXT    NOT NULL,
+	source        TEXT    NOT NULL,
+	citation      TEXT    NOT NULL DEFAULT '',
+	left_context  TEXT    NOT NULL,
+	right_context TEXT    NOT NULL,
+	UNIQUE (word, left_context, form, right_context)
+);
+CREATE INDEX IF NOT EXISTS idx_sentences_headword
+	ON sentences (headword_id);
+CREATE TABLE IF NOT EXISTS sentence_bank (
+	id          INTEGER PRIMARY KEY,
+	headword_id INTEGER NOT NULL,
+	lemma       TEXT    NOT NULL,
+	form        TEXT    NOT NULL,
+	sentence    TEXT    NOT NULL,
+	form_start  INTEGER NOT NULL,
+	form_end    INTEGER NOT NULL,
+	corpus      TEXT    NOT NULL,
+	source      TEXT    NOT NULL,
+	book        TEXT    NOT NULL,
+	paragraph   INTEGER NOT NULL,
+	citation    TEXT    NOT NULL DEFAULT '',
+	UNIQUE (headword_id, sentence)
+);
+CREATE INDEX IF NOT EXISTS idx_sentence_bank_headword
+	ON sentence_bank (headword_id);
+CREATE TABLE IF NOT EXISTS study_list (
+	rank         INTEGER PRIMARY KEY,
+	headword_id  INTEGER NOT NULL,
+	lemma        TEXT    NOT NULL,
+	pos          TEXT    NOT NULL,
+	count        INTEGER NOT NULL,
+	meaning      TEXT    NOT NULL,
+	construction TEXT    NOT NULL,
+	proper_noun  INTEGER NOT NULL DEFAULT 0,
+	number       TEXT    NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS learning_value (
+	rank        INTEGER PRIMARY KEY,
+	headword_id INTEGER NOT NULL,
+	lemma       TEXT    NOT NULL,
+	pos         TEXT    NOT NULL,
+	count       INTEGER NOT NULL,
+	per_million REAL    NOT NULL,
+	dp          REAL    NOT NULL,
+	length      INTEGER NOT NULL,
+	irregular   INTEGER NOT NULL,
+	score       REAL    NOT NULL
+);
+CREATE TABLE IF NOT EXISTS structure (
+	corpus     TEXT    NOT NULL,
+	source     TEXT    NOT NULL,
+	seq        INTEGER NOT NULL,
+	book       TEXT    NOT NULL,
+	section    TEXT    NOT NULL,
+	rend       TEXT    NOT NULL,
+	heading    INTEGER NOT NULL,
+	paranum    TEXT    NOT NULL,
+	nikaya     TEXT    NOT NULL,
+	book_title TEXT    NOT NULL,
+	chapter    TEXT    NOT NULL,
+	vagga      TEXT    NOT NULL,
+	sutta      TEXT    NOT NULL,
+	PRIMARY KEY (corpus, source, seq)
+);
+CREATE INDEX IF NOT EXISTS idx_structure_sutta
+	ON structure (corpus, book, sutta);
+`
+
+// migrations bring databases made by earlier versions up to Schema: each
+// is run when its probe query fails.
+var migrations = []struct{ probe, update string }{
+	{`SELECT proper_noun FROM study_list LIMIT 0`, `ALTER TABLE study_list ADD COLUMN proper_noun INTEGER NOT NULL DEFAULT 0`},
+	{`SELECT number FROM study_list LIMIT 0`, `ALTER TABLE study_list ADD COLUMN number TEXT NOT NULL DEFAULT ''`},
+	{`SELECT citation FROM sentences LIMIT 0`, `ALTER TABLE sentences ADD COLUMN citation TEXT NOT NULL DEFAULT ''`},
+	{`SELECT citation FROM sentence_bank LIMIT 0`, `ALTER TABLE sentence_bank ADD COLUMN citation TEXT NOT NULL DEFAULT ''`},
+}
+
+// rankIndexes are the secondary indexes of the tables the writers fill,
+// which DeferIndexes drops.
+var rankIndexes = []string{
+	"idx_word_frequency_corpus_rank",
+	"idx_word_frequency_book_rank",
+	"idx_lemma_frequency_corpus_rank",
+}
+
+// loadPragmas set up each connection for bulk writes: a write-ahead log
+// and a sync only at checkpoints, rather than a rollback journal synced on
+// every commit, and a page cache of up to 256 MiB rather than 2, which
+// holds the B-trees of a corpus being written. A crash may lose the last
+// transactions but cannot corrupt the database; Close folds the log back
+// in. They are the query of the database URI OpenBulk opens.
+const loadPragmas = "_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=cache_size(-262144)"
+
+// Open opens the SQLite database at path and creates the tables of Schema
+// if needed, adding the columns of newer versions to older tables. The
+// pool holds a single connection so corpora finishing at the same time
+// queue up instead of failing on a locked database. Close it with Close.
+func Open(path string) (*sql.DB, error) {
+	db, err := OpenBulk(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(Schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, m := range migrations {
+		if _, err := db.Exec(m.probe); err == nil {
+			continue
+		}
+		if _, err := db.Exec(m.update); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// OpenBulk opens the SQLite database at path for bulk writes, without
+// creating any table, in a pool of a single connection like Open. Close it
+// with Close.
+func OpenBulk(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", tools.SQLiteURI(path, loadPragmas))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// DeferIndexes drops the rank indexes of word_frequency,
+// word_frequency_book and lemma_frequency for a bulk load, so the writers
+// insert the rows of each table into its own B-tree only, in key order,
+// rather than into a rank index too, all over it. BuildIndexes builds them again, each in one
+// pass over its sorted table; if the load fails before, the next Open
+// does.
+func DeferIndexes(db *sql.DB) error {
+	for _, name := range rankIndexes {
+		if _, err := db.Exec(`DROP INDEX IF EXISTS ` + name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildIndexes creates the indexes of Schema that are missing, such as
+// those DeferIndexes dropped.
+func BuildIndexes(db *sql.DB) error {
+	_, err := db.Exec(Schema)
+	return err
+}
+
+// Close checkpoints the write-ahead log of a database from Open into the
+// database file, which it leaves in rollback-journal mode as a single file
+// to ship, and closes db.
+func Close(db *sql.DB) error {
+	_, err := db.Exec(`PRAGMA journal_mode = DELETE`)
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// WordFrequency replaces the word_frequency rows of one corpus with
+// counts, ranked 1..n by descending count.
+func WordFrequency(db *sql.DB, corpus string, counts map[string]int) error {
+	list := byWord(freq.Sorted(counts))
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM word_frequency WHERE corpus = ?`, corpus); err != nil {
+		return err
+	}
+	insert := NewInserter(tx, `INSERT INTO word_frequency (word, corpus, count, rank) VALUES`, ``, 4)
+	for _, rw := range list {
+		if err := insert.Add(rw.Word, corpus, rw.Count, rw.rank); err != nil {
+			return err
+		}
+	}
+	if err := insert.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rankedWord is a word of a sorted list with its rank in it, 1 for the
+// first.
+type rankedWord struct {
+	freq.WordCount
+	book string
+	rank int
+}
+
+// byWord ranks the words of list, sorted by descending count, and sorts
+// them by word, the order of the primary key they are inserted into.
+func byWord(list []freq.WordCount) []rankedWord {
+	ranked := make([]rankedWord, len(list))
+	for i, wc := range list {
+		ranked[i] = rankedWord{WordCount: wc, rank: i + 1}
+	}
+	slices.SortFunc(ranked, func(a, b rankedWord) int { return strings.Compare(a.Word, b.Word) })
+	return ranked
+}
+
+// BookFrequency replaces the word_frequency_book rows of one corpus,
+// ranked within each book.
+func BookFrequency(db *sql.DB, corpus string, books freq.Books) error {
+	list := rankBooks(books)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM word_frequency_book WHERE corpus = ?`, corpus); err != nil {
+		return err
+	}
+	insert := NewInserter(tx, `INSERT INTO word_frequency_book (word, corpus, book, count, rank) VALUES`, ``, 5)
+	for _, rw := range list {
+		if err := insert.Add(rw.Word, corpus, rw.book, rw.Count, rw.rank); err != nil {
+			return err
+		}
+	}
+	if err := insert.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rankBooks ranks the words of each book of books, the books at once, and
+// sorts them all by word and then book. The writers sort and index before
+// they begin their transaction, so corpora finishing together use the
+// CPUs while another holds the connection.
+func rankBooks(books freq.Books) []rankedWord {
+	names := slices.Sorted(maps.Keys(books))
+	ranked := make([][]rankedWord, len(names))
+	var wg sync.WaitGroup
+	for i, book := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ranked[i] = byWord(freq.Sorted(books[book]))
+			for j := range ranked[i] {
+				ranked[i][j].book = book
+			}
+		}()
+	}
+	wg.Wait()
+	list := slices.Concat(ranked...)
+	// stable, so the books of a word stay in order
+	slices.SortStableFunc(list, func(a, b rankedWord) int { return strings.Compare(a.Word, b.Word) })
+	return list
+}
+
+// LemmaFrequency replaces the lemma_frequency rows of one corpus with
+// list, ranked in its order.
+func LemmaFrequency(db *sql.DB, corpus string, list []freq.LemmaCount) error {
+	// the indexes of list by headword ID, the order of the primary key
+	order := make([]int, len(list))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int { return list[a].Headword.ID - list[b].Headword.ID })
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM lemma_frequency WHERE corpus = ?`, corpus); err != nil {
+		return err
+	}
+	insert := NewInserter(tx, `INSERT INTO lemma_frequency (headword_id, lemma, corpus, count, rank) VALUES`, ``, 5)
+	for _, i := range order {
+		lc := list[i]
+		if err := insert.Add(lc.Headword.ID, lc.Headword.Lemma1, corpus, lc.Count, i+1); err != nil {
+			return err
+		}
+	}
+	if err := insert.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Citations replaces the word_citation rows of one corpus, stored under
+// root, with the occurrences of each word in each source file, given by
+// file path as in freq.Table.Files.
+func Citations(db *sql.DB, corpus, root string, files map[string]map[string]int) error {
+	index := indexCitations(root, files)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM word_citation WHERE corpus = ?`, corpus); err != nil {
+		return err
+	}
+	insert := NewInserter(tx, `INSERT INTO word_citation (word, corpus, source, count) VALUES`, ``, 4)
+	for _, w := range index.words {
+		for _, c := range index.byWord[w] {
+			if err := insert.Add(w, corpus, index.sources[c.file], int(c.count)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := insert.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// citationIndex holds the citations of files in the order of the primary
+// key of word_citation, by word and then source, so SQLite appends the
+// rows to its B-tree rather than inserting them all over it. It is
+// inverted through the file numbers, so it takes little memory beside
+// files.
+type citationIndex struct {
+	sources []string // sorted
+	words   []string // sorted
+	byWord  map[string][]citation
+}
+
+// citation is the count of a word in the file sources[file].
+type citation struct{ file, count int32 }
+
+func indexCitations(root string, files map[string]map[string]int) *citationIndex {
+	index := &citationIndex{byWord: make(map[string][]citation)}
+	for path := range files {
+		index.sources = append(index.sources, SourceID(root, path))
+	}
+	// x.txt and x.TXT of one directory are one source
+	slices.Sort(index.sources)
+	index.sources = slices.Compact(index.sources)
+	for path, counts := range files {
+		file, _ := slices.BinarySearch(index.sources, SourceID(root, path))
+		for w, n := range counts {
+			index.byWord[w] = append(index.byWord[w], citation{int32(file), int32(n)})
+		}
+	}
+	index.words = slices.Sorted(maps.Keys(index.byWord))
+	for w, list := range index.byWord {
+		slices.SortFunc(list, func(a, b citation) int { return int(a.file - b.file) })
+		merged := list[:1]
+		for _, c := range list[1:] {
+			if last := &merged[len(merged)-1]; c.file == last.file {
+				last.count += c.count
+			} else {
+				merged = append(merged, c)
+			}
+		}
+		index.byWord[w] = merged
+	}
+	return index
+}
+
+// SourceID names a source file in the citation index: its path relative
+// to root, the directory of its corpus, slash-separated and without
+// extension, e.g. "s0101m.mul" for a CST file or "a/x" for a/x.txt of a
+// custom corpus, so files of one name in different directories stay
+// apart. It is the base name when root is "" or does not hold path.
+func SourceID(root, path string) string {
+	rel := filepath.Base(path)
+	if root != "" {
+		if r, err := filepath.Rel(root, path); err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			rel = filepath.ToSlash(r)
+		}
+	}
+	return strings.TrimSuffix(rel, filepath.Ext(rel))
+}
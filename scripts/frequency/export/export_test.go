@@ -0,0 +1,342 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/freq"
+)
+
+func TestOpenPath(t *testing.T) {
+	// a path that is a query and a fragment if left unescaped
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a?b#c%d e.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mode string
+	if err := db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Errorf("journal mode %s, want wal", mode)
+	}
+	if err := WordFrequency(db, "cst", map[string]int{"dhammo": 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Close(db); err != nil {
+		t.Fatal(err)
+	}
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ents) != 1 || ents[0].Name() != "a?b#c%d e.db" {
+		var names []string
+		for _, e := range ents {
+			names = append(names, e.Name())
+		}
+		t.Errorf("wrote %q, want a?b#c%%d e.db", names)
+	}
+}
+
+func TestWriters(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "pali.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	if err := DeferIndexes(db); err != nil {
+		t.Fatal(err)
+	}
+	// the second writes of a corpus replace its rows
+	for _, counts := range []map[string]int{{"evaṃ": 9, "me": 1}, {"dhammo": 2, "me": 5, "evaṃ": 2}} {
+		if err := WordFrequency(db, "cst", counts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := WordFrequency(db, "bjt", map[string]int{"me": 1}); err != nil {
+		t.Fatal(err)
+	}
+	books := freq.Books{"mn": {"evaṃ": 1, "me": 3}, "dn": {"me": 2, "sutaṃ": 2}}
+	if err := BookFrequency(db, "cst", books); err != nil {
+		t.Fatal(err)
+	}
+	lemmas := []freq.LemmaCount{{Headword: dpd.Headword{ID: 7, Lemma1: "me 1"}, Count: 5}, {Headword: dpd.Headword{ID: 3, Lemma1: "evaṃ 1"}, Count: 2}}
+	if err := LemmaFrequency(db, "cst", lemmas); err != nil {
+		t.Fatal(err)
+	}
+	if err := BuildIndexes(db); err != nil {
+		t.Fatal(err)
+	}
+	for q, want := range map[string]string{
+		`SELECT word, corpus, count, rank FROM word_frequency ORDER BY corpus, rank`:         "me bjt 1 1, me cst 5 1, evaṃ cst 2 2, dhammo cst 2 3",
+		`SELECT word, book, count, rank FROM word_frequency_book ORDER BY book, rank`:        "me dn 2 1, sutaṃ dn 2 2, me mn 3 1, evaṃ mn 1 2",
+		`SELECT headword_id, lemma, count, rank FROM lemma_frequency ORDER BY rank`:          "7 me 1 5 1, 3 evaṃ 1 2 2",
+		`SELECT count(*) FROM sqlite_schema WHERE type = 'index' AND name LIKE 'idx_%_rank'`: "3",
+	} {
+		rows, err := db.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cols, _ := rows.Columns()
+		var got []string
+		for rows.Next() {
+			vals := make([]any, len(cols))
+			ptrs := make([]any, len(cols))
+			for i := range vals {
+				ptrs[i] = &vals[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, strings.TrimSuffix(fmt.Sprintln(vals...), "\n"))
+		}
+		rows.Close()
+		if strings.Join(got, ", ") != want {
+			t.Errorf("%s:\n%s\nwant\n%s", q, strings.Join(got, ", "), want)
+		}
+	}
+}
+
+// benchCounts are counts of n words, about those of a corpus of the
+// canon when n is 200,000.
+func benchCounts(n int) map[string]int {
+	counts := make(map[string]int, n)
+	for i := range n {
+		counts[fmt.Sprintf("word%d", i)] = n/(i+1) + 1
+	}
+	return counts
+}
+
+// rowSchema is Schema as it was before batching, with a rowid in every
+// table.
+var rowSchema = strings.ReplaceAll(Schema, ") WITHOUT ROWID;", ");")
+
+// openRows opens a database the way the exporter did before batching: a
+// rollback journal and the tables of rowSchema.
+func openRows(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(rowSchema)
+	return db, err
+}
+
+// rowWordFrequency is WordFrequency as it wrote before Inserter, one
+// statement a row, for the benchmarks to compare against.
+func rowWordFrequency(db *sql.DB, corpus string, counts map[string]int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`CREATE TEMP TABLE IF NOT EXISTS seen_words (word TEXT PRIMARY KEY)`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM seen_words`); err != nil {
+		return err
+	}
+	upsert, err := tx.Prepare(`
+		INSERT INTO word_frequency (word, corpus, count, rank) VALUES (?, ?, ?, ?)
+		ON CONFLICT (word, corpus) DO UPDATE SET count = excluded.count, rank = excluded.rank`)
+	if err != nil {
+		return err
+	}
+	defer upsert.Close()
+	seen, err := tx.Prepare(`INSERT INTO seen_words (word) VALUES (?)`)
+	if err != nil {
+		return err
+	}
+	defer seen.Close()
+
+	for i, wc := range freq.Sorted(counts) {
+		if _, err := upsert.Exec(wc.Word, corpus, wc.Count, i+1); err != nil {
+			return err
+		}
+		if _, err := seen.Exec(wc.Word); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM word_frequency
+		WHERE corpus = ? AND word NOT IN (SELECT word FROM seen_words)`, corpus); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// BenchmarkWordFrequency writes the word_frequency rows of a corpus the
+// way the exporter did, a row a statement into a database with a rollback
+// journal, and the way it does, batched into one with a write-ahead log.
+func BenchmarkWordFrequency(b *testing.B) {
+	counts := benchCounts(50000)
+	for _, bc := range []struct {
+		name  string
+		open  func(path string) (*sql.DB, error)
+		write func(db *sql.DB, corpus string, counts map[string]int) error
+	}{
+		{"rows", openRows, rowWordFrequency},
+		{"batched", Open, WordFrequency},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			db, err := bc.open(filepath.Join(b.TempDir(), "pali.db"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer db.Close()
+			for i := 0; b.Loop(); i++ {
+				// a corpus of its own each time, so the rows are inserted
+				if err := bc.write(db, fmt.Sprint("corpus", i), counts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchCorpus is about a tenth of the tables of a corpus of the canon.
+type benchCorpus struct {
+	counts, verse, prose map[string]int
+	books                freq.Books
+	lemmas               []freq.LemmaCount
+	files                map[string]map[string]int
+}
+
+func newBenchCorpus() *benchCorpus {
+	bc := &benchCorpus{
+		counts: benchCounts(50000),
+		verse:  benchCounts(10000),
+		prose:  benchCounts(40000),
+		books:  make(freq.Books),
+		files:  make(map[string]map[string]int),
+	}
+	for b := range 20 {
+		bc.books.Add(fmt.Sprintf("book%02d", b), benchCounts(8000+b*100))
+	}
+	for f := range 100 {
+		counts := make(map[string]int)
+		for i := range 2000 {
+			counts[fmt.Sprintf("word%d", (i*7+f*13)%50000)] = i%50 + 1
+		}
+		bc.files[fmt.Sprintf("root/f%03d.xml", f)] = counts
+	}
+	for i := range 20000 {
+		// ranked by count, not by ID
+		id := (i * 7919) % 20000
+		bc.lemmas = append(bc.lemmas, freq.LemmaCount{Headword: dpd.Headword{ID: id, Lemma1: fmt.Sprint("lemma ", id)}, Count: 20000 - i})
+	}
+	return bc
+}
+
+// write writes the tables export fills and 5,000 sentence_bank rows, the
+// way the sentence-bank command does.
+func (bc *benchCorpus) write(db *sql.DB, wordFrequency func(*sql.DB, string, map[string]int) error) error {
+	for _, w := range []func() error{
+		func() error { return wordFrequency(db, "cst", bc.counts) },
+		func() error { return BookFrequency(db, "cst", bc.books) },
+		func() error { return LemmaFrequency(db, "cst", bc.lemmas) },
+		func() error { return Citations(db, "cst", "root", bc.files) },
+		func() error { return wordFrequency(db, "cst_verse", bc.verse) },
+		func() error { return wordFrequency(db, "cst_prose", bc.prose) },
+		func() error {
+			tx, err := db.Begin()
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback()
+			ins := NewInserter(tx, `
+				INSERT INTO sentence_bank (headword_id, lemma, form, sentence, form_start, form_end, corpus, source, book, paragraph, citation)
+				VALUES`, ``, 11)
+			for i := range 5000 {
+				if err := ins.Add(i/5, "lemma", "form", fmt.Sprint("evaṃ me sutaṃ ", i), 0, 4, "cst", "s0101m.mul", "book00", i, ""); err != nil {
+					return err
+				}
+			}
+			if err := ins.Close(); err != nil {
+				return err
+			}
+			return tx.Commit()
+		},
+	} {
+		if err := w(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BenchmarkExport writes the tables of a corpus into a new database, the
+// way the exporter did before batching and the way it does: batched, with
+// a write-ahead log, in key order into WITHOUT ROWID tables and with the
+// rank indexes built at the end.
+func BenchmarkExport(b *testing.B) {
+	bc := newBenchCorpus()
+	b.Run("rows", func(b *testing.B) {
+		saved := BatchRows
+		BatchRows = 1
+		b.Cleanup(func() { BatchRows = saved })
+		for i := 0; b.Loop(); i++ {
+			db, err := openRows(filepath.Join(b.TempDir(), "pali.db"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := bc.write(db, rowWordFrequency); err != nil {
+				b.Fatal(err)
+			}
+			db.Close()
+		}
+	})
+	b.Run("batched", func(b *testing.B) {
+		for b.Loop() {
+			db, err := Open(filepath.Join(b.TempDir(), "pali.db"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := DeferIndexes(db); err != nil {
+				b.Fatal(err)
+			}
+			if err := bc.write(db, WordFrequency); err != nil {
+				b.Fatal(err)
+			}
+			if err := BuildIndexes(db); err != nil {
+				b.Fatal(err)
+			}
+			if err := Close(db); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkBatchRows writes the word_citation rows of a corpus with
+// Inserters of several batch sizes.
+func BenchmarkBatchRows(b *testing.B) {
+	files := newBenchCorpus().files
+	for _, n := range []int{1, 4, 16, 64, 256, 1024} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			saved := BatchRows
+			BatchRows = n
+			b.Cleanup(func() { BatchRows = saved })
+			db, err := Open(filepath.Join(b.TempDir(), "pali.db"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer Close(db)
+			for b.Loop() {
+				if err := Citations(db, "cst", "root", files); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
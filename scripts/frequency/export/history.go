@@ -0,0 +1,151 @@
+package export
+
+import (
+	"database/sql"
+
+	"dpd/go_modules/frequency/freq"
+)
+
+// HistorySchema creates the tables of the history database the -history
+// flag of palifreq appends to, a database of its own rather than the app's:
+// the summary of each run of a corpus, one per snapshot of its texts,
+// version of palifreq and normalizer chain, and the top words of the run
+// by rank.
+const HistorySchema = `
+CREATE TABLE IF NOT EXISTS history_runs (
+	id         INTEGER PRIMARY KEY,
+	corpus     TEXT    NOT NULL,
+	snapshot   TEXT    NOT NULL,
+	version    TEXT    NOT NULL,
+	vcs_commit TEXT    NOT NULL DEFAULT '',
+	normalizer TEXT    NOT NULL,
+	created    TEXT    NOT NULL,
+	files      INTEGER NOT NULL,
+	books      INTEGER NOT NULL,
+	tokens     INTEGER NOT NULL,
+	types      INTEGER NOT NULL,
+	hapaxes    INTEGER NOT NULL,
+	UNIQUE (corpus, snapshot, version, vcs_commit, normalizer)
+);
+CREATE TABLE IF NOT EXISTS history_ranks (
+	run_id INTEGER NOT NULL,
+	rank   INTEGER NOT NULL,
+	word   TEXT    NOT NULL,
+	count  INTEGER NOT NULL,
+	PRIMARY KEY (run_id, rank)
+);
+`
+
+// HistoryRun is a row of history_runs. Snapshot is the checksum of the
+// corpus files counted, so two runs over the same texts share it.
+type HistoryRun struct {
+	ID                                   int64
+	Corpus, Snapshot, Version, Commit    string
+	Normalizer, Created                  string // Created is RFC 3339, UTC
+	Files, Books, Tokens, Types, Hapaxes int
+}
+
+// OpenHistory opens the history database at path like OpenBulk and creates
+// the tables of HistorySchema if needed. Close it with Close.
+func OpenHistory(path string) (*sql.DB, error) {
+	db, err := OpenBulk(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(HistorySchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// RecordRun appends run and its top words, ranked 1..n in the order given,
+// to the history database and returns the id of the run. A run of a
+// snapshot, version and normalizer already recorded replaces that one,
+// keeping its id, so rerunning a release does not add a step to its
+// history.
+func RecordRun(db *sql.DB, run HistoryRun, top []freq.WordCount) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow(`SELECT id FROM history_runs WHERE corpus = ? AND snapshot = ? AND version = ? AND vcs_commit = ? AND normalizer = ?`,
+		run.Corpus, run.Snapshot, run.Version, run.Commit, run.Normalizer).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		res, err := tx.Exec(`
+			INSERT INTO history_runs (corpus, snapshot, version, vcs_commit, normalizer, created, files, books, tokens, types, hapaxes)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			run.Corpus, run.Snapshot, run.Version, run.Commit, run.Normalizer, run.Created, run.Files, run.Books, run.Tokens, run.Types, run.Hapaxes)
+		if err != nil {
+			return 0, err
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return 0, err
+		}
+	case err != nil:
+		return 0, err
+	default:
+		if _, err := tx.Exec(`
+			UPDATE history_runs SET created = ?, files = ?, books = ?, tokens = ?, types = ?, hapaxes = ?
+			WHERE id = ?`,
+			run.Created, run.Files, run.Books, run.Tokens, run.Types, run.Hapaxes, id); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM history_ranks WHERE run_id = ?`, id); err != nil {
+			return 0, err
+		}
+	}
+	insert := NewInserter(tx, `INSERT INTO history_ranks (run_id, rank, word, count) VALUES`, ``, 4)
+	for i, wc := range top {
+		if err := insert.Add(id, i+1, wc.Word, wc.Count); err != nil {
+			return 0, err
+		}
+	}
+	if err := insert.Close(); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// HistoryRuns returns the runs of corpus recorded in the history database,
+// oldest first.
+func HistoryRuns(db *sql.DB, corpus string) ([]HistoryRun, error) {
+	rows, err := db.Query(`
+		SELECT id, corpus, snapshot, version, vcs_commit, normalizer, created, files, books, tokens, types, hapaxes
+		FROM history_runs WHERE corpus = ? ORDER BY id`, corpus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var runs []HistoryRun
+	for rows.Next() {
+		var r HistoryRun
+		if err := rows.Scan(&r.ID, &r.Corpus, &r.Snapshot, &r.Version, &r.Commit, &r.Normalizer, &r.Created, &r.Files, &r.Books, &r.Tokens, &r.Types, &r.Hapaxes); err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// HistoryRanks returns the top words recorded for the run id, by rank.
+func HistoryRanks(db *sql.DB, id int64) ([]freq.WordCount, error) {
+	rows, err := db.Query(`SELECT word, count FROM history_ranks WHERE run_id = ? ORDER BY rank`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []freq.WordCount
+	for rows.Next() {
+		var wc freq.WordCount
+		if err := rows.Scan(&wc.Word, &wc.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, wc)
+	}
+	return list, rows.Err()
+}
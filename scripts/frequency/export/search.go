@@ -0,0 +1,62 @@
+package export
+
+import (
+	"database/sql"
+)
+
+// SearchSchema creates the full-text search table palifreq build-search
+// fills: one row per paragraph of the corpus texts, with the book and
+// Tipiṭaka section of its file, searchable by text, book and section. The
+// tokenizer folds diacritics, so "sutam" also finds sutaṃ.
+const SearchSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS search USING fts5(
+	text,
+	book,
+	section,
+	corpus UNINDEXED,
+	source UNINDEXED,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+`
+
+// Passage is a row of the search table. Source names the file as
+// SourceID does.
+type Passage struct {
+	Text, Book, Section, Corpus, Source string
+}
+
+// Search replaces the search rows of corpus with the passages produced by
+// each, which calls add once per passage. When each fails, the corpus
+// keeps its previous rows.
+func Search(db *sql.DB, corpus string, each func(add func(Passage) error) error) error {
+	if _, err := db.Exec(SearchSchema); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM search WHERE corpus = ?`, corpus); err != nil {
+		return err
+	}
+	insert := NewInserter(tx, `INSERT INTO search (text, book, section, corpus, source) VALUES`, ``, 5)
+	err = each(func(p Passage) error {
+		return insert.Add(p.Text, p.Book, p.Section, corpus, p.Source)
+	})
+	if err != nil {
+		return err
+	}
+	if err := insert.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// OptimizeSearch merges the index segments of the search table into one,
+// which makes queries faster and the file smaller once it is built.
+func OptimizeSearch(db *sql.DB) error {
+	_, err := db.Exec(`INSERT INTO search (search) VALUES ('optimize')`)
+	return err
+}
@@ -0,0 +1,44 @@
+package export
+
+import (
+	"database/sql"
+)
+
+// Division is a row of the structure table: a paragraph of a source file
+// with the headings of the edition's markup it stands under. Seq is its
+// position in the file, from 1; Heading is set for the paragraphs that are
+// headings themselves.
+type Division struct {
+	Source, Book, Section string
+	Seq                   int
+	Rend, ParaNum         string
+	Heading               bool
+	Nikaya, BookTitle     string
+	Chapter, Vagga, Sutta string
+}
+
+// Structure replaces the structure rows of corpus with the divisions
+// produced by each, which calls add once per paragraph. When each fails,
+// the corpus keeps its previous rows.
+func Structure(db *sql.DB, corpus string, each func(add func(Division) error) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM structure WHERE corpus = ?`, corpus); err != nil {
+		return err
+	}
+	insert := NewInserter(tx, `INSERT INTO structure (corpus, source, seq, book, section, rend, heading, paranum, nikaya, book_title, chapter, vagga, sutta) VALUES`, ``, 13)
+	err = each(func(d Division) error {
+		return insert.Add(corpus, d.Source, d.Seq, d.Book, d.Section, d.Rend, d.Heading, d.ParaNum, d.Nikaya, d.BookTitle, d.Chapter, d.Vagga, d.Sutta)
+	})
+	if err != nil {
+		return err
+	}
+	if err := insert.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// paradigm is the inflection table of a headword: its forms in the order
+// of its template, each once with every grammar it expresses there.
+type paradigm struct {
+	pattern string
+	forms   []string
+	grammar map[string][]string
+}
+
+// loadParadigms returns the headwords pick chooses of those that have an
+// inflection template, given by id, and their paradigms.
+func loadParadigms(path string, pick func(inflected map[int]bool) []int) ([]int, map[int]*paradigm, error) {
+	db, err := dpd.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+	// the templates are read twice, so only the paradigms chosen are kept
+	inflected := make(map[int]bool)
+	if err := db.Inflections(func(in dpd.Inflection) { inflected[in.HeadwordID] = true }); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	ids := pick(inflected)
+	keep := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+	}
+	paradigms := make(map[int]*paradigm, len(ids))
+	err = db.Inflections(func(in dpd.Inflection) {
+		if !keep[in.HeadwordID] {
+			return
+		}
+		p := paradigms[in.HeadwordID]
+		if p == nil {
+			p = &paradigm{pattern: in.Pattern, grammar: make(map[string][]string)}
+			paradigms[in.HeadwordID] = p
+		}
+		g, seen := p.grammar[in.Form]
+		if !seen {
+			p.forms = append(p.forms, in.Form)
+		}
+		if !slices.Contains(g, in.Grammar) {
+			p.grammar[in.Form] = append(g, in.Grammar)
+		}
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ids, paradigms, nil
+}
+
+// pickHeadwords returns the ids of the headwords to tabulate, of those
+// inflected: the headwords of the lemmas given, in their order, a lemma
+// without its number ("buddha") standing for all its homonyms, or else the
+// top ones of ranked. Headwords whose pos is not in posSet are left out
+// unless it is empty.
+func pickHeadwords(lem *freq.Lemmatizer, ranked []freq.LemmaCount, inflected map[int]bool, lemmas []string, top int, posSet map[string]bool) []int {
+	ok := func(h dpd.Headword) bool {
+		return inflected[h.ID] && (len(posSet) == 0 || posSet[h.Pos])
+	}
+	var ids []int
+	if len(lemmas) > 0 {
+		heads := make([]dpd.Headword, 0, len(lem.Headwords))
+		for _, h := range lem.Headwords {
+			heads = append(heads, h)
+		}
+		sort.Slice(heads, func(i, j int) bool { return heads[i].ID < heads[j].ID })
+		for _, lemma := range lemmas {
+			found := false
+			for _, h := range heads {
+				if (h.Lemma1 == lemma || lemmaWord(h.Lemma1) == lemma) && ok(h) {
+					ids = append(ids, h.ID)
+					found = true
+				}
+			}
+			if !found {
+				tools.Warnf("%q: no inflected DPD headword of that lemma", lemma)
+			}
+		}
+		return ids
+	}
+	for _, lc := range ranked {
+		if top > 0 && len(ids) == top {
+			break
+		}
+		if ok(lc.Headword) {
+			ids = append(ids, lc.Headword.ID)
+		}
+	}
+	return ids
+}
+
+// saveForms writes the tables <name>_forms (columns headword_id, lemma,
+// pos, form, grammar, count, share), every form of each headword of ids
+// with its count in counts, most frequent first and those not found last,
+// and <name>_paradigm_coverage (headword_id, lemma, pos, pattern, forms,
+// occurring, coverage, count), how much of each paradigm occurs. A form
+// that several headwords share adds its full count to each, as the
+// headword counts do.
+func saveForms(s Sink, name string, lem *freq.Lemmatizer, ids []int, paradigms map[int]*paradigm, counts map[string]int) error {
+	forms := table{columns: []string{"headword_id", "lemma", "pos", "form", "grammar", "count", "share"}}
+	coverage := table{columns: []string{"headword_id", "lemma", "pos", "pattern", "forms", "occurring", "coverage", "count"}}
+	for _, id := range ids {
+		h, p := lem.Headwords[id], paradigms[id]
+		order := slices.Clone(p.forms)
+		// stable, so forms of equal count keep the order of the template
+		sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+		total, occurring := 0, 0
+		for _, f := range order {
+			if n := counts[f]; n > 0 {
+				total += n
+				occurring++
+			}
+		}
+		for _, f := range order {
+			share := 0.0
+			if total > 0 {
+				share = math.Round(float64(counts[f])/float64(total)*1e4) / 1e4
+			}
+			forms.rows = append(forms.rows, []any{id, h.Lemma1, h.Pos, f, strings.Join(p.grammar[f], "; "), counts[f], share})
+		}
+		cov := math.Round(float64(occurring)/float64(len(order))*1e4) / 1e4
+		coverage.rows = append(coverage.rows, []any{id, h.Lemma1, h.Pos, p.pattern, len(order), occurring, cov, total})
+	}
+	if err := s.Write(name+"_forms", forms); err != nil {
+		return err
+	}
+	return s.Write(name+"_paradigm_coverage", coverage)
+}
+
+// runForms implements the forms subcommand.
+func runForms(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("forms", flag.ExitOnError)
+	commandUsage(fs, "Writes, for the top DPD headwords or those given, how often each form of their inflection tables occurs, and how much of each paradigm does, from the counts of the last run.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to tabulate, from the counts of the last run; the headwords are ranked by their counts together")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database with the inflection templates")
+	top := fs.Int("top", 500, "number of most frequent inflected headwords to tabulate (0: all)")
+	headwords := fs.String("headwords", "", "comma-separated lemmas to tabulate instead of the top ones, e.g. buddha,dhamma 1 (a lemma without its number takes all its homonyms)")
+	pos := fs.String("pos", "", "comma-separated parts of speech to keep, e.g. masc,fem,nt (default: all)")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("saving form frequencies per headword")
+	tic := tools.Tic()
+
+	posSet := make(map[string]bool)
+	if *pos != "" {
+		for _, p := range strings.Split(*pos, ",") {
+			posSet[strings.TrimSpace(p)] = true
+		}
+	}
+	var lemmas []string
+	if *headwords != "" {
+		for _, l := range strings.Split(*headwords, ",") {
+			lemmas = append(lemmas, strings.TrimSpace(l))
+		}
+	}
+
+	list := strings.Split(*names, ",")
+	counts := make(map[string]map[string]int, len(list))
+	total := make(map[string]int)
+	for _, name := range list {
+		sites, err := loadWordSites(name)
+		if err != nil {
+			tools.Errorf("%s: %v (count it first)", name, err)
+			return
+		}
+		m := make(map[string]int, len(sites))
+		for w, s := range sites {
+			m[w] = s.count
+			total[w] += s.count
+		}
+		counts[name] = m
+	}
+	lem, err := freq.LoadLemmatizer(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	ranked := lem.Counts(total)
+	ids, paradigms, err := loadParadigms(*dpdPath, func(inflected map[int]bool) []int {
+		return pickHeadwords(lem, ranked, inflected, lemmas, *top, posSet)
+	})
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if len(ids) == 0 {
+		tools.Errorf("no inflected headwords to tabulate")
+		return
+	}
+	tools.Infof("%d headwords", len(ids))
+	for _, name := range list {
+		if err := saveForms(sink, name, lem, ids, paradigms, counts[name]); err != nil {
+			tools.Errorf("%s: %v", name, err)
+		}
+	}
+	tic.Toc()
+}
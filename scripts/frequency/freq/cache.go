@@ -0,0 +1,196 @@
+package freq
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// Cache remembers the token counts of each file by its SHA-256, so files
+// unchanged since the last run are not read and tokenized again. It is
+// stored as a gob file, one per corpus; palifreq keeps them next to its
+// outputs.
+type Cache struct {
+	path string
+
+	mu       sync.Mutex
+	manifest cacheManifest
+	used     map[string]bool
+}
+
+type cacheManifest struct {
+	// Settings identifies the tokenizer configuration and the cleaning
+	// rules the counts were made with; a cache made with other settings is
+	// discarded.
+	Settings string
+	Files    map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	SHA256  string
+	Counts  map[string]int
+	Suspect string // why the text looks wrong, if it does
+}
+
+// LoadCache reads the cache at path, or starts an empty one when the file
+// is missing, unreadable or was written with other tokenizer settings,
+// cleaning rules or -strict-encoding.
+func LoadCache(path string, tok pali.Tokenizer, cleaning []corpora.CleaningRule) *Cache {
+	settings := fmt.Sprintf("%+v", tok)
+	// files that are not UTF-8 are read otherwise under -strict-encoding
+	if corpora.StrictEncoding {
+		settings += " strict-encoding"
+	}
+	if len(cleaning) > 0 {
+		settings += fmt.Sprintf(" cleaning=%v", cleaning)
+	}
+	c := &Cache{
+		path:     path,
+		manifest: cacheManifest{Settings: settings, Files: make(map[string]cacheEntry)},
+		used:     make(map[string]bool),
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return c
+	}
+	defer f.Close()
+	var m cacheManifest
+	if err := gob.NewDecoder(f).Decode(&m); err != nil || m.Settings != settings || m.Files == nil {
+		return c
+	}
+	c.manifest = m
+	return c
+}
+
+// Get returns the cached counts of file and why its text looks wrong, if
+// it does, when its hash is unchanged.
+func (c *Cache) Get(file, sum string) (counts map[string]int, suspect string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.manifest.Files[file]
+	if !ok || e.SHA256 != sum {
+		return nil, "", false
+	}
+	c.used[file] = true
+	return e.Counts, e.Suspect, true
+}
+
+// Put caches the counts of file, and why its text looks wrong, with its
+// hash.
+func (c *Cache) Put(file, sum string, counts map[string]int, suspect string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.manifest.Files[file] = cacheEntry{sum, counts, suspect}
+	c.used[file] = true
+}
+
+// keep keeps the entry of file, if any, through Save without using it,
+// for files whose counts come from a Checkpoint.
+func (c *Cache) keep(file string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.manifest.Files[file]; ok {
+		c.used[file] = true
+	}
+}
+
+// Cached is the number of files that have an entry, changed or not.
+func (c *Cache) Cached(files []string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, f := range files {
+		if _, ok := c.manifest.Files[f]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// Save writes the entries used since loading, dropping files that are
+// gone. The file is replaced atomically.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for file := range c.manifest.Files {
+		if !c.used[file] {
+			delete(c.manifest.Files, file)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	f, err := tools.CreateAtomic(c.path, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if err := gob.NewEncoder(f).Encode(c.manifest); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// ReadCache returns the word counts of a saved cache by file path, as
+// counted by the last run, without checking its settings.
+func ReadCache(path string) (map[string]map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var m cacheManifest
+	if err := gob.NewDecoder(f).Decode(&m); err != nil {
+		return nil, err
+	}
+	files := make(map[string]map[string]int, len(m.Files))
+	for file, e := range m.Files {
+		files[file] = e.Counts
+	}
+	return files, nil
+}
+
+// CacheChecksum is the hex SHA-256 of the files of the saved cache at
+// path, a "<path> <SHA-256>" line each in path order: a checksum of the
+// snapshot of the corpus the last run counted, whatever the counts.
+func CacheChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var m cacheManifest
+	if err := gob.NewDecoder(f).Decode(&m); err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, file := range slices.Sorted(maps.Keys(m.Files)) {
+		fmt.Fprintf(h, "%s %s\n", file, m.Files[file].SHA256)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashFile is the hex SHA-256 of the file at path, as the cache keys it.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,273 @@
+package freq
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/tools"
+)
+
+// Checkpoint keeps what Count has counted of a corpus on disk, saved every
+// so often while it counts and once more when it stops, so a run that
+// crashed or was interrupted can resume with the files not counted yet
+// instead of starting over. The n-gram runs of the counted files are kept
+// next to it. Unlike the Cache, a checkpoint also holds n-gram and verse
+// counts, and it is only read when resuming.
+type Checkpoint struct {
+	path  string
+	every time.Duration
+	state checkpointState // as loaded; its Files are nil when starting afresh
+}
+
+type checkpointState struct {
+	// Settings identifies the options the counts were made with; a
+	// checkpoint made with other options is discarded.
+	Settings string
+	Files    map[string]checkpointFile // the files counted, by path
+	// totals of the files counted
+	Verse, Prose, Collapsed map[string]int
+	Problems                []FileProblem // of the files counted
+	// the first locations of each word in the files counted
+	Locations map[string][]Location
+	// bytes of each n-gram shard once the files counted were added, by
+	// n-gram size
+	Shards map[int][]int64
+}
+
+// checkpointFile is a file counted before the checkpoint was saved. Its
+// size and modification time tell whether it changed since.
+type checkpointFile struct {
+	Size    int64
+	ModTime time.Time
+	Counts  map[string]int
+}
+
+// LoadCheckpoint returns the checkpoint at path, which Count saves every
+// interval while counting. With resume set, the counts saved there by an
+// earlier run are read; otherwise, or when there are none, counting starts
+// afresh and replaces them.
+func LoadCheckpoint(path string, every time.Duration, resume bool) *Checkpoint {
+	c := &Checkpoint{path: path, every: every}
+	if !resume {
+		return c
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return c
+	}
+	defer f.Close()
+	var s checkpointState
+	if err := gob.NewDecoder(f).Decode(&s); err != nil {
+		tools.Warnf("%s: %v; counting afresh", path, err)
+		return c
+	}
+	c.state = s
+	return c
+}
+
+// Remove deletes the checkpoint and its n-gram runs, once they are no
+// longer needed.
+func (c *Checkpoint) Remove() error {
+	err := os.Remove(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	return errors.Join(err, os.RemoveAll(c.ngramDir()))
+}
+
+func (c *Checkpoint) ngramDir() string { return c.path + ".ngrams" }
+
+// checkpointSettings describes the options of opts that shape the counts.
+func checkpointSettings(c corpora.Corpus, opts *Options, verse bool) string {
+	var layers []string
+	for l := range opts.Layers {
+		layers = append(layers, l)
+	}
+	slices.Sort(layers)
+	var variants any
+	if opts.Variants != nil {
+		variants = opts.Variants.Rules()
+	}
+	s := fmt.Sprintf("%s %+v cleaning=%v layers=%v variants=%v ngrams=%v verse=%v exclude-suspect=%v provenance=%d", c.Name(), opts.Tokenizer, corpora.CleanerOf(c).Rules(), layers, variants, opts.Ngrams, verse, opts.ExcludeSuspect, opts.Provenance)
+	if opts.Filter != nil {
+		s += " filter=" + opts.Filter.String()
+	}
+	// files that are not UTF-8 are read otherwise under -strict-encoding
+	if corpora.StrictEncoding {
+		s += " strict-encoding"
+	}
+	return s
+}
+
+// restore fills t with the counts of the checkpoint and returns the files
+// they cover, when the checkpoint was made with settings and none of its
+// files changed since. Otherwise it drops them and returns nil.
+func (c *Checkpoint) restore(settings string, t *Table, corpus corpora.Corpus, cache *Cache) map[string]bool {
+	s := c.state
+	c.state = checkpointState{}
+	if s.Files == nil {
+		return nil
+	}
+	if s.Settings != settings {
+		tools.Warnf("%s: made with other settings; counting afresh", c.path)
+		return nil
+	}
+	for path, f := range s.Files {
+		info, err := os.Stat(path)
+		if err != nil || info.Size() != f.Size || !info.ModTime().Equal(f.ModTime) {
+			tools.Warnf("%s: %s changed since; counting afresh", c.path, path)
+			return nil
+		}
+	}
+	done := make(map[string]bool, len(s.Files))
+	for path, f := range s.Files {
+		t.Books.Add(corpora.BookOf(corpus, path), f.Counts)
+		t.Files[path] = f.Counts
+		done[path] = true
+		if cache != nil {
+			cache.keep(path)
+		}
+	}
+	for w, n := range s.Collapsed {
+		t.Collapsed[w] += n
+	}
+	if t.Verse != nil {
+		t.Verse, t.Prose = s.Verse, s.Prose
+	}
+	if t.Locations != nil && s.Locations != nil {
+		t.Locations = s.Locations
+	}
+	t.Problems = append(t.Problems, s.Problems...)
+	c.state.Settings, c.state.Shards = settings, s.Shards
+	tools.Infof("%s: resuming after %d counted files", corpus.Name(), len(done))
+	return done
+}
+
+// spill returns the n-gram spill of size n kept with the checkpoint,
+// holding the runs of the files restored, if any.
+func (c *Checkpoint) spill(n int) (*NgramSpill, error) {
+	dir := filepath.Join(c.ngramDir(), strconv.Itoa(n))
+	sizes, ok := c.state.Shards[n]
+	if !ok {
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &NgramSpill{dir: dir, keep: true}
+	if ok {
+		if err := s.truncate(sizes); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// save writes the counts of t, those of the files in t.Files, replacing
+// the checkpoint atomically. Count calls it while no file is being added,
+// so the n-gram shards hold the runs of exactly those files.
+func (c *Checkpoint) save(settings string, t *Table) error {
+	s := checkpointState{
+		Settings:  settings,
+		Files:     make(map[string]checkpointFile, len(t.Files)),
+		Verse:     t.Verse,
+		Prose:     t.Prose,
+		Collapsed: t.Collapsed,
+		Locations: t.Locations,
+		Shards:    make(map[int][]int64, len(t.Ngrams)),
+	}
+	for path, counts := range t.Files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		s.Files[path] = checkpointFile{info.Size(), info.ModTime(), counts}
+	}
+	for _, p := range t.Problems {
+		// files that could not be read are tried again on resuming
+		if !p.Skipped {
+			s.Problems = append(s.Problems, p)
+		}
+	}
+	for n, spill := range t.Ngrams {
+		sizes, err := spill.sizes()
+		if err != nil {
+			return err
+		}
+		s.Shards[n] = sizes
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	f, err := tools.CreateAtomic(c.path, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if err := gob.NewEncoder(f).Encode(s); err != nil {
+		return err
+	}
+	if err := f.Commit(); err != nil {
+		return err
+	}
+	tools.Debugf("%s: checkpoint of %d files", c.path, len(s.Files))
+	return nil
+}
+
+// sizes returns the length of each shard file, 0 for those not written.
+func (s *NgramSpill) sizes() ([]int64, error) {
+	sizes := make([]int64, ngramShards)
+	for i := range sizes {
+		info, err := os.Stat(s.shardPath(i))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sizes[i] = info.Size()
+	}
+	return sizes, nil
+}
+
+// truncate cuts every shard back to the length sizes gives it, dropping
+// the runs appended after a checkpoint was saved.
+func (s *NgramSpill) truncate(sizes []int64) error {
+	if len(sizes) != ngramShards {
+		return fmt.Errorf("%s: %d shards, want %d", s.dir, len(sizes), ngramShards)
+	}
+	for i, size := range sizes {
+		path := s.shardPath(i)
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) && size == 0 {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.Size() < size {
+			return fmt.Errorf("%s: shorter than its checkpoint", path)
+		}
+		if err := os.Truncate(path, size); err != nil {
+			return err
+		}
+	}
+	// ranked files of an earlier merge are made again
+	matches, _ := filepath.Glob(filepath.Join(s.dir, "ranked-*"))
+	for _, m := range matches {
+		if err := os.Remove(m); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
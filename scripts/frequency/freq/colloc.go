@@ -0,0 +1,160 @@
+package freq
+
+import (
+	"math"
+	"sort"
+
+	"dpd/go_modules/tools"
+)
+
+// Collocations counts the words found near the forms of a set of node
+// headwords, within a window of words on either side, and rates how much
+// more often than by chance each word is found there.
+type Collocations struct {
+	Window int            // words on each side of a node form
+	Tokens int            // words counted
+	Words  map[string]int // count of every word
+	Nodes  map[int]int    // occurrences of each node headword
+
+	nodes map[string][]int // form → node headwords it belongs to
+	pairs map[collocPair]int
+}
+
+type collocPair struct {
+	id   int
+	word string
+}
+
+// NewCollocations returns an empty count of the words within window words
+// of the forms of nodes, which maps each form to its node headwords.
+func NewCollocations(window int, nodes map[string][]int) *Collocations {
+	return &Collocations{
+		Window: window,
+		Words:  make(map[string]int),
+		Nodes:  make(map[int]int),
+		nodes:  nodes,
+		pairs:  make(map[collocPair]int),
+	}
+}
+
+// Add counts one run of words, such as a paragraph; windows never reach
+// past it. A word near a node form counts once for each node headword of
+// the form, and each time it is in a window: twice when two forms of the
+// headword are that close. Forms of the node headword itself are not its
+// collocates.
+func (c *Collocations) Add(words []string) {
+	for i, w := range words {
+		c.Tokens++
+		c.Words[w]++
+		ids := c.nodes[w]
+		for _, id := range ids {
+			c.Nodes[id]++
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		lo, hi := max(i-c.Window, 0), min(i+c.Window, len(words)-1)
+		for j := lo; j <= hi; j++ {
+			if j == i {
+				continue
+			}
+			for _, id := range ids {
+				if !c.isForm(words[j], id) {
+					c.pairs[collocPair{id, words[j]}]++
+				}
+			}
+		}
+	}
+}
+
+// isForm reports whether w is a form of the node headword id.
+func (c *Collocations) isForm(w string, id int) bool {
+	for _, other := range c.nodes[w] {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Collocate is a word found near a node headword, with its association
+// measures, both rounded to four decimals.
+type Collocate struct {
+	Word      string
+	Count     int // times in a window of the headword
+	WordCount int // times in all, near the headword or not
+	// PMI is the log2 of Count over the count expected by chance.
+	PMI float64
+	// LogLikelihood is Dunning's G² of Count against that expectation,
+	// negative when the word is found less often than expected.
+	LogLikelihood float64
+}
+
+// Collocates returns the collocates found at least minCount times near
+// each node headword, most strongly associated first: by PMI when byPMI
+// is set, else by log-likelihood, then by word in Pāḷi order.
+//
+// A node of f occurrences has 2·Window·f window positions, of the
+// 2·Window·Tokens of all words, and a word of count g takes g of every
+// Tokens positions by chance, so 2·Window·f·g/Tokens of the node's. The
+// log-likelihood compares the positions of the node's windows and the
+// rest, holding the word or not; positions past the ends of a run are
+// counted as if they held words, which matters only for short runs.
+func (c *Collocations) Collocates(minCount int, byPMI bool) map[int][]Collocate {
+	span := float64(2 * c.Window)
+	total := float64(c.Tokens) * span
+	byNode := make(map[int][]Collocate)
+	for p, n := range c.pairs {
+		if n < minCount {
+			continue
+		}
+		g := c.Words[p.word]
+		near := float64(c.Nodes[p.id]) * span // positions in the node's windows
+		with := float64(g) * span             // positions next to the word
+		o11 := float64(n)
+		e11 := near * with / total
+		llr := logLikelihood(o11, near-o11, with-o11, max(total-near-with+o11, 0))
+		if o11 < e11 {
+			llr = -llr
+		}
+		byNode[p.id] = append(byNode[p.id], Collocate{
+			Word:          p.word,
+			Count:         n,
+			WordCount:     g,
+			PMI:           math.Round(math.Log2(o11/e11)*1e4) / 1e4,
+			LogLikelihood: math.Round(llr*1e4) / 1e4,
+		})
+	}
+	for _, list := range byNode {
+		sort.Slice(list, func(i, j int) bool {
+			a, b := list[i], list[j]
+			sa, sb := a.LogLikelihood, b.LogLikelihood
+			if byPMI {
+				sa, sb = a.PMI, b.PMI
+			}
+			if sa != sb {
+				return sa > sb
+			}
+			return tools.ComparePali(a.Word, b.Word) < 0
+		})
+	}
+	return byNode
+}
+
+// logLikelihood is G² = 2 Σ O ln(O/E) of the 2×2 table o11 o12 / o21 o22,
+// each E the product of its row and column totals over the table's total.
+func logLikelihood(o11, o12, o21, o22 float64) float64 {
+	n := o11 + o12 + o21 + o22
+	cells := [4]float64{o11, o12, o21, o22}
+	rows := [2]float64{o11 + o12, o21 + o22}
+	cols := [2]float64{o11 + o21, o12 + o22}
+	g := 0.0
+	for i, o := range cells {
+		if o <= 0 {
+			continue
+		}
+		e := rows[i/2] * cols[i%2] / n
+		g += o * math.Log(o/e)
+	}
+	return 2 * g
+}
@@ -0,0 +1,207 @@
+package freq
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/pali"
+)
+
+// Dedup configures the deduplicated counts of Count, in which a paragraph
+// repeating an earlier one, as the repetition series of the texts do, is
+// counted with a smaller weight.
+type Dedup struct {
+	// Threshold is the least share of word shingles a paragraph must have
+	// in common with an earlier one to repeat it, as MinHash estimates
+	// their Jaccard similarity, from 0 to 1.
+	Threshold float64
+	// Damping is the weight of the words of a repeat: 0 counts a repeated
+	// block once, 1 as often as it occurs.
+	Damping float64
+	// MinWords is the least number of words of a paragraph that can
+	// repeat; shorter ones, like titles and stock phrases, always count.
+	MinWords int
+}
+
+// DefaultDedup counts a block repeated nearly word for word once.
+var DefaultDedup = Dedup{Threshold: 0.8, Damping: 0, MinWords: 8}
+
+// DedupStats tells how much of a corpus repeats.
+type DedupStats struct {
+	Paragraphs int // paragraphs with words
+	Repeats    int // of them, those repeating an earlier one
+	Tokens     int // tokens of all paragraphs
+	Repeated   int // tokens of the repeats
+}
+
+// MinHash parameters: a signature has minhashSize values, grouped into
+// minhashBands bands; two paragraphs agreeing on a whole band are compared.
+// With bands of 4 values, paragraphs of similarity 0.8 meet with a
+// probability above 0.99, and those of 0.4 below 0.2.
+const (
+	minhashSize    = 32
+	minhashBands   = 8
+	minhashShingle = 3 // words per shingle
+)
+
+// minhashSeeds are the seeds of the hash functions of a signature.
+var minhashSeeds = func() [minhashSize]uint64 {
+	var seeds [minhashSize]uint64
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range seeds {
+		x = splitmix64(x)
+		seeds[i] = x
+	}
+	return seeds
+}()
+
+// splitmix64 scrambles x; it turns one hash into many independent ones.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// signature is the MinHash of the shingles of a paragraph.
+type signature [minhashSize]uint32
+
+// minhash returns the signature of the word shingles of tokens, which are
+// at least minhashShingle long.
+func minhash(tokens []string) signature {
+	var sig signature
+	for i := range sig {
+		sig[i] = math.MaxUint32
+	}
+	for i := 0; i+minhashShingle <= len(tokens); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(tokens[i:i+minhashShingle], " ")))
+		base := h.Sum64()
+		for j, seed := range minhashSeeds {
+			if v := uint32(splitmix64(base ^ seed)); v < sig[j] {
+				sig[j] = v
+			}
+		}
+	}
+	return sig
+}
+
+// similarity estimates the Jaccard similarity of the paragraphs of a and b.
+func (a *signature) similarity(b *signature) float64 {
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / minhashSize
+}
+
+// bandKey identifies band i of sig.
+func (sig *signature) bandKey(i int) uint64 {
+	rows := minhashSize / minhashBands
+	var b [4*minhashSize/minhashBands + 1]byte
+	b[0] = byte(i)
+	for r := 0; r < rows; r++ {
+		binary.LittleEndian.PutUint32(b[1+4*r:], sig[i*rows+r])
+	}
+	h := fnv.New64a()
+	h.Write(b[:])
+	return h.Sum64()
+}
+
+// deduper finds the paragraphs repeating an earlier one.
+type deduper struct {
+	opts  Dedup
+	sigs  []signature      // of the paragraphs that repeat none
+	bands map[uint64][]int // band key → indexes of sigs
+}
+
+// repeats reports whether the paragraph of tokens repeats an earlier one,
+// and remembers it as one to compare later paragraphs with when it does
+// not.
+func (d *deduper) repeats(tokens []string) bool {
+	if len(tokens) < max(d.opts.MinWords, minhashShingle) {
+		return false
+	}
+	sig := minhash(tokens)
+	seen := make(map[int]bool)
+	for i := 0; i < minhashBands; i++ {
+		for _, j := range d.bands[sig.bandKey(i)] {
+			if seen[j] {
+				continue
+			}
+			seen[j] = true
+			if sig.similarity(&d.sigs[j]) >= d.opts.Threshold {
+				return true
+			}
+		}
+	}
+	for i := 0; i < minhashBands; i++ {
+		k := sig.bandKey(i)
+		d.bands[k] = append(d.bands[k], len(d.sigs))
+	}
+	d.sigs = append(d.sigs, sig)
+	return false
+}
+
+// dedupCount counts the files of c, in order, with the repeats of earlier
+// paragraphs weighed by opts.Dedup.Damping. The files are read one after
+// the other, so which copy of a block counts in full does not depend on
+// the order counting happens to finish in.
+func dedupCount(ctx context.Context, c corpora.Corpus, opts *Options, files []string) (map[string]int, DedupStats, error) {
+	d := &deduper{opts: *opts.Dedup, bands: make(map[uint64][]int)}
+	weighed := make(map[string]float64)
+	var stats DedupStats
+	repair := opts.Tokenizer.Normalizer.RepairsMojibake()
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, stats, err
+		}
+		err := c.ScanText(path, func(line string) error {
+			if repair {
+				line = pali.RepairMojibake(line)
+			}
+			tokens := opts.Tokenizer.Tokenize(c.Normalize(line))
+			if len(tokens) == 0 {
+				return nil
+			}
+			stats.Paragraphs++
+			stats.Tokens += len(tokens)
+			weight := 1.0
+			if d.repeats(tokens) {
+				stats.Repeats++
+				stats.Repeated += len(tokens)
+				weight = d.opts.Damping
+			}
+			if weight == 0 {
+				return nil
+			}
+			counts := make(map[string]int)
+			for _, w := range tokens {
+				counts[w]++
+			}
+			if opts.Variants != nil {
+				counts = opts.Variants.Collapse(counts, make(map[string]int))
+			}
+			for w, n := range counts {
+				weighed[w] += weight * float64(n)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, stats, err
+		}
+	}
+	counts := make(map[string]int, len(weighed))
+	for w, f := range weighed {
+		if n := int(math.Round(f)); n > 0 {
+			counts[w] = n
+		}
+	}
+	return counts, stats, nil
+}
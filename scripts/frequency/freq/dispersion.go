@@ -0,0 +1,57 @@
+package freq
+
+import (
+	"maps"
+	"math"
+	"slices"
+)
+
+// Dispersion describes how evenly a word is spread over the files of a
+// corpus.
+type Dispersion struct {
+	Docs int     // number of files containing the word
+	DP   float64 // Gries' deviation of proportions, rounded to four decimals
+}
+
+// DispersionStats computes the document frequency and DP of every word of
+// files, the per-file counts of one corpus.
+//
+// DP is half the sum, over all files, of |v - s|, where v is the share of
+// the word's occurrences found in a file and s the share of the corpus's
+// tokens in that file. It is 0 for a word spread exactly like the text and
+// approaches 1 for a word found in a single small file.
+func DispersionStats(files map[string]map[string]int) map[string]Dispersion {
+	total := 0
+	sizes := make(map[string]int, len(files))
+	freq := make(map[string]int)
+	for path, counts := range files {
+		for w, n := range counts {
+			sizes[path] += n
+			freq[w] += n
+		}
+		total += sizes[path]
+	}
+	if total == 0 {
+		return nil
+	}
+	// files without the word contribute s each, and those s sum to 1 minus
+	// the shares of the files with it, so only those need visiting
+	// files are visited in a fixed order so the float sums, and with them
+	// the rounded values, are the same on every run
+	sum := make(map[string]float64, len(freq))
+	docs := make(map[string]int, len(freq))
+	for _, path := range slices.Sorted(maps.Keys(files)) {
+		s := float64(sizes[path]) / float64(total)
+		for w, n := range files[path] {
+			v := float64(n) / float64(freq[w])
+			sum[w] += math.Abs(v-s) - s
+			docs[w]++
+		}
+	}
+	stats := make(map[string]Dispersion, len(freq))
+	for w := range freq {
+		dp := (sum[w] + 1) / 2
+		stats[w] = Dispersion{docs[w], math.Round(dp*1e4) / 1e4}
+	}
+	return stats
+}
@@ -0,0 +1,161 @@
+package freq
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+)
+
+// ErrNoFilterFiles is returned for a corpus none of whose files pass the
+// Filter of the options.
+var ErrNoFilterFiles = errors.New("no files pass the include and exclude filters")
+
+// Filter selects the files of a corpus by file name or book, so a part of
+// it can be counted without moving files around. A file passes when it
+// matches one of the include patterns, if there are any, and none of the
+// exclude patterns. A pattern matches a file when it matches its base name
+// or its book key, as corpora.BookOf gives it: "mn" selects the Majjhima
+// Nikāya, "*.tik.xml" the ṭīkās of CST. A pattern between slashes, like
+// /^s0[23]/, is a regular expression found anywhere in them; any other is a
+// glob as filepath.Match reads it.
+type Filter struct {
+	include, exclude []filterPattern
+}
+
+// filterPattern is one pattern of a Filter.
+type filterPattern struct {
+	text string
+	re   *regexp.Regexp // of a /regexp/ pattern; nil for a glob
+}
+
+// NewFilter returns the Filter of the include and exclude patterns, or
+// nil when there are none.
+func NewFilter(include, exclude []string) (*Filter, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+	f := &Filter{}
+	for _, list := range []struct {
+		texts []string
+		into  *[]filterPattern
+	}{{include, &f.include}, {exclude, &f.exclude}} {
+		for _, text := range list.texts {
+			p, err := parseFilterPattern(text)
+			if err != nil {
+				return nil, err
+			}
+			*list.into = append(*list.into, p)
+		}
+	}
+	return f, nil
+}
+
+func parseFilterPattern(text string) (filterPattern, error) {
+	p := filterPattern{text: text}
+	if expr, ok := strings.CutPrefix(text, "/"); ok && len(expr) > 0 && strings.HasSuffix(expr, "/") {
+		re, err := regexp.Compile(strings.TrimSuffix(expr, "/"))
+		if err != nil {
+			return p, fmt.Errorf("filter %s: %w", text, err)
+		}
+		p.re = re
+		return p, nil
+	}
+	if _, err := filepath.Match(text, ""); err != nil {
+		return p, fmt.Errorf("filter %s: %w", text, err)
+	}
+	return p, nil
+}
+
+func (p filterPattern) match(s string) bool {
+	if p.re != nil {
+		return p.re.MatchString(s)
+	}
+	ok, _ := filepath.Match(p.text, s)
+	return ok
+}
+
+// Match reports whether the file path of c passes f. Every file passes a
+// nil Filter.
+func (f *Filter) Match(c corpora.Corpus, path string) bool {
+	if f == nil {
+		return true
+	}
+	name, book := filepath.Base(path), corpora.BookOf(c, path)
+	matches := func(p filterPattern) bool { return p.match(name) || p.match(book) }
+	if len(f.include) > 0 && !slices.ContainsFunc(f.include, matches) {
+		return false
+	}
+	return !slices.ContainsFunc(f.exclude, matches)
+}
+
+// Include and Exclude return the patterns of f as given.
+func (f *Filter) Include() []string { return f.texts(false) }
+func (f *Filter) Exclude() []string { return f.texts(true) }
+
+func (f *Filter) texts(exclude bool) []string {
+	if f == nil {
+		return nil
+	}
+	list := f.include
+	if exclude {
+		list = f.exclude
+	}
+	var texts []string
+	for _, p := range list {
+		texts = append(texts, p.text)
+	}
+	return texts
+}
+
+// String describes f for the checkpoint settings and the logs, e.g.
+// "include mn exclude *.tik.xml".
+func (f *Filter) String() string {
+	var parts []string
+	if in := f.Include(); len(in) > 0 {
+		parts = append(parts, "include "+strings.Join(in, " "))
+	}
+	if ex := f.Exclude(); len(ex) > 0 {
+		parts = append(parts, "exclude "+strings.Join(ex, " "))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Key names the selection of f in output file names: the include
+// patterns, then "not" and the exclude patterns, with what is not a lower
+// case letter or digit written "_", e.g. "mn" or "not_tik_xml".
+func (f *Filter) Key() string {
+	var parts []string
+	parts = append(parts, f.Include()...)
+	if ex := f.Exclude(); len(ex) > 0 {
+		parts = append(parts, "not")
+		parts = append(parts, ex...)
+	}
+	key := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(strings.Join(parts, "_")))
+	for strings.Contains(key, "__") {
+		key = strings.ReplaceAll(key, "__", "_")
+	}
+	return strings.Trim(key, "_")
+}
+
+// Apply returns the files of c among files that pass f, or
+// ErrNoFilterFiles when none does.
+func (f *Filter) Apply(c corpora.Corpus, files []string) ([]string, error) {
+	if f == nil {
+		return files, nil
+	}
+	files = slices.DeleteFunc(files, func(path string) bool { return !f.Match(c, path) })
+	if len(files) == 0 {
+		return nil, ErrNoFilterFiles
+	}
+	return files, nil
+}
@@ -0,0 +1,607 @@
+// Package freq counts word frequencies in the Pāḷi text editions of
+// package corpora: per file, per book and for the corpus as a whole,
+// optionally with n-grams, merged spelling variants and a file cache that
+// spares rereading unchanged files.
+//
+//	c := corpora.NewCst("resources/dpd_submodules/cst/romn")
+//	t, err := freq.Count(ctx, c, freq.Options{Tokenizer: pali.Default})
+//	if err != nil {
+//		return err
+//	}
+//	defer t.Close()
+//	for _, wc := range freq.Sorted(t.Counts()) {
+//		fmt.Println(wc.Word, wc.Count)
+//	}
+//
+// CountText counts text that is not a corpus, read from any io.Reader.
+//
+// palifreq builds its tables, word lists and database rows on Count; the
+// writers of the database rows are in package export.
+package freq
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// WordCount is a word, or n-gram, and its count.
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+// Sorted orders counts by descending count, then by word.
+func Sorted(counts map[string]int) []WordCount {
+	list := make([]WordCount, 0, len(counts))
+	for w, n := range counts {
+		list = append(list, WordCount{w, n})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return tools.ComparePali(list[i].Word, list[j].Word) < 0
+	})
+	return list
+}
+
+// TokenTotal is the number of tokens behind counts.
+func TokenTotal(counts map[string]int) int {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
+
+// PerMillion is count per million of total, rounded to four decimals.
+func PerMillion(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(count)*1e10/float64(total)) / 1e4
+}
+
+// CountText counts the tokens of the lines of r as tok tokenizes them:
+// text that is no corpus, such as a handout piped in, with none of the
+// cleaning of a corpus. A byte order mark at the start is dropped.
+func CountText(r io.Reader, tok pali.Tokenizer) (map[string]int, error) {
+	counts := make(map[string]int)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for first := true; sc.Scan(); first = false {
+		line := sc.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		for _, t := range tok.Tokenize(line) {
+			counts[t]++
+		}
+	}
+	return counts, sc.Err()
+}
+
+// Books holds the word counts of one corpus per book key.
+type Books map[string]map[string]int
+
+// Add adds counts to those of book.
+func (b Books) Add(book string, counts map[string]int) {
+	m := b[book]
+	if m == nil {
+		m = make(map[string]int, len(counts))
+		b[book] = m
+	}
+	for w, n := range counts {
+		m[w] += n
+	}
+}
+
+// Total rolls the per-book counts up into one table.
+func (b Books) Total() map[string]int {
+	total := make(map[string]int)
+	for _, m := range b {
+		for w, n := range m {
+			total[w] += n
+		}
+	}
+	return total
+}
+
+// Options configure Count. The zero value counts every file of the corpus
+// with the zero Tokenizer, on as many goroutines as there are CPUs.
+type Options struct {
+	Tokenizer pali.Tokenizer
+	// Layers selects the files to count by layer key, as corpora.LayerOf
+	// gives it; nil counts every file.
+	Layers map[string]bool
+	// Filter, when set, also selects the files by name or book.
+	Filter *Filter
+	// Variants, when set, merges spelling variants in the counts, after
+	// the cache, which keeps the counts as tokenized.
+	Variants *pali.Variants
+	// Ngrams are the n-gram sizes to count, e.g. [2 3].
+	Ngrams []int
+	// Jobs bounds the files counted at once; 0 means runtime.NumCPU().
+	Jobs int
+	// Limit, when set, bounds the files counted at once in place of Jobs;
+	// one channel shared by several calls bounds them all together.
+	Limit chan struct{}
+	// Verse also counts the verse and prose of corpora that tell them
+	// apart (corpora.PassageScanner) on their own.
+	Verse bool
+	// Cache, when set, supplies the counts of files unchanged since they
+	// were cached, unless Force is set or n-grams or verse are counted,
+	// and learns those of the files counted. Count saves it when done.
+	Cache *Cache
+	Force bool
+	// ExcludeSuspect leaves the files whose text looks wrong out of the
+	// counts, as skipped Problems, instead of counting and flagging them.
+	ExcludeSuspect bool
+	// Dedup, when set, also counts the corpus with the paragraphs that
+	// repeat an earlier one weighed down, into Table.Dedup. It reads the
+	// files counted once more, one after the other.
+	Dedup *Dedup
+	// Checkpoint, when set, is restored before counting, saved every so
+	// often while counting and once more when Count stops, done or not.
+	Checkpoint *Checkpoint
+	// Provenance, when above 0, keeps the first Provenance locations of
+	// every word in Table.Locations. The cache holds no locations, so it
+	// only learns the counts, as with n-grams.
+	Provenance int
+	// Progress, when set, is told the number of files to count, then of
+	// each file done; *tools.Progress fits.
+	Progress interface {
+		AddTotal(n int)
+		Add(n int)
+	}
+}
+
+// Table is the result of counting one corpus.
+type Table struct {
+	Corpus string                    // the corpus name
+	Books  Books                     // counts per book key
+	Files  map[string]map[string]int // counts per file path
+	Ngrams map[int]*NgramSpill       // by n-gram size, empty without Options.Ngrams
+	// the counts of the verse and of the prose passages, which add up to
+	// Counts; nil without Options.Verse or when the corpus cannot tell
+	// them apart
+	Verse, Prose map[string]int
+	// tokens rewritten per variant rule name, empty without
+	// Options.Variants
+	Collapsed map[string]int
+	// files that could not be read, and so are missing from the counts,
+	// or whose text looks wrong, by path
+	Problems []FileProblem
+	// the counts with repeated paragraphs weighed by Options.Dedup, and
+	// how much repeats; nil without it
+	Dedup      map[string]int
+	DedupStats DedupStats
+	// the first Options.Provenance locations of each word, in file, line
+	// and column order, as merged from the files counted; nil without it
+	Locations map[string][]Location
+}
+
+// Location is where a word was found: the file, the line of it as the
+// corpus reads it, 1-based — a line of the text editions, a paragraph of
+// the XML ones, an entry of the JSON one — and the column, the 1-based
+// position in characters of the word in the line as normalized (cleaned,
+// lower-cased and, for the other scripts, transliterated).
+type Location struct {
+	Path         string
+	Line, Column int
+}
+
+// addLocations merges the locations of one file into those of t, keeping
+// the first k of each word.
+func (t *Table) addLocations(locs map[string][]Location, k int) {
+	for w, add := range locs {
+		all := append(t.Locations[w], add...)
+		slices.SortFunc(all, compareLocations)
+		if len(all) > k {
+			all = all[:k:k]
+		}
+		t.Locations[w] = all
+	}
+}
+
+// collapseLocations files the locations of each variant under the form
+// it is counted as.
+func collapseLocations(locs map[string][]Location, v *pali.Variants) map[string][]Location {
+	out := make(map[string][]Location, len(locs))
+	for w, l := range locs {
+		w = v.Rewrite(w)
+		out[w] = append(out[w], l...)
+	}
+	return out
+}
+
+func compareLocations(a, b Location) int {
+	if c := strings.Compare(a.Path, b.Path); c != 0 {
+		return c
+	}
+	if a.Line != b.Line {
+		return a.Line - b.Line
+	}
+	return a.Column - b.Column
+}
+
+// FileProblem is a file of a corpus that Count could not read, or read
+// but found suspicious: text in an unexpected script or encoding, little
+// of it made of Pāḷi words, words of a translation or tokens run together
+// (see quality).
+type FileProblem struct {
+	Path    string
+	Reason  string
+	Skipped bool // the file is left out of the counts
+}
+
+// Counts is the word counts of the whole corpus.
+func (t *Table) Counts() map[string]int { return t.Books.Total() }
+
+// Close removes the n-gram spill files of t.
+func (t *Table) Close() error {
+	var err error
+	for _, s := range t.Ngrams {
+		err = errors.Join(err, s.Cleanup())
+	}
+	return err
+}
+
+// ErrNoFiles is returned for a corpus directory without source files, and
+// ErrNoLayerFiles for a corpus without files of the selected layers. A
+// missing directory gives an error wrapping fs.ErrNotExist.
+var (
+	ErrNoFiles      = errors.New("no source files")
+	ErrNoLayerFiles = errors.New("no files of the selected layers")
+)
+
+// Files lists the files of c that Count counts with layers.
+func Files(c corpora.Corpus, layers map[string]bool) ([]string, error) {
+	files, err := c.Files()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, ErrNoFiles
+	}
+	if layers != nil {
+		files = slices.DeleteFunc(files, func(path string) bool { return !layers[corpora.LayerOf(c, path)] })
+		if len(files) == 0 {
+			return nil, ErrNoLayerFiles
+		}
+	}
+	return files, nil
+}
+
+// Count reads, normalizes and counts every file of c selected by opts,
+// tagging each file with its book. Files are counted concurrently. A file
+// that cannot be read is left out and listed in the table's Problems, with
+// the files that read but look wrong, so one bad file does not lose the
+// rest of the corpus. When ctx is done, Count stops reading and returns
+// its error. The caller closes the table when done with its n-grams.
+func Count(ctx context.Context, c corpora.Corpus, opts Options) (*Table, error) {
+	files, err := Files(c, opts.Layers)
+	if err != nil {
+		return nil, err
+	}
+	if files, err = opts.Filter.Apply(c, files); err != nil {
+		return nil, err
+	}
+	sem := opts.Limit
+	if sem == nil {
+		jobs := opts.Jobs
+		if jobs <= 0 {
+			jobs = runtime.NumCPU()
+		}
+		sem = make(chan struct{}, jobs)
+	}
+	t := &Table{
+		Corpus: c.Name(),
+		Books:  make(Books),
+		Files:  make(map[string]map[string]int, len(files)),
+		Ngrams: make(map[int]*NgramSpill),
+
+		Collapsed: make(map[string]int),
+	}
+	verse := opts.Verse && corpora.TellsVerse(c)
+	if verse {
+		t.Verse, t.Prose = make(map[string]int), make(map[string]int)
+	}
+	if opts.Provenance > 0 {
+		t.Locations = make(map[string][]Location)
+	}
+	ck := opts.Checkpoint
+	var (
+		settings string
+		done     map[string]bool // files restored from the checkpoint
+	)
+	if ck != nil {
+		settings = checkpointSettings(c, &opts, verse)
+		done = ck.restore(settings, t, c, opts.Cache)
+	}
+	for _, n := range opts.Ngrams {
+		if ck != nil {
+			t.Ngrams[n], err = ck.spill(n)
+		} else {
+			t.Ngrams[n], err = NewNgramSpill()
+		}
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+	}
+	if opts.Progress != nil {
+		opts.Progress.AddTotal(len(files) - len(done))
+	}
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		// held shared by each file being counted and added, and whole
+		// while a checkpoint is saved
+		round sync.RWMutex
+	)
+	saveCheckpoint := func() error {
+		round.Lock()
+		defer round.Unlock()
+		return ck.save(settings, t)
+	}
+	if ck != nil && ck.every > 0 {
+		ticker := time.NewTicker(ck.every)
+		stop := make(chan struct{})
+		defer func() {
+			ticker.Stop()
+			close(stop)
+		}()
+		go func() {
+			for {
+				select {
+				case <-ticker.C:
+					if err := saveCheckpoint(); err != nil {
+						tools.Warnf("%s: checkpoint: %v", c.Name(), err)
+					}
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+scan:
+	for _, path := range files {
+		if done[path] {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break scan
+		}
+		wg.Add(1)
+		go func() {
+			round.RLock()
+			defer round.RUnlock()
+			defer func() {
+				<-sem
+				if opts.Progress != nil {
+					opts.Progress.Add(1)
+				}
+				wg.Done()
+			}()
+			local, verses, locs, suspect, err := countFile(ctx, c, &opts, t.Ngrams, verse, path)
+			hits := make(map[string]int)
+			if err == nil && opts.Variants != nil {
+				local = opts.Variants.Collapse(local, hits)
+				if verse {
+					verses = opts.Variants.Collapse(verses, make(map[string]int))
+				}
+				if locs != nil {
+					locs = collapseLocations(locs, opts.Variants)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && ctx.Err() == nil {
+				// readers often prefix the path already
+				reason := strings.TrimPrefix(err.Error(), path+": ")
+				t.Problems = append(t.Problems, FileProblem{Path: path, Reason: reason, Skipped: true})
+				return
+			}
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			if suspect != "" {
+				t.Problems = append(t.Problems, FileProblem{Path: path, Reason: suspect, Skipped: opts.ExcludeSuspect})
+				if opts.ExcludeSuspect {
+					return
+				}
+			}
+			for rule, n := range hits {
+				t.Collapsed[rule] += n
+			}
+			t.Books.Add(corpora.BookOf(c, path), local)
+			t.Files[path] = local
+			if locs != nil {
+				t.addLocations(locs, opts.Provenance)
+			}
+			if verse {
+				for w, n := range local {
+					if v := verses[w]; v > 0 {
+						t.Verse[w] += v
+					}
+					if prose := n - verses[w]; prose > 0 {
+						t.Prose[w] += prose
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	slices.SortFunc(t.Problems, func(a, b FileProblem) int { return strings.Compare(a.Path, b.Path) })
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if ck != nil {
+		// also after an interrupt, so resuming starts where counting stopped
+		if err := saveCheckpoint(); err != nil {
+			firstErr = errors.Join(firstErr, fmt.Errorf("checkpoint: %w", err))
+		}
+	}
+	if firstErr == nil && opts.Cache != nil {
+		firstErr = opts.Cache.Save()
+	}
+	if firstErr == nil && opts.Dedup != nil {
+		var counted []string
+		for _, path := range files {
+			if _, ok := t.Files[path]; ok {
+				counted = append(counted, path)
+			}
+		}
+		t.Dedup, t.DedupStats, firstErr = dedupCount(ctx, c, &opts, counted)
+	}
+	if firstErr != nil {
+		t.Close()
+		return nil, firstErr
+	}
+	return t, nil
+}
+
+// Stages of counting, reported by tools.PrintStages. read is the time
+// spent in the corpus's ScanText outside the per-line work; count includes
+// spilling n-grams.
+var (
+	stageRead      = tools.Stage("read")
+	stageNormalize = tools.Stage("normalize")
+	stageTokenize  = tools.Stage("tokenize")
+	stageCount     = tools.Stage("count")
+)
+
+// countFile counts the words of a single file, reusing the cached counts
+// when the file is unchanged, opts.Force is not set and neither n-grams,
+// verse nor locations are wanted. N-gram counts go to a sorted run in
+// ngrams. When verse is set, the counts of the verse lines are returned as
+// well; those of the prose are the rest. Under opts.Provenance, locs holds
+// the first locations of each word in the file. suspect, when not empty,
+// says why the text of a file read looks wrong; the cache keeps it with
+// the counts. The n-grams of such a file are not spilled under
+// opts.ExcludeSuspect.
+func countFile(ctx context.Context, c corpora.Corpus, opts *Options, ngrams map[int]*NgramSpill, verse bool, path string) (counts, verses map[string]int, locs map[string][]Location, suspect string, err error) {
+	var sum string
+	if opts.Cache != nil {
+		if sum, err = HashFile(path); err != nil {
+			return nil, nil, nil, "", err
+		}
+		if !opts.Force && len(ngrams) == 0 && !verse && opts.Provenance == 0 {
+			if counts, suspect, ok := opts.Cache.Get(path, sum); ok {
+				tools.Debugf("%s: unchanged, using cached counts", path)
+				return counts, nil, nil, suspect, nil
+			}
+		}
+	}
+	start := time.Now()
+	counts = make(map[string]int)
+	if verse {
+		verses = make(map[string]int)
+	}
+	grams := make(map[int]map[string]int, len(ngrams))
+	for n := range ngrams {
+		grams[n] = make(map[string]int)
+	}
+	if opts.Provenance > 0 {
+		locs = make(map[string][]Location)
+	}
+	line := 0
+	// lines are tokenized as they are read, so memory follows the
+	// vocabulary of the file rather than its size; stage times are summed
+	// per file and recorded once
+	var normalizing, tokenizing, counting time.Duration
+	var q quality
+	repair := opts.Tokenizer.Normalizer.RepairsMojibake()
+	err = corpora.ScanPassages(c, path, func(passage string, isVerse bool) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line++
+		t0 := time.Now()
+		if repair {
+			passage = pali.RepairMojibake(passage)
+		}
+		text := c.Normalize(passage)
+		t1 := time.Now()
+		var tokens []string
+		if locs != nil {
+			var columns []int
+			tokens, columns = opts.Tokenizer.TokenizeColumns(text)
+			for i, w := range tokens {
+				if len(locs[w]) < opts.Provenance {
+					locs[w] = append(locs[w], Location{path, line, columns[i]})
+				}
+			}
+		} else {
+			tokens = opts.Tokenizer.Tokenize(text)
+		}
+		t2 := time.Now()
+		normalizing += t1.Sub(t0)
+		tokenizing += t2.Sub(t1)
+		q.addLine(passage, text, tokens)
+		for _, w := range tokens {
+			counts[w]++
+		}
+		if verse && isVerse {
+			for _, w := range tokens {
+				verses[w]++
+			}
+		}
+		if len(grams) > 0 && opts.Variants != nil {
+			for i, w := range tokens {
+				tokens[i] = opts.Variants.Rewrite(w)
+			}
+		}
+		for n, m := range grams {
+			addNgrams(m, tokens, n)
+		}
+		counting += time.Since(t2)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, "", err
+	}
+	scanned := time.Since(start)
+	suspect = q.verdict()
+	for n, spill := range ngrams {
+		if suspect != "" && opts.ExcludeSuspect {
+			break
+		}
+		if err := spill.addRun(grams[n]); err != nil {
+			return nil, nil, nil, "", err
+		}
+	}
+	stageRead.Add(scanned - normalizing - tokenizing - counting)
+	stageNormalize.Add(normalizing)
+	stageTokenize.Add(tokenizing)
+	stageCount.Add(counting + time.Since(start) - scanned)
+	if len(counts) == 0 {
+		tools.Warnf("%s: no tokens", path)
+	}
+	tools.Debugf("%s: %d types in %s", path, len(counts), time.Since(start).Round(time.Millisecond))
+	if opts.Cache != nil {
+		opts.Cache.Put(path, sum, counts, suspect)
+	}
+	return counts, verses, locs, suspect, nil
+}
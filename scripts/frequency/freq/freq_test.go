@@ -0,0 +1,756 @@
+package freq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/pali"
+)
+
+func testCorpus(t *testing.T) corpora.Corpus {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"dn1.txt": "evaṃ me sutaṃ [PTS Page 001] ekaṃ samayaṃ bhagavā\nantarā ca rājagahaṃ antarā ca nāḷandaṃ\n",
+		"mn1.txt": "evaṃ me sutaṃ ekaṃ samayaṃ bhagavā ukkaṭṭhāyaṃ viharati\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return corpora.NewBjt(dir)
+}
+
+func TestCount(t *testing.T) {
+	c := testCorpus(t)
+	cache := LoadCache(filepath.Join(t.TempDir(), "bjt.gob"), pali.Default, nil)
+	opts := Options{Tokenizer: pali.Default, Ngrams: []int{2}, Cache: cache}
+	tab, err := Count(context.Background(), c, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tab.Close()
+
+	counts := tab.Counts()
+	if counts["evaṃ"] != 2 || counts["antarā"] != 2 || counts["page"] != 0 {
+		t.Errorf("counts %v", counts)
+	}
+	// the BJT text files are not tagged by book
+	if len(tab.Books) != 1 || tab.Books["other"]["ca"] != 2 {
+		t.Errorf("books %v", tab.Books)
+	}
+	if len(tab.Files) != 2 {
+		t.Errorf("%d files, want 2", len(tab.Files))
+	}
+
+	ranked, total, err := tab.Ngrams[2].Merge(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var top []WordCount
+	ranked(func(wc WordCount) error {
+		top = append(top, wc)
+		return nil
+	})
+	// paragraphs of 12 and 7 words make 11 and 6 bigrams; of those seen
+	// twice, antarā ca comes first
+	if total != 17 || len(top) != 6 || top[0] != (WordCount{"antarā ca", 2}) {
+		t.Errorf("%d bigrams, top %v", total, top)
+	}
+
+	// the cached counts of unchanged files give the same table
+	opts.Ngrams = nil
+	again, err := Count(context.Background(), c, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(again.Counts(), counts) {
+		t.Errorf("from the cache: %v, want %v", again.Counts(), counts)
+	}
+	files, _ := Files(c, nil)
+	if n := LoadCache(cache.path, pali.Default, nil).Cached(files); n != 2 {
+		t.Errorf("%d files cached, want 2", n)
+	}
+}
+
+func TestCountErrors(t *testing.T) {
+	c := testCorpus(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Count(ctx, c, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled: %v", err)
+	}
+	if _, err := Count(context.Background(), c, Options{Layers: map[string]bool{corpora.Atthakatha: true}}); !errors.Is(err, ErrNoLayerFiles) {
+		t.Errorf("no commentaries: %v", err)
+	}
+	if _, err := Count(context.Background(), corpora.NewBjt(t.TempDir()), Options{}); !errors.Is(err, ErrNoFiles) {
+		t.Errorf("empty: %v", err)
+	}
+}
+
+func TestCountText(t *testing.T) {
+	counts, err := CountText(strings.NewReader("\uFEFFEvaṃ me sutaṃ.\nEkaṃ samayaṃ bhagavā, evaṃ.\n"), pali.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"evaṃ": 2, "me": 1, "sutaṃ": 1, "ekaṃ": 1, "samayaṃ": 1, "bhagavā": 1}
+	if !maps.Equal(counts, want) {
+		t.Errorf("counts %v, want %v", counts, want)
+	}
+}
+
+func TestCountVerse(t *testing.T) {
+	dir := t.TempDir()
+	text := "atha kho bhagavā etadavoca\n\tmanopubbaṅgamā dhammā manoseṭṭhā manomayā\n\nbhagavā etadavoca\n"
+	if err := os.WriteFile(filepath.Join(dir, "dhp.txt"), []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tab, err := Count(context.Background(), corpora.NewBjt(dir), Options{Tokenizer: pali.Default, Verse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tab.Verse["dhammā"] != 1 || tab.Verse["bhagavā"] != 0 || tab.Prose["bhagavā"] != 2 || tab.Prose["dhammā"] != 0 {
+		t.Errorf("verse %v, prose %v", tab.Verse, tab.Prose)
+	}
+	if TokenTotal(tab.Verse)+TokenTotal(tab.Prose) != TokenTotal(tab.Counts()) {
+		t.Errorf("verse and prose do not add up to the counts")
+	}
+
+	// without the option, or for a corpus not marking verse, there are none
+	if tab, err = Count(context.Background(), corpora.NewSya(dir), Options{Tokenizer: pali.Default, Verse: true}); err != nil {
+		t.Fatal(err)
+	}
+	if tab.Verse != nil || tab.Prose != nil {
+		t.Errorf("sya: verse %v, prose %v", tab.Verse, tab.Prose)
+	}
+}
+
+func TestCountProvenance(t *testing.T) {
+	c := testCorpus(t)
+	cache := LoadCache(filepath.Join(t.TempDir(), "bjt.gob"), pali.Default, nil)
+	if _, err := Count(context.Background(), c, Options{Tokenizer: pali.Default, Cache: cache}); err != nil {
+		t.Fatal(err)
+	}
+	// the cached counts hold no locations, so the files are read again
+	tab, err := Count(context.Background(), c, Options{Tokenizer: pali.Default, Cache: cache, Provenance: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := Files(c, nil)
+	slices.Sort(files)
+	dn1, mn1 := files[0], files[1]
+	want := map[string][]Location{
+		"evaṃ": {{dn1, 1, 1}, {mn1, 1, 1}},
+		"ca":   {{dn1, 2, 8}, {dn1, 2, 28}},
+	}
+	for w, locs := range want {
+		if got := tab.Locations[w]; !slices.Equal(got, locs) {
+			t.Errorf("%s: %v, want %v", w, got, locs)
+		}
+	}
+	if len(tab.Locations) != len(tab.Counts()) {
+		t.Errorf("locations of %d words, %d counted", len(tab.Locations), len(tab.Counts()))
+	}
+
+	// the first K are kept, across files
+	if tab, err = Count(context.Background(), c, Options{Tokenizer: pali.Default, Provenance: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := tab.Locations["evaṃ"]; !slices.Equal(got, []Location{{dn1, 1, 1}}) {
+		t.Errorf("evaṃ, first only: %v", got)
+	}
+	if tab, err = Count(context.Background(), c, Options{Tokenizer: pali.Default}); err != nil {
+		t.Fatal(err)
+	}
+	if tab.Locations != nil {
+		t.Errorf("without Provenance: %v", tab.Locations)
+	}
+}
+
+func TestCountProblems(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"dn-1.json": `{"pages":[{"pali":{"entries":[{"text":"එවං මෙ සුතං"}]}}]}`,
+		"dn-2.json": `{"pages":[{"pali":`,
+		// a translation left in: far more Latin text than Pāḷi words
+		"dn-3.json": `{"pages":[{"pali":{"entries":[{"text":"` + strings.Repeat("1234567890 ", 100) + `"}]}}]}`,
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tab, err := Count(context.Background(), corpora.NewBjtSinhala(dir), Options{Tokenizer: pali.Default})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tab.Counts()["evaṃ"] != 1 || len(tab.Files) != 2 {
+		t.Errorf("counts %v of %d files", tab.Counts(), len(tab.Files))
+	}
+	if len(tab.Problems) != 2 {
+		t.Fatalf("problems %v", tab.Problems)
+	}
+	if p := tab.Problems[0]; !strings.HasSuffix(p.Path, "dn-2.json") || !p.Skipped {
+		t.Errorf("malformed file: %+v", p)
+	}
+	if p := tab.Problems[1]; !strings.HasSuffix(p.Path, "dn-3.json") || p.Skipped {
+		t.Errorf("suspicious file: %+v", p)
+	}
+}
+
+func TestCountSuspect(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"dn1.txt": "evaṃ me sutaṃ ekaṃ samayaṃ bhagavā\n",
+		// a translation left in
+		"dn2.txt": "evaṃ me sutaṃ\n" + strings.Repeat("thus have I heard at one time the Blessed One was staying\n", 12),
+		// the spaces of a line lost
+		"dn3.txt": "evaṃ me sutaṃ\n" + strings.Repeat("ekaṃsamayaṃbhagavā", 10) + "\n",
+		// ā, ṃ and ṭ read as Latin-1 halfway through
+		"dn4.txt": "evaṃ me sutaṃ\n" + strings.Repeat("bhagavÄ\u0081 ukkaá¹\u00adá¹\u00adhÄ\u0081yaá¹\u0083\n", 3),
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := corpora.NewBjt(dir)
+	tab, err := Count(context.Background(), c, Options{Tokenizer: pali.Default})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"words not Pāḷi", "letters or more", "change script or encoding"}
+	if len(tab.Problems) != len(want) {
+		t.Fatalf("problems %v", tab.Problems)
+	}
+	for i, p := range tab.Problems {
+		if !strings.Contains(p.Reason, want[i]) || p.Skipped {
+			t.Errorf("%s: %+v, want %q counted", filepath.Base(p.Path), p, want[i])
+		}
+	}
+	if len(tab.Files) != 4 {
+		t.Errorf("counted %d files, want 4", len(tab.Files))
+	}
+
+	tab, err = Count(context.Background(), c, Options{Tokenizer: pali.Default, ExcludeSuspect: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tab.Files) != 1 || tab.Counts()["evaṃ"] != 1 {
+		t.Errorf("counts %v of %d files, want dn1.txt only", tab.Counts(), len(tab.Files))
+	}
+	for _, p := range tab.Problems {
+		if !p.Skipped {
+			t.Errorf("%s not skipped", p.Path)
+		}
+	}
+}
+
+// fileTally is an Options.Progress counting the files to count.
+type fileTally struct{ total int }
+
+func (f *fileTally) AddTotal(n int) { f.total += n }
+func (f *fileTally) Add(int)        {}
+
+func TestCountResume(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"dn1.txt": "evaṃ me sutaṃ ekaṃ samayaṃ bhagavā\nantarā ca rājagahaṃ antarā ca nāḷandaṃ\n",
+		"mn1.txt": "evaṃ me sutaṃ ekaṃ samayaṃ bhagavā ukkaṭṭhāyaṃ viharati\n",
+	}
+	// a first run that got as far as dn1.txt
+	if err := os.WriteFile(filepath.Join(dir, "dn1.txt"), []byte(files["dn1.txt"]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := corpora.NewBjt(dir)
+	path := filepath.Join(t.TempDir(), "bjt.gob")
+	ck := LoadCheckpoint(path, 0, false)
+	tab, err := Count(context.Background(), c, Options{Tokenizer: pali.Default, Ngrams: []int{2}, Checkpoint: ck})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab.Close()
+	if err := os.WriteFile(filepath.Join(dir, "mn1.txt"), []byte(files["mn1.txt"]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tally := &fileTally{}
+	tab, err = Count(context.Background(), c, Options{Tokenizer: pali.Default, Ngrams: []int{2}, Checkpoint: LoadCheckpoint(path, 0, true), Progress: tally})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tab.Close()
+	if tally.total != 1 {
+		t.Errorf("resumed with %d files to count, want 1", tally.total)
+	}
+	if counts := tab.Counts(); counts["evaṃ"] != 2 || counts["antarā"] != 2 || len(tab.Files) != 2 {
+		t.Errorf("counts %v of %d files", counts, len(tab.Files))
+	}
+	// the bigrams of dn1.txt, 5 + 5, come from the kept runs, those of
+	// mn1.txt, 7, are added
+	if _, total, err := tab.Ngrams[2].Merge(2); err != nil || total != 17 {
+		t.Errorf("%d bigrams, %v", total, err)
+	}
+
+	// other settings count afresh
+	tally = &fileTally{}
+	if _, err := Count(context.Background(), c, Options{Tokenizer: pali.Default, Checkpoint: LoadCheckpoint(path, 0, true), Progress: tally}); err != nil {
+		t.Fatal(err)
+	}
+	if tally.total != 2 {
+		t.Errorf("other settings: %d files to count, want 2", tally.total)
+	}
+	// as do counts of files read as Windows-1252, under -strict-encoding
+	opts := Options{Tokenizer: pali.Default}
+	lax := checkpointSettings(c, &opts, false)
+	corpora.StrictEncoding = true
+	strict := checkpointSettings(c, &opts, false)
+	corpora.StrictEncoding = false
+	if lax == strict {
+		t.Errorf("checkpoint settings %q with and without -strict-encoding", lax)
+	}
+	if err := ck.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("checkpoint left after Remove: %v", err)
+	}
+}
+
+func TestCountCleaning(t *testing.T) {
+	c := testCorpus(t)
+	shipped := corpora.CleanerOf(c)
+	if _, err := Count(context.Background(), c, Options{Tokenizer: pali.Default}); err != nil {
+		t.Fatal(err)
+	}
+	if got := shipped.Fired(); !slices.Equal(got, []int64{1}) {
+		t.Errorf("shipped rules fired %v times, want [1]", got)
+	}
+
+	// rules replacing the shipped ones apply in order, the second seeing
+	// the text the first left
+	cleaner, err := corpora.NewCleaner([]corpora.CleaningRule{
+		{Name: "brackets", Pattern: `\[[^\]]*\]`, Replace: " "},
+		{Name: "antara", Pattern: `\bantarā (ca) `, Replace: "$1 "},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(corpora.Cleaned).SetCleaner(cleaner)
+	tab, err := Count(context.Background(), c, Options{Tokenizer: pali.Default})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := tab.Books.Total()
+	if counts["antarā"] != 0 || counts["ca"] != 2 || counts["pts"] != 0 {
+		t.Errorf("counts = %v", counts)
+	}
+	if got := cleaner.Fired(); !slices.Equal(got, []int64{1, 2}) {
+		t.Errorf("rules fired %v times, want [1 2]", got)
+	}
+	if _, err := corpora.NewCleaner([]corpora.CleaningRule{{Name: "bad", Pattern: "["}}); err == nil {
+		t.Error("NewCleaner took a bad pattern")
+	}
+}
+
+func TestCountDedup(t *testing.T) {
+	block := "tatra kho bhagavā bhikkhū āmantesi bhikkhavo ti bhadante ti te bhikkhū bhagavato paccassosuṃ " +
+		"bhagavā etadavoca sabbadhammamūlapariyāyaṃ vo desessāmi taṃ suṇātha sādhukaṃ manasi karotha bhāsissāmī ti " +
+		"evaṃ bhante ti"
+	dir := t.TempDir()
+	files := map[string]string{
+		"dn1.txt": "evaṃ me sutaṃ\n" + block + " rūpaṃ\n",
+		// the block again, word for word and with another last word, and
+		// a short line that is never taken for a repeat
+		"mn1.txt": "evaṃ me sutaṃ\n" + block + " rūpaṃ\n" + block + " vedanaṃ\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := corpora.NewBjt(dir)
+	d := DefaultDedup
+	tab, err := Count(context.Background(), c, Options{Tokenizer: pali.Default, Dedup: &d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tab.Counts()["tatra"]; got != 3 {
+		t.Errorf("raw count of tatra = %d, want 3", got)
+	}
+	st := tab.DedupStats
+	if st.Paragraphs != 5 || st.Repeats != 2 {
+		t.Errorf("stats %+v, want 2 of 5 paragraphs repeating", st)
+	}
+	if tab.Dedup["tatra"] != 1 || tab.Dedup["evaṃ"] != 3 || tab.Dedup["vedanaṃ"] != 0 || tab.Dedup["rūpaṃ"] != 1 {
+		t.Errorf("dedup counts %v", tab.Dedup)
+	}
+
+	// a damping of 0.5 counts each repeat half
+	d.Damping = 0.5
+	tab, err = Count(context.Background(), c, Options{Tokenizer: pali.Default, Dedup: &d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tab.Dedup["tatra"] != 2 || tab.Dedup["rūpaṃ"] != 2 {
+		t.Errorf("damped counts of tatra and rūpaṃ = %d, %d, want 2, 2", tab.Dedup["tatra"], tab.Dedup["rūpaṃ"])
+	}
+
+	tab, err = Count(context.Background(), c, Options{Tokenizer: pali.Default})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tab.Dedup != nil {
+		t.Error("counted without Dedup, Dedup is set")
+	}
+}
+
+func TestCollocations(t *testing.T) {
+	c := NewCollocations(1, map[string][]int{"bhikkhu": {1}, "bhikkhū": {1}})
+	for range 3 {
+		c.Add([]string{"bhikkhū", "āmantesi", "ca"})
+	}
+	for range 2 {
+		c.Add([]string{"ca", "kho", "ca", "ca"})
+	}
+	c.Add([]string{"ca", "bhikkhu"})
+	// two forms of the headword are not each other's collocates
+	c.Add([]string{"bhikkhu", "bhikkhū"})
+	if c.Tokens != 21 || c.Nodes[1] != 6 || c.Words["ca"] != 10 {
+		t.Fatalf("%d tokens, %d of the node, ca %d times", c.Tokens, c.Nodes[1], c.Words["ca"])
+	}
+
+	got := c.Collocates(1, false)[1]
+	if len(got) != 2 || got[0].Word != "āmantesi" || got[1].Word != "ca" {
+		t.Fatalf("collocates %+v", got)
+	}
+	// 12 window positions of the node, of 42, and āmantesi in 6 of 42:
+	// 12·6/42 expected, 3 found
+	if a := got[0]; a.Count != 3 || a.WordCount != 3 || a.PMI != 0.8074 || a.LogLikelihood <= 0 {
+		t.Errorf("āmantesi %+v", a)
+	}
+	// ca is found less often than expected
+	if ca := got[1]; ca.Count != 1 || ca.LogLikelihood >= 0 || ca.PMI >= 0 {
+		t.Errorf("ca %+v", ca)
+	}
+	if got := c.Collocates(2, true)[1]; len(got) != 1 || got[0].Word != "āmantesi" {
+		t.Errorf("found twice or more: %+v", got)
+	}
+	if g := logLikelihood(10, 10, 10, 10); g != 0 {
+		t.Errorf("G² of an even table = %v, want 0", g)
+	}
+}
+
+func TestSortedPaliOrder(t *testing.T) {
+	// equal counts fall back to the Pāḷi alphabet, aspirates after their
+	// plain consonants
+	got := Sorted(map[string]int{"khandha": 1, "kusala": 1, "āpo": 1, "gati": 1, "attha": 1, "dhamma": 2})
+	want := []string{"dhamma", "attha", "āpo", "kusala", "khandha", "gati"}
+	for i, wc := range got {
+		if wc.Word != want[i] {
+			t.Fatalf("order %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLearningScores(t *testing.T) {
+	lem := &Lemmatizer{
+		Lookup: map[string][]int{"ca": {1}, "dhammo": {2}, "dhammaṃ": {2}, "sammāsambuddho": {3}},
+		Headwords: map[int]dpd.Headword{
+			1: {ID: 1, Lemma1: "ca 1", Pos: "ind"},
+			2: {ID: 2, Lemma1: "dhamma 1", Pos: "masc"},
+			3: {ID: 3, Lemma1: "sammāsambuddha", Pos: "masc"},
+		},
+	}
+	books := Books{
+		"dn": {"ca": 10, "dhammo": 4, "sammāsambuddho": 9},
+		"mn": {"ca": 10, "dhammaṃ": 4},
+	}
+	order := func(list []HeadwordScore) []string {
+		var lemmas []string
+		for _, s := range list {
+			lemmas = append(lemmas, s.Headword.Lemma1)
+		}
+		return lemmas
+	}
+	// sammāsambuddha is met more often than dhamma, but in one book only
+	// and is long
+	got := LearningScores(books, lem, nil, DefaultScoreWeights)
+	if want := []string{"ca 1", "dhamma 1", "sammāsambuddha"}; !slices.Equal(order(got), want) {
+		t.Errorf("order %v, want %v", order(got), want)
+	}
+	if s := got[0]; s.Count != 20 || s.Length != 2 || s.Irregular || s.Score <= got[1].Score {
+		t.Errorf("ca: %+v", s)
+	}
+
+	// by frequency alone, or with regularity weighing most
+	w, err := ParseScoreWeights("dispersion=0, length=0, regularity=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := order(LearningScores(books, lem, nil, w)); !slices.Equal(got, []string{"ca 1", "sammāsambuddha", "dhamma 1"}) {
+		t.Errorf("by frequency: %v", got)
+	}
+	if w, err = ParseScoreWeights("regularity=1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := order(LearningScores(books, lem, map[int]bool{2: true}, w)); got[2] != "dhamma 1" {
+		t.Errorf("with dhamma irregular: %v", got)
+	}
+	for _, bad := range []string{"speed=1", "frequency=-1", "frequency=0,dispersion=0,length=0,regularity=0"} {
+		if _, err := ParseScoreWeights(bad); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+// benchCorpus writes a BJT corpus of files text of about 64 KiB each, a
+// vinaya opening and a sutta opening in turn, for the benchmarks to count.
+func benchCorpus(b *testing.B, files int) corpora.Corpus {
+	b.Helper()
+	dir := b.TempDir()
+	texts := []string{
+		"tena samayena buddho bhagavā verañjāyaṃ viharati naḷerupucimandamūle mahatā bhikkhusaṅghena saddhiṃ " +
+			"pañcamattehi bhikkhusatehi. assosi kho verañjo brāhmaṇo samaṇo khalu bho gotamo sakyaputto …pe…\n",
+		"evaṃ me sutaṃ – ekaṃ samayaṃ bhagavā antarā ca rājagahaṃ antarā ca nāḷandaṃ addhānamaggappaṭipanno " +
+			"hoti mahatā bhikkhusaṅghena saddhiṃ pañcamattehi bhikkhusatehi. suppiyopi kho paribbājako [pe]\n",
+	}
+	for i := range files {
+		text := strings.Repeat(texts[i%len(texts)], 64<<10/len(texts[i%len(texts)]))
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("dn%d.txt", i+1)), []byte(text), 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return corpora.NewBjt(dir)
+}
+
+func BenchmarkCount(b *testing.B) {
+	c := benchCorpus(b, 16)
+	files, err := Files(c, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var size int64
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size += fi.Size()
+	}
+	for _, bc := range []struct {
+		name string
+		opts Options
+	}{
+		{"words", Options{Tokenizer: pali.Default}},
+		{"words/jobs=1", Options{Tokenizer: pali.Default, Jobs: 1}},
+		{"bigrams", Options{Tokenizer: pali.Default, Ngrams: []int{2}}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.SetBytes(size)
+			b.ReportAllocs()
+			for range b.N {
+				tab, err := Count(context.Background(), c, bc.opts)
+				if err != nil {
+					b.Fatal(err)
+				}
+				tab.Close()
+			}
+		})
+	}
+}
+
+func TestRootCounts(t *testing.T) {
+	lem := &Lemmatizer{
+		Lookup: map[string][]int{
+			"gacchati": {1}, "gato": {2, 3}, "gataṃ": {2}, "āgacchati": {4},
+			"karoti": {5}, "kataṃ": {6, 2}, "dhammo": {7},
+		},
+		Headwords: map[int]dpd.Headword{
+			1: {ID: 1, Lemma1: "gacchati", Pos: "pr"},
+			2: {ID: 2, Lemma1: "gata 1", Pos: "pp"},
+			3: {ID: 3, Lemma1: "gata 2", Pos: "masc"},
+			4: {ID: 4, Lemma1: "āgacchati", Pos: "pr"},
+			5: {ID: 5, Lemma1: "karoti", Pos: "pr"},
+			6: {ID: 6, Lemma1: "kata 1", Pos: "pp"},
+			7: {ID: 7, Lemma1: "dhamma 1", Pos: "masc"},
+		},
+	}
+	gam, kar := dpd.Root{Key: "√gam", Meaning: "going"}, dpd.Root{Key: "√kar", Meaning: "doing"}
+	roots := map[int]dpd.Root{1: gam, 2: gam, 3: gam, 4: gam, 5: kar, 6: kar}
+	counts := map[string]int{"gacchati": 10, "gato": 6, "gataṃ": 2, "āgacchati": 3, "karoti": 8, "kataṃ": 4, "dhammo": 50}
+	verbs := func(h dpd.Headword) bool { return h.Pos != "masc" }
+
+	got := lem.RootCounts(counts, roots, verbs)
+	if len(got) != 2 {
+		t.Fatalf("RootCounts = %+v, want √gam and √kar", got)
+	}
+	// kataṃ may be of gata or kata and counts for both roots; gato, of two
+	// headwords of √gam, counts for it once
+	if got[0].Root != gam || got[0].Count != 25 || got[1].Root != kar || got[1].Count != 12 {
+		t.Errorf("roots %+v %d, %+v %d; want √gam 25, √kar 12", got[0].Root, got[0].Count, got[1].Root, got[1].Count)
+	}
+	var forms []string
+	for _, wc := range got[0].Forms {
+		forms = append(forms, fmt.Sprintf("%s %d", wc.Word, wc.Count))
+	}
+	if want := []string{"gacchati 10", "gato 6", "kataṃ 4", "āgacchati 3", "gataṃ 2"}; !slices.Equal(forms, want) {
+		t.Errorf("forms of √gam %q, want %q", forms, want)
+	}
+	if heads := got[0].Headwords; len(heads) != 3 || heads[0].Headword.Lemma1 != "gata 1" || heads[0].Count != 12 {
+		t.Errorf("headwords of √gam %+v, want gata 1 (12) first of 3", heads)
+	}
+
+	all := lem.RootCounts(counts, roots, func(dpd.Headword) bool { return true })
+	if all[0].Count != 25 || len(all[0].Headwords) != 4 {
+		t.Errorf("√gam of all headwords %+v, want the same count from 4 headwords", all[0])
+	}
+
+	prefixes := map[int]string{1: "", 2: "", 3: "", 4: "ā", 5: "", 6: ""}
+	byRoot := lem.PrefixCounts(counts, roots, prefixes, verbs)
+	if want := map[string]int{"": 22, "ā": 3}; !maps.Equal(byRoot["√gam"], want) {
+		t.Errorf("prefixes of √gam %v, want %v", byRoot["√gam"], want)
+	}
+}
+
+func TestCorrelate(t *testing.T) {
+	a := map[string]int{"ca": 50, "vā": 30, "hi": 20, "kho": 10}
+	if r := Correlate(a, a, 0); r != (RankCorrelation{4, 4, 1, 1}) {
+		t.Errorf("with itself: %+v", r)
+	}
+	reversed := map[string]int{"ca": 10, "vā": 20, "hi": 30, "kho": 50}
+	if r := Correlate(a, reversed, 0); r.Spearman != -1 || r.Kendall != -1 {
+		t.Errorf("reversed: %+v", r)
+	}
+	// the top 2 of each: ca and vā, and pana, missing from a
+	b := map[string]int{"ca": 40, "pana": 35, "vā": 5, "hi": 3}
+	if r := Correlate(a, b, 2); r.Words != 3 || r.Shared != 2 {
+		t.Errorf("top 2: %+v", r)
+	}
+
+	// τ-b with ties, against the definition
+	x := []int{1, 2, 2, 3, 3, 3, 5, 0, 0, 7}
+	y := []int{2, 1, 2, 3, 4, 3, 0, 0, 1, 7}
+	var conc, disc, tx, ty int
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			dx, dy := x[i]-x[j], y[i]-y[j]
+			switch {
+			case dx == 0 && dy == 0:
+			case dx == 0:
+				tx++
+			case dy == 0:
+				ty++
+			case (dx > 0) == (dy > 0):
+				conc++
+			default:
+				disc++
+			}
+		}
+	}
+	want := float64(conc-disc) / math.Sqrt(float64((conc+disc+tx)*(conc+disc+ty)))
+	if got := kendall(x, y); math.Abs(got-want) > 1e-12 {
+		t.Errorf("τ-b %v, want %v", got, want)
+	}
+	// ranks 1, 2.5, 2.5, 4 and 1.5, 1.5, 3, 4: 3.75 / 4.5
+	if got := spearman([]int{1, 2, 2, 4}, []int{0, 0, 5, 9}); math.Abs(got-3.75/4.5) > 1e-12 {
+		t.Errorf("ρ %v", got)
+	}
+}
+
+func TestZipfHeaps(t *testing.T) {
+	// exactly 12/r: s 1, C 12, a perfect fit
+	counts := map[string]int{"a": 12, "b": 6, "c": 4, "d": 3}
+	fit, points := Zipf(counts, 10)
+	if fit.S != 1 || fit.C != 12 || fit.R2 != 1 {
+		t.Errorf("Zipf fit %+v", fit)
+	}
+	if len(points) != 4 || points[3] != (ZipfPoint{4, "d", 3}) || fit.At(5) != 2.4 {
+		t.Errorf("Zipf points %v, At(5) %v", points, fit.At(5))
+	}
+	if got := logRanks(30, 2); !slices.Equal(got, []int{1, 3, 10, 30}) {
+		t.Errorf("logRanks(30, 2) = %v", got)
+	}
+
+	// V = 2·√N after each file: 4, 16 and 64 tokens with 4, 8, 16 types
+	files := map[string]map[string]int{"a": {}, "b": {}, "c": {}}
+	word := 0
+	add := func(path string, tokens, types int) {
+		for i := 0; i < types; i++ {
+			files[path][fmt.Sprint(word)] = 1
+			word++
+		}
+		files[path]["0"] += tokens - types
+	}
+	add("a", 4, 4)
+	add("b", 12, 4)
+	add("c", 48, 8)
+	heaps, growth := Heaps(files)
+	if heaps.K != 2 || heaps.Beta != 0.5 || heaps.R2 != 1 {
+		t.Errorf("Heaps fit %+v", heaps)
+	}
+	want := []GrowthPoint{{1, 4, 4}, {2, 16, 8}, {3, 64, 16}}
+	if !slices.Equal(growth, want) || heaps.At(100) != 20 {
+		t.Errorf("Heaps points %v, At(100) %v", growth, heaps.At(100))
+	}
+}
+
+func TestFilter(t *testing.T) {
+	c := testCorpus(t)
+	files, err := Files(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		include, exclude []string
+		want             []string
+	}{
+		// the file names do not tell BJT's books, so both are in other
+		{[]string{"other"}, nil, []string{"dn1.txt", "mn1.txt"}},
+		{nil, []string{"/^d/"}, []string{"mn1.txt"}},
+		{[]string{"*.txt"}, []string{"mn1.*"}, []string{"dn1.txt"}},
+		{[]string{"dn1.txt", "mn"}, nil, []string{"dn1.txt"}},
+	} {
+		f, err := NewFilter(tc.include, tc.exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f.Apply(c, slices.Clone(files))
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		var names []string
+		for _, path := range got {
+			names = append(names, filepath.Base(path))
+		}
+		if !slices.Equal(names, tc.want) {
+			t.Errorf("%s: %v, want %v", f, names, tc.want)
+		}
+	}
+
+	f, _ := NewFilter([]string{"mn"}, []string{"*.tik.xml"})
+	if key := f.Key(); key != "mn_not_tik_xml" {
+		t.Errorf("Key = %q", key)
+	}
+	if f, _ := NewFilter(nil, nil); f != nil {
+		t.Errorf("no patterns gave the filter %s", f)
+	}
+	if _, err := NewFilter([]string{"/[/"}, nil); err == nil {
+		t.Error("NewFilter accepted an invalid expression")
+	}
+	sn, _ := NewFilter([]string{"sn"}, nil)
+	if _, err := Count(context.Background(), c, Options{Filter: sn}); !errors.Is(err, ErrNoFilterFiles) {
+		t.Errorf("no sn files: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package freq
+
+import (
+	"fmt"
+	"sort"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/tools"
+)
+
+// Lemmatizer maps surface forms to DPD headwords.
+type Lemmatizer struct {
+	Lookup    map[string][]int     // headword IDs by inflected form
+	Headwords map[int]dpd.Headword // by ID
+}
+
+// LoadLemmatizer reads the lookup and headword tables of the DPD database
+// at path. Its errors name path.
+func LoadLemmatizer(path string) (*Lemmatizer, error) {
+	db, err := dpd.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	lookup, err := db.Lookup()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	heads, err := db.Headwords()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Lemmatizer{lookup, heads}, nil
+}
+
+// LemmaCount is a headword and its count.
+type LemmaCount struct {
+	Headword dpd.Headword
+	Count    int
+}
+
+// Counts aggregates surface counts by headword. A form that DPD
+// assigns to several headwords adds its full count to each of them, since
+// the corpus alone cannot tell which one is meant. Forms unknown to DPD are
+// left out.
+func (l *Lemmatizer) Counts(counts map[string]int) []LemmaCount {
+	byID := make(map[int]int)
+	for w, n := range counts {
+		for _, id := range l.Lookup[w] {
+			byID[id] += n
+		}
+	}
+	list := make([]LemmaCount, 0, len(byID))
+	for id, n := range byID {
+		h, ok := l.Headwords[id]
+		if !ok {
+			continue
+		}
+		list = append(list, LemmaCount{h, n})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Headword.ID < list[j].Headword.ID
+	})
+	return list
+}
+
+// RootCount is a root, its count and the counts of its headwords and forms
+// it was made of, most frequent first.
+type RootCount struct {
+	Root      dpd.Root
+	Count     int
+	Headwords []LemmaCount
+	Forms     []WordCount
+}
+
+// RootCounts aggregates surface counts by the DPD root of their headwords,
+// taking only the headwords keep accepts. A form of several headwords of
+// one root adds its count to the root once, and its full count to each of
+// the headwords, as Counts does; a form of headwords of several roots adds
+// it to each root. Forms of no headword with a root are left out.
+func (l *Lemmatizer) RootCounts(counts map[string]int, roots map[int]dpd.Root, keep func(dpd.Headword) bool) []RootCount {
+	byRoot := make(map[string]*RootCount)
+	heads := make(map[string]map[int]int)
+	forms := make(map[string]map[string]int)
+	for w, n := range counts {
+		seen := make(map[string]bool)
+		for _, id := range l.Lookup[w] {
+			h, ok := l.Headwords[id]
+			r, rooted := roots[id]
+			if !ok || !rooted || !keep(h) {
+				continue
+			}
+			rc := byRoot[r.Key]
+			if rc == nil {
+				rc = &RootCount{Root: r}
+				byRoot[r.Key] = rc
+				heads[r.Key] = make(map[int]int)
+				forms[r.Key] = make(map[string]int)
+			}
+			heads[r.Key][id] += n
+			if !seen[r.Key] {
+				seen[r.Key] = true
+				rc.Count += n
+				forms[r.Key][w] = n
+			}
+		}
+	}
+	list := make([]RootCount, 0, len(byRoot))
+	for key, rc := range byRoot {
+		for id, n := range heads[key] {
+			rc.Headwords = append(rc.Headwords, LemmaCount{l.Headwords[id], n})
+		}
+		sort.Slice(rc.Headwords, func(i, j int) bool {
+			a, b := rc.Headwords[i], rc.Headwords[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Headword.ID < b.Headword.ID
+		})
+		rc.Forms = Sorted(forms[key])
+		list = append(list, *rc)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return tools.ComparePali(list[i].Root.Key, list[j].Root.Key) < 0
+	})
+	return list
+}
+
+// PrefixCounts aggregates surface counts by root and by the prefixes
+// before it, as prefixes gives them for each headword ("pa ā", or "" for
+// the bare root), taking only the headwords of roots that keep accepts. A
+// form adds its count once to each pair of root and prefixes its
+// headwords have.
+func (l *Lemmatizer) PrefixCounts(counts map[string]int, roots map[int]dpd.Root, prefixes map[int]string, keep func(dpd.Headword) bool) map[string]map[string]int {
+	type pair struct{ root, prefix string }
+	byRoot := make(map[string]map[string]int)
+	for w, n := range counts {
+		seen := make(map[pair]bool)
+		for _, id := range l.Lookup[w] {
+			h, ok := l.Headwords[id]
+			r, rooted := roots[id]
+			prefix, known := prefixes[id]
+			p := pair{r.Key, prefix}
+			if !ok || !rooted || !known || !keep(h) || seen[p] {
+				continue
+			}
+			seen[p] = true
+			if byRoot[r.Key] == nil {
+				byRoot[r.Key] = make(map[string]int)
+			}
+			byRoot[r.Key][prefix] += n
+		}
+	}
+	return byRoot
+}
@@ -0,0 +1,285 @@
+package freq
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"dpd/go_modules/tools"
+)
+
+// addNgrams counts the n-grams of one line's tokens into counts. N-grams do
+// not cross line (paragraph) boundaries.
+func addNgrams(counts map[string]int, tokens []string, n int) {
+	for i := 0; i+n <= len(tokens); i++ {
+		counts[strings.Join(tokens[i:i+n], " ")]++
+	}
+}
+
+// ngramShards is the number of hash shards the n-gram counts of a corpus
+// are spread over. Shards are summed one at a time, so memory use is about
+// 1/ngramShards of the corpus's distinct n-grams.
+const ngramShards = 64
+
+// NgramSpill keeps the n-gram counts of a corpus on disk. Each file's
+// counts are appended to the shard files by hash of the n-gram, so all
+// counts of one n-gram land in one shard. When the corpus is done, Merge
+// sums every shard on its own into a file sorted by rank, and the table is
+// streamed from a merge of those files, so neither counting nor writing
+// holds the corpus's n-grams in memory.
+type NgramSpill struct {
+	dir  string
+	mu   [ngramShards]sync.Mutex // guards appending to each shard
+	keep bool                    // the spill of a Checkpoint: shards outlive Merge and Cleanup
+}
+
+// NewNgramSpill makes a spill in a new temporary directory, which Cleanup
+// removes.
+func NewNgramSpill() (*NgramSpill, error) {
+	dir, err := os.MkdirTemp("", "ngrams-")
+	if err != nil {
+		return nil, err
+	}
+	return &NgramSpill{dir: dir}, nil
+}
+
+func (s *NgramSpill) shardPath(i int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("shard-%02d", i))
+}
+
+func (s *NgramSpill) rankedPath(i int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("ranked-%02d", i))
+}
+
+// shardOf is the shard of an n-gram, by FNV-1a hash.
+func shardOf(key string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return int(h % ngramShards)
+}
+
+// addRun appends the counts of one file to the shards.
+func (s *NgramSpill) addRun(counts map[string]int) error {
+	var bufs [ngramShards]bytes.Buffer
+	for k, n := range counts {
+		fmt.Fprintf(&bufs[shardOf(k)], "%s\t%d\n", k, n)
+	}
+	for i := range bufs {
+		if bufs[i].Len() == 0 {
+			continue
+		}
+		if err := s.appendShard(i, bufs[i].Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *NgramSpill) appendShard(i int, data []byte) error {
+	s.mu[i].Lock()
+	defer s.mu[i].Unlock()
+	f, err := os.OpenFile(s.shardPath(i), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Merge sums each shard, keeping the n-grams seen at least minCount times,
+// and returns a function that yields them by descending count, then
+// n-gram, together with the total number of n-grams seen. The function
+// reads the spill files each time it is called, until Cleanup.
+func (s *NgramSpill) Merge(minCount int) (func(yield func(WordCount) error) error, int, error) {
+	total := 0
+	for i := range ngramShards {
+		n, err := s.rankShard(i, minCount)
+		if err != nil {
+			return nil, 0, err
+		}
+		total += n
+	}
+	return s.ranked, total, nil
+}
+
+// rankShard sums the counts of shard i and writes those of at least
+// minCount, sorted by rank, to its ranked file. It returns the number of
+// n-grams in the shard.
+func (s *NgramSpill) rankShard(i, minCount int) (int, error) {
+	counts := make(map[string]int)
+	err := readRun(s.shardPath(i), func(key string, n int) error {
+		counts[key] += n
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+	total := 0
+	kept := make(map[string]int)
+	for k, n := range counts {
+		total += n
+		if n >= minCount {
+			kept[k] = n
+		}
+	}
+	counts = nil
+
+	f, err := os.Create(s.rankedPath(i))
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	for _, wc := range Sorted(kept) {
+		fmt.Fprintf(w, "%s\t%d\n", wc.Word, wc.Count)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	if s.keep {
+		return total, nil
+	}
+	if err := os.Remove(s.shardPath(i)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+	return total, nil
+}
+
+// ranked yields the ranked files of all shards merged into one ranking.
+func (s *NgramSpill) ranked(yield func(WordCount) error) error {
+	h := &runHeap{}
+	defer h.close()
+	for i := range ngramShards {
+		f, err := os.Open(s.rankedPath(i))
+		if err != nil {
+			return err
+		}
+		r := &runReader{f: f, sc: bufio.NewScanner(f)}
+		r.sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		if err := r.next(); err != nil {
+			f.Close()
+			if err == errRunDone {
+				continue
+			}
+			return err
+		}
+		heap.Push(h, r)
+	}
+	for h.Len() > 0 {
+		r := (*h)[0]
+		if err := yield(WordCount{r.key, r.count}); err != nil {
+			return err
+		}
+		if err := r.next(); err == errRunDone {
+			heap.Pop(h)
+			r.f.Close()
+		} else if err != nil {
+			return err
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
+	return nil
+}
+
+// Cleanup removes the spill files; those of a checkpoint stay until the
+// checkpoint is removed.
+func (s *NgramSpill) Cleanup() error {
+	if s.keep {
+		return nil
+	}
+	return os.RemoveAll(s.dir)
+}
+
+var errRunDone = fmt.Errorf("run done")
+
+// readRun calls fn with each line of a spill file.
+func readRun(path string, fn func(key string, n int) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := &runReader{f: f, sc: bufio.NewScanner(f)}
+	r.sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for {
+		if err := r.next(); err == errRunDone {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(r.key, r.count); err != nil {
+			return err
+		}
+	}
+}
+
+type runReader struct {
+	f     *os.File
+	sc    *bufio.Scanner
+	key   string
+	count int
+}
+
+func (r *runReader) next() error {
+	if !r.sc.Scan() {
+		if err := r.sc.Err(); err != nil {
+			return err
+		}
+		return errRunDone
+	}
+	key, n, ok := strings.Cut(r.sc.Text(), "\t")
+	if !ok {
+		return fmt.Errorf("%s: malformed run line", filepath.Base(r.f.Name()))
+	}
+	count, err := strconv.Atoi(n)
+	if err != nil {
+		return err
+	}
+	r.key, r.count = key, count
+	return nil
+}
+
+// runHeap orders ranked file readers by their current n-gram: descending
+// count, then n-gram.
+type runHeap []*runReader
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count > h[j].count
+	}
+	return tools.ComparePali(h[i].key, h[j].key) < 0
+}
+func (h runHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)   { *h = append(*h, x.(*runReader)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+func (h *runHeap) close() {
+	for _, r := range *h {
+		r.f.Close()
+	}
+	*h = nil
+}
@@ -0,0 +1,186 @@
+package freq
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Thresholds of the checks of quality. A file is suspicious when its text
+// is at least suspectMinBytes long and less than suspectShare of its
+// non-space bytes end up in tokens or more than suspectForeignShare of its
+// letters are not Pāḷi, when at least suspectLatinWords words and
+// suspectLatinShare of all its words look like a modern language, when a
+// token is suspectTokenRunes letters or longer, or when suspectShiftLines
+// lines or more change script or are mojibake.
+const (
+	suspectMinBytes     = 1000
+	suspectShare        = 0.5
+	suspectForeignShare = 0.05
+	suspectLatinWords   = 20
+	suspectLatinShare   = 0.05
+	suspectTokenRunes   = 100
+	suspectShiftLines   = 3
+)
+
+// paliLetters are the letters of romanized Pāḷi, with ṁ for ṃ.
+const paliLetters = "aāiīuūeoṃṁkgṅcjñṭḍṇtdnpbmyrlḷvsh"
+
+// quality gathers what tells whether the text of a file read looks wrong:
+// a wrong script or encoding, a translation left in, or words run
+// together. countFile feeds it each line as the corpus normalized it, with
+// its tokens.
+type quality struct {
+	lines    int
+	badLines int // not valid UTF-8
+
+	textBytes, tokenBytes int // not counting spaces
+	letters, foreign      int // letters, and those outside paliLetters
+
+	words, latin int // space-separated words, and those of a modern language
+	latinExample string
+
+	long        int // tokens of suspectTokenRunes or more
+	longExample string
+
+	script     string // of the first line with letters
+	shifts     int    // lines in another script, or mojibake
+	firstShift int    // line number of the first of them
+}
+
+// addLine adds line, as read, its normalized text and its tokens.
+func (q *quality) addLine(line, text string, tokens []string) {
+	q.lines++
+	if !utf8.ValidString(line) {
+		q.badLines++
+	}
+	q.textBytes += len(text) - strings.Count(text, " ")
+	for _, w := range tokens {
+		q.tokenBytes += len(w)
+		if utf8.RuneCountInString(w) >= suspectTokenRunes {
+			if q.long == 0 {
+				q.longExample = w
+			}
+			q.long++
+		}
+	}
+	for _, w := range strings.Fields(text) {
+		w = strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) })
+		if w == "" {
+			continue
+		}
+		q.words++
+		if latinWord(w) {
+			if q.latin == 0 {
+				q.latinExample = w
+			}
+			q.latin++
+		}
+	}
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		q.letters++
+		if !strings.ContainsRune(paliLetters, unicode.ToLower(r)) {
+			q.foreign++
+		}
+		scripts[scriptOf(r)]++
+	}
+	script := ""
+	for s, n := range scripts {
+		if script == "" || n > scripts[script] || n == scripts[script] && s < script {
+			script = s
+		}
+	}
+	if q.script == "" {
+		q.script = script
+	}
+	if mojibake(text) || script != "" && script != q.script {
+		if q.shifts == 0 {
+			q.firstShift = q.lines
+		}
+		q.shifts++
+	}
+}
+
+// verdict says why the text looks wrong, one reason per failed check
+// joined by "; ", or returns "" when it looks right.
+func (q *quality) verdict() string {
+	var reasons []string
+	if q.badLines > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d lines not valid UTF-8", q.badLines))
+	}
+	if q.textBytes >= suspectMinBytes {
+		if float64(q.tokenBytes) < suspectShare*float64(q.textBytes) {
+			reasons = append(reasons, fmt.Sprintf("only %.0f%% of the text is in Pāḷi words", 100*float64(q.tokenBytes)/float64(q.textBytes)))
+		}
+		if q.letters > 0 && float64(q.foreign) > suspectForeignShare*float64(q.letters) {
+			reasons = append(reasons, fmt.Sprintf("%.0f%% of the letters are not Pāḷi", 100*float64(q.foreign)/float64(q.letters)))
+		}
+	}
+	if q.latin >= suspectLatinWords && float64(q.latin) >= suspectLatinShare*float64(q.words) {
+		reasons = append(reasons, fmt.Sprintf("%d words not Pāḷi, like %q", q.latin, q.latinExample))
+	}
+	if q.long > 0 {
+		example := q.longExample
+		if r := []rune(example); len(r) > 30 {
+			example = string(r[:30]) + "…"
+		}
+		reasons = append(reasons, fmt.Sprintf("%d tokens of %d letters or more, like %s", q.long, suspectTokenRunes, example))
+	}
+	if q.shifts >= suspectShiftLines {
+		reasons = append(reasons, fmt.Sprintf("%d lines change script or encoding, the first line %d", q.shifts, q.firstShift))
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// latinWord reports whether w, a word of letters, reads as a modern
+// language written in Latin letters rather than as romanized Pāḷi: it is
+// all ASCII and has a letter Pāḷi lacks, like f, q, w, x or z, or is one
+// of the commonest English words.
+func latinWord(w string) bool {
+	for _, r := range w {
+		if r >= utf8.RuneSelf {
+			return false
+		}
+	}
+	w = strings.ToLower(w)
+	return strings.ContainsAny(w, "fqwxz") || englishWords[w]
+}
+
+// englishWords are English words of a translation left in that have no
+// letter Pāḷi lacks and are no Pāḷi word.
+var englishWords = map[string]bool{
+	"the": true, "and": true, "of": true, "is": true, "in": true, "that": true,
+	"this": true, "are": true, "be": true, "by": true, "it": true, "as": true,
+	"not": true, "on": true, "his": true, "they": true, "with": true,
+}
+
+// scriptOf names the script of the letter r, for the scripts the editions
+// are published in.
+func scriptOf(r rune) string {
+	for _, s := range []string{"Latin", "Devanagari", "Sinhala", "Myanmar", "Thai", "Khmer", "Lao"} {
+		if unicode.Is(unicode.Scripts[s], r) {
+			return s
+		}
+	}
+	return "other"
+}
+
+// mojibake reports whether s holds the marks of a wrong decoding: the
+// replacement character, C1 control characters, or the Latin-1 reading of
+// the lead byte of a UTF-8 sequence followed by that of a continuation
+// byte, as ā turns into Ä plus U+0081.
+func mojibake(s string) bool {
+	prev := rune(0)
+	for _, r := range s {
+		if r == utf8.RuneError || r >= 0x80 && r <= 0x9f || prev >= 0xc2 && prev <= 0xef && r >= 0xa0 && r <= 0xbf {
+			return true
+		}
+		prev = r
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package freq
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// RankCorrelation compares the word frequencies of two corpora by rank.
+type RankCorrelation struct {
+	Words  int // compared: the top words of either corpus
+	Shared int // of them, those found in both
+	// Spearman's ρ and Kendall's τ-b of the counts, rounded to four
+	// decimals; 0 when fewer than two words or all counts of a side tie
+	Spearman float64
+	Kendall  float64
+}
+
+// Correlate computes the rank correlations of the counts a and b over the
+// union of the top words of each, all of them when top is 0. A word
+// missing from one side has the count 0 there. Tied counts share their
+// average rank for ρ, and τ-b corrects for them.
+//
+// Editions of one text should correlate strongly; a pair or a book that
+// does not points at a conversion problem or a real editorial difference.
+func Correlate(a, b map[string]int, top int) RankCorrelation {
+	seen := make(map[string]bool)
+	for _, counts := range []map[string]int{a, b} {
+		list := Sorted(counts)
+		if top > 0 && len(list) > top {
+			list = list[:top]
+		}
+		for _, wc := range list {
+			seen[wc.Word] = true
+		}
+	}
+	// in a fixed order, so the float sums are the same on every run
+	words := make([]string, 0, len(seen))
+	for w := range seen {
+		words = append(words, w)
+	}
+	slices.Sort(words)
+	x, y := make([]int, len(words)), make([]int, len(words))
+	r := RankCorrelation{Words: len(words)}
+	for i, w := range words {
+		x[i], y[i] = a[w], b[w]
+		if x[i] > 0 && y[i] > 0 {
+			r.Shared++
+		}
+	}
+	r.Spearman = round4(spearman(x, y))
+	r.Kendall = round4(kendall(x, y))
+	return r
+}
+
+func round4(v float64) float64 { return math.Round(v*1e4) / 1e4 }
+
+// spearman is the Pearson correlation of the average ranks of x and y.
+func spearman(x, y []int) float64 {
+	rx, ry := averageRanks(x), averageRanks(y)
+	n := float64(len(x))
+	if n < 2 {
+		return 0
+	}
+	// the mean rank is the same on both sides
+	mean := (n + 1) / 2
+	var sxy, sxx, syy float64
+	for i := range rx {
+		dx, dy := rx[i]-mean, ry[i]-mean
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return 0
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// averageRanks ranks v from 1, ties taking the mean of their ranks.
+func averageRanks(v []int) []float64 {
+	order := make([]int, len(v))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return v[order[i]] < v[order[j]] })
+	ranks := make([]float64, len(v))
+	for i := 0; i < len(order); {
+		j := i + 1
+		for j < len(order) && v[order[j]] == v[order[i]] {
+			j++
+		}
+		// ranks i+1 to j
+		avg := float64(i+1+j) / 2
+		for k := i; k < j; k++ {
+			ranks[order[k]] = avg
+		}
+		i = j
+	}
+	return ranks
+}
+
+// kendall is Kendall's τ-b of x and y, by Knight's algorithm: with the
+// pairs sorted by x, then y, the discordant pairs are the swaps a merge
+// sort by y makes, so it takes O(n log n) rather than a look at every
+// pair.
+func kendall(x, y []int) float64 {
+	n := len(x)
+	if n < 2 {
+		return 0
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if x[a] != x[b] {
+			return x[a] < x[b]
+		}
+		return y[a] < y[b]
+	})
+	// pairs tied in x, and in both x and y
+	var tiedX, tiedXY int64
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && x[order[j]] == x[order[i]] {
+			j++
+		}
+		tiedX += pairs(j - i)
+		for k := i; k < j; {
+			l := k + 1
+			for l < j && y[order[l]] == y[order[k]] {
+				l++
+			}
+			tiedXY += pairs(l - k)
+			k = l
+		}
+		i = j
+	}
+	ys := make([]int, n)
+	for i, k := range order {
+		ys[i] = y[k]
+	}
+	swaps := mergeCount(ys, make([]int, n))
+	// ys is now sorted: pairs tied in y
+	var tiedY int64
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && ys[j] == ys[i] {
+			j++
+		}
+		tiedY += pairs(j - i)
+		i = j
+	}
+	total := pairs(n)
+	denom := math.Sqrt(float64(total-tiedX) * float64(total-tiedY))
+	if denom == 0 {
+		return 0
+	}
+	return float64(total-tiedX-tiedY+tiedXY-2*swaps) / denom
+}
+
+func pairs(n int) int64 { return int64(n) * int64(n-1) / 2 }
+
+// mergeCount sorts v with tmp as scratch space and returns the number of
+// swaps of neighbours a bubble sort would make: the pairs out of order.
+func mergeCount(v, tmp []int) int64 {
+	if len(v) < 2 {
+		return 0
+	}
+	mid := len(v) / 2
+	swaps := mergeCount(v[:mid], tmp[:mid]) + mergeCount(v[mid:], tmp[mid:])
+	i, j, k := 0, mid, 0
+	for i < mid && j < len(v) {
+		if v[j] < v[i] {
+			tmp[k] = v[j]
+			swaps += int64(mid - i)
+			j++
+		} else {
+			tmp[k] = v[i]
+			i++
+		}
+		k++
+	}
+	k += copy(tmp[k:], v[i:mid])
+	copy(tmp[k:], v[j:])
+	copy(v, tmp[:len(v)])
+	return swaps
+}
@@ -0,0 +1,130 @@
+package freq
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/tools"
+)
+
+// ScoreWeights weigh the parts of a learning value. Only their ratios
+// matter; a part of weight 0 is ignored.
+type ScoreWeights struct {
+	Frequency  float64 // how often the headword is met
+	Dispersion float64 // how evenly it is spread over the books
+	Length     float64 // how short it is
+	Regularity float64 // whether it inflects by a common pattern
+}
+
+// DefaultScoreWeights rank a headword mostly by how often and how widely
+// a reader meets it.
+var DefaultScoreWeights = ScoreWeights{Frequency: 0.4, Dispersion: 0.3, Length: 0.15, Regularity: 0.15}
+
+// ParseScoreWeights reads weights written as "frequency=0.5,length=0.2";
+// the parts not named keep their DefaultScoreWeights.
+func ParseScoreWeights(s string) (ScoreWeights, error) {
+	w := DefaultScoreWeights
+	parts := map[string]*float64{
+		"frequency":  &w.Frequency,
+		"dispersion": &w.Dispersion,
+		"length":     &w.Length,
+		"regularity": &w.Regularity,
+	}
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(f, "=")
+		p, ok := parts[strings.TrimSpace(name)]
+		if !ok {
+			return w, fmt.Errorf("weight %q: not one of frequency, dispersion, length, regularity", f)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || v < 0 {
+			return w, fmt.Errorf("weight %q: want a number of 0 or more", f)
+		}
+		*p = v
+	}
+	if w.Frequency+w.Dispersion+w.Length+w.Regularity == 0 {
+		return w, fmt.Errorf("weights %q: all are 0", s)
+	}
+	return w, nil
+}
+
+// scoreMaxLength is the length in letters from which a headword earns
+// nothing for its length.
+const scoreMaxLength = 20
+
+// HeadwordScore is the learning value of a headword with the measures it
+// is made of.
+type HeadwordScore struct {
+	Headword   dpd.Headword
+	Count      int
+	PerMillion float64
+	DP         float64 // across books, see DispersionStats
+	Length     int     // letters of the lemma, without its homonym number
+	Irregular  bool
+	Score      float64 // 0 to 1, rounded to four decimals
+}
+
+// LearningScores rates every headword of the counts of books, through
+// lem, by how much learning it pays off: the weighted mean of its
+// frequency, on a log scale relative to the most frequent headword, its
+// evenness over the books (1 - DP), its shortness (1 for one letter, 0 for
+// scoreMaxLength and more) and its regularity (0 for the headwords in
+// irregular, 1 for the others). The list is ordered by descending score,
+// then by lemma.
+func LearningScores(books Books, lem *Lemmatizer, irregular map[int]bool, w ScoreWeights) []HeadwordScore {
+	byBook := make(map[string]map[string]int, len(books))
+	total := make(map[int]int)
+	for book, counts := range books {
+		m := make(map[string]int)
+		for _, lc := range lem.Counts(counts) {
+			m[strconv.Itoa(lc.Headword.ID)] = lc.Count
+			total[lc.Headword.ID] += lc.Count
+		}
+		byBook[book] = m
+	}
+	disp := DispersionStats(byBook)
+	tokens := TokenTotal(books.Total())
+	most := 0
+	for _, n := range total {
+		most = max(most, n)
+	}
+	sum := w.Frequency + w.Dispersion + w.Length + w.Regularity
+
+	list := make([]HeadwordScore, 0, len(total))
+	for id, n := range total {
+		h := lem.Headwords[id]
+		word, _, _ := strings.Cut(h.Lemma1, " ")
+		s := HeadwordScore{
+			Headword:   h,
+			Count:      n,
+			PerMillion: PerMillion(n, tokens),
+			DP:         disp[strconv.Itoa(id)].DP,
+			Length:     utf8.RuneCountInString(word),
+			Irregular:  irregular[id],
+		}
+		frequency := math.Log1p(float64(n)) / math.Log1p(float64(most))
+		shortness := 1 - float64(min(max(s.Length, 1)-1, scoreMaxLength-1))/float64(scoreMaxLength-1)
+		regularity := 1.0
+		if s.Irregular {
+			regularity = 0
+		}
+		score := (w.Frequency*frequency + w.Dispersion*(1-s.DP) + w.Length*shortness + w.Regularity*regularity) / sum
+		s.Score = math.Round(score*1e4) / 1e4
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return tools.ComparePali(list[i].Headword.Lemma1, list[j].Headword.Lemma1) < 0
+	})
+	return list
+}
@@ -0,0 +1,152 @@
+package freq
+
+import (
+	"maps"
+	"math"
+	"slices"
+)
+
+// ZipfFit is Zipf's law f(r) = C / r^S fitted to the counts of a corpus,
+// the word of rank r occurring f(r) times. S is near 1 for natural text;
+// a tokenizer that splits or glues words bends the curve and moves it.
+type ZipfFit struct {
+	C, S float64
+	R2   float64 // of the fit on the log-log points, 1 for a straight line
+}
+
+// At is the count the fit predicts for rank, rounded to two decimals.
+func (f ZipfFit) At(rank int) float64 {
+	return math.Round(f.C/math.Pow(float64(rank), f.S)*100) / 100
+}
+
+// ZipfPoint is a word of a rank-frequency curve.
+type ZipfPoint struct {
+	Rank  int
+	Word  string
+	Count int
+}
+
+// Zipf fits Zipf's law to counts. It samples perDecade ranks in each
+// power of ten, evenly spaced on a log scale, and the last rank, and fits
+// them, so the long tail of rare words weighs no more than the head; the
+// points are returned for plotting, by ascending rank.
+func Zipf(counts map[string]int, perDecade int) (ZipfFit, []ZipfPoint) {
+	list := Sorted(counts)
+	var points []ZipfPoint
+	for _, r := range logRanks(len(list), perDecade) {
+		points = append(points, ZipfPoint{r, list[r-1].Word, list[r-1].Count})
+	}
+	xs, ys := make([]float64, len(points)), make([]float64, len(points))
+	for i, p := range points {
+		xs[i], ys[i] = float64(p.Rank), float64(p.Count)
+	}
+	c, e, r2 := fitPowerLaw(xs, ys)
+	// 0 - e rather than -e, for no -0 of a flat curve
+	return ZipfFit{C: c, S: 0 - e, R2: r2}, points
+}
+
+// logRanks returns the ranks 1 to n spaced evenly on a log scale, perDecade
+// in each power of ten, n included.
+func logRanks(n, perDecade int) []int {
+	if perDecade < 1 {
+		perDecade = 1
+	}
+	var ranks []int
+	for i := 0; ; i++ {
+		r := int(math.Round(math.Pow(10, float64(i)/float64(perDecade))))
+		if r > n {
+			break
+		}
+		if len(ranks) == 0 || r > ranks[len(ranks)-1] {
+			ranks = append(ranks, r)
+		}
+	}
+	if n > 0 && (len(ranks) == 0 || ranks[len(ranks)-1] < n) {
+		ranks = append(ranks, n)
+	}
+	return ranks
+}
+
+// HeapsFit is Heaps' law V(N) = K·N^Beta fitted to the vocabulary growth
+// of a corpus, V distinct words having been seen after N tokens. Beta is
+// usually between 0.4 and 0.6, higher for a highly inflected language.
+type HeapsFit struct {
+	K, Beta float64
+	R2      float64
+}
+
+// At is the vocabulary the fit predicts after tokens, rounded to two
+// decimals.
+func (f HeapsFit) At(tokens int) float64 {
+	return math.Round(f.K*math.Pow(float64(tokens), f.Beta)*100) / 100
+}
+
+// GrowthPoint is the vocabulary after the first Files files of a corpus.
+type GrowthPoint struct {
+	Files, Tokens, Types int
+}
+
+// Heaps fits Heaps' law to files, the per-file counts of one corpus. The
+// files are read in path order, the order of the corpus, and the
+// vocabulary measured after each, as the order of the tokens within a
+// file is not kept; the points are returned for plotting.
+func Heaps(files map[string]map[string]int) (HeapsFit, []GrowthPoint) {
+	seen := make(map[string]bool)
+	var (
+		points []GrowthPoint
+		tokens int
+	)
+	for i, path := range slices.Sorted(maps.Keys(files)) {
+		for w, n := range files[path] {
+			tokens += n
+			seen[w] = true
+		}
+		if tokens > 0 {
+			points = append(points, GrowthPoint{i + 1, tokens, len(seen)})
+		}
+	}
+	xs, ys := make([]float64, len(points)), make([]float64, len(points))
+	for i, p := range points {
+		xs[i], ys[i] = float64(p.Tokens), float64(p.Types)
+	}
+	k, beta, r2 := fitPowerLaw(xs, ys)
+	return HeapsFit{K: k, Beta: beta, R2: r2}, points
+}
+
+// fitPowerLaw fits y = c·x^e by least squares on log y against log x,
+// with r2 the coefficient of determination there; all zero for fewer than
+// two distinct x. The values are rounded to four decimals, c to four
+// significant digits.
+func fitPowerLaw(xs, ys []float64) (c, e, r2 float64) {
+	n := float64(len(xs))
+	var sx, sy, sxx, sxy, syy float64
+	for i := range xs {
+		x, y := math.Log(xs[i]), math.Log(ys[i])
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+		syy += y * y
+	}
+	vx := sxx - sx*sx/n
+	if len(xs) < 2 || vx <= 0 {
+		return 0, 0, 0
+	}
+	cov := sxy - sx*sy/n
+	e = cov / vx
+	c = math.Exp((sy - e*sx) / n)
+	r2 = 1
+	if vy := syy - sy*sy/n; vy > 0 {
+		r2 = cov * cov / (vx * vy)
+	}
+	return roundSignificant(c, 4), round4(e), round4(r2)
+}
+
+// roundSignificant rounds v to digits significant digits.
+func roundSignificant(v float64, digits int) float64 {
+	if v == 0 {
+		return 0
+	}
+	scale := math.Pow(10, float64(digits)-math.Ceil(math.Log10(math.Abs(v))))
+	return math.Round(v*scale) / scale
+}
@@ -0,0 +1,244 @@
+// Package freqdb queries the SQLite database package export writes: the
+// counts and ranks of word_frequency, word_frequency_book and
+// lemma_frequency and the sources of word_citation. Statements are
+// prepared once, on first use, and the answers for a word are cached, so
+// tools and tests can ask in a loop without writing SQL against the
+// schema. A DB is safe for concurrent use; it is meant for a database no
+// longer being written, since the cache does not see later writes.
+package freqdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	_ "modernc.org/sqlite"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// ErrNoTable is returned for a query on a table the database lacks, such as
+// word_citation in a database exported without -index.
+var ErrNoTable = errors.New("no such table")
+
+// cacheSize bounds the words whose answers a DB keeps; the cache starts
+// over when it is full.
+const cacheSize = 4096
+
+// Frequency is the count and rank of a word or headword in one corpus, or
+// one book of it.
+type Frequency struct {
+	Corpus string
+	Book   string // "" for the whole corpus
+	Count  int
+	Rank   int // 1 for the most frequent
+}
+
+// Ranked is a word of a corpus with its count and rank.
+type Ranked struct {
+	Word  string
+	Count int
+	Rank  int
+}
+
+// Occurrence is the count of a word in one source file of a corpus, named
+// as export.SourceID names it.
+type Occurrence struct {
+	Corpus, Source string
+	Count          int
+}
+
+// DB answers queries on an exported database.
+type DB struct {
+	db     *sql.DB
+	own    bool // opened by Open, so closed by Close
+	tables map[string]bool
+
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt // by query
+	cache map[string]any       // by query and arguments
+}
+
+// Open opens the database at path read-only.
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite", tools.SQLiteURI(path, "mode=ro"))
+	if err != nil {
+		return nil, err
+	}
+	d, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	d.own = true
+	return d, nil
+}
+
+// New returns a DB querying db, which stays the caller's to close.
+func New(db *sql.DB) (*DB, error) {
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	d := &DB{db: db, tables: make(map[string]bool), stmts: make(map[string]*sql.Stmt), cache: make(map[string]any)}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		d.tables[name] = true
+	}
+	return d, rows.Err()
+}
+
+// Close closes the prepared statements, and the database if Open opened
+// it.
+func (d *DB) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, st := range d.stmts {
+		st.Close()
+	}
+	d.stmts = nil
+	if d.own {
+		return d.db.Close()
+	}
+	return nil
+}
+
+// Has reports whether the database has the table.
+func (d *DB) Has(table string) bool { return d.tables[table] }
+
+// Corpora lists the corpora of word_frequency, sorted.
+func (d *DB) Corpora() ([]string, error) {
+	return cached(d, "corpora", "word_frequency", `SELECT DISTINCT corpus FROM word_frequency ORDER BY corpus`, nil,
+		func(rows *sql.Rows) (string, error) {
+			var c string
+			return c, rows.Scan(&c)
+		})
+}
+
+// Lookup returns the count and rank of word in each corpus it occurs in,
+// by corpus name.
+func (d *DB) Lookup(word string) ([]Frequency, error) {
+	return cached(d, "lookup", "word_frequency", `SELECT corpus, count, rank FROM word_frequency WHERE word = ? ORDER BY corpus`, []any{word},
+		func(rows *sql.Rows) (Frequency, error) {
+			var f Frequency
+			return f, rows.Scan(&f.Corpus, &f.Count, &f.Rank)
+		})
+}
+
+// Books returns the count and rank of word in each book of each corpus it
+// occurs in, by corpus and book key.
+func (d *DB) Books(word string) ([]Frequency, error) {
+	return cached(d, "books", "word_frequency_book", `SELECT corpus, book, count, rank FROM word_frequency_book WHERE word = ? ORDER BY corpus, book`, []any{word},
+		func(rows *sql.Rows) (Frequency, error) {
+			var f Frequency
+			return f, rows.Scan(&f.Corpus, &f.Book, &f.Count, &f.Rank)
+		})
+}
+
+// Lemma returns the count and rank of the DPD headword id in each corpus.
+func (d *DB) Lemma(id int) ([]Frequency, error) {
+	return cached(d, "lemma", "lemma_frequency", `SELECT corpus, count, rank FROM lemma_frequency WHERE headword_id = ? ORDER BY corpus`, []any{id},
+		func(rows *sql.Rows) (Frequency, error) {
+			var f Frequency
+			return f, rows.Scan(&f.Corpus, &f.Count, &f.Rank)
+		})
+}
+
+// TopN returns the n most frequent words of corpus, by rank.
+func (d *DB) TopN(corpus string, n int) ([]Ranked, error) {
+	return cached(d, "top", "word_frequency", `SELECT word, count, rank FROM word_frequency WHERE corpus = ? ORDER BY rank LIMIT ?`, []any{corpus, n},
+		func(rows *sql.Rows) (Ranked, error) {
+			var r Ranked
+			return r, rows.Scan(&r.Word, &r.Count, &r.Rank)
+		})
+}
+
+// Prefix returns the n most frequent words starting with prefix, with
+// their counts over all corpora.
+func (d *DB) Prefix(prefix string, n int) ([]freq.WordCount, error) {
+	return cached(d, "prefix", "word_frequency", `
+		SELECT word, sum(count) AS n FROM word_frequency
+		WHERE substr(word, 1, length(?1)) = ?1
+		GROUP BY word ORDER BY n DESC, word LIMIT ?2`, []any{prefix, n},
+		func(rows *sql.Rows) (freq.WordCount, error) {
+			var wc freq.WordCount
+			return wc, rows.Scan(&wc.Word, &wc.Count)
+		})
+}
+
+// Occurrences returns the source files word occurs in, in each corpus,
+// those with the most occurrences first.
+func (d *DB) Occurrences(word string) ([]Occurrence, error) {
+	return cached(d, "occurrences", "word_citation", `SELECT corpus, source, count FROM word_citation WHERE word = ? ORDER BY count DESC, corpus, source`, []any{word},
+		func(rows *sql.Rows) (Occurrence, error) {
+			var o Occurrence
+			return o, rows.Scan(&o.Corpus, &o.Source, &o.Count)
+		})
+}
+
+// stmt returns the statement of query, prepared on first use. d.mu is
+// held.
+func (d *DB) stmt(query string) (*sql.Stmt, error) {
+	if d.stmts == nil {
+		return nil, errors.New("freqdb: query on a closed DB")
+	}
+	if st := d.stmts[query]; st != nil {
+		return st, nil
+	}
+	st, err := d.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	d.stmts[query] = st
+	return st, nil
+}
+
+// cached returns the rows of query on table with args, each read by scan,
+// from the cache of d when it has them, under the key name and args. The
+// slice returned is the caller's.
+func cached[T any](d *DB, name, table, query string, args []any, scan func(*sql.Rows) (T, error)) ([]T, error) {
+	if !d.tables[table] {
+		return nil, fmt.Errorf("%s: %w", table, ErrNoTable)
+	}
+	key := fmt.Sprint(name, args)
+	d.mu.Lock()
+	if list, ok := d.cache[key]; ok {
+		d.mu.Unlock()
+		return slices.Clone(list.([]T)), nil
+	}
+	st, err := d.stmt(query)
+	d.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := st.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []T
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	if len(d.cache) >= cacheSize {
+		clear(d.cache)
+	}
+	d.cache[key] = list
+	d.mu.Unlock()
+	return slices.Clone(list), nil
+}
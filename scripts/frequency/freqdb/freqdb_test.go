@@ -0,0 +1,120 @@
+package freqdb
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/frequency/freq"
+)
+
+func TestQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pali.db")
+	db, err := export.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for corpus, counts := range map[string]map[string]int{
+		"cst": {"dhammo": 30, "dhammaṃ": 12, "ca": 50},
+		"sya": {"dhammaṃ": 10, "ca": 40},
+	} {
+		if err := export.WordFrequency(db, corpus, counts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]map[string]int{"romn/s0101m.mul.xml": {"dhammaṃ": 8, "ca": 5}, "romn/s0201m.mul.xml": {"dhammaṃ": 4}}
+	if err := export.Citations(db, "cst", "romn", files); err != nil {
+		t.Fatal(err)
+	}
+	// files of one name in two directories of a custom corpus
+	files = map[string]map[string]int{"texts/a/x.txt": {"dhammaṃ": 2}, "texts/b/x.txt": {"dhammaṃ": 3}}
+	if err := export.Citations(db, "custom", "texts", files); err != nil {
+		t.Fatal(err)
+	}
+	if err := export.Close(db); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	for range 2 { // the second time from the cache
+		got, err := d.Lookup("dhammaṃ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Frequency{{Corpus: "cst", Count: 12, Rank: 3}, {Corpus: "sya", Count: 10, Rank: 2}}
+		if !slices.Equal(got, want) {
+			t.Errorf("Lookup = %v, want %v", got, want)
+		}
+		got[0].Count = 0 // the cache keeps its own copy
+	}
+	top, err := d.TopN("cst", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Ranked{{"ca", 50, 1}, {"dhammo", 30, 2}}; !slices.Equal(top, want) {
+		t.Errorf("TopN = %v, want %v", top, want)
+	}
+	prefix, err := d.Prefix("dham", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []freq.WordCount{{Word: "dhammo", Count: 30}, {Word: "dhammaṃ", Count: 22}}; !slices.Equal(prefix, want) {
+		t.Errorf("Prefix = %v, want %v", prefix, want)
+	}
+	occ, err := d.Occurrences("dhammaṃ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Occurrence{{"cst", "s0101m.mul", 8}, {"cst", "s0201m.mul", 4}, {"custom", "b/x", 3}, {"custom", "a/x", 2}}; !slices.Equal(occ, want) {
+		t.Errorf("Occurrences = %v, want %v", occ, want)
+	}
+	corpora, err := d.Corpora()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(corpora, []string{"cst", "sya"}) {
+		t.Errorf("Corpora = %v", corpora)
+	}
+	if !d.Has("lemma_frequency") {
+		t.Error("lemma_frequency missing")
+	}
+	if _, err := d.Lemma(7); err != nil {
+		t.Errorf("Lemma on an empty table: %v", err)
+	}
+	d.tables["lemma_frequency"] = false
+	if _, err := d.Lemma(7); !errors.Is(err, ErrNoTable) {
+		t.Errorf("Lemma without the table: %v, want ErrNoTable", err)
+	}
+}
+
+func TestOpenPath(t *testing.T) {
+	// names that are a fragment and a query if left unescaped
+	for _, name := range []string{"a#b.db", "a?b.db", "a%20b.db"} {
+		path := filepath.Join(t.TempDir(), name)
+		db, err := export.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := export.WordFrequency(db, "cst", map[string]int{"ca": 5}); err != nil {
+			t.Fatal(err)
+		}
+		if err := export.Close(db); err != nil {
+			t.Fatal(err)
+		}
+		d, err := Open(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := d.Lookup("ca")
+		d.Close()
+		if err != nil || len(got) != 1 {
+			t.Errorf("%s: Lookup = %v, %v, want the row of cst", name, got, err)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// candidateName is the kind of gazetteer entries DPD does not tag as names,
+// found by how often their forms are nominatives and vocatives.
+const candidateName = "candidate"
+
+// lengthFold writes the long vowels short, so the spellings of a name that
+// differ in vowel length, like sāvatthi and sāvatthī, group together.
+var lengthFold = strings.NewReplacer("ā", "a", "ī", "i", "ū", "u")
+
+// gazetteerName is an entry of the gazetteer: the headwords of one name
+// in all its spellings, and their counts.
+type gazetteerName struct {
+	kind      string
+	ids       []int // headwords, most frequent first
+	counts    []int // by corpus
+	total     int
+	inflected int // tokens of the forms of the headwords' inflection tables
+	address   int // of them, nominatives and vocatives
+}
+
+// addressShare is the share of the tokens of the inflected forms of pn
+// that are nominatives or vocatives, or 0 when it has none.
+func (pn *gazetteerName) addressShare() float64 {
+	if pn.inflected == 0 {
+		return 0
+	}
+	return math.Round(float64(pn.address)/float64(pn.inflected)*1e4) / 1e4
+}
+
+// addressForms reads, for each headword of scope, its inflected forms, true
+// for those its template gives as a nominative or vocative.
+func addressForms(db *dpd.DB, scope map[int]string) (map[int]map[string]bool, error) {
+	forms := make(map[int]map[string]bool)
+	err := db.Inflections(func(in dpd.Inflection) {
+		if _, ok := scope[in.HeadwordID]; !ok {
+			return
+		}
+		m := forms[in.HeadwordID]
+		if m == nil {
+			m = make(map[string]bool)
+			forms[in.HeadwordID] = m
+		}
+		fields := strings.Fields(in.Grammar)
+		m[in.Form] = m[in.Form] || slices.Contains(fields, "nom") || slices.Contains(fields, "voc")
+	})
+	return forms, err
+}
+
+// buildGazetteer groups the headwords of scope, each with its kind, into
+// names: headwords of one kind whose lemmas, without homonym number, are
+// the same once v rewrites them and their long vowels are written short.
+// It adds up the counts of each corpus by name, a form of several names
+// counting fully for each, as the headword counts do, but once for a
+// name, however many of its headwords share it.
+func buildGazetteer(lem *freq.Lemmatizer, scope map[int]string, forms map[int]map[string]bool, v *pali.Variants, counts []map[string]int) []*gazetteerName {
+	ranked := make(map[string]int)
+	for _, c := range counts {
+		for w, n := range c {
+			ranked[w] += n
+		}
+	}
+	byID := make(map[int]int)
+	for _, lc := range lem.Counts(ranked) {
+		byID[lc.Headword.ID] = lc.Count
+	}
+	ids := make([]int, 0, len(scope))
+	for id := range scope {
+		if _, ok := lem.Headwords[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if byID[ids[i]] != byID[ids[j]] {
+			return byID[ids[i]] > byID[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	var names []*gazetteerName
+	group := make(map[int]*gazetteerName)
+	byKey := make(map[string]*gazetteerName)
+	for _, id := range ids {
+		key := scope[id] + " " + lengthFold.Replace(v.Rewrite(lemmaWord(lem.Headwords[id].Lemma1)))
+		pn := byKey[key]
+		if pn == nil {
+			pn = &gazetteerName{kind: scope[id], counts: make([]int, len(counts))}
+			byKey[key] = pn
+			names = append(names, pn)
+		}
+		pn.ids = append(pn.ids, id)
+		group[id] = pn
+	}
+
+	for i, c := range counts {
+		for w, n := range c {
+			var seen []*gazetteerName
+			for _, id := range lem.Lookup[w] {
+				pn := group[id]
+				if pn == nil || slices.Contains(seen, pn) {
+					continue
+				}
+				seen = append(seen, pn)
+				pn.counts[i] += n
+				pn.total += n
+			}
+			for _, pn := range seen {
+				inflected, address := false, false
+				for _, id := range pn.ids {
+					a, ok := forms[id][w]
+					inflected, address = inflected || ok, address || a
+				}
+				if inflected {
+					pn.inflected += n
+				}
+				if address {
+					pn.address += n
+				}
+			}
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool { return names[i].total > names[j].total })
+	return names
+}
+
+// gazetteerTable lists the names found at least minCount times, most
+// frequent first: the lemma of the most frequent headword, the kind, the
+// spellings and headwords grouped, the meaning of the first and the counts
+// in each corpus.
+func gazetteerTable(names []*gazetteerName, lem *freq.Lemmatizer, glosses map[int]dpd.Gloss, corpusNames []string, minCount int) table {
+	t := table{columns: []string{"lemma", "kind", "spellings", "headword_ids", "meaning", "count"}}
+	t.columns = append(t.columns, corpusNames...)
+	t.columns = append(t.columns, "address_share")
+	for _, pn := range names {
+		if pn.total < minCount {
+			continue
+		}
+		var spellings, ids []string
+		for _, id := range pn.ids {
+			if w := lemmaWord(lem.Headwords[id].Lemma1); !slices.Contains(spellings, w) {
+				spellings = append(spellings, w)
+			}
+			ids = append(ids, strconv.Itoa(id))
+		}
+		row := []any{spellings[0], pn.kind, strings.Join(spellings, ", "), strings.Join(ids, ","), glosses[pn.ids[0]].Meaning, pn.total}
+		for _, n := range pn.counts {
+			row = append(row, n)
+		}
+		t.rows = append(t.rows, append(row, pn.addressShare()))
+	}
+	return t
+}
+
+// runGazetteer implements the gazetteer subcommand: the people and places
+// of the texts, DPD's proper nouns grouped by spelling, with their counts
+// in each corpus.
+func runGazetteer(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("gazetteer", flag.ExitOnError)
+	commandUsage(fs, "Writes the people and places of the texts, the DPD proper nouns with their spelling variants grouped, and their counts in each corpus, from the counts of the last run.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to count, from the counts of the last run")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database")
+	minCount := fs.Int("min-count", 1, "minimum count over all corpora of a name listed")
+	candidates := fs.Bool("candidates", false, "also list nouns DPD does not tag as names whose tokens are mostly nominatives and vocatives, as kind candidate")
+	minAddress := fs.Float64("min-address", 0.6, "share of nominatives and vocatives among its tokens a candidate needs")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("saving the gazetteer of people and places")
+	tic := tools.Tic()
+	list := strings.Split(*names, ",")
+	counts := make([]map[string]int, len(list))
+	for i, name := range list {
+		sites, err := loadWordSites(name)
+		if err != nil {
+			tools.Errorf("%s: %v (count it first)", name, err)
+			return
+		}
+		counts[i] = make(map[string]int, len(sites))
+		for w, s := range sites {
+			counts[i][w] = s.count
+		}
+	}
+	rules := cfg.Variants
+	if len(rules) == 0 {
+		rules = pali.DefaultVariants
+	}
+	v, err := pali.NewVariants(rules)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	lem, err := freq.LoadLemmatizer(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	db, err := dpd.Open(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	scope, err := db.Names()
+	var glosses map[int]dpd.Gloss
+	if err == nil {
+		glosses, err = db.Glosses()
+	}
+	if err == nil && *candidates {
+		for id, h := range lem.Headwords {
+			if _, ok := scope[id]; !ok && (h.Pos == "masc" || h.Pos == "fem") {
+				scope[id] = candidateName
+			}
+		}
+	}
+	var forms map[int]map[string]bool
+	if err == nil {
+		forms, err = addressForms(db, scope)
+	}
+	db.Close()
+	if err != nil {
+		tools.Errorf("%s: %v", *dpdPath, err)
+		return
+	}
+
+	gazetteer := buildGazetteer(lem, scope, forms, v, counts)
+	gazetteer = slices.DeleteFunc(gazetteer, func(pn *gazetteerName) bool {
+		return pn.kind == candidateName && pn.addressShare() < *minAddress
+	})
+	t := gazetteerTable(gazetteer, lem, glosses, list, *minCount)
+	if err := sink.Write("gazetteer", t); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	kinds := make(map[string]int)
+	for _, row := range t.rows {
+		kinds[row[1].(string)]++
+	}
+	tools.Infof("%d names: %d people, %d places, %d other, %d candidates", len(t.rows), kinds[dpd.Person], kinds[dpd.Place], kinds[dpd.OtherName], kinds[candidateName])
+	tic.Toc()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dpd/go_modules/frequency/corpora"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// runGolden counts the test corpora into a fresh output directory and
+// returns the files written, by path relative to it.
+func runGolden(t *testing.T) map[string][]byte {
+	t.Helper()
+	list := []corpora.Corpus{corpora.NewSya("testdata/sya"), corpora.NewBjt("testdata/bjt")}
+	out, err := countSample(context.Background(), list, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// TestGoldenOutputs checks that unchanged inputs give byte-identical
+// outputs, run after run, and that they match testdata/golden. Run with
+// -update to accept new outputs.
+func TestGoldenOutputs(t *testing.T) {
+	first := runGolden(t)
+	for range 3 {
+		again := runGolden(t)
+		if len(again) != len(first) {
+			t.Fatalf("a rerun wrote %d files, the first run %d", len(again), len(first))
+		}
+		for name, data := range first {
+			if !bytes.Equal(again[name], data) {
+				t.Errorf("%s differs between runs", name)
+			}
+		}
+	}
+	for name, data := range first {
+		if bytes.Contains(data, []byte("\r")) {
+			t.Errorf("%s contains a carriage return", name)
+		}
+	}
+
+	golden := filepath.Join("testdata", "golden")
+	if *update {
+		os.RemoveAll(golden)
+		for name, data := range first {
+			path := filepath.Join(golden, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return
+	}
+	for name, data := range first {
+		want, err := os.ReadFile(filepath.Join(golden, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("%s: no golden file (run go test -update)", name)
+			continue
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("%s differs from its golden file:\n%s", name, firstDiff(string(want), string(data)))
+		}
+	}
+	filepath.WalkDir(golden, func(path string, e fs.DirEntry, err error) error {
+		if err == nil && !e.IsDir() {
+			rel, _ := filepath.Rel(golden, path)
+			if _, ok := first[filepath.ToSlash(rel)]; !ok {
+				t.Errorf("%s is no longer written", rel)
+			}
+		}
+		return nil
+	})
+}
+
+// TestSelftest checks the sample corpus of the selftest command against
+// its golden files in selftest/golden, rewritten with -update.
+func TestSelftest(t *testing.T) {
+	got, err := runSample(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		golden := filepath.Join("selftest", "golden")
+		os.RemoveAll(golden)
+		for name, data := range got {
+			path := filepath.Join(golden, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return
+	}
+	want, err := sampleGolden()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range compareOutputs(got, want) {
+		t.Error(p)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// suttaGrade is how hard the vocabulary of one sutta is for a reader who
+// knows the top words of a frequency-ranked list.
+type suttaGrade struct {
+	book    string
+	number  int // in the book, from 1
+	title   string
+	tokens  int
+	types   int
+	rank    int     // of the percentile, the list size that covers that share of the tokens
+	outside float64 // share of the tokens outside the top words
+	unknown int     // distinct words outside the top words
+}
+
+// gradeSutta grades the words counts of a sutta against rank, the ranks of
+// the list; a word the list lacks ranks after all of it, at listSize+1.
+func gradeSutta(counts map[string]int, rank map[string]int, listSize int, percentile float64, top int) suttaGrade {
+	g := suttaGrade{types: len(counts)}
+	type ranked struct{ rank, count int }
+	var words []ranked
+	outside := 0
+	for w, n := range counts {
+		r, ok := rank[w]
+		if !ok {
+			r = listSize + 1
+		}
+		words = append(words, ranked{r, n})
+		g.tokens += n
+		if r > top {
+			outside += n
+			g.unknown++
+		}
+	}
+	if g.tokens == 0 {
+		return g
+	}
+	sort.Slice(words, func(i, j int) bool { return words[i].rank < words[j].rank })
+	// the least rank whose words and those before cover the percentile
+	need := int(math.Ceil(percentile / 100 * float64(g.tokens)))
+	sum := 0
+	for _, w := range words {
+		sum += w.count
+		if sum >= need {
+			g.rank = w.rank
+			break
+		}
+	}
+	g.outside = share(outside, g.tokens)
+	return g
+}
+
+// gradeSuttas grades the suttas of each book of suttas with at least
+// minTokens tokens, and returns them per book in canon order, easiest
+// first: by the rank of the percentile, then by the share of tokens
+// outside the top words.
+func gradeSuttas(suttas map[string][]sutta, tok pali.Tokenizer, rank map[string]int, listSize int, percentile float64, top, minTokens int) []suttaGrade {
+	var grades []suttaGrade
+	for _, book := range corpora.Books {
+		var inBook []suttaGrade
+		for i, s := range suttas[book] {
+			counts := make(map[string]int)
+			for _, para := range s.paras {
+				for _, t := range tok.Tokenize(para) {
+					counts[t]++
+				}
+			}
+			g := gradeSutta(counts, rank, listSize, percentile, top)
+			if g.tokens < minTokens {
+				continue
+			}
+			g.book, g.number, g.title = book, i+1, s.title
+			inBook = append(inBook, g)
+		}
+		slices.SortStableFunc(inBook, func(a, b suttaGrade) int {
+			if a.rank != b.rank {
+				return a.rank - b.rank
+			}
+			if a.outside != b.outside {
+				return int(math.Copysign(1, a.outside-b.outside))
+			}
+			return a.number - b.number
+		})
+		grades = append(grades, inBook...)
+	}
+	return grades
+}
+
+// gradeTable is the table of the grades, with the order of each sutta in
+// its book, easiest first.
+func gradeTable(grades []suttaGrade, percentile float64, top int) table {
+	t := table{columns: []string{"book", "order", "sutta", "title", "tokens", "types",
+		fmt.Sprintf("rank_p%g", percentile), fmt.Sprintf("outside_top_%d", top), "unknown_words"}}
+	order, book := 0, ""
+	for _, g := range grades {
+		if g.book != book {
+			order, book = 0, g.book
+		}
+		order++
+		t.rows = append(t.rows, []any{g.book, order, g.number, g.title, g.tokens, g.types, g.rank, g.outside, g.unknown})
+	}
+	return t
+}
+
+// runGrade implements the grade subcommand: the suttas of each nikāya
+// ordered from the easiest vocabulary to the hardest, for a reading order.
+func runGrade(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("grade", flag.ExitOnError)
+	commandUsage(fs, "Grades every sutta of a corpus by the frequency ranks of its vocabulary and writes them per book, easiest first, into <corpus>_grades.")
+	name := fs.String("corpus", "cst", "corpus whose suttas to grade")
+	list := fs.String("list", "master", "frequency-ranked list, as for coverage")
+	bookList := fs.String("books", "", "comma-separated book keys to grade, e.g. dn,mn (default: all)")
+	percentile := fs.Float64("percentile", 95, "share of a sutta's tokens, in percent, whose list size rank_p<percentile> gives")
+	top := fs.Int("top", 2000, "list size for the outside_top_<N> share and the unknown words")
+	minTokens := fs.Int("min-tokens", 20, "leave out suttas of fewer tokens, such as the stubs of peyyāla series")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("grading suttas")
+	tic := tools.Tic()
+	if *percentile <= 0 || *percentile > 100 {
+		tools.Errorf("-percentile %v: want more than 0 and at most 100", *percentile)
+		return
+	}
+	c, ok := corpora.Get(*name)
+	if !ok {
+		tools.Errorf("unknown corpus %q (have %s)", *name, strings.Join(corpusNames(), ", "))
+		return
+	}
+	books, err := parseBookList(*bookList)
+	if err != nil {
+		tools.Errorf("-books: %v", err)
+		return
+	}
+	path, err := rankedListPath(*list)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	words, err := readRankedList(path)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	rank := listRanks(words)
+	suttas, err := readSuttas(ctx, c, books)
+	if err != nil {
+		tools.Errorf("%s: %v", c.Name(), err)
+		return
+	}
+
+	grades := gradeSuttas(suttas, cfg.tokenizer(), rank, len(words), *percentile, *top, *minTokens)
+	for _, book := range corpora.Books {
+		i := slices.IndexFunc(grades, func(g suttaGrade) bool { return g.book == book })
+		if i < 0 {
+			continue
+		}
+		j := i
+		for j+1 < len(grades) && grades[j+1].book == book {
+			j++
+		}
+		tools.Infof("%s: %d suttas, easiest %s (rank %d), hardest %s (rank %d)", book, j-i+1, grades[i].title, grades[i].rank, grades[j].title, grades[j].rank)
+	}
+	if err := sink.Write(c.Name()+"_grades", gradeTable(grades, *percentile, *top)); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	tic.Toc()
+}
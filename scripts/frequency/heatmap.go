@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// heatmap is the per-section frequency data of one corpus, shaped for the
+// word detail view of the app. All arrays are aligned with Sections.
+type heatmap struct {
+	Corpus   string   `json:"corpus"`
+	Sections []string `json:"sections"`
+	// Tokens is the size of each section, so cells can be compared.
+	Tokens []int                   `json:"tokens"`
+	Words  map[string]heatmapEntry `json:"words"`
+}
+
+// heatmapEntry is the blob for one word.
+type heatmapEntry struct {
+	Count      int       `json:"count"`
+	Rank       int       `json:"rank"`
+	Counts     []int     `json:"counts"`
+	PerMillion []float64 `json:"per_million"`
+}
+
+// buildHeatmap spreads the per-file counts of c over the section grid and
+// keeps the top words of the sections; top <= 0 keeps them all. Files
+// outside the grid are left out.
+func buildHeatmap(c corpora.Corpus, files map[string]map[string]int, top int) heatmap {
+	h := heatmap{
+		Corpus:   c.Name(),
+		Sections: corpora.Sections,
+		Tokens:   make([]int, len(corpora.Sections)),
+		Words:    make(map[string]heatmapEntry),
+	}
+	cells := make(map[string][]int)
+	total := make(map[string]int)
+	for _, path := range slices.Sorted(maps.Keys(files)) {
+		i := slices.Index(corpora.Sections, corpora.SectionOf(c, path))
+		if i < 0 {
+			continue
+		}
+		for w, n := range files[path] {
+			row := cells[w]
+			if row == nil {
+				row = make([]int, len(corpora.Sections))
+				cells[w] = row
+			}
+			row[i] += n
+			total[w] += n
+			h.Tokens[i] += n
+		}
+	}
+
+	list := freq.Sorted(total)
+	if top > 0 && len(list) > top {
+		list = list[:top]
+	}
+	for rank, wc := range list {
+		row := cells[wc.Word]
+		pm := make([]float64, len(row))
+		for i, n := range row {
+			pm[i] = freq.PerMillion(n, h.Tokens[i])
+		}
+		h.Words[wc.Word] = heatmapEntry{Count: wc.Count, Rank: rank + 1, Counts: row, PerMillion: pm}
+	}
+	return h
+}
+
+// runHeatmap implements the heatmap subcommand: it writes, per corpus, the
+// counts of each word across the Tipiṭaka sections, from the counts of the
+// last run.
+func runHeatmap(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("heatmap", flag.ExitOnError)
+	commandUsage(fs, "Writes per-word frequency heatmaps across the Tipiṭaka sections, from the counts of the last run.")
+	names := fs.String("corpora", "cst", "comma-separated corpora to map (cst, vri and bjt know their sections)")
+	top := fs.Int("top", 10000, "number of most frequent words to include; 0 includes every word")
+	fs.Parse(args)
+
+	tools.PTitle("saving frequency heatmaps")
+	tic := tools.Tic()
+	for _, name := range strings.Split(*names, ",") {
+		c, ok := corpora.Get(name)
+		if !ok {
+			tools.Errorf("unknown corpus %q", name)
+			continue
+		}
+		if _, ok := c.(corpora.SectionTagger); !ok {
+			tools.Warnf("%s: the edition's files cannot be placed in sections; skipped", name)
+			continue
+		}
+		files, err := loadFileCounts(name)
+		if err != nil {
+			tools.Errorf("%s: %v (count it first)", name, err)
+			continue
+		}
+		h := buildHeatmap(c, files, *top)
+		if err := saveHeatmap(filepath.Join(freqDir, name+"_heatmap.json"), h); err != nil {
+			tools.Errorf("%v", err)
+			continue
+		}
+		placed := 0
+		for _, n := range h.Tokens {
+			placed += n
+		}
+		tools.Infof("%s: %d words, %d of %d tokens in the grid", name, len(h.Words), placed, cacheTokens(files))
+	}
+	tic.Toc()
+}
+
+// cacheTokens is the number of tokens in the per-file counts.
+func cacheTokens(files map[string]map[string]int) int {
+	n := 0
+	for _, counts := range files {
+		n += freq.TokenTotal(counts)
+	}
+	return n
+}
+
+func saveHeatmap(path string, h heatmap) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("heatmap %s: %w", h.Corpus, err)
+	}
+	return tools.WriteFileAtomic(path, append(data, '\n'), 0o644)
+}
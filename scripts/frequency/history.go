@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// recordHistory appends the summary and the top p.historyTop words of the
+// corpus saved under name to the history database, under the checksum of
+// its files, the version of palifreq and the normalizer chain.
+func (p *pipeline) recordHistory(name string, cc *freq.Table, counts map[string]int) error {
+	run := export.HistoryRun{
+		Corpus:     name,
+		Snapshot:   corpusChecksum(name),
+		Normalizer: p.tok.Normalizer.String(),
+		Created:    time.Now().UTC().Format(time.RFC3339),
+		Files:      len(cc.Files),
+		Books:      len(cc.Books),
+		Tokens:     freq.TokenTotal(counts),
+		Types:      len(counts),
+	}
+	version, commit, dirty := buildInfo()
+	run.Version, run.Commit = version, commit
+	if dirty {
+		run.Commit += "+dirty"
+	}
+	for _, n := range counts {
+		if n == 1 {
+			run.Hapaxes++
+		}
+	}
+	list := freq.Sorted(counts)
+	id, err := export.RecordRun(p.history, run, list[:min(len(list), p.historyTop)])
+	if err == nil {
+		tools.Debugf("%s: history run %d", name, id)
+	}
+	return err
+}
+
+// rankDrift is how the rank of a word moved between two runs of the
+// history. A word outside the top words of a run has rank 0 there.
+type rankDrift struct {
+	word               string
+	oldRank, newRank   int
+	oldCount, newCount int
+}
+
+// drift is the number of ranks the word rose by, counting a word missing
+// from a list of size n as ranked n+1 in it.
+func (d rankDrift) drift(oldSize, newSize int) int {
+	o, n := d.oldRank, d.newRank
+	if o == 0 {
+		o = oldSize + 1
+	}
+	if n == 0 {
+		n = newSize + 1
+	}
+	return o - n
+}
+
+// status tells whether the word entered or left the top words, or "" for
+// one in both.
+func (d rankDrift) status() string {
+	switch {
+	case d.oldRank == 0:
+		return "entered"
+	case d.newRank == 0:
+		return "left"
+	}
+	return ""
+}
+
+// rankDrifts pairs the words of two ranked lists, those moving most first,
+// then by new rank.
+func rankDrifts(old, new []freq.WordCount) []rankDrift {
+	byWord := make(map[string]*rankDrift)
+	var list []*rankDrift
+	for i, wc := range old {
+		d := &rankDrift{word: wc.Word, oldRank: i + 1, oldCount: wc.Count}
+		byWord[wc.Word] = d
+		list = append(list, d)
+	}
+	for i, wc := range new {
+		d := byWord[wc.Word]
+		if d == nil {
+			d = &rankDrift{word: wc.Word}
+			list = append(list, d)
+		}
+		d.newRank, d.newCount = i+1, wc.Count
+	}
+	drifts := make([]rankDrift, len(list))
+	for i, d := range list {
+		drifts[i] = *d
+	}
+	abs := func(d rankDrift) int {
+		n := d.drift(len(old), len(new))
+		return max(n, -n)
+	}
+	newRank := func(d rankDrift) int {
+		if d.newRank == 0 {
+			return len(new) + 1
+		}
+		return d.newRank
+	}
+	sort.SliceStable(drifts, func(i, j int) bool {
+		if a, b := abs(drifts[i]), abs(drifts[j]); a != b {
+			return a > b
+		}
+		if a, b := newRank(drifts[i]), newRank(drifts[j]); a != b {
+			return a < b
+		}
+		return drifts[i].oldRank < drifts[j].oldRank
+	})
+	return drifts
+}
+
+// topOverlap is how many of the first n words of old are among the first n
+// of new, n cut to the shorter list.
+func topOverlap(old, new []freq.WordCount, n int) (kept, size int) {
+	size = min(n, len(old), len(new))
+	top := make(map[string]bool, size)
+	for _, wc := range new[:size] {
+		top[wc.Word] = true
+	}
+	for _, wc := range old[:size] {
+		if top[wc.Word] {
+			kept++
+		}
+	}
+	return kept, size
+}
+
+// historyRunsTable lists the runs of a corpus in the history, oldest first.
+func historyRunsTable(runs []export.HistoryRun) table {
+	t := table{columns: []string{"run", "corpus", "snapshot", "version", "commit", "normalizer", "created", "files", "books", "tokens", "types", "hapaxes"}}
+	for _, r := range runs {
+		t.rows = append(t.rows, []any{r.ID, r.Corpus, r.Snapshot, r.Version, r.Commit, r.Normalizer, r.Created, r.Files, r.Books, r.Tokens, r.Types, r.Hapaxes})
+	}
+	return t
+}
+
+// historyDriftTable lists the words whose rank moved by at least minDrift,
+// or entered or left the top words, with their ranks and counts in both
+// runs; a rank or count is empty in the run the word is missing from.
+func historyDriftTable(drifts []rankDrift, oldSize, newSize, minDrift int) table {
+	t := table{columns: []string{"word", "old_rank", "new_rank", "drift", "status", "old_count", "new_count"}}
+	blank := func(n int) any {
+		if n == 0 {
+			return ""
+		}
+		return n
+	}
+	for _, d := range drifts {
+		n := d.drift(oldSize, newSize)
+		if d.status() == "" && max(n, -n) < minDrift {
+			continue
+		}
+		t.rows = append(t.rows, []any{d.word, blank(d.oldRank), blank(d.newRank), n, d.status(), blank(d.oldCount), blank(d.newCount)})
+	}
+	return t
+}
+
+// findRun returns the run of runs with the id or, when id is 0, the last
+// one before the run with the id before, or the last of all when before
+// is 0.
+func findRun(runs []export.HistoryRun, id, before int64) (export.HistoryRun, bool) {
+	var found export.HistoryRun
+	ok := false
+	for _, r := range runs {
+		if id == 0 && (before == 0 || r.ID < before) || r.ID == id {
+			found, ok = r, true
+		}
+	}
+	return found, ok
+}
+
+// runHistory implements the history subcommand: the runs of a corpus
+// recorded by -history, and how the ranks of its top words drifted from
+// one run to another, so that the decks of the app can be kept stable
+// across releases of the corpora and of palifreq.
+func runHistory(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	commandUsage(fs, "Lists the runs of a corpus recorded by -history and how the ranks of its top words drifted between two of them, by default the last two.")
+	path := fs.String("db", "history.db", "history database written by -history")
+	corpus := fs.String("corpus", "cst", "corpus whose runs to compare, as saved, e.g. cst or cst_mul")
+	from := fs.Int64("from", 0, "run to compare from (default: the one before -to)")
+	to := fs.Int64("to", 0, "run to compare to (default: the last)")
+	minDrift := fs.Int("min-drift", 1, "ranks a word of both runs must move by to be listed")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("comparing the history of " + *corpus)
+	tic := tools.Tic()
+	if _, err := os.Stat(*path); err != nil {
+		tools.Errorf("%v (record runs with -history first)", err)
+		return
+	}
+	db, err := export.OpenHistory(*path)
+	if err != nil {
+		tools.Errorf("%s: %v", *path, err)
+		return
+	}
+	defer export.Close(db)
+	runs, err := export.HistoryRuns(db, *corpus)
+	if err != nil {
+		tools.Errorf("%s: %v", *path, err)
+		return
+	}
+	if err := sink.Write("history_runs", historyRunsTable(runs)); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	newRun, ok := findRun(runs, *to, 0)
+	if !ok {
+		tools.Errorf("%s: no run %s of %s (%d recorded)", *path, runName(*to, "last"), *corpus, len(runs))
+		return
+	}
+	oldRun, ok := findRun(runs, *from, newRun.ID)
+	if !ok {
+		tools.Errorf("%s: no run %s of %s to compare run %d with (%d recorded)", *path, runName(*from, "before"), *corpus, newRun.ID, len(runs))
+		return
+	}
+	old, err := export.HistoryRanks(db, oldRun.ID)
+	var new []freq.WordCount
+	if err == nil {
+		new, err = export.HistoryRanks(db, newRun.ID)
+	}
+	if err != nil {
+		tools.Errorf("%s: %v", *path, err)
+		return
+	}
+	t := historyDriftTable(rankDrifts(old, new), len(old), len(new), *minDrift)
+	if err := sink.Write("history_drift", t); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	tools.Infof("run %d of %s to run %d of %s: %+d tokens, %+d types, %d words listed", oldRun.ID, oldRun.Version, newRun.ID, newRun.Version, newRun.Tokens-oldRun.Tokens, newRun.Types-oldRun.Types, len(t.rows))
+	for _, n := range []int{100, 1000, 10000} {
+		if kept, size := topOverlap(old, new, n); size == n {
+			tools.Infof("top %d: %d kept, %d replaced", n, kept, n-kept)
+		}
+	}
+	tic.Toc()
+}
+
+// runName names the run id of a flag in messages, or the default one.
+func runName(id int64, def string) string {
+	if id == 0 {
+		return "(" + def + ")"
+	}
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/frequency/freq"
+)
+
+func TestHistoryDrift(t *testing.T) {
+	db, err := export.OpenHistory(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer export.Close(db)
+
+	run := export.HistoryRun{Corpus: "cst", Snapshot: "a", Version: "v1", Normalizer: "nfc"}
+	old := []freq.WordCount{{Word: "ca", Count: 9}, {Word: "na", Count: 7}, {Word: "evaṃ", Count: 5}, {Word: "hoti", Count: 3}}
+	new := []freq.WordCount{{Word: "na", Count: 9}, {Word: "ca", Count: 8}, {Word: "hoti", Count: 6}, {Word: "bhikkhu", Count: 2}}
+	if _, err := export.RecordRun(db, run, old); err != nil {
+		t.Fatal(err)
+	}
+	// a rerun of the same release replaces the run, a new snapshot adds one
+	if _, err := export.RecordRun(db, run, old); err != nil {
+		t.Fatal(err)
+	}
+	run.Snapshot = "b"
+	id, err := export.RecordRun(db, run, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err := export.HistoryRuns(db, "cst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[1].ID != id {
+		t.Fatalf("runs = %+v, want 2 with the last %d", runs, id)
+	}
+	got, err := export.HistoryRanks(db, runs[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, old) {
+		t.Errorf("ranks of run %d = %v, want %v", runs[0].ID, got, old)
+	}
+
+	tab := historyDriftTable(rankDrifts(old, new), len(old), len(new), 1)
+	want := [][]any{
+		{"evaṃ", 3, "", -2, "left", 5, ""},
+		{"na", 2, 1, 1, "", 7, 9},
+		{"ca", 1, 2, -1, "", 9, 8},
+		{"hoti", 4, 3, 1, "", 3, 6},
+		{"bhikkhu", "", 4, 1, "entered", "", 2},
+	}
+	if len(tab.rows) != len(want) {
+		t.Fatalf("drift rows = %v, want %v", tab.rows, want)
+	}
+	for i, row := range tab.rows {
+		if !slices.Equal(row, want[i]) {
+			t.Errorf("drift row %d = %v, want %v", i, row, want[i])
+		}
+	}
+	if kept, size := topOverlap(old, new, 2); kept != 2 || size != 2 {
+		t.Errorf("top 2 overlap = %d of %d, want 2 of 2", kept, size)
+	}
+}
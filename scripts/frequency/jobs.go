@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// job is a job file: the steps of a data build, run in order by the run
+// subcommand.
+type job struct {
+	Steps []jobStep `toml:"step"`
+}
+
+// jobStep is one step of a job: a palifreq command with its flags, given
+// as options, and the files it reads and writes.
+type jobStep struct {
+	Name    string         `toml:"name"` // in the log; the command when empty
+	Command string         `toml:"command"`
+	Options map[string]any `toml:"options"` // flag name to value
+	Args    []string       `toml:"args"`    // after the flags
+	Inputs  []string       `toml:"inputs"`  // paths or globs that must exist before the step
+	Outputs []string       `toml:"outputs"` // paths or globs the step must write
+}
+
+// loadJob reads the job file at path and checks its steps: each names a
+// command other than run, under a name no other step has.
+func loadJob(path string) (job, error) {
+	var j job
+	md, err := toml.DecodeFile(path, &j)
+	if err != nil {
+		return j, err
+	}
+	if keys := md.Undecoded(); len(keys) > 0 {
+		return j, fmt.Errorf("unknown key %s", keys[0])
+	}
+	if len(j.Steps) == 0 {
+		return j, errors.New("no [[step]]")
+	}
+	seen := make(map[string]bool)
+	for i := range j.Steps {
+		s := &j.Steps[i]
+		if s.Name == "" {
+			s.Name = s.Command
+		}
+		switch {
+		case s.Command == "":
+			return j, fmt.Errorf("step %d: no command", i+1)
+		case s.Command == "run":
+			return j, fmt.Errorf("step %s: a job cannot run another", s.Name)
+		case jobCommands[s.Command] == nil:
+			return j, fmt.Errorf("step %s: unknown command %q", s.Name, s.Command)
+		case seen[s.Name]:
+			return j, fmt.Errorf("step %s given twice; name one of them", s.Name)
+		}
+		seen[s.Name] = true
+		if _, err := s.args(); err != nil {
+			return j, fmt.Errorf("step %s: %w", s.Name, err)
+		}
+	}
+	return j, nil
+}
+
+// jobCommands are the commands a step may run, by name. init fills it, as
+// commands holds runJob. A step runs its command in a palifreq process of
+// its own, so that what one step sets, and an exit, stay in it.
+var jobCommands = make(map[string]func(context.Context, []string))
+
+func init() {
+	for _, c := range commands {
+		if c.name != "run" {
+			jobCommands[c.name] = c.run
+		}
+	}
+}
+
+// args returns the command line of the step: its options as flags, in the
+// order of their names, then its args. A true option is given as -name, a
+// list as its items joined by commas.
+func (s jobStep) args() ([]string, error) {
+	var args []string
+	for _, name := range slices.Sorted(maps.Keys(s.Options)) {
+		var v string
+		switch x := s.Options[name].(type) {
+		case bool:
+			if x {
+				args = append(args, "-"+name)
+				continue
+			}
+			v = "false"
+		case string:
+			v = x
+		case int64, float64:
+			v = fmt.Sprint(x)
+		case []any:
+			items := make([]string, len(x))
+			for i, item := range x {
+				switch item.(type) {
+				case string, int64, float64:
+					items[i] = fmt.Sprint(item)
+				default:
+					return nil, fmt.Errorf("option %s: want a list of strings or numbers", name)
+				}
+			}
+			v = strings.Join(items, ",")
+		default:
+			return nil, fmt.Errorf("option %s: want a string, number, boolean or list, not %T", name, x)
+		}
+		args = append(args, "-"+name+"="+v)
+	}
+	return append(args, s.Args...), nil
+}
+
+// jobFile is a file a step read or wrote, in the log of a run.
+type jobFile struct {
+	Path   string `json:"path"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256,omitempty"` // empty for a directory
+}
+
+// jobStepLog is the log of one step of a run.
+type jobStepLog struct {
+	Name    string    `json:"name"`
+	Command string    `json:"command"`
+	Args    []string  `json:"args"`
+	Started string    `json:"started"` // RFC 3339, UTC
+	Seconds float64   `json:"seconds"`
+	Status  string    `json:"status"` // running, ok, failed or interrupted
+	Inputs  []jobFile `json:"inputs,omitempty"`
+	Outputs []jobFile `json:"outputs,omitempty"`
+}
+
+// jobLog is the log of a run of a job file, rewritten after every step, so
+// a build can be traced to the job, the palifreq and the files it used
+// even when it stopped halfway.
+type jobLog struct {
+	Job      string       `json:"job"`
+	JobSHA   string       `json:"job_sha256"`
+	Version  string       `json:"version"`
+	Commit   string       `json:"vcs_commit,omitempty"`
+	Started  string       `json:"started"`
+	Status   string       `json:"status"` // running, ok, failed or interrupted
+	Steps    []jobStepLog `json:"steps"`
+	logPath  string
+	writeErr bool
+}
+
+// save writes the log to its file, warning once when it cannot.
+func (l *jobLog) save() {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err == nil {
+		err = tools.WriteFileAtomic(l.logPath, append(data, '\n'), 0o644)
+	}
+	if err != nil && !l.writeErr {
+		tools.Warnf("run log: %v", err)
+		l.writeErr = true
+	}
+}
+
+// jobFiles lists the files the globs match, sorted, with their sizes and,
+// when hash is set, their SHA-256. newer, when not zero, is the time the
+// files must have been modified since. A glob that matches nothing, or no
+// file new enough, is an error.
+func jobFiles(globs []string, hash bool, newer time.Time) ([]jobFile, error) {
+	var files []jobFile
+	for _, g := range globs {
+		paths, err := filepath.Glob(g)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", g, err)
+		}
+		sort.Strings(paths)
+		found := false
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if !newer.IsZero() && info.ModTime().Before(newer) {
+				continue
+			}
+			found = true
+			f := jobFile{Path: filepath.ToSlash(path), Bytes: info.Size()}
+			if info.IsDir() {
+				f.Bytes = 0
+			} else if hash {
+				if f.SHA256, err = freq.HashFile(path); err != nil {
+					return nil, err
+				}
+			}
+			files = append(files, f)
+		}
+		switch {
+		case len(paths) == 0:
+			return nil, fmt.Errorf("%s: not found", g)
+		case !found:
+			return nil, fmt.Errorf("%s: not written by the step", g)
+		}
+	}
+	return files, nil
+}
+
+// runStep runs command with args as a step: the running palifreq, in a
+// process of its own with the standard streams of this one. When ctx is
+// done, the step is interrupted as by Ctrl-C, to stop as palifreq stops.
+func runStep(ctx context.Context, command string, args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, exe, append([]string{command}, args...)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	return cmd.Run()
+}
+
+// runJob implements the run subcommand: the steps of a job file run in
+// order as palifreq commands, each checked for its inputs before and its
+// outputs after, stopping at the first that fails, with a log of what
+// each read and wrote, so a full data build is reproducible from one
+// committed file.
+func runJob(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	commandUsage(fs, "Runs the steps of the job file JOB.toml, given after the flags, in order: palifreq commands with their options, each checked for the inputs and outputs it declares, stopping at the first step that fails. The log of the run records the checksums of those files.")
+	logPath := fs.String("log", "", "file to write the log of the run to (default: <output dir>/runs/<job>-<time>.json)")
+	from := fs.String("from", "", "step to start at, skipping those before it")
+	dryRun := fs.Bool("dry-run", false, "list the command lines of the steps without running them")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		tools.Errorf("run needs one job file, after the flags")
+		return
+	}
+	path := fs.Arg(0)
+
+	tools.PTitle("running the job " + path)
+	tic := tools.Tic()
+	j, err := loadJob(path)
+	if err != nil {
+		tools.Errorf("%s: %v", path, err)
+		return
+	}
+	start := 0
+	if *from != "" {
+		start = slices.IndexFunc(j.Steps, func(s jobStep) bool { return s.Name == *from })
+		if start < 0 {
+			tools.Errorf("%s: no step %s", path, *from)
+			return
+		}
+	}
+	if *dryRun {
+		for _, s := range j.Steps[start:] {
+			a, _ := s.args()
+			tools.Infof("%s: palifreq %s %s", s.Name, s.Command, strings.Join(a, " "))
+		}
+		return
+	}
+
+	started := time.Now().UTC()
+	l := &jobLog{Job: filepath.ToSlash(path), Started: started.Format(time.RFC3339), Status: "running", logPath: *logPath}
+	if l.JobSHA, err = freq.HashFile(path); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	version, commit, dirty := buildInfo()
+	l.Version, l.Commit = version, commit
+	if dirty {
+		l.Commit += "+dirty"
+	}
+	if l.logPath == "" {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		l.logPath = filepath.Join(freqDir, "runs", name+"-"+started.Format("20060102T150405Z")+".json")
+	}
+	if err := os.MkdirAll(filepath.Dir(l.logPath), 0o755); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer func() {
+		l.save()
+		tools.Infof("run log: %s", l.logPath)
+	}()
+
+	for i, s := range j.Steps[start:] {
+		a, _ := s.args()
+		sl := jobStepLog{Name: s.Name, Command: s.Command, Args: a, Status: "running"}
+		tools.PTitle(fmt.Sprintf("step %d/%d: %s", start+i+1, len(j.Steps), s.Name))
+		tools.Infof("palifreq %s %s", s.Command, strings.Join(a, " "))
+		inputs, err := jobFiles(s.Inputs, true, time.Time{})
+		if err != nil {
+			tools.Errorf("%s: input %v", s.Name, err)
+			l.Status = "failed"
+			return
+		}
+		sl.Inputs = inputs
+		// outputs must be newer than the step; file times may be coarser
+		// than the clock
+		begun := time.Now()
+		sl.Started = begun.UTC().Format(time.RFC3339)
+		l.Steps = append(l.Steps, sl)
+		l.save()
+
+		err = runStep(ctx, s.Command, a)
+		cur := &l.Steps[len(l.Steps)-1]
+		cur.Seconds = time.Since(begun).Seconds()
+		var exit *exec.ExitError
+		switch {
+		case ctx.Err() != nil || errors.As(err, &exit) && exit.ExitCode() == 130:
+			cur.Status, l.Status = "interrupted", "interrupted"
+			return
+		case err != nil:
+			cur.Status, l.Status = "failed", "failed"
+			tools.Errorf("run: step %s failed (%v); stopping", s.Name, err)
+			return
+		}
+		if cur.Outputs, err = jobFiles(s.Outputs, true, begun.Truncate(time.Second)); err != nil {
+			cur.Status, l.Status = "failed", "failed"
+			tools.Errorf("%s: output %v", s.Name, err)
+			return
+		}
+		cur.Status = "ok"
+		for _, f := range cur.Outputs {
+			tools.Debugf("%s: wrote %s (%s)", s.Name, f.Path, byteSize(f.Bytes))
+		}
+		l.save()
+	}
+	l.Status = "ok"
+	tools.Infof("%s: %d steps done", path, len(j.Steps)-start)
+	tic.Toc()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"dpd/go_modules/tools"
+)
+
+// TestMain runs the test binary as palifreq when it runs a step of a job.
+func TestMain(m *testing.M) {
+	if os.Getenv("PALIFREQ_TEST_STEP") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestLoadJob(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	j, err := loadJob(write("build.toml", `
+[[step]]
+command = "freq"
+args    = ["-verbose"]
+[step.options]
+corpora = ["cst", "bjt"]
+lemmas  = true
+strict  = false
+ngrams  = "2,3"
+jobs    = 4
+
+[[step]]
+name    = "bank"
+command = "sentence-bank"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.Steps) != 2 || j.Steps[0].Name != "freq" || j.Steps[1].Name != "bank" {
+		t.Fatalf("steps = %+v", j.Steps)
+	}
+	got, _ := j.Steps[0].args()
+	want := []string{"-corpora=cst,bjt", "-jobs=4", "-lemmas", "-ngrams=2,3", "-strict=false", "-verbose"}
+	if !slices.Equal(got, want) {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+
+	for text, msg := range map[string]string{
+		"[[step]]\ncommand = \"nosuch\"\n":                             "unknown command",
+		"[[step]]\ncommand = \"run\"\n":                                "cannot run another",
+		"[[step]]\ncommand = \"freq\"\n[[step]]\ncommand = \"freq\"\n": "given twice",
+		"[[step]]\ncommand = \"freq\"\ninput = [\"dpd.db\"]\n":         "unknown key",
+		"[[step]]\ncommand = \"freq\"\noptions = { x = 2025-05-01 }\n": "option x",
+		"": "no [[step]]",
+	} {
+		if _, err := loadJob(write("bad.toml", text)); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("loadJob(%q) = %v, want an error with %q", text, err, msg)
+		}
+	}
+}
+
+func TestJobFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.tsv")
+	if err := os.WriteFile(old, []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+	files, err := jobFiles([]string{filepath.Join(dir, "*.tsv")}, true, time.Time{})
+	if err != nil || len(files) != 1 || files[0].Bytes != 2 || len(files[0].SHA256) != 64 {
+		t.Errorf("jobFiles = %+v, %v; want old.tsv with its hash", files, err)
+	}
+	if _, err := jobFiles([]string{old}, false, time.Now().Add(-time.Minute)); err == nil || !strings.Contains(err.Error(), "not written") {
+		t.Errorf("jobFiles of a file older than the step: %v, want not written", err)
+	}
+	if _, err := jobFiles([]string{filepath.Join(dir, "new.tsv")}, false, time.Time{}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("jobFiles of a missing file: %v, want not found", err)
+	}
+}
+
+func TestRunJob(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("PALIFREQ_TEST_STEP", "1")
+	t.Setenv("PALIFREQ_CORPUS_SYA", filepath.Join(dir, "nosya"))
+	if err := os.Mkdir("texts", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("texts", "a.txt"), []byte("evaṃ me sutaṃ. ekaṃ samayaṃ bhagavā.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(text string) jobLog {
+		t.Helper()
+		if err := os.WriteFile("job.toml", []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		runJob(context.Background(), []string{"-log", "run.json", "job.toml"})
+		data, err := os.ReadFile("run.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var l jobLog
+		if err := json.Unmarshal(data, &l); err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+
+	// each step registers the custom corpus afresh
+	l := run(`
+[[step]]
+name    = "first"
+command = "freq"
+outputs = ["shared_data/frequency/x_freq.tsv"]
+[step.options]
+custom-corpus = "x=texts"
+corpora       = "x"
+
+[[step]]
+name    = "second"
+command = "freq"
+outputs = ["shared_data/frequency/x_freq.tsv"]
+[step.options]
+custom-corpus = "x=texts"
+corpora       = "x"
+force         = true
+`)
+	if l.Status != "ok" || len(l.Steps) != 2 || l.Steps[1].Status != "ok" {
+		t.Errorf("custom corpus twice: status %s, steps %+v; want both ok", l.Status, l.Steps)
+	}
+
+	// a step that exits fails the run, which records it and stops
+	before := tools.Errors()
+	l = run(`
+[[step]]
+name    = "strict"
+command = "freq"
+[step.options]
+corpora = "sya"
+strict  = true
+
+[[step]]
+name    = "after"
+command = "freq"
+`)
+	if l.Status != "failed" || len(l.Steps) != 1 || l.Steps[0].Status != "failed" {
+		t.Errorf("strict step: status %s, steps %+v; want the first failed", l.Status, l.Steps)
+	}
+	if tools.Errors() == before {
+		t.Errorf("failed step logged no error")
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import "dpd/go_modules/frequency/freq"
+
+// saveLemmaFreq writes the table <name>_lemma_freq to s, with the
+// columns headword_id, lemma (DPD lemma_1), count, rank and per_million.
+// per_million is relative to the corpus's tokens, as lemma counts overlap.
+func saveLemmaFreq(s Sink, name string, list []freq.LemmaCount, tokens int) error {
+	t := table{columns: []string{"headword_id", "lemma", "count", "rank", "per_million"}}
+	for i, lc := range list {
+		t.rows = append(t.rows, []any{lc.Headword.ID, lc.Headword.Lemma1, lc.Count, i + 1, freq.PerMillion(lc.Count, tokens)})
+	}
+	return s.Write(name+"_lemma_freq", t)
+}
@@ -0,0 +1,219 @@
+// Command palifreq counts word frequencies in the Pāḷi text editions and
+// writes the tables, word lists and database rows the app is built from.
+//
+//	palifreq freq        frequency tables, word lists and the master list
+//	palifreq wordlist    word lists only
+//	palifreq export      frequency rows in a SQLite database
+//	palifreq compare     words unique to one edition
+//	palifreq concordance keyword-in-context snippets
+//	palifreq build-search full-text search table of the corpus texts
+//	palifreq structure   nikāya, book, vagga and sutta of each CST paragraph
+//	palifreq sentence-bank short sentences of the top headwords for cloze cards
+//	palifreq endings     ending frequencies from DPD inflection templates
+//	palifreq forms       form frequencies of each headword's paradigm
+//	palifreq collocations strongest collocates of the top headwords
+//	palifreq study       top headwords with their DPD glosses
+//	palifreq anki        the study list as an Anki deck
+//	palifreq score       headwords ranked by learning value
+//	palifreq roots       DPD roots ranked by the counts of their verbs and derivatives
+//	palifreq heatmap     per-word counts across the Tipiṭaka sections
+//	palifreq ebt         EBT word and headword counts in the format of DPD's build
+//	palifreq gazetteer   people and places with their spellings and counts
+//	palifreq stopwords   function-word candidates for -exclude
+//	palifreq rare        hapaxes and rare words with their files
+//	palifreq charset     words outside the Roman Pāḷi character set, with fixes
+//	palifreq segmentation words split by line-break hyphens and stray spaces
+//	palifreq contamination Sanskrit and English spans of the corpora, by file
+//	palifreq coverage    share of a text the top words of a list cover
+//	palifreq count-stdin words of the text piped in, with their coverage
+//	palifreq grade       suttas ordered by the difficulty of their vocabulary
+//	palifreq orthography niggahīta and vowel-length differences between the editions
+//	palifreq minimal-pairs word pairs differing only in vowel length or retroflex letters
+//	palifreq diff        count changes between two output sets
+//	palifreq history     rank drift between the runs recorded by -history
+//	palifreq stats       word length, syllable and character statistics
+//	palifreq crosscheck  count differences between two script editions
+//	palifreq correlate   rank correlations of the counts between corpora
+//	palifreq align       paragraph pairs of the same suttas in parallel editions
+//	palifreq serve       JSON HTTP API over the tables of the last run
+//	palifreq explore     interactive word lookup in a database of the outputs
+//	palifreq run         the steps of a job file, for reproducible data builds
+//	palifreq bundle      versioned, compressed data files for an app release
+//	palifreq parquet     database tables as Parquet files
+//	palifreq download    corpus sources from their archives
+//	palifreq selftest    the pipeline checked on a built-in sample corpus
+//
+// Run "palifreq <command> -h" for the flags of a command. Without a
+// command, palifreq runs freq.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/tools"
+)
+
+var (
+	cfg     config // loaded from palifreq.toml and the environment
+	freqDir string // output directory, cfg.OutputDir
+)
+
+// registerCorpora registers the editions at their configured directories,
+// with the cleaning rules of cfg.CleaningDir where it has some.
+func registerCorpora() error {
+	corpora.Register(corpora.NewCst(cfg.Corpora["cst"]))
+	corpora.Register(corpora.NewBjt(cfg.Corpora["bjt"]))
+	corpora.Register(corpora.NewSya(cfg.Corpora["sya"]))
+	corpora.Register(corpora.NewVri(cfg.Corpora["vri"]))
+	corpora.Register(corpora.NewSyaThai(cfg.Corpora["sya_thai"]))
+	corpora.Register(corpora.NewBjtSinhala(cfg.Corpora["bjt_sinh"]))
+	corpora.Register(corpora.NewCstMyanmar(cfg.Corpora["cst_mymr"]))
+	corpora.Register(corpora.NewCstDevanagari(cfg.Corpora["cst_deva"]))
+	corpora.Register(corpora.NewKhmer(cfg.Corpora["khmer"]))
+	if cfg.CleaningDir == "" {
+		return nil
+	}
+	for _, c := range corpora.Registered() {
+		cc, ok := c.(corpora.Cleaned)
+		if !ok {
+			continue
+		}
+		cleaner, _, err := corpora.LoadCleaner(c.Name(), cfg.CleaningDir)
+		if err != nil {
+			return err
+		}
+		cc.SetCleaner(cleaner)
+	}
+	return nil
+}
+
+// command is one palifreq subcommand; run gets the arguments after its
+// name and a context canceled on interrupt.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string)
+}
+
+var commands = []command{
+	{"freq", "count corpora into frequency tables, word lists and the master list", runFreq},
+	{"wordlist", "count corpora into word lists only", runWordlist},
+	{"export", "count corpora into frequency tables of a SQLite database", runExport},
+	{"compare", "list the words only one edition has", runCompare},
+	{"concordance", "store keyword-in-context snippets in a SQLite database", runConcordance},
+	{"build-search", "load the corpus texts into an FTS5 search table of a SQLite database", runBuildSearch},
+	{"structure", "load the nikāya, book, vagga and sutta structure of the CST XML sources into a SQLite database", runStructure},
+	{"sentence-bank", "store short sentences of the top headwords for cloze cards in a SQLite database", runSentenceBank},
+	{"endings", "count inflectional endings of the counted forms", runEndings},
+	{"forms", "count the forms of the inflection tables of the top headwords", runForms},
+	{"collocations", "list the words most strongly associated with each top headword", runCollocations},
+	{"study", "list the top headwords with their DPD glosses", runStudy},
+	{"anki", "write the top headwords as an Anki deck (.apkg)", runAnki},
+	{"score", "rank the headwords by learning value for the card scheduler", runScore},
+	{"roots", "rank the DPD roots by the counts of their verbs, with the most common forms under each", runRoots},
+	{"heatmap", "write per-word frequency heatmaps across the Tipiṭaka sections", runHeatmap},
+	{"ebt", "write the Early Buddhist Text counts in the format of DPD's frequency build", runEbt},
+	{"gazetteer", "write the people and places of the texts with their spelling variants and counts", runGazetteer},
+	{"stopwords", "propose function words to leave out of learner word lists", runStopwords},
+	{"rare", "list hapaxes and rare words with the files they occur in", runRare},
+	{"charset", "list the words outside the Roman Pāḷi character set by file, with fixes", runCharset},
+	{"segmentation", "list the words split by line-break hyphens and the short fragments DPD does not know, by file", runSegmentation},
+	{"contamination", "list the Sanskrit and English spans of the corpora, which counting drops, by file", runContamination},
+	{"coverage", "report how much of a text the top words of a frequency list cover", runCoverage},
+	{"count-stdin", "count the words of the text piped in and how much of it a frequency list covers", runCountStdin},
+	{"grade", "order the suttas of each book from the easiest vocabulary to the hardest", runGrade},
+	{"orthography", "report the niggahīta and vowel-length differences between the editions", runOrthography},
+	{"minimal-pairs", "list the word pairs that differ only in vowel length or retroflex against dental letters, for drills", runMinimalPairs},
+	{"diff", "compare the frequency tables of two runs", runDiff},
+	{"history", "list the runs recorded by -history and the rank drift of the top words between two", runHistory},
+	{"stats", "write word length, syllable and character statistics", runStats},
+	{"crosscheck", "compare two script editions of a text file by file", runCrossCheck},
+	{"correlate", "write the rank correlations of the word frequencies between corpora", runCorrelate},
+	{"align", "pair the paragraphs of the same suttas in parallel editions", runAlign},
+	{"serve", "serve the frequency tables of the last run as a JSON HTTP API", runServe},
+	{"explore", "look words up interactively in a SQLite database of the outputs", runExplore},
+	{"parquet", "write the frequency, citation and sentence tables of a SQLite database as Parquet files", runParquet},
+	{"bundle", "run the pipeline and assemble the app's data files into a versioned bundle", runBundle},
+	{"run", "run the steps of a job file in order, logging what each reads and writes", runJob},
+	{"download", "fetch, verify and unpack corpus archives", runDownload},
+	{"selftest", "count a built-in sample corpus and check the outputs against the expected ones", runSelftest},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: palifreq <command> [flags]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\ncorpora: %s\n", strings.Join(corpusNames(), ", "))
+}
+
+// interruptContext returns a context canceled by the first SIGINT or
+// SIGTERM. Counting stops at it and outputs are only ever replaced whole,
+// so an interrupted run leaves each file either as it was or complete; a
+// second signal quits at once.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		signal.Stop(sig)
+		tools.Warnf("interrupted; stopping (interrupt again to quit at once)")
+		cancel()
+	}()
+	return ctx
+}
+
+func main() {
+	var err error
+	if cfg, err = loadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "palifreq: %v\n", err)
+		os.Exit(1)
+	}
+	freqDir = cfg.OutputDir
+	dpd.PinnedRelease = cfg.DPDRelease
+	if err := registerCorpora(); err != nil {
+		fmt.Fprintf(os.Stderr, "palifreq: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := interruptContext()
+	args := os.Args[1:]
+	// plain flags keep working as before: they are freq's
+	run := runFreq
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		run = nil
+		for _, c := range commands {
+			if c.name == args[0] {
+				run, args = c.run, args[1:]
+				break
+			}
+		}
+	}
+	if run != nil {
+		run(ctx, args)
+		// 128 + SIGINT, as shells report a command stopped by Ctrl-C
+		if ctx.Err() != nil {
+			os.Exit(130)
+		}
+		// the commands log their errors and return
+		if tools.Errors() > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if args[0] != "help" {
+		fmt.Fprintf(os.Stderr, "palifreq: unknown command %q\n\n", args[0])
+	}
+	usage()
+	if args[0] != "help" {
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"sort"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// masterCorpora are the editions merged into the master list, in column
+// order.
+var masterCorpora = []string{"cst", "bjt", "sya"}
+
+// masterColumns are the corpora with a count column in the master list:
+// the editions, then the custom corpora.
+func masterColumns() []string {
+	return append(slices.Clip(masterCorpora), customCorpora...)
+}
+
+type wordScore struct {
+	Word  string
+	Score float64
+}
+
+// masterScores merges corpus frequencies into one score per word: the sum
+// over corpora of weight × occurrences per million tokens of that corpus.
+// Using relative frequencies keeps the larger editions from drowning out
+// the smaller ones; the weights then say how much each edition counts.
+func masterScores(counts map[string]map[string]int, weights map[string]float64) []wordScore {
+	scores := make(map[string]float64)
+	// corpora are added in a fixed order so equal inputs give equal sums
+	for _, name := range slices.Sorted(maps.Keys(counts)) {
+		m := counts[name]
+		w := weights[name]
+		total := freq.TokenTotal(m)
+		if w == 0 || total == 0 {
+			continue
+		}
+		for word, n := range m {
+			scores[word] += w * float64(n) * 1e6 / float64(total)
+		}
+	}
+	list := make([]wordScore, 0, len(scores))
+	for word, s := range scores {
+		list = append(list, wordScore{word, s})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return tools.ComparePali(list[i].Word, list[j].Word) < 0
+	})
+	return list
+}
+
+// saveMasterList writes the table <name>_freq to s: the top words of
+// masterScores (all of them when top is 0) with their rank, score and raw
+// count in each merged corpus.
+func saveMasterList(s Sink, name string, counts map[string]map[string]int, weights map[string]float64, top int) error {
+	list := masterScores(counts, weights)
+	if top > 0 && len(list) > top {
+		list = list[:top]
+	}
+	columns := masterColumns()
+	t := table{columns: append([]string{"word", "rank", "score"}, columns...)}
+	for i, ws := range list {
+		row := []any{ws.Word, i + 1, math.Round(ws.Score*1e4) / 1e4}
+		for _, name := range columns {
+			row = append(row, counts[name][ws.Word])
+		}
+		t.rows = append(t.rows, row)
+	}
+	return s.Write(name+"_freq", t)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// metaExt is added to the path of an output for that of its sidecar.
+const metaExt = ".meta.json"
+
+// artifactMeta is the sidecar <output>.meta.json written next to the
+// table files and word lists: what made the output and from which counts,
+// so two outputs can be told apart by more than their contents.
+type artifactMeta struct {
+	Tool    string `json:"tool"`
+	Version string `json:"version"`          // of the palifreq module, "(devel)" for a source build
+	Commit  string `json:"commit,omitempty"` // of the source tree it was built from
+	Dirty   bool   `json:"dirty,omitempty"`  // the tree had uncommitted changes
+	Created string `json:"created"`          // RFC 3339, UTC
+	// the normalizer steps the counts were made with, in order
+	Normalizer []string `json:"normalizer"`
+	// checksum of each corpus the output comes from, by label, as
+	// corpusChecksum gives it
+	Corpora map[string]string `json:"corpora"`
+	// the selection of the files counted, when not all of them
+	Filters *runFilters `json:"filters,omitempty"`
+	Bytes   int64       `json:"bytes"`
+	SHA256  string      `json:"sha256"` // of the output
+}
+
+// runFilters are the -layers, -include-files and -exclude-files of a run.
+type runFilters struct {
+	Layers  string   `json:"layers,omitempty"` // the layer keys, e.g. "mul,att"
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// String is the filters as the flags give them.
+func (f *runFilters) String() string {
+	if f == nil {
+		return "none"
+	}
+	var parts []string
+	if f.Layers != "" {
+		parts = append(parts, "-layers "+f.Layers)
+	}
+	for _, p := range f.Include {
+		parts = append(parts, "-include-files "+p)
+	}
+	for _, p := range f.Exclude {
+		parts = append(parts, "-exclude-files "+p)
+	}
+	return strings.Join(parts, " ")
+}
+
+// buildInfo returns the module version and the VCS revision palifreq was
+// built from, as the go command stamps them.
+func buildInfo() (version, commit string, dirty bool) {
+	version = "(unknown)"
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version, "", false
+	}
+	version = info.Main.Version
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			commit = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	return version, commit, dirty
+}
+
+// sources records what the outputs of this process come from: the
+// corpora counted or read from the file caches, and the normalizer chain
+// of the counts, that of the config unless a pipeline set its own.
+var sources struct {
+	sync.Mutex
+	corpora    map[string]bool
+	normalizer *pali.Normalizer
+	filters    *runFilters
+}
+
+// useCorpus records that outputs come from the counts of the corpus
+// label.
+func useCorpus(label string) {
+	sources.Lock()
+	defer sources.Unlock()
+	if sources.corpora == nil {
+		sources.corpora = make(map[string]bool)
+	}
+	sources.corpora[label] = true
+}
+
+// useNormalizer records the chain the corpora of this run are counted
+// with.
+func useNormalizer(nz pali.Normalizer) {
+	sources.Lock()
+	defer sources.Unlock()
+	sources.normalizer = &nz
+}
+
+// useFilters records the file selection of the corpora of this run: the
+// key of its layers and its filter, either of them empty.
+func useFilters(layerKey string, f *freq.Filter) {
+	sources.Lock()
+	defer sources.Unlock()
+	sources.filters = nil
+	if layerKey != "" || f != nil {
+		sources.filters = &runFilters{Layers: strings.ReplaceAll(layerKey, "_", ","), Include: f.Include(), Exclude: f.Exclude()}
+	}
+}
+
+// corpusChecksum is the checksum of the snapshot of the corpus label last
+// counted, from its file cache, or "" when it has none.
+func corpusChecksum(label string) string {
+	sum, err := freq.CacheChecksum(filepath.Join(freqDir, ".cache", label+".gob"))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			tools.Warnf("%s: no corpus checksum: %v", label, err)
+		}
+		return ""
+	}
+	return sum
+}
+
+// runMeta returns the metadata the outputs of this process share.
+func runMeta() artifactMeta {
+	m := artifactMeta{Tool: "palifreq", Created: time.Now().UTC().Format(time.RFC3339), Corpora: map[string]string{}}
+	m.Version, m.Commit, m.Dirty = buildInfo()
+	sources.Lock()
+	nz := cfg.tokenizer().Normalizer
+	if sources.normalizer != nil {
+		nz = *sources.normalizer
+	}
+	labels := slices.Sorted(maps.Keys(sources.corpora))
+	m.Filters = sources.filters
+	sources.Unlock()
+	for _, s := range nz.Steps() {
+		m.Normalizer = append(m.Normalizer, s.String())
+	}
+	for _, label := range labels {
+		if sum := corpusChecksum(label); sum != "" {
+			m.Corpora[label] = sum
+		}
+	}
+	return m
+}
+
+// sidecars collects the outputs written, whose sidecars write adds once
+// the run is done and the corpora it read are known. The nil *sidecars
+// collects nothing.
+type sidecars struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (s *sidecars) add(path string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paths = append(s.paths, path)
+}
+
+// write writes the sidecar of every output collected and forgets them.
+func (s *sidecars) write() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	paths := s.paths
+	s.paths = nil
+	s.mu.Unlock()
+	if len(paths) == 0 {
+		return nil
+	}
+	run := runMeta()
+	slices.Sort(paths)
+	for _, path := range slices.Compact(paths) {
+		m := run
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if m.SHA256, err = freq.HashFile(path); err != nil {
+			return err
+		}
+		m.Bytes = info.Size()
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := tools.WriteFileAtomic(path+metaExt, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+		wroteOutput(jobFile{Path: filepath.ToSlash(path), Bytes: m.Bytes, SHA256: m.SHA256})
+	}
+	return nil
+}
+
+// outputs are the outputs given sidecars by this process, in the order
+// they were, for the run summary.
+var outputs struct {
+	sync.Mutex
+	files []jobFile
+}
+
+// wroteOutput records the output f.
+func wroteOutput(f jobFile) {
+	outputs.Lock()
+	defer outputs.Unlock()
+	outputs.files = append(outputs.files, f)
+}
+
+// outputsSince returns the outputs recorded after the first n.
+func outputsSince(n int) []jobFile {
+	outputs.Lock()
+	defer outputs.Unlock()
+	return slices.Clone(outputs.files[min(n, len(outputs.files)):])
+}
+
+// readMeta reads the sidecar of the output at path; ok is false when it
+// has none.
+func readMeta(path string) (m artifactMeta, ok bool, err error) {
+	data, err := os.ReadFile(path + metaExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, false, nil
+	}
+	if err != nil {
+		return m, false, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, false, fmt.Errorf("%s: %w", path+metaExt, err)
+	}
+	return m, true, nil
+}
+
+// metaChanges describes how the runs that wrote two outputs differ, by
+// their sidecars: another build, normalizer chain or corpus snapshot. The
+// time and checksum of the outputs themselves are left out.
+func metaChanges(old, new artifactMeta) []string {
+	var changes []string
+	change := func(what, a, b string) {
+		if a != b {
+			changes = append(changes, what+": "+a+" → "+b)
+		}
+	}
+	build := func(m artifactMeta) string {
+		s := m.Version
+		if m.Commit != "" {
+			s += " " + m.Commit
+		}
+		if m.Dirty {
+			s += " (dirty)"
+		}
+		return s
+	}
+	change("palifreq", build(old), build(new))
+	change("normalizer", strings.Join(old.Normalizer, ","), strings.Join(new.Normalizer, ","))
+	change("filters", old.Filters.String(), new.Filters.String())
+	for _, label := range slices.Sorted(maps.Keys(old.Corpora)) {
+		if sum, ok := new.Corpora[label]; ok {
+			change(label+" corpus", short(old.Corpora[label]), short(sum))
+		}
+	}
+	return changes
+}
+
+// warnMixedChains warns when the frequency tables of the corpora names
+// in the output directory were counted with different normalizer chains,
+// by their sidecars, as their words are then not spelled alike.
+func warnMixedChains(names []string) {
+	tables, err := countTables(freqDir)
+	if err != nil {
+		return
+	}
+	chains := make(map[string][]string)
+	for _, name := range names {
+		path, ok := tables[name+"_freq"]
+		if !ok {
+			continue
+		}
+		if m, ok, err := readMeta(path); err == nil && ok {
+			chain := strings.Join(m.Normalizer, ",")
+			chains[chain] = append(chains[chain], name)
+		}
+	}
+	if len(chains) < 2 {
+		return
+	}
+	for _, chain := range slices.Sorted(maps.Keys(chains)) {
+		tools.Warnf("%s counted with the normalizer chain %s", strings.Join(chains[chain], ", "), chain)
+	}
+}
+
+// short cuts a hex checksum to its first 12 digits, enough to tell two
+// apart in a report.
+func short(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
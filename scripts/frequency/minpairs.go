@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"slices"
+	"strings"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// pairContrasts are the letters learners mis-hear and mis-type for one
+// another, each with the letter it is written as in a word's skeleton and
+// the contrast it is of: the long vowels against the short, the retroflex
+// consonants against the dental.
+var pairContrasts = map[rune]struct {
+	base     rune
+	contrast string
+}{
+	'ā': {'a', "length"}, 'ī': {'i', "length"}, 'ū': {'u', "length"},
+	'ṭ': {'t', "retroflex"}, 'ḍ': {'d', "retroflex"}, 'ṇ': {'n', "retroflex"}, 'ḷ': {'l', "retroflex"},
+}
+
+// pairSkeleton returns word with the long vowels written short and the
+// retroflex consonants dental: the words that share a skeleton differ in
+// those letters only. The aspirates keep their h, so ṭh goes to th.
+func pairSkeleton(word string) string {
+	return strings.Map(func(r rune) rune {
+		if c, ok := pairContrasts[r]; ok {
+			return c.base
+		}
+		return r
+	}, word)
+}
+
+// minimalPair is two counted words of one skeleton, the more frequent
+// first, with the contrasts and the letters they differ in.
+type minimalPair struct {
+	a, b     freq.WordCount
+	contrast string // length, retroflex, or length+retroflex
+	letters  string // the differing letters in order, as "a/ā, t/ṭ"
+}
+
+// minimalPairs returns the pairs of the words of counts, each counted at
+// least minCount times, that differ only in vowel length or in retroflex
+// against dental consonants, by the combined counts of the two, most first.
+// contrast keeps only the pairs of one contrast, length or retroflex; ""
+// keeps them all.
+func minimalPairs(counts map[string]int, minCount int, contrast string) []minimalPair {
+	groups := make(map[string][]freq.WordCount)
+	for _, wc := range freq.Sorted(counts) {
+		if wc.Count < minCount {
+			continue
+		}
+		key := pairSkeleton(wc.Word)
+		groups[key] = append(groups[key], wc)
+	}
+	var pairs []minimalPair
+	for _, g := range groups {
+		for i := range g {
+			for j := i + 1; j < len(g); j++ {
+				p := minimalPair{a: g[i], b: g[j]}
+				var kinds, letters []string
+				ra, rb := []rune(p.a.Word), []rune(p.b.Word)
+				for k := range ra {
+					if ra[k] == rb[k] {
+						continue
+					}
+					// one of them is the long or retroflex letter
+					kind := pairContrasts[ra[k]].contrast + pairContrasts[rb[k]].contrast
+					if !slices.Contains(kinds, kind) {
+						kinds = append(kinds, kind)
+					}
+					short, marked := ra[k], rb[k]
+					if _, ok := pairContrasts[short]; ok {
+						short, marked = marked, short
+					}
+					letters = append(letters, string(short)+"/"+string(marked))
+				}
+				slices.Sort(kinds)
+				p.contrast = strings.Join(kinds, "+")
+				if contrast != "" && p.contrast != contrast {
+					continue
+				}
+				p.letters = strings.Join(letters, ", ")
+				pairs = append(pairs, p)
+			}
+		}
+	}
+	slices.SortFunc(pairs, func(x, y minimalPair) int {
+		if n, m := x.a.Count+x.b.Count, y.a.Count+y.b.Count; n != m {
+			return m - n
+		}
+		if c := tools.ComparePali(x.a.Word, y.a.Word); c != 0 {
+			return c
+		}
+		return tools.ComparePali(x.b.Word, y.b.Word)
+	})
+	return pairs
+}
+
+// minimalPairsTable returns the minimal_pairs table of pairs, with the
+// per million counts of tokens.
+func minimalPairsTable(pairs []minimalPair, tokens int) table {
+	t := table{columns: []string{"rank", "word_a", "word_b", "count_a", "count_b", "count", "per_million", "contrast", "letters"}}
+	for i, p := range pairs {
+		n := p.a.Count + p.b.Count
+		t.rows = append(t.rows, []any{i + 1, p.a.Word, p.b.Word, p.a.Count, p.b.Count, n, freq.PerMillion(n, tokens), p.contrast, p.letters})
+	}
+	return t
+}
+
+// runMinimalPairs implements the minimal-pairs subcommand.
+func runMinimalPairs(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("minimal-pairs", flag.ExitOnError)
+	commandUsage(fs, "Writes the pairs of corpus words that differ only in vowel length or in retroflex against dental consonants (vata/vaṭa, kali/kālī), ranked by their combined counts of the last run, for listening and spelling drills.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora whose counts of the last run are searched for pairs")
+	minCount := fs.Int("min-count", 5, "count each word of a pair needs, to leave out typos and one-off spellings")
+	contrast := fs.String("contrast", "all", "contrast of the pairs kept: length, retroflex, or all, those of both included")
+	top := fs.Int("top", 0, "number of pairs in the table (0: all)")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	want := *contrast
+	switch want {
+	case "all":
+		want = ""
+	case "length", "retroflex":
+	default:
+		tools.Errorf("-contrast %s: want length, retroflex or all", *contrast)
+		return
+	}
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("finding the minimal pairs")
+	tic := tools.Tic()
+
+	total, err := corpusTotals(strings.Split(*names, ","))
+	if err != nil {
+		tools.Errorf("%v (count the corpora first)", err)
+		return
+	}
+	pairs := minimalPairs(total, *minCount, want)
+	if *top > 0 && len(pairs) > *top {
+		pairs = pairs[:*top]
+	}
+	if err := sink.Write("minimal_pairs", minimalPairsTable(pairs, freq.TokenTotal(total))); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	tools.Infof("%d pairs", len(pairs))
+
+	tic.Toc()
+}
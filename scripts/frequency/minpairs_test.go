@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestMinimalPairs(t *testing.T) {
+	counts := map[string]int{
+		"vata": 30, "vaṭa": 12, // retroflex
+		"kali": 8, "kālī": 9, // length, twice
+		"kuṭī": 20, "kuti": 4, // both, but kuti is too rare
+		"nāma": 40, "nama": 6, "naṇa": 1,
+		"dhamma": 500, // no pair
+	}
+	pairs := minimalPairs(counts, 5, "")
+	want := []struct{ a, b, contrast, letters string }{
+		{"nāma", "nama", "length", "a/ā"},
+		{"vata", "vaṭa", "retroflex", "t/ṭ"},
+		{"kālī", "kali", "length", "a/ā, i/ī"},
+	}
+	if len(pairs) != len(want) {
+		t.Fatalf("%d pairs, want %d: %v", len(pairs), len(want), pairs)
+	}
+	for i, w := range want {
+		p := pairs[i]
+		if p.a.Word != w.a || p.b.Word != w.b || p.contrast != w.contrast || p.letters != w.letters {
+			t.Errorf("pair %d: %s/%s %s %q, want %s/%s %s %q", i, p.a.Word, p.b.Word, p.contrast, p.letters, w.a, w.b, w.contrast, w.letters)
+		}
+	}
+
+	counts["kuti"] = 5
+	if pairs := minimalPairs(counts, 5, "retroflex"); len(pairs) != 1 || pairs[0].b.Word != "vaṭa" {
+		t.Errorf("retroflex pairs %v, want vata/vaṭa", pairs)
+	}
+	pairs = minimalPairs(counts, 5, "")
+	if p := pairs[2]; p.a.Word != "kuṭī" || p.contrast != "length+retroflex" || p.letters != "t/ṭ, i/ī" {
+		t.Errorf("pair 2 %s/%s %s %q, want kuṭī/kuti length+retroflex", p.a.Word, p.b.Word, p.contrast, p.letters)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"dpd/go_modules/frequency/dpd"
+)
+
+// nameForms returns the forms of lookup all of whose headwords are proper
+// nouns in names. A form that can also be a common word is kept out, so
+// leaving the returned forms out of a word list never drops vocabulary.
+func nameForms(lookup map[string][]int, names map[int]bool) map[string]bool {
+	forms := make(map[string]bool)
+	for form, ids := range lookup {
+		all := len(ids) > 0
+		for _, id := range ids {
+			all = all && names[id]
+		}
+		if all {
+			forms[form] = true
+		}
+	}
+	return forms
+}
+
+// excludeNames adds the forms of the proper nouns of the DPD database at
+// dpdPath to the word exclusion set exclude, which may be nil, and returns
+// the set.
+func excludeNames(exclude map[string]bool, dpdPath string) (map[string]bool, error) {
+	db, err := dpd.Open(dpdPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	names, err := db.ProperNouns()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dpdPath, err)
+	}
+	lookup, err := db.Lookup()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dpdPath, err)
+	}
+	if exclude == nil {
+		exclude = make(map[string]bool)
+	}
+	for form := range nameForms(lookup, names) {
+		exclude[form] = true
+	}
+	return exclude, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dpd/go_modules/frequency/freq"
+)
+
+// parseNgramSizes parses the -ngrams flag, a comma-separated list of
+// n-gram sizes of at least 2.
+func parseNgramSizes(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var sizes []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 2 {
+			return nil, fmt.Errorf("invalid n-gram size %q", f)
+		}
+		sizes = append(sizes, n)
+	}
+	return sizes, nil
+}
+
+// saveNgramFreq writes the table <name>_<n>gram_freq to s, with the
+// columns ngram, count, rank and per_million (of all n-grams of that size,
+// including those under the minimum count). The rows are streamed from
+// ranked, as yielded by freq.NgramSpill.Merge.
+func saveNgramFreq(s Sink, name string, n int, ranked func(yield func(freq.WordCount) error) error, total int) error {
+	t := table{columns: []string{"ngram", "count", "rank", "per_million"}}
+	t.stream = func(yield func([]any) error) error {
+		rank := 0
+		return ranked(func(wc freq.WordCount) error {
+			rank++
+			return yield([]any{wc.Word, wc.Count, rank, freq.PerMillion(wc.Count, total)})
+		})
+	}
+	return s.Write(fmt.Sprintf("%s_%dgram_freq", name, n), t)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+)
+
+// numeral is the kind of the numbers written in digits; number words are
+// dpd.Cardinal or dpd.Ordinal.
+const numeral = "numeral"
+
+// numberForms returns the kind of the forms of lookup all of whose
+// headwords are number words in numbers: ordinal when all of them are
+// ordinals, as paṭhama, else cardinal. A form that can also be another
+// word is kept out, as for nameForms.
+func numberForms(lookup map[string][]int, numbers map[int]string) map[string]string {
+	forms := make(map[string]string)
+	for form, ids := range lookup {
+		kind := ""
+		for _, id := range ids {
+			k := numbers[id]
+			if k == "" {
+				kind = ""
+				break
+			}
+			if kind == "" || k == dpd.Cardinal {
+				kind = k
+			}
+		}
+		if kind != "" {
+			forms[form] = kind
+		}
+	}
+	return forms
+}
+
+// loadNumberForms reads the forms of the number words of the DPD database
+// at dpdPath, with their kind.
+func loadNumberForms(dpdPath string) (map[string]string, error) {
+	db, err := dpd.Open(dpdPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	numbers, err := db.Numbers()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dpdPath, err)
+	}
+	lookup, err := db.Lookup()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dpdPath, err)
+	}
+	return numberForms(lookup, numbers), nil
+}
+
+// numberKind returns the kind of the number w, numeral for digits or the
+// kind forms gives a number word, or "" when w is not a number.
+func numberKind(forms map[string]string, w string) string {
+	if pali.IsNumeral(w) {
+		return numeral
+	}
+	return forms[w]
+}
+
+// numberTable is the table of columns word, kind and count of the numbers
+// of list, in its order.
+func numberTable(list []freq.WordCount, forms map[string]string) table {
+	t := table{columns: []string{"word", "kind", "count"}}
+	for _, wc := range list {
+		if kind := numberKind(forms, wc.Word); kind != "" {
+			t.rows = append(t.rows, []any{wc.Word, kind, wc.Count})
+		}
+	}
+	return t
+}
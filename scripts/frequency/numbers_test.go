@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/freq"
+)
+
+func TestNumberForms(t *testing.T) {
+	numbers := map[int]string{1: dpd.Cardinal, 2: dpd.Ordinal, 3: dpd.Cardinal}
+	lookup := map[string][]int{
+		"dvādasa":  {1},
+		"paṭhamaṃ": {2},
+		"tayo":     {3, 2},
+		"ekaṃ":     {1, 9}, // also a common word
+		"dhammo":   {9},
+	}
+	forms := numberForms(lookup, numbers)
+	want := map[string]string{"dvādasa": dpd.Cardinal, "paṭhamaṃ": dpd.Ordinal, "tayo": dpd.Cardinal}
+	if len(forms) != len(want) {
+		t.Fatalf("numberForms = %v, want %v", forms, want)
+	}
+	for form, kind := range want {
+		if forms[form] != kind {
+			t.Errorf("%s is %q, want %q", form, forms[form], kind)
+		}
+	}
+
+	freqDir = t.TempDir()
+	list := freq.Sorted(map[string]int{"dvādasa": 3, "12": 2, "dhammo": 5, "ekaṃ": 4})
+	p := &pipeline{exclude: map[string]bool{"dhammo": true}}
+	path := filepath.Join(freqDir, "cst_wordlist.json")
+	if err := saveWordlist(path, list, p.unlisted); err != nil {
+		t.Fatal(err)
+	}
+	if got := readWordlist(t, path); !slices.Equal(got, []string{"ekaṃ", "dvādasa", "12"}) {
+		t.Errorf("without -drop-numbers the word list is %q", got)
+	}
+	p.numbers = forms
+	if err := saveWordlist(path, list, p.unlisted); err != nil {
+		t.Fatal(err)
+	}
+	if got := readWordlist(t, path); !slices.Equal(got, []string{"ekaṃ"}) {
+		t.Errorf("with -drop-numbers the word list is %q", got)
+	}
+	tab := numberTable(list, forms)
+	if len(tab.rows) != 2 || tab.rows[0][1] != dpd.Cardinal || tab.rows[1][1] != numeral {
+		t.Errorf("number table %v", tab.rows)
+	}
+}
+
+func readWordlist(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var words []string
+	if err := json.Unmarshal(data, &words); err != nil {
+		t.Fatal(err)
+	}
+	return words
+}
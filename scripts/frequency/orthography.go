@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"maps"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// lengthCandidates are the vowel-length rules the orthography report
+// weighs beside the variant rules: the long vowels, anywhere and at the
+// end of a word, written short.
+var lengthCandidates = []pali.VariantRule{
+	{Name: "ā", From: "ā", To: "a"},
+	{Name: "ī", From: "ī", To: "i"},
+	{Name: "ū", From: "ū", To: "u"},
+	{Name: "-ā", From: "ā$", To: "a"},
+	{Name: "-ī", From: "ī$", To: "i"},
+	{Name: "-ū", From: "ū$", To: "u"},
+}
+
+// spellingPair is a word and the spelling a rule rewrites it to, both
+// counted, with their counts in each corpus.
+type spellingPair struct {
+	word, variant string
+	counts        []int // of word, by corpus
+	variantCounts []int // of variant, by corpus
+	tokens        int   // of both over all corpora
+}
+
+// contrast reports whether some corpus writes both spellings, the rarer
+// at least share of the tokens of the pair there: a difference that
+// edition makes, such as bhikkhu and bhikkhū, rather than one between
+// the editions.
+func (sp spellingPair) contrast(share float64) bool {
+	for i, n := range sp.counts {
+		m := sp.variantCounts[i]
+		if n > 0 && m > 0 && float64(min(n, m)) >= share*float64(n+m) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleReport is what an orthography rule would merge.
+type ruleReport struct {
+	rule            pali.VariantRule
+	inUse           bool // a rule of -variants
+	pairs           []spellingPair
+	split, contrast int             // pairs split between editions, and those an edition contrasts
+	splitWords      map[string]bool // the word side of the split pairs
+	tokens          int             // of the word side of all pairs
+}
+
+// suggest reports whether the rule is worth merging with: it finds split
+// pairs, at least minSplit of all it finds.
+func (rr ruleReport) suggest(minSplit float64) bool {
+	return rr.split > 0 && float64(rr.split) >= minSplit*float64(len(rr.pairs))
+}
+
+// weighRules pairs the words of union, the counts of each word by corpus,
+// with their spelling under each of rules; a rewrite that union does not
+// count is no pair. A pair is split unless it is a contrast at share.
+func weighRules(union map[string][]int, rules []pali.VariantRule, inUse map[string]bool, share float64) ([]ruleReport, error) {
+	words := slices.Sorted(maps.Keys(union))
+	var reports []ruleReport
+	for _, r := range rules {
+		v, err := pali.NewVariants([]pali.VariantRule{r})
+		if err != nil {
+			return nil, err
+		}
+		rr := ruleReport{rule: r, inUse: inUse[r.Name], splitWords: make(map[string]bool)}
+		for _, w := range words {
+			to := v.Rewrite(w)
+			if to == w || union[to] == nil {
+				continue
+			}
+			sp := spellingPair{word: w, variant: to, counts: union[w], variantCounts: union[to]}
+			for i := range sp.counts {
+				sp.tokens += sp.counts[i] + sp.variantCounts[i]
+				rr.tokens += sp.counts[i]
+			}
+			if sp.contrast(share) {
+				rr.contrast++
+			} else {
+				rr.split++
+				rr.splitWords[w] = true
+			}
+			rr.pairs = append(rr.pairs, sp)
+		}
+		sort.SliceStable(rr.pairs, func(i, j int) bool { return rr.pairs[i].tokens > rr.pairs[j].tokens })
+		reports = append(reports, rr)
+	}
+	return reports, nil
+}
+
+// niggahitaCount is how often a text writes each sign of the niggahīta.
+type niggahitaCount struct {
+	book                            string
+	dotBelow, dotAbove, candrabindu int // ṃ, ṁ and m̐
+}
+
+// add adds the signs of o to nc.
+func (nc *niggahitaCount) add(o niggahitaCount) {
+	nc.dotBelow += o.dotBelow
+	nc.dotAbove += o.dotAbove
+	nc.candrabindu += o.candrabindu
+}
+
+// countNiggahita counts the niggahīta signs of one file, read as the
+// counts are but without unifying them: cleaned, lower-cased and composed
+// to NFC.
+func countNiggahita(c corpora.Corpus, path string) (niggahitaCount, error) {
+	nc := niggahitaCount{book: corpora.BookOf(c, path)}
+	err := c.ScanText(path, func(line string) error {
+		text := norm.NFC.String(c.Normalize(line))
+		nc.dotBelow += strings.Count(text, "ṃ")
+		nc.dotAbove += strings.Count(text, "ṁ")
+		nc.candrabindu += strings.Count(text, "m̐")
+		return nil
+	})
+	return nc, err
+}
+
+// orthographyRulesTable lists each rule with the pairs it finds.
+func orthographyRulesTable(reports []ruleReport, minSplit float64) table {
+	t := table{columns: []string{"rule", "from", "to", "in_use", "pairs", "split_pairs", "contrast_pairs", "tokens", "suggest"}}
+	for _, rr := range reports {
+		t.rows = append(t.rows, []any{rr.rule.Name, rr.rule.From, rr.rule.To, rr.inUse, len(rr.pairs), rr.split, rr.contrast, rr.tokens, rr.suggest(minSplit)})
+	}
+	return t
+}
+
+// orthographyPairsTable lists the top pairs of each rule, most tokens
+// first, with the counts of both spellings in each corpus.
+func orthographyPairsTable(reports []ruleReport, names []string, top int, share float64) table {
+	t := table{columns: []string{"rule", "word", "variant", "split"}}
+	for _, name := range names {
+		t.columns = append(t.columns, name, name+"_variant")
+	}
+	for _, rr := range reports {
+		for _, sp := range rr.pairs[:min(top, len(rr.pairs))] {
+			row := []any{rr.rule.Name, sp.word, sp.variant, !sp.contrast(share)}
+			for i := range names {
+				row = append(row, sp.counts[i], sp.variantCounts[i])
+			}
+			t.rows = append(t.rows, row)
+		}
+	}
+	return t
+}
+
+// orthographyBooksTable gives, for each book of each corpus and each
+// rule, the tokens of the words the rule merges with a spelling of
+// another edition: where each edition writes the variant.
+func orthographyBooksTable(reports []ruleReport, names []string, books []freq.Books) table {
+	t := table{columns: []string{"corpus", "book", "rule", "tokens", "variant_tokens", "share"}}
+	for i, name := range names {
+		for _, book := range corpora.Books {
+			counts, ok := books[i][book]
+			if !ok {
+				continue
+			}
+			tokens := freq.TokenTotal(counts)
+			for _, rr := range reports {
+				n := 0
+				for w := range rr.splitWords {
+					n += counts[w]
+				}
+				t.rows = append(t.rows, []any{name, book, rr.rule.Name, tokens, n, share(n, tokens)})
+			}
+		}
+	}
+	return t
+}
+
+// orthographyNiggahitaTable gives the niggahīta signs of each book of
+// each corpus, and the share of them not written ṃ.
+func orthographyNiggahitaTable(names []string, signs []map[string]niggahitaCount) table {
+	t := table{columns: []string{"corpus", "book", "niggahita", "dot_below", "dot_above", "candrabindu", "other_share"}}
+	for i, name := range names {
+		for _, book := range corpora.Books {
+			nc, ok := signs[i][book]
+			if !ok {
+				continue
+			}
+			all := nc.dotBelow + nc.dotAbove + nc.candrabindu
+			t.rows = append(t.rows, []any{name, book, all, nc.dotBelow, nc.dotAbove, nc.candrabindu, share(nc.dotAbove+nc.candrabindu, all)})
+		}
+	}
+	return t
+}
+
+// runOrthography implements the orthography subcommand: how the editions
+// differ in the niggahīta and the vowel lengths, per corpus and book, and
+// which variant rules would merge those differences.
+func runOrthography(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("orthography", flag.ExitOnError)
+	commandUsage(fs, "Reports the niggahīta signs of each book as the editions write them, and weighs the variant rules and vowel-length candidates by the spelling pairs they would merge, from the counts of the last run.")
+	list := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to compare")
+	top := fs.Int("pairs", 20, "spelling pairs listed per rule in orthography_pairs")
+	contrastShare := fs.Float64("contrast", 0.1, "share of a pair's tokens in one corpus the rarer spelling needs for the pair to be a contrast that edition makes")
+	minSplit := fs.Float64("suggest", 0.75, "share of its pairs a rule must find split between editions to be suggested")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "number of files scanned concurrently for the niggahīta signs")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("comparing orthography")
+	tic := tools.Tic()
+	selected, err := selectCorpora(*list)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	rules := cfg.Variants
+	if len(rules) == 0 {
+		rules = pali.DefaultVariants
+	}
+	inUse := make(map[string]bool)
+	for _, r := range rules {
+		inUse[r.Name] = true
+	}
+	for _, r := range lengthCandidates {
+		if !inUse[r.Name] {
+			rules = append(rules, r)
+		}
+	}
+
+	var (
+		names = make([]string, len(selected))
+		books = make([]freq.Books, len(selected))
+		signs = make([]map[string]niggahitaCount, len(selected))
+		union = make(map[string][]int)
+	)
+	for i, c := range selected {
+		names[i] = c.Name()
+		files, err := loadFileCounts(c.Name())
+		if err != nil {
+			tools.Errorf("%s: %v (count it first)", c.Name(), err)
+			return
+		}
+		books[i] = make(freq.Books)
+		for path, counts := range files {
+			books[i].Add(corpora.BookOf(c, path), counts)
+		}
+		for w, n := range books[i].Total() {
+			if union[w] == nil {
+				union[w] = make([]int, len(selected))
+			}
+			union[w][i] = n
+		}
+
+		paths, err := c.Files()
+		if err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			return
+		}
+		signs[i] = make(map[string]niggahitaCount)
+		prog := tools.NewProgress(c.Name(), len(paths))
+		err = scanInOrder(ctx, paths, *jobs, func(path string) (niggahitaCount, error) {
+			return countNiggahita(c, path)
+		}, func(nc niggahitaCount) error {
+			prog.Add(1)
+			sum := signs[i][nc.book]
+			sum.add(nc)
+			signs[i][nc.book] = sum
+			return nil
+		})
+		if err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			return
+		}
+		prog.Finish()
+	}
+	reports, err := weighRules(union, rules, inUse, *contrastShare)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	for _, rr := range reports {
+		if rr.suggest(*minSplit) && !rr.inUse {
+			tools.Infof("%s: %d of %d pairs split between editions; consider [[variants]] name = %q, from = %q, to = %q", rr.rule.Name, rr.split, len(rr.pairs), rr.rule.Name, rr.rule.From, rr.rule.To)
+		}
+	}
+
+	for _, out := range []struct {
+		name string
+		t    table
+	}{
+		{"orthography_rules", orthographyRulesTable(reports, *minSplit)},
+		{"orthography_pairs", orthographyPairsTable(reports, names, *top, *contrastShare)},
+		{"orthography_books", orthographyBooksTable(reports, names, books)},
+		{"orthography_niggahita", orthographyNiggahitaTable(names, signs)},
+	} {
+		if err := sink.Write(out.name, out.t); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+	}
+	tic.Toc()
+}
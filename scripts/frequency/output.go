@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// saveFreq writes the frequency table <name>_freq, with the dispersion
+// columns of disp, to s.
+func saveFreq(s Sink, name string, list []freq.WordCount, disp map[string]freq.Dispersion) error {
+	return s.Write(name+"_freq", withDispersion(freqTable(list), disp))
+}
+
+// saveBookFreq writes one table books/<name>_<book>_freq per book to s.
+func saveBookFreq(s Sink, name string, counts freq.Books) error {
+	for book, m := range counts {
+		if err := s.Write("books/"+name+"_"+book+"_freq", freqTable(freq.Sorted(m))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveGenreFreq writes, from the counts of the files of c, one table
+// pitakas/<name>_<pitaka>_freq per piṭaka and one genres/<name>_<genre>_freq
+// per genre to s, empty for those c has no files of, and the tags of each
+// file to <name>_file_tags.
+func saveGenreFreq(s Sink, name string, c corpora.Corpus, files map[string]map[string]int) error {
+	pitakas, genres := make(freq.Books), make(freq.Books)
+	tags := table{columns: []string{"file", "book", "section", "pitaka", "genre"}}
+	for _, path := range slices.Sorted(maps.Keys(files)) {
+		pitaka, genre := corpora.PitakaOf(c, path), corpora.GenreOf(c, path)
+		tags.rows = append(tags.rows, []any{path, corpora.BookOf(c, path), corpora.SectionOf(c, path), pitaka, genre})
+		if pitaka != "" {
+			pitakas.Add(pitaka, files[path])
+		}
+		if genre != "" {
+			genres.Add(genre, files[path])
+		}
+	}
+	for _, p := range corpora.Pitakas {
+		if err := s.Write("pitakas/"+name+"_"+p+"_freq", freqTable(freq.Sorted(pitakas[p]))); err != nil {
+			return err
+		}
+	}
+	for _, g := range corpora.Genres {
+		if err := s.Write("genres/"+name+"_"+g+"_freq", freqTable(freq.Sorted(genres[g]))); err != nil {
+			return err
+		}
+	}
+	return s.Write(name+"_file_tags", tags)
+}
+
+// saveWordlist writes the words of list, in order, as a JSON array to
+// path, leaving out those unlisted reports.
+func saveWordlist(path string, list []freq.WordCount, unlisted func(w string) bool) error {
+	words := make([]string, 0, len(list))
+	for _, wc := range list {
+		if !unlisted(wc.Word) {
+			words = append(words, wc.Word)
+		}
+	}
+	data, err := json.MarshalIndent(words, "", "  ")
+	if err != nil {
+		return err
+	}
+	return tools.WriteFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// saveVariantReport writes the table <name>_variants to s: each variant
+// rule with the number of tokens whose spelling it changed.
+func saveVariantReport(s Sink, name string, v *pali.Variants, collapsed map[string]int) error {
+	t := table{columns: []string{"rule", "from", "to", "tokens"}}
+	for _, r := range v.Rules() {
+		t.rows = append(t.rows, []any{r.Name, r.From, r.To, collapsed[r.Name]})
+	}
+	return s.Write(name+"_variants", t)
+}
+
+// cleaningTable is the cleaning report of a corpus: its cleaning rules, in
+// the order they apply, with the matches each replaced.
+func cleaningTable(c *corpora.Cleaner) table {
+	t := table{columns: []string{"rule", "pattern", "replace", "fired"}}
+	fired := c.Fired()
+	for i, r := range c.Rules() {
+		t.rows = append(t.rows, []any{r.Name, r.Pattern, r.Replace, int(fired[i])})
+	}
+	return t
+}
+
+// logCleaning logs the cleaning report of the corpus name.
+func logCleaning(name string, c *corpora.Cleaner) {
+	rules := c.Rules()
+	if len(rules) == 0 {
+		tools.Infof("%s: no cleaning rules", name)
+		return
+	}
+	fired := c.Fired()
+	for i, r := range rules {
+		tools.Infof("%s: cleaning rule %s fired %d times", name, r.Name, fired[i])
+	}
+}
+
+// withDispersion appends the doc_freq and dp columns to a table whose
+// first column is the word.
+func withDispersion(t table, stats map[string]freq.Dispersion) table {
+	t.columns = append(t.columns, "doc_freq", "dp")
+	for i, row := range t.rows {
+		d := stats[row[0].(string)]
+		t.rows[i] = append(row, d.Docs, d.DP)
+	}
+	return t
+}
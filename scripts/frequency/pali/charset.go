@@ -0,0 +1,128 @@
+package pali
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// paliLetters are the letters of romanized Pāḷi, with ṁ beside ṃ.
+const paliLetters = "aāiīuūeoṃṁkgṅcjñṭḍṇtdnpbmyrlḷvsh"
+
+// candrabindu is the combining mark of the niggahīta m̐, which has no
+// precomposed letter.
+const candrabindu = '\u0310'
+
+// IsPaliLetter reports whether r is a letter of romanized Pāḷi, in either
+// case.
+func IsPaliLetter(r rune) bool {
+	return strings.ContainsRune(paliLetters, unicode.ToLower(r))
+}
+
+// IsPaliRune reports whether r belongs in Roman Pāḷi text: a Pāḷi letter,
+// the candrabindu, a Latin digit, punctuation or a space.
+func IsPaliRune(r rune) bool {
+	return IsPaliLetter(r) || r == candrabindu || r >= '0' && r <= '9' || unicode.IsPunct(r) || unicode.IsSpace(r)
+}
+
+// cp1252 are the characters Windows-1252 reads the bytes 0x80–0x9f as,
+// where Latin-1 reads C1 control characters.
+var cp1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// misreadByte returns the byte that Latin-1 or Windows-1252 reads as r, if
+// it is one of the upper half a misread UTF-8 sequence is made of.
+func misreadByte(r rune) (byte, bool) {
+	if r >= 0x80 && r <= 0xff {
+		return byte(r), true
+	}
+	b, ok := cp1252[r]
+	return b, ok
+}
+
+// RepairMojibake undoes the misreading of UTF-8 as Latin-1 or
+// Windows-1252 in text, as "Ä" plus U+0081 for ā or "á¹ƒ" for ṃ: each
+// sequence of characters whose bytes decode as UTF-8 to a Pāḷi letter or
+// to punctuation is replaced by what it decodes to. Anything else, a ñ or
+// an ā written right included, is kept, so the repair is safe on text
+// that is only misread in part, and text without mojibake comes back as
+// it is.
+func RepairMojibake(text string) string {
+	if !strings.ContainsFunc(text, func(r rune) bool { _, ok := misreadByte(r); return ok }) {
+		return text
+	}
+	runes := []rune(text)
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		if r, n := decodeMisread(runes[i:]); n > 0 {
+			b.WriteRune(r)
+			i += n
+			continue
+		}
+		b.WriteRune(runes[i])
+		i++
+	}
+	return b.String()
+}
+
+// decodeMisread decodes the UTF-8 sequence whose misread characters start
+// runes, returning the character and how many of runes it takes, or 0 when
+// they do not start one decoding to a Pāḷi letter or punctuation.
+func decodeMisread(runes []rune) (rune, int) {
+	lead, ok := misreadByte(runes[0])
+	if !ok || lead < 0xc2 || lead > 0xef {
+		return 0, 0
+	}
+	size := 2
+	if lead >= 0xe0 {
+		size = 3
+	}
+	if len(runes) < size {
+		return 0, 0
+	}
+	seq := []byte{lead}
+	for _, r := range runes[1:size] {
+		b, ok := misreadByte(r)
+		if !ok {
+			return 0, 0
+		}
+		seq = append(seq, b)
+	}
+	r, n := utf8.DecodeRune(seq)
+	if r == utf8.RuneError || n != size || !IsPaliLetter(r) && !unicode.IsPunct(r) {
+		return 0, 0
+	}
+	return r, size
+}
+
+// lookalikes are characters mistaken for Pāḷi letters they resemble: the
+// long vowels of other transcriptions and letters of other scripts that
+// look Latin.
+var lookalikes = strings.NewReplacer(
+	"â", "ā", "î", "ī", "û", "ū", "ä", "ā", "ï", "ī", "ü", "ū",
+	"Â", "Ā", "Î", "Ī", "Û", "Ū", "Ä", "Ā", "Ï", "Ī", "Ü", "Ū",
+	"ŋ", "ṅ", "Ŋ", "Ṅ",
+	"а", "a", "е", "e", "о", "o", "р", "p", "с", "c", "і", "i", "к", "k", "м", "m", // Cyrillic
+	"ο", "o", "α", "a", "ν", "v", // Greek
+)
+
+// SuggestSpelling returns a spelling of word whose characters all belong in
+// Pāḷi, and whether it is certain: undoing mojibake is, replacing letters
+// that look like Pāḷi ones is not. It returns "" when it finds none.
+func SuggestSpelling(word string) (fix string, certain bool) {
+	valid := func(s string) bool {
+		return s != word && !strings.ContainsFunc(s, func(r rune) bool { return !IsPaliRune(r) })
+	}
+	fixed := RepairMojibake(word)
+	if valid(fixed) {
+		return fixed, true
+	}
+	if fixed = lookalikes.Replace(fixed); valid(fixed) {
+		return fixed, false
+	}
+	return "", false
+}
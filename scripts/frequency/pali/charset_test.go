@@ -0,0 +1,56 @@
+package pali
+
+import "testing"
+
+func TestRepairMojibake(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"dhammÄ\u0081", "dhammā"}, // Latin-1
+		{"evaá¹ƒ me", "evaṃ me"},   // Windows-1252
+		{"bhikkhÅ«naá¹\u0081", "bhikkhūnaṁ"},
+		{"ñāṇa", "ñāṇa"},  // right already
+		{"Ã± Ã©", "ñ Ã©"}, // é is no Pāḷi letter
+		{"â€¦pe", "…pe"},
+		{"dhamma", "dhamma"},
+	}
+	for _, tt := range tests {
+		if got := RepairMojibake(tt.in); got != tt.want {
+			t.Errorf("RepairMojibake(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestSpelling(t *testing.T) {
+	tests := []struct {
+		in      string
+		fix     string
+		certain bool
+	}{
+		{"dhammÄ\u0081", "dhammā", true},
+		{"bhagavâ", "bhagavā", false},
+		{"sаṅgha", "saṅgha", false}, // Cyrillic а
+		{"xyz", "", false},
+		{"dhamma", "", false},
+	}
+	for _, tt := range tests {
+		fix, certain := SuggestSpelling(tt.in)
+		if fix != tt.fix || certain != tt.certain {
+			t.Errorf("SuggestSpelling(%q) = %q, %v, want %q, %v", tt.in, fix, certain, tt.fix, tt.certain)
+		}
+	}
+}
+
+func TestNormalizerRepairFirst(t *testing.T) {
+	nz, err := ParseNormalizer("repair-mojibake,nfc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nz.RepairsMojibake() {
+		t.Error("chain does not repair")
+	}
+	if _, err := ParseNormalizer("nfc,repair-mojibake"); err == nil {
+		t.Error("repair-mojibake after nfc accepted")
+	}
+	if got := (Normalizer{}).With(StepRepairMojibake).String(); got != "repair-mojibake,nfc,unify-niggahita,lowercase" {
+		t.Errorf("With = %s", got)
+	}
+}
@@ -0,0 +1,32 @@
+package pali
+
+import "regexp"
+
+// hyphenBreakRe matches a word split where a line once broke: a hyphen
+// right after a letter, then the space the lost line break left and the
+// rest of the word in lower case, as in "paṭi- ccasamuppāda". A hyphen
+// with a space before it is a dash, and one joining two words without a
+// space is kept.
+var hyphenBreakRe = regexp.MustCompile(`(\pL[\pL\pM]*)[-‐‑]\s+(\p{Ll}[\pL\pM]*)`)
+
+// HyphenBreak is a word split by a line-break hyphen and the word it
+// rejoins to.
+type HyphenBreak struct {
+	Broken, Joined string
+}
+
+// HyphenBreaks returns the line-break splits of text, in order.
+func HyphenBreaks(text string) []HyphenBreak {
+	var list []HyphenBreak
+	for _, m := range hyphenBreakRe.FindAllStringSubmatch(text, -1) {
+		list = append(list, HyphenBreak{Broken: m[0], Joined: m[1] + m[2]})
+	}
+	return list
+}
+
+// RejoinHyphens rejoins the words of text that a line-break hyphen split,
+// as HyphenBreaks finds them: "paṭi- ccasamuppāda" becomes
+// "paṭiccasamuppāda".
+func RejoinHyphens(text string) string {
+	return hyphenBreakRe.ReplaceAllString(text, "$1$2")
+}
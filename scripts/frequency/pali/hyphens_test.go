@@ -0,0 +1,32 @@
+package pali
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRejoinHyphens(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"paṭi- ccasamuppāda", "paṭiccasamuppāda"},
+		{"evaṃ me su-  taṃ ekaṃ", "evaṃ me sutaṃ ekaṃ"},
+		{"dham‐\tmo ca", "dhammo ca"},
+		{"sīla-samādhi", "sīla-samādhi"},     // no break
+		{"bhagavā - ekaṃ", "bhagavā - ekaṃ"}, // a dash
+		{"ca- Nando", "ca- Nando"},           // capital: a new word
+	} {
+		if got := RejoinHyphens(tc.in); got != tc.want {
+			t.Errorf("RejoinHyphens(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+	want := []HyphenBreak{{Broken: "paṭi- ccasamuppāda", Joined: "paṭiccasamuppāda"}, {Broken: "su- taṃ", Joined: "sutaṃ"}}
+	if got := HyphenBreaks("paṭi- ccasamuppāda evaṃ me su- taṃ"); !slices.Equal(got, want) {
+		t.Errorf("HyphenBreaks = %v, want %v", got, want)
+	}
+	nz, err := ParseNormalizer("nfc,rejoin-hyphens,lowercase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nz.Normalize("Paṭi- ccasamuppādo"); got != "paṭiccasamuppādo" {
+		t.Errorf("Normalize = %q, want paṭiccasamuppādo", got)
+	}
+}
@@ -0,0 +1,222 @@
+package pali
+
+import (
+	"embed"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Language is the language Classify finds a word or span of text in.
+type Language uint8
+
+const (
+	Pali Language = iota
+	Sanskrit
+	English
+	numLanguages
+)
+
+var languageNames = [numLanguages]string{"pali", "sanskrit", "english"}
+
+func (l Language) String() string {
+	if l < numLanguages {
+		return languageNames[l]
+	}
+	return "unknown"
+}
+
+// Languages lists the languages Classify tells apart, Pāḷi first.
+func Languages() []Language {
+	return []Language{Pali, Sanskrit, English}
+}
+
+const (
+	// switchCost is the log-likelihood, in nats, a change of language
+	// costs Classify.
+	switchCost = 8
+	// MinForeignWords is the number of words of a language other than
+	// Pāḷi a span needs: a single word, like a proper name or a loan,
+	// is too little to tell a quotation.
+	MinForeignWords = 2
+)
+
+// The samples the trigram models are made from, one file per language,
+// named after it.
+//
+//go:embed languages/*.txt
+var languageSamples embed.FS
+
+// trigram is three lower-cased characters of a word padded with a space at
+// each end, so the start and end of words count.
+type trigram [3]rune
+
+// models holds the log-probability in each language of each trigram seen
+// in a sample.
+var models = loadModels()
+
+// loadModels counts the trigrams of the samples, add-one smoothed over
+// the trigrams of all of them. The samples are part of the program, so a
+// missing one panics.
+func loadModels() map[trigram][numLanguages]float64 {
+	var counts [numLanguages]map[trigram]int
+	var totals [numLanguages]int
+	seen := make(map[trigram]bool)
+	for _, l := range Languages() {
+		sample, err := languageSamples.ReadFile("languages/" + l.String() + ".txt")
+		if err != nil {
+			panic(err)
+		}
+		counts[l] = make(map[trigram]int)
+		for _, w := range sampleWordRe.FindAllString(Normalize(string(sample)), -1) {
+			trigrams(w, func(g trigram) {
+				counts[l][g]++
+				totals[l]++
+				seen[g] = true
+			})
+		}
+	}
+	var unseen [numLanguages]float64
+	for _, l := range Languages() {
+		unseen[l] = -math.Log(float64(totals[l] + len(seen)))
+	}
+	m := make(map[trigram][numLanguages]float64, len(seen))
+	for g := range seen {
+		var p [numLanguages]float64
+		for _, l := range Languages() {
+			p[l] = math.Log(float64(counts[l][g]+1)) + unseen[l]
+		}
+		m[g] = p
+	}
+	return m
+}
+
+// sampleWordRe matches a word of a sample or of the text Classify reads.
+var sampleWordRe = regexp.MustCompile(`[\pL\pM]+`)
+
+// trigrams calls f with each trigram of the word w.
+func trigrams(w string, f func(trigram)) {
+	g := trigram{' ', ' ', ' '}
+	shift := func(r rune) {
+		g[0], g[1], g[2] = g[1], g[2], r
+	}
+	shift(' ')
+	for _, r := range w {
+		shift(unicode.ToLower(r))
+		f(g)
+	}
+	shift(' ')
+	f(g)
+}
+
+// wordScores returns the log-likelihood of the word w in each language. A
+// trigram no sample has tells nothing, so a word of another script, such
+// as Myanmar Pāḷi, scores 0 in all three.
+func wordScores(w string) [numLanguages]float64 {
+	var s [numLanguages]float64
+	trigrams(w, func(g trigram) {
+		p, ok := models[g]
+		if !ok {
+			return
+		}
+		for l := range s {
+			s[l] += p[l]
+		}
+	})
+	return s
+}
+
+// ForeignSpan is a run of words Classify finds in a language other than
+// Pāḷi: the byte offsets in the text of the start of its first word and the
+// end of its last, and its words.
+type ForeignSpan struct {
+	Start, End int
+	Language   Language
+	Words      int
+}
+
+// Classify tags the spans of text in Sanskrit or English, such as the
+// quotations and editorial notes of the commentaries, with character
+// trigram models of the three languages. Each word is given the language
+// of the likeliest labelling of the whole text, where a change of
+// language costs switchCost and the text is taken to start and end in
+// Pāḷi, so a span must be clearly foreign as a whole to outweigh the two
+// changes around it, while a shared word within it, like na or ca, does
+// not break it. A span needs MinForeignWords words.
+func Classify(text string) []ForeignSpan {
+	words := sampleWordRe.FindAllStringIndex(text, -1)
+	if len(words) < MinForeignWords {
+		return nil
+	}
+	cost := func(from, to Language) float64 {
+		if from == to {
+			return 0
+		}
+		return switchCost
+	}
+	// best[l] is the score of the likeliest labelling of the words so far
+	// with the last in l, and from[i][l] the language of word i-1 in it.
+	var best [numLanguages]float64
+	from := make([][numLanguages]Language, len(words))
+	for i, s := range words {
+		scores := wordScores(text[s[0]:s[1]])
+		var next [numLanguages]float64
+		for _, l := range Languages() {
+			if i == 0 {
+				next[l] = scores[l] - cost(Pali, l)
+				continue
+			}
+			next[l] = math.Inf(-1)
+			for _, prev := range Languages() {
+				if v := best[prev] - cost(prev, l); v > next[l] {
+					next[l], from[i][l] = v, prev
+				}
+			}
+			next[l] += scores[l]
+		}
+		best = next
+	}
+	last := Pali
+	for _, l := range Languages() {
+		if best[l]-cost(l, Pali) > best[last]-cost(last, Pali) {
+			last = l
+		}
+	}
+	langs := make([]Language, len(words))
+	for i := len(words) - 1; i >= 0; i-- {
+		langs[i] = last
+		last = from[i][last]
+	}
+	var spans []ForeignSpan
+	for i := 0; i < len(words); {
+		j := i + 1
+		for j < len(words) && langs[j] == langs[i] {
+			j++
+		}
+		if langs[i] != Pali && j-i >= MinForeignWords {
+			spans = append(spans, ForeignSpan{Start: words[i][0], End: words[j-1][1], Language: langs[i], Words: j - i})
+		}
+		i = j
+	}
+	return spans
+}
+
+// BlankForeign returns text with the spans Classify finds blanked out, a
+// space for each character, so the columns of the words around them stay.
+func BlankForeign(text string) string {
+	spans := Classify(text)
+	if len(spans) == 0 {
+		return text
+	}
+	var b strings.Builder
+	at := 0
+	for _, s := range spans {
+		b.WriteString(text[at:s.Start])
+		b.WriteString(strings.Repeat(" ", utf8.RuneCountInString(text[s.Start:s.End])))
+		at = s.End
+	}
+	b.WriteString(text[at:])
+	return b.String()
+}
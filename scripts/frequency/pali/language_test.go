@@ -0,0 +1,45 @@
+package pali
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string // language: span
+	}{
+		{"evaṃ me sutaṃ ekaṃ samayaṃ bhagavā antarā ca rājagahaṃ", nil},
+		{"vipassanāñāṇaṃ nāma udayabbayānupassanādi, tena saddhiṃ kaṅkhāvitaraṇavisuddhi ca veditabbā", nil},
+		{"ဧဝံ မေ သုတံ ဧကံ သမယံ ဘဂဝါ", nil},
+		{"yathāha bhagavā sarvaṃ duḥkhaṃ sarvam anātmakaṃ iti, taṃ idha na gahetabbaṃ", []string{"sanskrit: sarvaṃ duḥkhaṃ sarvam anātmakaṃ iti"}},
+		{"evaṃ me sutaṃ (see the note on the variant reading in the burmese edition) ekaṃ samayaṃ", []string{"english: see the note on the variant reading in the burmese edition"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, s := range Classify(tt.text) {
+			got = append(got, s.Language.String()+": "+tt.text[s.Start:s.End])
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("Classify(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeForeign(t *testing.T) {
+	text := "evaṃ me sutaṃ (see the note on the burmese edition) ekaṃ samayaṃ"
+	if got, want := strings.Join(Tokenize(text), " "), "evaṃ me sutaṃ ekaṃ samayaṃ"; got != want {
+		t.Errorf("Tokenize = %q, want %q", got, want)
+	}
+	if got := (Tokenizer{KeepForeign: true}).Tokenize(text); len(got) != 12 {
+		t.Errorf("Tokenize keeping foreign spans = %q, want 12 tokens", got)
+	}
+	// the columns of the words after a span stay
+	_, columns := Default.TokenizeColumns(text)
+	want := utf8.RuneCountInString(text[:strings.Index(text, "ekaṃ")]) + 1
+	if len(columns) != 5 || columns[3] != want {
+		t.Errorf("columns %v, want the 4th at %d", columns, want)
+	}
+}
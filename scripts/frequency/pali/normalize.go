@@ -0,0 +1,40 @@
+// Package pali holds the text handling shared by the frequency tools:
+// Unicode normalization, tokenization and syllabification of Roman-script
+// Pāḷi.
+package pali
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// invisible are zero-width and formatting characters that editions leave
+// inside words.
+var invisible = strings.NewReplacer(
+	"\u200b", "", // zero width space
+	"\u200c", "", // zero width non-joiner
+	"\u200d", "", // zero width joiner
+	"\u2060", "", // word joiner
+	"\ufeff", "", // byte order mark / zero width no-break space
+	"\u00ad", "", // soft hyphen
+)
+
+// niggahita spells every form of the niggahīta as ṃ, as CST and DPD do.
+// It runs after NFC, so combining sequences are already composed where
+// Unicode has a precomposed letter; m̐ (m + candrabindu) has none.
+var niggahita = strings.NewReplacer(
+	"ṁ", "ṃ",
+	"Ṁ", "Ṃ",
+	"m\u0310", "ṃ",
+	"M\u0310", "Ṃ",
+)
+
+// Normalize composes text to NFC, unifies the niggahīta on ṃ and strips
+// zero-width characters, so one word is spelled by one byte sequence
+// whatever edition it came from.
+func Normalize(text string) string {
+	text = invisible.Replace(text)
+	text = norm.NFC.String(text)
+	return niggahita.Replace(text)
+}
@@ -0,0 +1,31 @@
+package pali
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"cst precomposed", "evaṃ me sutaṃ", "evaṃ me sutaṃ"},
+		{"cst combining dot below", "evam\u0323", "evaṃ"},
+		{"bjt dot above", "evaṁ me sutaṁ", "evaṃ me sutaṃ"},
+		{"bjt combining dot above", "evam\u0307", "evaṃ"},
+		{"sya candrabindu", "dhammam\u0310", "dhammaṃ"},
+		{"combining macron", "bhagava\u0304", "bhagavā"},
+		{"combining retroflex", "pan\u0323n\u0303a\u0304", "paṇñā"},
+		{"zero width space", "dham\u200bma", "dhamma"},
+		{"zero width joiner", "sut\u200dtaṃ", "suttaṃ"},
+		{"soft hyphen", "paṭic\u00adcasamuppāda", "paṭiccasamuppāda"},
+		{"bom", "\ufeffnamo", "namo"},
+		{"capital niggahita", "EVAṀ", "EVAṂ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package pali
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Step is a named step of a Normalizer chain.
+type Step uint8
+
+const (
+	// StepNFC strips zero-width characters and composes the text to NFC.
+	StepNFC Step = iota + 1
+	// StepNiggahita spells every form of the niggahīta as ṃ.
+	StepNiggahita
+	// StepStripDigits drops the Latin digits, those of footnote markers
+	// inside words included, so the word around them is joined.
+	StepStripDigits
+	// StepVariantMap merges spelling variants. It rewrites the tokens
+	// rather than the text, with the rules the caller holds, so it comes
+	// last.
+	StepVariantMap
+	// StepLowercase lower-cases the text.
+	StepLowercase
+	// StepRepairMojibake undoes the misreading of UTF-8 as Latin-1 or
+	// Windows-1252 (see RepairMojibake). It runs on the text as read,
+	// before the corpus cleans and lower-cases it, so the caller runs it,
+	// and it comes first.
+	StepRepairMojibake
+	// StepRejoinHyphens rejoins the words a line-break hyphen split (see
+	// RejoinHyphens).
+	StepRejoinHyphens
+)
+
+var stepNames = map[Step]string{
+	StepNFC:            "nfc",
+	StepNiggahita:      "unify-niggahita",
+	StepStripDigits:    "strip-digits",
+	StepVariantMap:     "variant-map",
+	StepLowercase:      "lowercase",
+	StepRepairMojibake: "repair-mojibake",
+	StepRejoinHyphens:  "rejoin-hyphens",
+}
+
+func (s Step) String() string {
+	if name, ok := stepNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Step(%d)", int(s))
+}
+
+// DefaultSteps is the chain of the zero Normalizer, the normalization
+// Tokenize has always done.
+var DefaultSteps = []Step{StepNFC, StepNiggahita, StepLowercase}
+
+// maxSteps bounds a chain, so a Normalizer is a comparable value like the
+// rest of the Tokenizer.
+const maxSteps = 8
+
+// Normalizer is an ordered chain of normalization steps, run on the text
+// before it is split into tokens. The zero value runs DefaultSteps.
+type Normalizer struct {
+	steps [maxSteps]Step
+	n     int
+}
+
+// ParseNormalizer returns the chain of the comma-separated step names s,
+// e.g. "nfc,unify-niggahita,lowercase". An empty s is the default chain.
+func ParseNormalizer(s string) (Normalizer, error) {
+	var nz Normalizer
+	if strings.TrimSpace(s) == "" {
+		return nz, nil
+	}
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		step := parseStep(f)
+		steps := nz.steps[:nz.n]
+		switch {
+		case step == 0:
+			return Normalizer{}, fmt.Errorf("unknown normalizer step %q (want %s)", f, strings.Join(StepNames(), ", "))
+		case slices.Contains(steps, step):
+			return Normalizer{}, fmt.Errorf("normalizer step %s given twice", f)
+		case slices.Contains(steps, StepVariantMap):
+			return Normalizer{}, fmt.Errorf("normalizer step %s after variant-map, which rewrites the tokens and comes last", f)
+		case step == StepRepairMojibake && len(steps) > 0:
+			return Normalizer{}, fmt.Errorf("normalizer step %s after %s; it repairs the text as read and comes first", f, steps[len(steps)-1])
+		}
+		nz.steps[nz.n] = step
+		nz.n++
+	}
+	return nz, nil
+}
+
+// StepNames lists the names of the steps a chain may hold.
+func StepNames() []string {
+	var names []string
+	for s := StepNFC; s <= StepRejoinHyphens; s++ {
+		names = append(names, s.String())
+	}
+	return names
+}
+
+func parseStep(name string) Step {
+	for s, n := range stepNames {
+		if n == name {
+			return s
+		}
+	}
+	return 0
+}
+
+// Steps returns the chain in order.
+func (nz Normalizer) Steps() []Step {
+	if nz.n == 0 {
+		return DefaultSteps
+	}
+	return append([]Step(nil), nz.steps[:nz.n]...)
+}
+
+// String is the chain as ParseNormalizer reads it.
+func (nz Normalizer) String() string {
+	var names []string
+	for _, s := range nz.Steps() {
+		names = append(names, s.String())
+	}
+	return strings.Join(names, ",")
+}
+
+// With returns the chain with step added at the end, or before
+// variant-map, or at the start for repair-mojibake, unless it holds it
+// already.
+func (nz Normalizer) With(step Step) Normalizer {
+	if nz.has(step) {
+		return nz
+	}
+	steps := nz.Steps()
+	if step == StepRepairMojibake {
+		steps = append([]Step{step}, steps...)
+	} else if n := len(steps); n > 0 && steps[n-1] == StepVariantMap {
+		steps = append(steps[:n-1:n-1], step, StepVariantMap)
+	} else {
+		steps = append(steps, step)
+	}
+	out := Normalizer{n: len(steps)}
+	copy(out.steps[:], steps)
+	return out
+}
+
+// MapsVariants reports whether the chain holds variant-map, which the
+// caller applies to the tokens.
+func (nz Normalizer) MapsVariants() bool { return nz.has(StepVariantMap) }
+
+// RepairsMojibake reports whether the chain holds repair-mojibake, which
+// the caller runs on the text as read.
+func (nz Normalizer) RepairsMojibake() bool { return nz.has(StepRepairMojibake) }
+
+func (nz Normalizer) has(step Step) bool { return slices.Contains(nz.Steps(), step) }
+
+// Normalize runs the text steps of the chain on text, in order, all but
+// repair-mojibake, which runs before the corpus cleans the text.
+func (nz Normalizer) Normalize(text string) string {
+	if nz.n == 0 {
+		// the default chain, on text the corpora lower-cased already
+		return lower(Normalize(text))
+	}
+	for _, s := range nz.steps[:nz.n] {
+		switch s {
+		case StepNFC:
+			text = norm.NFC.String(invisible.Replace(text))
+		case StepNiggahita:
+			text = niggahita.Replace(text)
+		case StepStripDigits:
+			if strings.ContainsAny(text, "0123456789") {
+				text = strings.Map(func(r rune) rune {
+					if r >= '0' && r <= '9' {
+						return -1
+					}
+					return r
+				}, text)
+			}
+		case StepLowercase:
+			text = lower(text)
+		case StepRejoinHyphens:
+			text = RejoinHyphens(text)
+		}
+	}
+	return text
+}
+
+// lower lower-cases text, without copying it when it has no capitals.
+func lower(text string) string {
+	if strings.IndexFunc(text, unicode.IsUpper) < 0 {
+		return text
+	}
+	return strings.ToLower(text)
+}
@@ -0,0 +1,67 @@
+package pali
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizer(t *testing.T) {
+	tests := []struct {
+		chain string
+		in    string
+		want  string
+	}{
+		{"", "Evaṁ me sutaṃ", "evaṃ me sutaṃ"},
+		{"nfc,unify-niggahita,lowercase", "Evaṁ me sutaṃ", "evaṃ me sutaṃ"},
+		{"nfc,lowercase", "Evaṁ me sut\u200baṃ", "evaṁ me sutaṃ"},
+		{"unify-niggahita", "evaṁ", "evaṁ"},
+		{"nfc,unify-niggahita", "evaṁ", "evaṃ"},
+		{"strip-digits", "kata3ññū {12} 4", "kataññū {} "},
+		{"lowercase,variant-map", "Āyasmā", "āyasmā"},
+	}
+	for _, tt := range tests {
+		nz, err := ParseNormalizer(tt.chain)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.chain, err)
+		}
+		if got := nz.Normalize(tt.in); got != tt.want {
+			t.Errorf("%q: Normalize(%q) = %q, want %q", tt.chain, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseNormalizer(t *testing.T) {
+	nz, err := ParseNormalizer(" strip-digits, nfc ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nz.Steps(); !slices.Equal(got, []Step{StepStripDigits, StepNFC}) {
+		t.Errorf("steps %v", got)
+	}
+	if nz.String() != "strip-digits,nfc" || nz.MapsVariants() {
+		t.Errorf("%s, maps variants %v", nz, nz.MapsVariants())
+	}
+	if got := (Normalizer{}).String(); got != "nfc,unify-niggahita,lowercase" {
+		t.Errorf("default chain %s", got)
+	}
+	with := nz.With(StepVariantMap).With(StepLowercase)
+	if with.String() != "strip-digits,nfc,lowercase,variant-map" || !with.MapsVariants() {
+		t.Errorf("with variant-map and lowercase: %s", with)
+	}
+	if with.With(StepNFC) != with {
+		t.Error("adding a step held already changed the chain")
+	}
+	for _, bad := range []string{"nfc,nfkc", "nfc,nfc", "variant-map,lowercase"} {
+		if _, err := ParseNormalizer(bad); err == nil {
+			t.Errorf("%q was taken", bad)
+		}
+	}
+}
+
+func TestTokenizeNormalizer(t *testing.T) {
+	nz, _ := ParseNormalizer("nfc,unify-niggahita,strip-digits")
+	tok := Tokenizer{KeepDigits: true, Normalizer: nz}
+	if got := tok.Tokenize("kata3ññū 12 evaṁ"); !slices.Equal(got, []string{"kataññū", "evaṃ"}) {
+		t.Errorf("tokens %q", got)
+	}
+}
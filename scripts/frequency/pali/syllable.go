@@ -0,0 +1,83 @@
+package pali
+
+import "strings"
+
+// vowels are the Roman Pāḷi vowels; e and o are long but for before a
+// consonant cluster.
+const vowels = "aāiīuūeo"
+
+// aspirable are the stops written with a following h for their aspirate,
+// which is one consonant: kh, gh, ch, jh, ṭh, ḍh, th, dh, ph, bh.
+const aspirable = "kgcjṭḍtdpb"
+
+// unit is one letter of a word: a vowel, a consonant (aspirates included)
+// or the niggahīta.
+type unit struct {
+	text string
+	kind byte // 'v' vowel, 'c' consonant, 'n' niggahīta
+}
+
+func letters(word string) []unit {
+	var units []unit
+	runes := []rune(word)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case strings.ContainsRune(vowels, r):
+			units = append(units, unit{string(r), 'v'})
+		case r == 'ṃ':
+			units = append(units, unit{string(r), 'n'})
+		case strings.ContainsRune(aspirable, r) && i+1 < len(runes) && runes[i+1] == 'h':
+			units = append(units, unit{string(runes[i : i+2]), 'c'})
+			i++
+		default:
+			units = append(units, unit{string(r), 'c'})
+		}
+	}
+	return units
+}
+
+// Syllables splits a lowercase Roman Pāḷi word into its syllables by the
+// rules of the grammarians: each syllable holds one vowel; a consonant
+// between two vowels begins the second syllable, while of a cluster the
+// first consonant closes the syllable before (dham-ma, bud-dho); the
+// niggahīta closes its syllable (saṃ-yut-taṃ). An aspirate such as kh is
+// one consonant (su-khaṃ). Consonants before the first vowel or after the
+// last go with the first or last syllable. A word without vowels has no
+// syllables.
+func Syllables(word string) []string {
+	units := letters(word)
+	var nuclei []int
+	for i, u := range units {
+		if u.kind == 'v' {
+			nuclei = append(nuclei, i)
+		}
+	}
+	if len(nuclei) == 0 {
+		return nil
+	}
+	// starts[k] is the unit that begins syllable k
+	starts := make([]int, len(nuclei))
+	for k := 1; k < len(nuclei); k++ {
+		from, to := nuclei[k-1]+1, nuclei[k]
+		starts[k] = from
+		// a niggahīta or the first consonant of a cluster closes the
+		// syllable before
+		if units[from].kind == 'n' || to-from >= 2 {
+			starts[k]++
+		}
+	}
+	syllables := make([]string, len(nuclei))
+	for k, start := range starts {
+		end := len(units)
+		if k+1 < len(starts) {
+			end = starts[k+1]
+		}
+		var b strings.Builder
+		for _, u := range units[start:end] {
+			b.WriteString(u.text)
+		}
+		syllables[k] = b.String()
+	}
+	return syllables
+}
@@ -0,0 +1,31 @@
+package pali
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSyllables(t *testing.T) {
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"dhamma", []string{"dham", "ma"}},
+		{"buddho", []string{"bud", "dho"}},
+		{"bhagavā", []string{"bha", "ga", "vā"}},
+		{"sukhaṃ", []string{"su", "khaṃ"}},
+		{"saṃyuttaṃ", []string{"saṃ", "yut", "taṃ"}},
+		{"saṅgha", []string{"saṅ", "gha"}},
+		{"brahmajāla", []string{"brah", "ma", "jā", "la"}},
+		{"tvaṃ", []string{"tvaṃ"}},
+		{"ajja", []string{"aj", "ja"}},
+		{"iti", []string{"i", "ti"}},
+		{"paṭiccasamuppāda", []string{"pa", "ṭic", "ca", "sa", "mup", "pā", "da"}},
+		{"ṃ", nil},
+	}
+	for _, tt := range tests {
+		if got := Syllables(tt.word); !slices.Equal(got, tt.want) {
+			t.Errorf("Syllables(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package pali
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Letters are the characters a Roman Pāḷi word may contain after
+// normalization and lowercasing.
+const Letters = "aāiīuūeokgṅcjñṭḍṇtdnpbmyrlvshḷṃ"
+
+// IsLetter reports whether r is a Roman Pāḷi letter.
+func IsLetter(r rune) bool {
+	return strings.ContainsRune(Letters, r)
+}
+
+// IsNumeral reports whether token is a numeral, a run of Latin digits as
+// KeepDigits emits them. Normalization and transliteration write the
+// digits of the other scripts as Latin ones.
+func IsNumeral(token string) bool {
+	if token == "" {
+		return false
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Tokenizer splits normalized text into tokens. Pāḷi words are always
+// tokens; the fields choose which other marks count as tokens too. The zero
+// value keeps words only.
+type Tokenizer struct {
+	// KeepDandas emits daṇḍas (। and ॥) as tokens.
+	KeepDandas bool
+	// KeepParagraphNumbers emits paragraph numbers in braces, like {12},
+	// as tokens, braces included.
+	KeepParagraphNumbers bool
+	// KeepDigits emits runs of Latin digits outside braces as tokens.
+	KeepDigits bool
+	// KeepEditorial emits the elision markers [pe], …pe… and ...pe... as
+	// the token "pe". When false they are dropped.
+	KeepEditorial bool
+	// KeepForeign keeps the words of the spans Classify finds in Sanskrit
+	// or English. When false they are dropped.
+	KeepForeign bool
+	// Normalizer is the chain run on the text before it is split. Its
+	// variant-map step is left to the caller, which holds the rules.
+	Normalizer Normalizer
+}
+
+// Default is the tokenizer used when none is configured. It matches the
+// historical behaviour, where the "pe" of elision markers counts as a word.
+var Default = Tokenizer{KeepEditorial: true}
+
+// tokenRe matches, in order of preference: a braced paragraph number, an
+// elision marker, a daṇḍa, a digit run and a word.
+var tokenRe = regexp.MustCompile(`\{[0-9]+\}|\[pe\]|…pe…|\.\.\.pe\.\.\.|[।॥]|[0-9]+|[` + Letters + `]+`)
+
+// Tokenize normalizes text with the chain of t and returns its tokens.
+func (t Tokenizer) Tokenize(text string) []string {
+	matches := tokenRe.FindAllString(t.normalize(text), -1)
+	tokens := matches[:0]
+	for _, m := range matches {
+		if tok, ok := t.token(m); ok {
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens
+}
+
+// TokenizeColumns is Tokenize, also returning the column of each token:
+// the 1-based position in characters of its start in text once normalized.
+func (t Tokenizer) TokenizeColumns(text string) ([]string, []int) {
+	text = t.normalize(text)
+	var (
+		tokens  []string
+		columns []int
+		col     = 1
+		at      = 0 // byte offset of col
+	)
+	for _, s := range tokenRe.FindAllStringIndex(text, -1) {
+		tok, ok := t.token(text[s[0]:s[1]])
+		if !ok {
+			continue
+		}
+		col += utf8.RuneCountInString(text[at:s[0]])
+		at = s[0]
+		tokens = append(tokens, tok)
+		columns = append(columns, col)
+	}
+	return tokens, columns
+}
+
+// normalize runs the chain of t on text and blanks out its foreign spans
+// unless t keeps them.
+func (t Tokenizer) normalize(text string) string {
+	text = t.Normalizer.Normalize(text)
+	if !t.KeepForeign {
+		text = BlankForeign(text)
+	}
+	return text
+}
+
+// token returns the token of the match m of tokenRe, and false when t
+// drops it.
+func (t Tokenizer) token(m string) (string, bool) {
+	r, _ := utf8.DecodeRuneInString(m)
+	switch {
+	case r == '{':
+		return m, t.KeepParagraphNumbers
+	case m == "[pe]" || m == "…pe…" || m == "...pe...":
+		return "pe", t.KeepEditorial
+	case r == '।' || r == '॥':
+		return m, t.KeepDandas
+	case r >= '0' && r <= '9':
+		return m, t.KeepDigits
+	}
+	return m, true
+}
+
+// Tokenize splits text with the Default tokenizer.
+func Tokenize(text string) []string {
+	return Default.Tokenize(text)
+}
+
+// WordSpans returns the byte offsets of the Pāḷi words of text, which must
+// be normalized and lowercased already, each as a start and end pair:
+// the words Tokenize keeps, but not the other tokens, so text[s[0]:s[1]]
+// is a word.
+func WordSpans(text string) [][]int {
+	spans := tokenRe.FindAllStringIndex(text, -1)
+	words := spans[:0]
+	for _, s := range spans {
+		if r, _ := utf8.DecodeRuneInString(text[s[0]:]); IsLetter(r) {
+			words = append(words, s)
+		}
+	}
+	return words
+}
@@ -0,0 +1,107 @@
+package pali
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("evaṁ me sutaṃ – ekaṃ sama\u200byaṃ bhagavā, 12.")
+	want := []string{"evaṃ", "me", "sutaṃ", "ekaṃ", "samayaṃ", "bhagavā"}
+	if len(got) != len(want) {
+		t.Fatalf("Tokenize = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("token %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTokenizerOptions(t *testing.T) {
+	text := "{12} evaṃ me sutaṃ …pe… bhagavā [pe] 3 ।"
+	tests := []struct {
+		name string
+		tok  Tokenizer
+		want string
+	}{
+		{"words only", Tokenizer{}, "evaṃ me sutaṃ bhagavā"},
+		{"default", Default, "evaṃ me sutaṃ pe bhagavā pe"},
+		{"dandas", Tokenizer{KeepDandas: true}, "evaṃ me sutaṃ bhagavā ।"},
+		{"paragraph numbers", Tokenizer{KeepParagraphNumbers: true}, "{12} evaṃ me sutaṃ bhagavā"},
+		{"digits", Tokenizer{KeepDigits: true}, "evaṃ me sutaṃ bhagavā 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(tt.tok.Tokenize(text), " "); got != tt.want {
+				t.Errorf("Tokenize = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeColumns(t *testing.T) {
+	text := "{12} evaṃ me sutaṃ …pe… bhagavā"
+	tokens, columns := Default.TokenizeColumns(text)
+	if got, want := strings.Join(tokens, " "), strings.Join(Default.Tokenize(text), " "); got != want {
+		t.Errorf("TokenizeColumns gives %q, Tokenize %q", got, want)
+	}
+	// in characters, so ṃ counts one
+	want := []int{6, 11, 14, 20, 25}
+	if len(columns) != len(want) {
+		t.Fatalf("columns %v, want %v", columns, want)
+	}
+	for i := range want {
+		if columns[i] != want[i] {
+			t.Errorf("column of %q = %d, want %d", tokens[i], columns[i], want[i])
+		}
+	}
+}
+
+func TestIsNumeral(t *testing.T) {
+	for _, tt := range []struct {
+		token string
+		want  bool
+	}{
+		{"12", true}, {"0", true}, {"", false}, {"dvādasa", false}, {"{12}", false}, {"12ma", false},
+	} {
+		if got := IsNumeral(tt.token); got != tt.want {
+			t.Errorf("IsNumeral(%q) = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestWordSpans(t *testing.T) {
+	text := "{12} evaṃ me …pe… sutaṃ [pe] 3 ।"
+	var got []string
+	for _, s := range WordSpans(text) {
+		got = append(got, text[s[0]:s[1]])
+	}
+	if want := "evaṃ me sutaṃ"; strings.Join(got, " ") != want {
+		t.Errorf("WordSpans gives %q, want %q", got, want)
+	}
+}
+
+// benchText is a sutta opening as the editions print it, in both spellings
+// of the niggahīta, with a paragraph number, elisions and punctuation,
+// repeated to the size of a short file.
+var benchText = strings.Repeat("{1} evaṁ me sutaṁ – ekaṃ samayaṃ bhagavā antarā ca rājagahaṃ antarā ca nāḷandaṃ "+
+	"addhānamaggappaṭipanno hoti mahatā bhikkhusaṅghena saddhiṃ pañcamattehi bhikkhusatehi. "+
+	"suppiyopi kho paribbājako …pe… brahmadattena māṇavena saddhiṃ, [pe] ।\n", 100)
+
+func BenchmarkTokenize(b *testing.B) {
+	b.SetBytes(int64(len(benchText)))
+	b.ReportAllocs()
+	for range b.N {
+		Default.Tokenize(benchText)
+	}
+}
+
+func BenchmarkWordSpans(b *testing.B) {
+	text := Normalize(benchText)
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	for range b.N {
+		WordSpans(text)
+	}
+}
@@ -0,0 +1,80 @@
+package pali
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// VariantRule rewrites one orthographic variant that the editions spell
+// differently, so its forms are counted as one.
+type VariantRule struct {
+	Name string `toml:"name"` // short key used in reports, e.g. "vy"
+	From string `toml:"from"` // regular expression matched within a token
+	To   string `toml:"to"`   // replacement; $1 refers to a group of From
+}
+
+// DefaultVariants collapses the systematic differences between CST, BJT
+// and SYA onto the plainer spelling. The merged forms are for comparing
+// editions; they no longer always match DPD's headword spellings. The
+// niggahīta before a stop, which BJT and SYA often keep where CST writes
+// the nasal of the stop's class (saṃgha, saṅgha), is written as that
+// nasal. Vowel length is left alone: the editions contrast bhikkhu and
+// bhikkhū as they do every grammatical ending; palifreq orthography
+// weighs both kinds of rule on the counts.
+var DefaultVariants = []VariantRule{
+	{"ḷ", "ḷ", "l"},
+	{"vy", "^vy", "by"},
+	{"ṇṇ", "ṇṇ", "nn"},
+	{"ṃk", "ṃ(k|g)", "ṅ$1"},
+	{"ṃc", "ṃ(c|j)", "ñ$1"},
+	{"ṃṭ", "ṃ(ṭ|ḍ)", "ṇ$1"},
+	{"ṃt", "ṃ(t|d)", "n$1"},
+	{"ṃp", "ṃ(p|b)", "m$1"},
+}
+
+// Variants applies a list of variant rules, in order, to tokens.
+type Variants struct {
+	rules []VariantRule
+	res   []*regexp.Regexp
+}
+
+// NewVariants compiles rules.
+func NewVariants(rules []VariantRule) (*Variants, error) {
+	v := &Variants{rules: rules}
+	for _, r := range rules {
+		re, err := regexp.Compile(r.From)
+		if err != nil {
+			return nil, fmt.Errorf("variant rule %q: %w", r.Name, err)
+		}
+		v.res = append(v.res, re)
+	}
+	return v, nil
+}
+
+// Rules returns the rules v applies.
+func (v *Variants) Rules() []VariantRule { return v.rules }
+
+// Rewrite returns token with every rule applied.
+func (v *Variants) Rewrite(token string) string {
+	for i, re := range v.res {
+		token = re.ReplaceAllString(token, v.rules[i].To)
+	}
+	return token
+}
+
+// Collapse rewrites the words of counts, merging the counts of words that
+// become equal. For each rule it adds to collapsed, by rule name, the
+// number of tokens whose spelling the rule changed.
+func (v *Variants) Collapse(counts map[string]int, collapsed map[string]int) map[string]int {
+	out := make(map[string]int, len(counts))
+	for w, n := range counts {
+		for i, re := range v.res {
+			if r := re.ReplaceAllString(w, v.rules[i].To); r != w {
+				collapsed[v.rules[i].Name] += n
+				w = r
+			}
+		}
+		out[w] += n
+	}
+	return out
+}
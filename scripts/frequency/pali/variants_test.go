@@ -0,0 +1,70 @@
+package pali
+
+import "testing"
+
+func TestVariantsCollapse(t *testing.T) {
+	v, err := NewVariants(DefaultVariants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{
+		"veḷuvane":   2,
+		"veluvane":   1,
+		"vyākaraṇaṃ": 3,
+		"avyākataṃ":  1,
+		"paṇṇaṃ":     4,
+	}
+	collapsed := make(map[string]int)
+	got := v.Collapse(counts, collapsed)
+	want := map[string]int{
+		"veluvane":   3,
+		"byākaraṇaṃ": 3,
+		"avyākataṃ":  1,
+		"pannaṃ":     4,
+	}
+	if len(got) != len(want) {
+		t.Errorf("Collapse = %v, want %v", got, want)
+	}
+	for w, n := range want {
+		if got[w] != n {
+			t.Errorf("count of %q = %d, want %d", w, got[w], n)
+		}
+	}
+	wantCollapsed := map[string]int{"ḷ": 2, "vy": 3, "ṇṇ": 4}
+	for rule, n := range wantCollapsed {
+		if collapsed[rule] != n {
+			t.Errorf("rule %q collapsed %d tokens, want %d", rule, collapsed[rule], n)
+		}
+	}
+	if got := v.Rewrite("vyāḷo"); got != "byālo" {
+		t.Errorf("Rewrite(vyāḷo) = %q, want byālo", got)
+	}
+}
+
+func TestNewVariantsBadRule(t *testing.T) {
+	if _, err := NewVariants([]VariantRule{{"bad", "(", ""}}); err == nil {
+		t.Error("NewVariants accepted an invalid expression")
+	}
+}
+
+func TestDefaultVariantsNiggahita(t *testing.T) {
+	v, err := NewVariants(DefaultVariants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for in, want := range map[string]string{
+		"saṃgha":    "saṅgha",
+		"saṃkhāra":  "saṅkhāra",
+		"kiñci":     "kiñci",
+		"saṃjāta":   "sañjāta",
+		"saṃbuddha": "sambuddha",
+		"santi":     "santi",
+		"saṃti":     "santi",
+		"dhammaṃ":   "dhammaṃ",
+		"saṃyutta":  "saṃyutta",
+	} {
+		if got := v.Rewrite(in); got != want {
+			t.Errorf("Rewrite(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
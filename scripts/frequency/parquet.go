@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"dpd/go_modules/frequency/parquet"
+	"dpd/go_modules/tools"
+)
+
+// parquetCodec is the page compression of the Parquet files for comp.
+func parquetCodec(comp compression) parquet.Codec {
+	switch comp {
+	case compressGzip:
+		return parquet.Gzip
+	case compressZstd:
+		return parquet.Zstd
+	}
+	return parquet.Uncompressed
+}
+
+// parquetType is the column type for values like v, as sqlType is for
+// SQLite: strings for what is no number or boolean.
+func parquetType(v any) parquet.Type {
+	switch v.(type) {
+	case int, int64:
+		return parquet.Int64
+	case float64:
+		return parquet.Double
+	case bool:
+		return parquet.Bool
+	case []byte:
+		return parquet.Bytes
+	}
+	return parquet.String
+}
+
+// writeParquet writes t to w as a Parquet file compressed with comp. The
+// column types are those of the first value in each column that is not
+// nil, so the rows before every column has one are held back; a column of
+// nils only is a string column. All columns are nullable.
+func writeParquet(w io.Writer, comp compression, t table) error {
+	columns := make([]parquet.Column, len(t.columns))
+	typed := make([]bool, len(t.columns))
+	for i, name := range t.columns {
+		columns[i] = parquet.Column{Name: name, Type: parquet.String, Optional: true}
+	}
+	var (
+		pw   *parquet.Writer
+		held [][]any
+	)
+	write := func(row []any) error {
+		for i, v := range row {
+			if s, ok := v.(string); ok {
+				row[i] = newlines.Replace(s)
+			} else if v != nil && columns[i].Type == parquet.String {
+				row[i] = formatValue(v)
+			}
+		}
+		return pw.Write(row)
+	}
+	start := func() error {
+		var err error
+		if pw, err = parquet.NewWriter(w, columns, parquetCodec(comp)); err != nil {
+			return err
+		}
+		for _, row := range held {
+			if err := write(row); err != nil {
+				return err
+			}
+		}
+		held = nil
+		return nil
+	}
+	err := t.each(func(row []any) error {
+		if pw != nil {
+			return write(slices.Clone(row))
+		}
+		held = append(held, slices.Clone(row))
+		for i, v := range row {
+			if !typed[i] && v != nil {
+				columns[i].Type, typed[i] = parquetType(v), true
+			}
+		}
+		if slices.Contains(typed, false) {
+			return nil
+		}
+		return start()
+	})
+	if err == nil && pw == nil {
+		err = start()
+	}
+	if err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// parquetTables are the tables of the app's database the parquet command
+// writes by default: the frequencies, the word_citation inverted index
+// and the sentences.
+var parquetTables = []string{"word_frequency", "word_frequency_book", "lemma_frequency", "word_citation", "sentences", "sentence_bank"}
+
+// dbColumns returns the Parquet schema of the SQLite table name and its
+// primary key columns, in order. INTEGER columns are int64, REAL columns
+// double, BLOB columns bytes and the others strings; the columns that may
+// be NULL are nullable.
+func dbColumns(db *sql.DB, name string) ([]parquet.Column, []string, error) {
+	rows, err := db.Query(`SELECT name, type, "notnull", pk FROM pragma_table_info(?)`, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var (
+		columns []parquet.Column
+		keys    = make(map[int]string)
+	)
+	for rows.Next() {
+		var (
+			col, typ    string
+			notNull, pk int
+		)
+		if err := rows.Scan(&col, &typ, &notNull, &pk); err != nil {
+			return nil, nil, err
+		}
+		c := parquet.Column{Name: col, Type: parquet.String, Optional: notNull == 0 && pk == 0}
+		switch typ = strings.ToUpper(typ); {
+		case strings.Contains(typ, "INT"):
+			c.Type = parquet.Int64
+		case strings.Contains(typ, "REAL"), strings.Contains(typ, "FLOA"), strings.Contains(typ, "DOUB"):
+			c.Type = parquet.Double
+		case strings.Contains(typ, "BLOB"):
+			c.Type = parquet.Bytes
+		}
+		columns = append(columns, c)
+		if pk > 0 {
+			keys[pk] = col
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	var key []string
+	for i := 1; i <= len(keys); i++ {
+		key = append(key, keys[i])
+	}
+	return columns, key, nil
+}
+
+// writeDBTable writes the rows of the SQLite table name to path as a
+// Parquet file, ordered by the primary key, and returns their number.
+func writeDBTable(ctx context.Context, db *sql.DB, name, path string, comp compression) (int, error) {
+	columns, key, err := dbColumns(db, name)
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = quoteIdent(c.Name)
+	}
+	query := `SELECT ` + strings.Join(names, ", ") + ` FROM ` + quoteIdent(name)
+	if len(key) > 0 {
+		for i, k := range key {
+			key[i] = quoteIdent(k)
+		}
+		query += ` ORDER BY ` + strings.Join(key, ", ")
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	f, err := tools.CreateAtomic(path, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Abort()
+	pw, err := parquet.NewWriter(f, columns, parquetCodec(comp))
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	row := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range row {
+		ptrs[i] = &row[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return 0, err
+		}
+		for i, v := range row {
+			// SQLite keeps any value in any column; text read from a BLOB
+			// column or the reverse is what Parquet cannot mix
+			if b, ok := v.([]byte); ok && columns[i].Type == parquet.String {
+				row[i] = string(b)
+			} else if s, ok := v.(string); ok && columns[i].Type == parquet.Bytes {
+				row[i] = []byte(s)
+			}
+		}
+		if err := pw.Write(row); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if err := pw.Close(); err != nil {
+		return 0, err
+	}
+	return n, f.Commit()
+}
+
+// runParquet implements the parquet subcommand: tables of the app's
+// database as Parquet files, for data-science tools.
+func runParquet(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("parquet", flag.ExitOnError)
+	commandUsage(fs, "Writes tables of a SQLite database made by export, concordance and sentence-bank as Parquet files <table>.parquet, with the column types of the database.")
+	dbPath := fs.String("db", "", "SQLite database to read (required)")
+	out := fs.String("out", filepath.Join(freqDir, "parquet"), "directory to write the Parquet files into")
+	tables := fs.String("tables", "", "comma-separated tables to write (default "+strings.Join(parquetTables, ",")+", those the database has)")
+	comp := fs.String("compress", "zstd", "compression of the pages: none, gzip or zstd")
+	fs.Parse(args)
+
+	tools.PTitle("writing parquet tables")
+	tic := tools.Tic()
+	if *dbPath == "" {
+		tools.Errorf("parquet needs -db")
+		return
+	}
+	c, err := parseCompression(*comp)
+	if err != nil {
+		tools.Errorf("-compress: %v", err)
+		return
+	}
+	if _, err := os.Stat(*dbPath); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	db, err := sql.Open("sqlite", tools.SQLiteURI(*dbPath, "mode=ro"))
+	if err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+	defer db.Close()
+
+	names := parquetTables
+	explicit := *tables != ""
+	if explicit {
+		names = strings.Split(*tables, ",")
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	for _, name := range names {
+		var found int
+		if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&found); err != nil {
+			tools.Errorf("%s: %v", *dbPath, err)
+			return
+		}
+		if found == 0 {
+			if explicit {
+				tools.Errorf("%s has no table %s", *dbPath, name)
+				return
+			}
+			tools.Infof("%s: not in %s", name, *dbPath)
+			continue
+		}
+		path := filepath.Join(*out, name+".parquet")
+		n, err := writeDBTable(ctx, db, name, path, c)
+		if err != nil {
+			tools.Errorf("%s: %v", name, err)
+			return
+		}
+		tools.Infof("%s: %d rows", path, n)
+	}
+	tic.Toc()
+}
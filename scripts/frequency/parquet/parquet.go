@@ -0,0 +1,463 @@
+// Package parquet writes tables as Apache Parquet files, for reading with
+// pandas, Polars, DuckDB or Spark with the column types kept. It writes
+// the plain subset of the format every reader takes: flat schemas of
+// 64-bit integers, doubles, booleans, UTF-8 strings and byte strings, each
+// optionally nullable, in row groups of one data page (v1, PLAIN encoding)
+// per column, uncompressed or compressed with gzip or zstd.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Type is the type of the values of a column.
+type Type int
+
+const (
+	Int64  Type = iota // INT64
+	Double             // DOUBLE
+	String             // BYTE_ARRAY annotated as a UTF-8 string
+	Bool               // BOOLEAN
+	Bytes              // BYTE_ARRAY
+)
+
+func (t Type) String() string {
+	switch t {
+	case Int64:
+		return "int64"
+	case Double:
+		return "double"
+	case String:
+		return "string"
+	case Bool:
+		return "bool"
+	case Bytes:
+		return "bytes"
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// physical is the Parquet physical type of t.
+func (t Type) physical() int32 {
+	switch t {
+	case Int64:
+		return 2
+	case Double:
+		return 5
+	case Bool:
+		return 0
+	}
+	return 6
+}
+
+// Column is one column of the schema. An Optional column may hold nil.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// Codec is the compression of the data pages, numbered as in the format.
+type Codec int32
+
+const (
+	Uncompressed Codec = 0
+	Gzip         Codec = 2
+	Zstd         Codec = 6
+)
+
+// RowGroupRows is the number of rows written per row group; a row group is
+// held in memory until it is full.
+const RowGroupRows = 1 << 16
+
+// createdBy names the writer in the footer.
+const createdBy = "palifreq parquet writer"
+
+var magic = []byte("PAR1")
+
+// Writer writes rows to a Parquet file. Close writes the footer; the file
+// is not valid before.
+type Writer struct {
+	w       *countWriter
+	columns []Column
+	codec   Codec
+
+	chunks []chunk // the row group being filled
+	rows   int     // in it
+	total  int64   // rows of the whole file
+	groups []rowGroup
+	zstd   *zstd.Encoder // made by the first zstd page
+	err    error
+}
+
+// chunk holds the values of one column of a row group: PLAIN-encoded in
+// values, and for an optional column whether each row has one.
+type chunk struct {
+	values  bytes.Buffer
+	defined []bool
+	bits    byte // booleans not yet written to values
+	nbits   int
+}
+
+type rowGroup struct {
+	columns    []columnChunk
+	rows       int
+	size       int64 // uncompressed
+	compressed int64
+	offset     int64
+}
+
+type columnChunk struct {
+	offset             int64 // of the data page header
+	values             int
+	uncompressed, size int64
+}
+
+// NewWriter returns a writer of a file of columns to w, compressing the
+// pages with codec.
+func NewWriter(w io.Writer, columns []Column, codec Codec) (*Writer, error) {
+	switch codec {
+	case Uncompressed, Gzip, Zstd:
+	default:
+		return nil, fmt.Errorf("parquet: unsupported codec %d", codec)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("parquet: no columns")
+	}
+	cw := &countWriter{w: w}
+	if _, err := cw.Write(magic); err != nil {
+		return nil, err
+	}
+	return &Writer{w: cw, columns: columns, codec: codec, chunks: make([]chunk, len(columns))}, nil
+}
+
+// Write adds a row, a value per column in schema order. An Int64 column
+// takes any Go integer, a Double column floats and integers, a String or
+// Bytes column strings and byte slices, and an optional column nil.
+func (w *Writer) Write(row []any) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row of %d values for %d columns", len(row), len(w.columns))
+	}
+	for i, v := range row {
+		if err := w.chunks[i].add(w.columns[i], v); err != nil {
+			// the values of the columns before are in; the row group is
+			// no longer consistent
+			w.err = err
+			return err
+		}
+	}
+	w.rows++
+	if w.rows == RowGroupRows {
+		return w.flush()
+	}
+	return nil
+}
+
+func (c *chunk) add(col Column, v any) error {
+	if v == nil {
+		if !col.Optional {
+			return fmt.Errorf("parquet: column %s: nil in a required column", col.Name)
+		}
+		c.defined = append(c.defined, false)
+		return nil
+	}
+	var b [8]byte
+	switch col.Type {
+	case Int64:
+		n, ok := toInt64(v)
+		if !ok {
+			return typeError(col, v)
+		}
+		binary.LittleEndian.PutUint64(b[:], uint64(n))
+		c.values.Write(b[:])
+	case Double:
+		f, ok := v.(float64)
+		if !ok {
+			if f32, is := v.(float32); is {
+				f, ok = float64(f32), true
+			} else if n, is := toInt64(v); is {
+				f, ok = float64(n), true
+			}
+		}
+		if !ok {
+			return typeError(col, v)
+		}
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+		c.values.Write(b[:])
+	case String, Bytes:
+		var s []byte
+		switch v := v.(type) {
+		case string:
+			s = []byte(v)
+		case []byte:
+			s = v
+		default:
+			return typeError(col, v)
+		}
+		binary.LittleEndian.PutUint32(b[:4], uint32(len(s)))
+		c.values.Write(b[:4])
+		c.values.Write(s)
+	case Bool:
+		t, ok := v.(bool)
+		if !ok {
+			return typeError(col, v)
+		}
+		if t {
+			c.bits |= 1 << c.nbits
+		}
+		if c.nbits++; c.nbits == 8 {
+			c.values.WriteByte(c.bits)
+			c.bits, c.nbits = 0, 0
+		}
+	}
+	if col.Optional {
+		c.defined = append(c.defined, true)
+	}
+	return nil
+}
+
+func typeError(col Column, v any) error {
+	return fmt.Errorf("parquet: column %s: %T value in a %s column", col.Name, v, col.Type)
+}
+
+func toInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// flush writes the row group being filled as a data page per column.
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	rg := rowGroup{rows: w.rows, offset: w.w.n}
+	for i := range w.chunks {
+		cc, err := w.writePage(w.columns[i], &w.chunks[i])
+		if err != nil {
+			w.err = err
+			return err
+		}
+		rg.columns = append(rg.columns, cc)
+		rg.size += cc.uncompressed
+		rg.compressed += cc.size
+		w.chunks[i] = chunk{}
+	}
+	w.groups = append(w.groups, rg)
+	w.total += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+func (w *Writer) writePage(col Column, c *chunk) (columnChunk, error) {
+	if c.nbits > 0 {
+		c.values.WriteByte(c.bits)
+	}
+	var body []byte
+	if col.Optional {
+		levels := rleLevels(c.defined)
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(levels)))
+		body = append(body, levels...)
+	}
+	body = append(body, c.values.Bytes()...)
+	data, err := w.compress(body)
+	if err != nil {
+		return columnChunk{}, err
+	}
+
+	var h compact
+	h.beginStruct()
+	h.i32(1, 0) // DATA_PAGE
+	h.i32(2, int32(len(body)))
+	h.i32(3, int32(len(data)))
+	h.structField(5)
+	h.i32(1, int32(w.rows))
+	h.i32(2, 0) // PLAIN
+	h.i32(3, 3) // RLE
+	h.i32(4, 3)
+	h.endStruct()
+	h.endStruct()
+
+	cc := columnChunk{
+		offset:       w.w.n,
+		values:       w.rows,
+		uncompressed: int64(len(h.buf) + len(body)),
+		size:         int64(len(h.buf) + len(data)),
+	}
+	if _, err := w.w.Write(h.buf); err != nil {
+		return columnChunk{}, err
+	}
+	if _, err := w.w.Write(data); err != nil {
+		return columnChunk{}, err
+	}
+	return cc, nil
+}
+
+// rleLevels encodes the definition levels of defined, of bit width 1, as
+// runs of the RLE/bit-packing hybrid.
+func rleLevels(defined []bool) []byte {
+	var out []byte
+	for i := 0; i < len(defined); {
+		j := i + 1
+		for j < len(defined) && defined[j] == defined[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		if defined[i] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i = j
+	}
+	return out
+}
+
+// compress compresses data, a page, with the codec of w.
+func (w *Writer) compress(data []byte) ([]byte, error) {
+	switch w.codec {
+	case Gzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(data)
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case Zstd:
+		if w.zstd == nil {
+			enc, err := zstd.NewWriter(nil)
+			if err != nil {
+				return nil, err
+			}
+			w.zstd = enc
+		}
+		return w.zstd.EncodeAll(data, nil), nil
+	}
+	return data, nil
+}
+
+// Close writes the last row group and the footer. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.zstd != nil {
+		defer w.zstd.Close()
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if w.err != nil {
+		return w.err
+	}
+	footer := w.footer()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	footer = append(footer, magic...)
+	_, err := w.w.Write(footer)
+	return err
+}
+
+// footer encodes the FileMetaData of the file.
+func (w *Writer) footer() []byte {
+	var c compact
+	c.beginStruct()
+	c.i32(1, 1) // version
+	c.list(2, tStruct, len(w.columns)+1)
+	c.beginStruct()
+	c.string(4, "schema")
+	c.i32(5, int32(len(w.columns)))
+	c.endStruct()
+	for _, col := range w.columns {
+		c.beginStruct()
+		c.i32(1, col.Type.physical())
+		if col.Optional {
+			c.i32(3, 1)
+		} else {
+			c.i32(3, 0)
+		}
+		c.string(4, col.Name)
+		if col.Type == String {
+			c.i32(6, 0) // UTF8
+			c.structField(10)
+			c.structField(1) // STRING
+			c.endStruct()
+			c.endStruct()
+		}
+		c.endStruct()
+	}
+	c.i64(3, w.total)
+	c.list(4, tStruct, len(w.groups))
+	for _, rg := range w.groups {
+		c.beginStruct()
+		c.list(1, tStruct, len(rg.columns))
+		for i, cc := range rg.columns {
+			col := w.columns[i]
+			c.beginStruct()
+			c.i64(2, cc.offset)
+			c.structField(3)
+			c.i32(1, col.Type.physical())
+			c.list(2, tI32, 2)
+			c.varint(0) // PLAIN
+			c.varint(3) // RLE
+			c.list(3, tBinary, 1)
+			c.rawString(col.Name)
+			c.i32(4, int32(w.codec))
+			c.i64(5, int64(cc.values))
+			c.i64(6, cc.uncompressed)
+			c.i64(7, cc.size)
+			c.i64(9, cc.offset)
+			c.endStruct()
+			c.endStruct()
+		}
+		c.i64(2, rg.size)
+		c.i64(3, int64(rg.rows))
+		c.i64(5, rg.offset)
+		c.i64(6, rg.compressed)
+		c.endStruct()
+	}
+	c.string(6, createdBy)
+	c.endStruct()
+	return c.buf
+}
+
+// countWriter counts the bytes written, for the offsets of the footer.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,255 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// readCompact decodes a Thrift compact struct from b into a map by field
+// id, with integers as int64, binaries as string, lists as []any and
+// structs as maps, and returns the bytes after it.
+func readCompact(t *testing.T, b []byte) (map[int16]any, []byte) {
+	t.Helper()
+	fields := make(map[int16]any)
+	var last int16
+	for {
+		h := b[0]
+		b = b[1:]
+		if h == 0 {
+			return fields, b
+		}
+		typ := h & 0x0f
+		if d := int16(h >> 4); d != 0 {
+			last += d
+		} else {
+			var id int64
+			id, b = readVarint(b)
+			last = int16(id)
+		}
+		fields[last], b = readValue(t, typ, b)
+	}
+}
+
+func readValue(t *testing.T, typ byte, b []byte) (any, []byte) {
+	switch typ {
+	case tI32, tI64:
+		return readVarint(b)
+	case tBinary:
+		n, k := binary.Uvarint(b)
+		return string(b[k : k+int(n)]), b[k+int(n):]
+	case tStruct:
+		return readCompact(t, b)
+	case tList:
+		n, et := int(b[0]>>4), b[0]&0x0f
+		b = b[1:]
+		if n == 15 {
+			u, k := binary.Uvarint(b)
+			n, b = int(u), b[k:]
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i], b = readValue(t, et, b)
+		}
+		return list, b
+	}
+	t.Fatalf("unexpected compact type %d", typ)
+	return nil, nil
+}
+
+func readVarint(b []byte) (int64, []byte) {
+	u, k := binary.Uvarint(b)
+	return int64(u>>1) ^ -int64(u&1), b[k:]
+}
+
+// readColumn decodes the pages of column i of a file written by Writer.
+func readColumn(t *testing.T, file []byte, meta map[int16]any, i int, col Column) []any {
+	t.Helper()
+	var out []any
+	for _, g := range meta[4].([]any) {
+		cc := g.(map[int16]any)[1].([]any)[i].(map[int16]any)[3].(map[int16]any)
+		page := file[cc[9].(int64):]
+		h, rest := readCompact(t, page)
+		data := rest[:h[3].(int64)]
+		switch Codec(cc[4].(int64)) {
+		case Gzip:
+			zr, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ = io.ReadAll(zr)
+		case Zstd:
+			zr, _ := zstd.NewReader(nil)
+			var err error
+			if data, err = zr.DecodeAll(data, nil); err != nil {
+				t.Fatal(err)
+			}
+			zr.Close()
+		}
+		if int64(len(data)) != h[2].(int64) {
+			t.Fatalf("page of %d bytes, header says %d", len(data), h[2])
+		}
+		n := int(h[5].(map[int16]any)[1].(int64))
+		defined := make([]bool, n)
+		for j := range defined {
+			defined[j] = true
+		}
+		if col.Optional {
+			size := binary.LittleEndian.Uint32(data)
+			levels := data[4 : 4+size]
+			data = data[4+size:]
+			j := 0
+			for len(levels) > 0 {
+				run, k := binary.Uvarint(levels)
+				if run&1 != 0 {
+					t.Fatal("bit-packed run; want RLE runs")
+				}
+				for range run >> 1 {
+					defined[j] = levels[k] == 1
+					j++
+				}
+				levels = levels[k+1:]
+			}
+		}
+		bit := 0
+		for _, ok := range defined {
+			if !ok {
+				out = append(out, nil)
+				continue
+			}
+			switch col.Type {
+			case Int64:
+				out = append(out, int64(binary.LittleEndian.Uint64(data)))
+				data = data[8:]
+			case Double:
+				out = append(out, math.Float64frombits(binary.LittleEndian.Uint64(data)))
+				data = data[8:]
+			case String:
+				size := binary.LittleEndian.Uint32(data)
+				out = append(out, string(data[4:4+size]))
+				data = data[4+size:]
+			case Bool:
+				out = append(out, data[bit/8]>>(bit%8)&1 == 1)
+				bit++
+			}
+		}
+	}
+	return out
+}
+
+func TestWriter(t *testing.T) {
+	columns := []Column{
+		{Name: "word", Type: String},
+		{Name: "count", Type: Int64},
+		{Name: "per_million", Type: Double, Optional: true},
+		{Name: "proper_noun", Type: Bool},
+	}
+	rows := [][]any{
+		{"ca", 51063, 21.5, false},
+		{"bhagavā", int64(20000), nil, true},
+		{"dhammo", 3, 7, true},
+	}
+	// enough rows for a second row group, in which the levels of
+	// per_million run long
+	for i := range RowGroupRows {
+		rows = append(rows, []any{fmt.Sprint("w", i), i, nil, i%3 == 0})
+	}
+	for _, codec := range []Codec{Uncompressed, Gzip, Zstd} {
+		t.Run(fmt.Sprint(codec), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, columns, codec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range rows {
+				if err := w.Write(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			file := buf.Bytes()
+			if !bytes.HasPrefix(file, magic) || !bytes.HasSuffix(file, magic) {
+				t.Fatal("no PAR1 at the start and end")
+			}
+			size := binary.LittleEndian.Uint32(file[len(file)-8:])
+			meta, rest := readCompact(t, file[len(file)-8-int(size):len(file)-8])
+			if len(rest) != 0 {
+				t.Fatalf("%d bytes after the footer", len(rest))
+			}
+			if got := meta[3].(int64); got != int64(len(rows)) {
+				t.Fatalf("num_rows %d, want %d", got, len(rows))
+			}
+			if got := len(meta[4].([]any)); got != 2 {
+				t.Fatalf("%d row groups, want 2", got)
+			}
+			schema := meta[2].([]any)
+			if n := schema[0].(map[int16]any)[5].(int64); n != int64(len(columns)) {
+				t.Fatalf("root has %d children", n)
+			}
+			for i, col := range columns {
+				el := schema[i+1].(map[int16]any)
+				if el[4] != col.Name || el[1].(int64) != int64(col.Type.physical()) {
+					t.Errorf("schema element %d: %v", i, el)
+				}
+				if optional := el[3].(int64) == 1; optional != col.Optional {
+					t.Errorf("%s: optional %v", col.Name, optional)
+				}
+				got := readColumn(t, file, meta, i, col)
+				if len(got) != len(rows) {
+					t.Fatalf("%s: %d values, want %d", col.Name, len(got), len(rows))
+				}
+				for j, row := range rows {
+					if want := normalize(col, row[i]); got[j] != want {
+						t.Errorf("%s row %d: %v, want %v", col.Name, j, got[j], want)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+// normalize is v as readColumn decodes it from a column of col.
+func normalize(col Column, v any) any {
+	if v == nil {
+		return nil
+	}
+	switch col.Type {
+	case Int64:
+		n, _ := toInt64(v)
+		return n
+	case Double:
+		if n, ok := toInt64(v); ok {
+			return float64(n)
+		}
+	}
+	return v
+}
+
+func TestWriterErrors(t *testing.T) {
+	w, err := NewWriter(io.Discard, []Column{{Name: "count", Type: Int64}}, Uncompressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]any{nil}); err == nil {
+		t.Error("nil in a required column was taken")
+	}
+	w, _ = NewWriter(io.Discard, []Column{{Name: "count", Type: Int64}}, Uncompressed)
+	if err := w.Write([]any{"ten"}); err == nil {
+		t.Error("a string in an int64 column was taken")
+	}
+	if err := w.Write([]any{1, 2}); err == nil {
+		t.Error("a row of two values for one column was taken")
+	}
+	if _, err := NewWriter(io.Discard, nil, Uncompressed); err == nil {
+		t.Error("a schema without columns was taken")
+	}
+}
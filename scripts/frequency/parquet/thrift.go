@@ -0,0 +1,81 @@
+package parquet
+
+import "encoding/binary"
+
+// Field types of the Thrift compact protocol, in which the Parquet footer
+// and page headers are encoded.
+const (
+	tI32    = 5
+	tI64    = 6
+	tBinary = 8
+	tList   = 9
+	tStruct = 12
+)
+
+// compact encodes Thrift structs in the compact protocol. Each field
+// header holds the difference from the id of the field before it in the
+// same struct, so the last ids of the structs being written are kept.
+type compact struct {
+	buf  []byte
+	last []int16
+}
+
+func (c *compact) uvarint(v uint64) { c.buf = binary.AppendUvarint(c.buf, v) }
+
+// varint writes v zigzag-encoded, as the compact protocol has all integers.
+func (c *compact) varint(v int64) { c.uvarint(uint64(v<<1) ^ uint64(v>>63)) }
+
+func (c *compact) beginStruct() { c.last = append(c.last, 0) }
+
+func (c *compact) endStruct() {
+	c.buf = append(c.buf, 0)
+	c.last = c.last[:len(c.last)-1]
+}
+
+func (c *compact) field(id int16, typ byte) {
+	last := &c.last[len(c.last)-1]
+	if d := id - *last; d > 0 && d <= 15 {
+		c.buf = append(c.buf, byte(d)<<4|typ)
+	} else {
+		c.buf = append(c.buf, typ)
+		c.varint(int64(id))
+	}
+	*last = id
+}
+
+func (c *compact) i32(id int16, v int32) {
+	c.field(id, tI32)
+	c.varint(int64(v))
+}
+
+func (c *compact) i64(id int16, v int64) {
+	c.field(id, tI64)
+	c.varint(v)
+}
+
+func (c *compact) string(id int16, s string) {
+	c.field(id, tBinary)
+	c.rawString(s)
+}
+
+func (c *compact) rawString(s string) {
+	c.uvarint(uint64(len(s)))
+	c.buf = append(c.buf, s...)
+}
+
+// list writes the header of a list of n elements of typ, which follow.
+func (c *compact) list(id int16, typ byte, n int) {
+	c.field(id, tList)
+	if n < 15 {
+		c.buf = append(c.buf, byte(n)<<4|typ)
+		return
+	}
+	c.buf = append(c.buf, 0xf0|typ)
+	c.uvarint(uint64(n))
+}
+
+// structField begins a struct held by field id; endStruct ends it.
+func (c *compact) structField(id int16) {
+	c.field(id, tStruct)
+	c.beginStruct()
+}
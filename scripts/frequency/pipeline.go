@@ -0,0 +1,735 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/frequency/translit"
+	"dpd/go_modules/tools"
+)
+
+// pipeline holds the settings shared by every corpus of a run.
+type pipeline struct {
+	ctx   context.Context       // canceled on interrupt
+	sem   chan struct{}         // bounds the files counted at once
+	db    *sql.DB               // nil unless rows are exported
+	roman translit.Romanization // of the words of the rows exported
+	tok   pali.Tokenizer
+	lem   *freq.Lemmatizer // nil unless -lemmas is given
+	split *splitter        // nil unless -split is given
+	force bool             // recount files even when the cache has them
+	index bool             // keep per-file counts for the word_citation table
+	files bool             // write the frequency tables and word lists
+	sink  Sink             // where the frequency tables go when files is set
+
+	exclude map[string]bool   // words left out of the word lists; the tables keep them
+	numbers map[string]string // kinds of the DPD number words under -drop-numbers, else nil
+
+	variants *pali.Variants // nil unless -variants is given
+
+	layers   map[string]bool // layers to count, nil for all files
+	layerKey string          // added to output names when layers is set, e.g. "mul"
+	filter   *freq.Filter    // of -include-files and -exclude-files, nil for all files; its key is added to output names
+
+	strict  bool // exit nonzero when a corpus is skipped or fails
+	failed  int  // corpora skipped or failed so far
+	timings bool // print the time spent per stage at the end
+
+	maxFileErrors  int  // file problems tolerated before exiting nonzero
+	excludeSuspect bool // leave the files whose text looks wrong out of the counts
+	problemsMu     sync.Mutex
+	problems       []fileProblem // of the corpora counted so far
+
+	ngramSizes []int // n-gram tables to build, e.g. [2 3]
+	ngramMin   int   // minimum count for an n-gram to be written
+
+	verse  bool        // count verse and prose apart where the corpus tells them
+	genres bool        // write the frequency tables of each piṭaka and genre
+	dedup  *freq.Dedup // also count with repeated paragraphs weighed down
+
+	provenance int // locations kept per word for the provenance table, 0 for none
+
+	cleaningReport bool // report how often each cleaning rule fired
+
+	checkpointEvery time.Duration // 0 disables checkpoints
+	resume          bool          // resume from the checkpoints of an earlier run
+	checkpointsMu   sync.Mutex
+	checkpoints     []*freq.Checkpoint // of the corpora counted so far
+	complete        bool               // every corpus was counted or skipped for missing input
+
+	prog *tools.Progress // files counted so far, across corpora
+
+	meta *sidecars // the word lists written, given sidecars by finish
+
+	history    *sql.DB // the -history database, nil without it
+	historyTop int     // words of each run ranked in the history
+
+	prof *profiles // of -cpuprofile and -memprofile
+
+	dbPath  string      // of db, listed among the outputs of the run summary
+	summary *summaryLog // nil for no summary.json
+}
+
+// pipelineFlags are the flags of every subcommand that counts corpora.
+type pipelineFlags struct {
+	jobs     *int
+	names    *string
+	tok      pali.Tokenizer
+	force    *bool
+	verbose  *bool
+	layers   *string
+	include  []string
+	exclude  []string
+	strict   *bool
+	variants *bool
+	repair   *bool
+	rejoin   *bool
+	chain    *string
+	timings  *bool
+	dryRun   *bool
+
+	maxFileErrors  *int
+	excludeSuspect *bool
+	strictEncoding *bool
+
+	checkpoint *time.Duration
+	resume     *bool
+
+	cleaningReport *bool
+
+	history    *string
+	historyTop *int
+
+	cpuProfile *string
+	memProfile *string
+
+	fs      *flag.FlagSet // of the command, for the run summary
+	summary *string
+}
+
+func addPipelineFlags(fs *flag.FlagSet) *pipelineFlags {
+	pf := &pipelineFlags{tok: cfg.tokenizer(), fs: fs}
+	pf.jobs = fs.Int("jobs", runtime.NumCPU(), "number of files counted concurrently")
+	pf.names = fs.String("corpora", "", "comma-separated corpora to count (default: all)")
+	fs.Func("custom-corpus", "also count your own texts: `[NAME=]DIR`, a directory of UTF-8 Roman Pāḷi .txt files, counted as NAME (default custom); repeatable", addCustomCorpus)
+	fs.BoolVar(&pf.tok.KeepDandas, "keep-dandas", pf.tok.KeepDandas, "count daṇḍas (। ॥) as tokens")
+	fs.BoolVar(&pf.tok.KeepParagraphNumbers, "keep-paranums", pf.tok.KeepParagraphNumbers, "count braced paragraph numbers like {12} as tokens")
+	fs.BoolVar(&pf.tok.KeepDigits, "keep-digits", pf.tok.KeepDigits, "count Latin digit runs as tokens")
+	fs.BoolVar(&pf.tok.KeepEditorial, "keep-editorial", pf.tok.KeepEditorial, "count elision markers ([pe], …pe…) as the token pe")
+	fs.BoolVar(&pf.tok.KeepForeign, "keep-foreign", pf.tok.KeepForeign, "count the words of Sanskrit and English spans too (see contamination)")
+	pf.chain = fs.String("normalize", pf.tok.Normalizer.String(), "comma-separated normalizer steps run on the text in order, of "+strings.Join(pali.StepNames(), ", "))
+	pf.layers = fs.String("layers", "all", "text layers to count: all, mula, commentaries, or layer keys like mul,att,tik,nrf")
+	fs.Func("include-files", "count only the files whose name or book matches `PATTERN`, a glob like s02*.xml or mn, or a /regexp/; repeatable", func(s string) error {
+		pf.include = append(pf.include, s)
+		return nil
+	})
+	fs.Func("exclude-files", "leave out the files whose name or book matches `PATTERN`, as for -include-files; repeatable", func(s string) error {
+		pf.exclude = append(pf.exclude, s)
+		return nil
+	})
+	pf.force = fs.Bool("force", false, "ignore the file cache and recount every file")
+	pf.repair = fs.Bool("auto-repair", false, "undo mojibake before counting, such as Ä plus U+0081 for ā, where charset finds a certain fix; adds repair-mojibake to -normalize")
+	pf.rejoin = fs.Bool("rejoin-hyphens", false, "rejoin words a line-break hyphen split, such as paṭi- ccasamuppāda, before counting; adds rejoin-hyphens to -normalize")
+	pf.variants = fs.Bool("variants", false, "merge spelling variants (ḷ/l, vy/by, ṇṇ/nn, ṃ/ṅ and the other niggahīta before a stop, or the [[variants]] of palifreq.toml) before counting; adds variant-map to -normalize")
+	pf.strict = fs.Bool("strict", false, "exit with status 1 when a corpus is skipped or fails")
+	pf.verbose = fs.Bool("verbose", false, "log per-file details")
+	pf.dryRun = fs.Bool("dry-run", false, "report the files, bytes and outputs of the run and missing prerequisites, without counting")
+	pf.maxFileErrors = fs.Int("max-file-errors", 0, "exit with status 1 when more files than this could not be read or look wrong")
+	pf.excludeSuspect = fs.Bool("exclude-suspect", false, "leave the files whose text looks wrong out of the counts instead of counting and flagging them")
+	pf.strictEncoding = fs.Bool("strict-encoding", false, "fail the text files that are not valid UTF-8 instead of reading their stray bytes as Windows-1252")
+	pf.checkpoint = fs.Duration("checkpoint", 10*time.Minute, "save the counts of each corpus this often while counting, for -resume (0: never)")
+	pf.resume = fs.Bool("resume", false, "resume from the checkpoints of an interrupted or crashed run")
+	pf.timings = fs.Bool("timings", false, "print the time spent reading, normalizing, tokenizing, counting and writing")
+	pf.cleaningReport = fs.Bool("dump-cleaning-report", false, "report how many times each cleaning rule of the corpora fired; every file is recounted")
+	pf.history = fs.String("history", "", "append the summary and top words of each corpus counted to the history database at `PATH`, which palifreq history reports on")
+	pf.historyTop = fs.Int("history-top", 10000, "number of top words of each corpus kept in the -history database")
+	pf.cpuProfile = fs.String("cpuprofile", "", "write a CPU profile of the run to this file, for go tool pprof")
+	pf.memProfile = fs.String("memprofile", "", "write a heap profile to this file when the run is done, for go tool pprof")
+	pf.summary = fs.String("summary", "", "file to write the machine-readable summary of the run to (default: <output dir>/summary.json)")
+	return pf
+}
+
+// pipeline returns a pipeline configured by the parsed flags, counting
+// until ctx is done, and the corpora it should count.
+func (pf *pipelineFlags) pipeline(ctx context.Context) (*pipeline, []corpora.Corpus, error) {
+	if *pf.verbose {
+		tools.SetLogLevel(tools.LevelDebug)
+	}
+	corpora.StrictEncoding = *pf.strictEncoding
+	list, err := selectCorpora(*pf.names)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the custom corpora are counted whatever -corpora names
+	for _, name := range customCorpora {
+		if !slices.ContainsFunc(list, func(c corpora.Corpus) bool { return c.Name() == name }) {
+			c, _ := corpora.Get(name)
+			list = append(list, c)
+		}
+	}
+	// one semaphore for all corpora, so -jobs bounds the whole run
+	p := &pipeline{ctx: ctx, sem: make(chan struct{}, max(*pf.jobs, 1)), tok: pf.tok, force: *pf.force, strict: *pf.strict, timings: *pf.timings, maxFileErrors: *pf.maxFileErrors, excludeSuspect: *pf.excludeSuspect, checkpointEvery: *pf.checkpoint, resume: *pf.resume}
+	// cached counts were made without cleaning anything this run
+	if *pf.cleaningReport {
+		p.cleaningReport, p.force = true, true
+	}
+	if p.layers, p.layerKey, err = parseLayers(*pf.layers); err != nil {
+		return nil, nil, err
+	}
+	if p.filter, err = freq.NewFilter(pf.include, pf.exclude); err != nil {
+		return nil, nil, err
+	}
+	useFilters(p.layerKey, p.filter)
+	nz, err := pali.ParseNormalizer(*pf.chain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("-normalize: %w", err)
+	}
+	if *pf.repair {
+		nz = nz.With(pali.StepRepairMojibake)
+	}
+	if *pf.rejoin {
+		nz = nz.With(pali.StepRejoinHyphens)
+	}
+	if *pf.variants {
+		nz = nz.With(pali.StepVariantMap)
+	}
+	p.tok.Normalizer = nz
+	p.meta = &sidecars{}
+	useNormalizer(nz)
+	tools.Debugf("normalizer: %s", nz)
+	if nz.MapsVariants() {
+		rules := cfg.Variants
+		if len(rules) == 0 {
+			rules = pali.DefaultVariants
+		}
+		if p.variants, err = pali.NewVariants(rules); err != nil {
+			return nil, nil, err
+		}
+	}
+	if *pf.history != "" {
+		if p.history, err = export.OpenHistory(*pf.history); err != nil {
+			return nil, nil, fmt.Errorf("-history: %w", err)
+		}
+		p.historyTop = max(*pf.historyTop, 1)
+	}
+	summary := *pf.summary
+	if summary == "" {
+		summary = filepath.Join(freqDir, "summary.json")
+	}
+	p.summary = newSummaryLog(pf.fs, summary)
+	// started last, so an error above leaves no profile running
+	if p.prof, err = startProfiles(*pf.cpuProfile, *pf.memProfile); err != nil {
+		return nil, nil, err
+	}
+	return p, list, nil
+}
+
+// layerAliases are the -layers shorthands for common selections.
+var layerAliases = map[string][]string{
+	"mula":         {corpora.Mula},
+	"commentaries": {corpora.Atthakatha, corpora.Tika},
+}
+
+// parseLayers parses the -layers flag into the set of layers to count and
+// the key naming the selection in output files, the layers in canonical
+// order joined by "_". "all" selects every file, including those of no
+// known layer, and returns a nil set.
+func parseLayers(s string) (map[string]bool, string, error) {
+	if s == "" || s == "all" {
+		return nil, "", nil
+	}
+	set := make(map[string]bool)
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if keys, ok := layerAliases[f]; ok {
+			for _, k := range keys {
+				set[k] = true
+			}
+			continue
+		}
+		if !slices.Contains(corpora.Layers, f) {
+			return nil, "", fmt.Errorf("unknown layer %q (want all, mula, commentaries or %s)", f, strings.Join(corpora.Layers, ", "))
+		}
+		set[f] = true
+	}
+	var keys []string
+	for _, k := range corpora.Layers {
+		if set[k] {
+			keys = append(keys, k)
+		}
+	}
+	return set, strings.Join(keys, "_"), nil
+}
+
+// label is the name under which the counts of c are saved: the corpus name,
+// followed by the layer key when only some layers are counted and the
+// filter key when only some files are.
+func (p *pipeline) label(c corpora.Corpus) string {
+	return p.layered(c.Name())
+}
+
+// layered appends the layer key and the filter key, if any, to an output
+// name.
+func (p *pipeline) layered(name string) string {
+	if p.layerKey != "" {
+		name += "_" + p.layerKey
+	}
+	if p.filter != nil {
+		name += "_" + p.filter.Key()
+	}
+	return name
+}
+
+// selectCorpora returns the registered corpora named in the comma-separated
+// list names, or all of them when names is empty.
+func selectCorpora(names string) ([]corpora.Corpus, error) {
+	if names == "" {
+		return corpora.Registered(), nil
+	}
+	var list []corpora.Corpus
+	for _, name := range strings.Split(names, ",") {
+		c, ok := corpora.Get(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown corpus %q (have %s)", name, strings.Join(corpusNames(), ", "))
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+func corpusNames() []string {
+	var names []string
+	for _, c := range corpora.Registered() {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+func commandUsage(fs *flag.FlagSet, summary string) {
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: palifreq %s [flags]\n\n%s\n\n", fs.Name(), summary)
+		fs.PrintDefaults()
+	}
+}
+
+// runAll counts every corpus of list concurrently with makeFreq and returns
+// the word counts of those that succeeded, by corpus name without the layer
+// key. Corpora whose input is missing are skipped; they and the failed ones
+// are summed up once all are done. After an interrupt, the corpora left
+// unfinished are only counted up.
+func (p *pipeline) runAll(list []corpora.Corpus) map[string]map[string]int {
+	p.summary.write("running", 0, nil)
+	p.prog = tools.NewProgress("counting", 0)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		totals   = make(map[string]map[string]int)
+		problems = make(map[string]error)
+		took     = make(map[string]time.Duration)
+	)
+	for _, c := range list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			counts, err := p.makeFreq(c)
+			mu.Lock()
+			defer mu.Unlock()
+			took[p.label(c)] = time.Since(start)
+			if err != nil {
+				problems[p.label(c)] = err
+				return
+			}
+			totals[c.Name()] = counts
+		}()
+	}
+	wg.Wait()
+	p.prog.Finish()
+
+	unfinished, failed := 0, 0
+	for _, c := range list {
+		name := p.label(c)
+		err, ok := problems[name]
+		if !ok {
+			p.summary.ended(name, "ok", nil, took[name])
+			continue
+		}
+		if p.ctx.Err() != nil && errors.Is(err, p.ctx.Err()) {
+			p.summary.ended(name, "interrupted", nil, took[name])
+			unfinished++
+			continue
+		}
+		p.failed++
+		if errors.As(err, new(skipError)) {
+			p.summary.ended(name, "skipped", err, took[name])
+			tools.Warnf("%s: %v", name, err)
+		} else {
+			p.summary.ended(name, "failed", err, took[name])
+			tools.Errorf("%s: %v", name, err)
+			failed++
+		}
+	}
+	if unfinished > 0 {
+		tools.Warnf("%d corpora not finished; their outputs were left as they were", unfinished)
+		if p.checkpointEvery > 0 {
+			tools.Infof("rerun with -resume to continue where counting stopped")
+		}
+	}
+	p.complete = unfinished == 0 && failed == 0
+	return totals
+}
+
+// fileProblem is a freq.FileProblem of the corpus saved under label.
+type fileProblem struct {
+	label string
+	freq.FileProblem
+}
+
+// addProblems records the file problems of the corpus saved under label
+// for the report of finish.
+func (p *pipeline) addProblems(label string, list []freq.FileProblem) {
+	p.problemsMu.Lock()
+	defer p.problemsMu.Unlock()
+	for _, fp := range list {
+		p.problems = append(p.problems, fileProblem{label, fp})
+	}
+}
+
+// reportProblems lists the files that could not be read or look wrong,
+// by corpus and path, and reports whether there are more of them than
+// p.maxFileErrors.
+func (p *pipeline) reportProblems() bool {
+	if len(p.problems) == 0 {
+		return false
+	}
+	slices.SortFunc(p.problems, func(a, b fileProblem) int {
+		return cmp.Or(strings.Compare(a.label, b.label), strings.Compare(a.Path, b.Path))
+	})
+	tools.Warnf("files with problems: %d", len(p.problems))
+	for _, fp := range p.problems {
+		if fp.Skipped {
+			tools.Warnf("  %s: %s: %s (skipped)", fp.label, fp.Path, fp.Reason)
+		} else {
+			tools.Warnf("  %s: %s: %s (counted)", fp.label, fp.Path, fp.Reason)
+		}
+	}
+	if !p.excludeSuspect && slices.ContainsFunc(p.problems, func(fp fileProblem) bool { return !fp.Skipped }) {
+		tools.Infof("rerun with -exclude-suspect to leave the suspicious files out of the counts")
+	}
+	if len(p.problems) > p.maxFileErrors {
+		tools.Errorf("%d files with problems, more than -max-file-errors %d allows", len(p.problems), p.maxFileErrors)
+		return true
+	}
+	return false
+}
+
+// provenanceTable is the table of columns word, file, line and column of
+// the first locations of each word of list, in its order.
+func provenanceTable(list []freq.WordCount, locs map[string][]freq.Location) table {
+	t := table{columns: []string{"word", "file", "line", "column"}}
+	for _, wc := range list {
+		for _, l := range locs[wc.Word] {
+			t.rows = append(t.rows, []any{wc.Word, l.Path, l.Line, l.Column})
+		}
+	}
+	return t
+}
+
+// qaTable is the quality report of a corpus: each file that could not be
+// read or looks wrong, whether it was counted, and why.
+func qaTable(problems []freq.FileProblem) table {
+	t := table{columns: []string{"file", "status", "reason"}}
+	for _, fp := range problems {
+		status := "counted"
+		if fp.Skipped {
+			status = "skipped"
+		}
+		t.rows = append(t.rows, []any{fp.Path, status, fp.Reason})
+	}
+	return t
+}
+
+// finish builds the rank indexes the export deferred, closes the databases
+// and the sink, if any, writes the sidecars of the word lists, removes the
+// checkpoints once every corpus was counted, reports the files with
+// problems, prints the stage timings under -timings, stops the profiles
+// and writes the run summary. It logs an error, for palifreq to exit with
+// status 1, when more files had problems than -max-file-errors allows, or
+// under -strict when a corpus was skipped or failed.
+func (p *pipeline) finish() {
+	if p.db != nil {
+		err := export.BuildIndexes(p.db)
+		if cerr := export.Close(p.db); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			tools.Errorf("%v", err)
+			p.failed++
+		} else if p.dbPath != "" {
+			p.addOutput(p.dbPath)
+		}
+	}
+	if p.history != nil {
+		if err := export.Close(p.history); err != nil {
+			tools.Errorf("%v", err)
+			p.failed++
+		}
+	}
+	if p.sink != nil {
+		if err := p.sink.Close(); err != nil {
+			tools.Errorf("%v", err)
+			p.failed++
+		}
+	}
+	if err := p.meta.write(); err != nil {
+		tools.Errorf("%v", err)
+		p.failed++
+	}
+	if p.complete {
+		for _, ck := range p.checkpoints {
+			if err := ck.Remove(); err != nil {
+				tools.Warnf("%v", err)
+			}
+		}
+	}
+	tooMany := p.reportProblems()
+	if p.timings {
+		tools.PrintStages()
+	}
+	p.prof.stop()
+	if p.strict && p.failed > 0 {
+		tools.Errorf("%d corpora skipped or failed (-strict)", p.failed)
+	}
+	exit := tooMany || (p.strict && p.failed > 0)
+	if p.summary != nil {
+		status, code := "ok", 0
+		switch {
+		case p.ctx.Err() != nil:
+			status, code = "interrupted", 130
+		case exit || tools.Errors() > p.summary.errors:
+			status, code = "failed", 1
+		}
+		p.summary.write(status, code, p.problems)
+	}
+}
+
+// addOutput records the output at path, which has no sidecar, for the run
+// summary.
+func (p *pipeline) addOutput(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		tools.Warnf("%v", err)
+		return
+	}
+	f := jobFile{Path: filepath.ToSlash(path), Bytes: info.Size()}
+	if f.SHA256, err = freq.HashFile(path); err != nil {
+		tools.Warnf("%v", err)
+		return
+	}
+	wroteOutput(f)
+}
+
+// makeFreq counts one corpus and returns its word counts. When p.files is
+// set it saves the frequency file, word list and coverage table of the
+// corpus and a frequency file per book, and with them:
+//   - the split table, when p.split is set;
+//   - n-gram tables, when p.ngramSizes is set;
+//   - headword frequencies, when p.lem is set;
+//   - verse and prose tables, when p.verse is set and the corpus marks
+//     verse;
+//   - a deduplicated table, when p.dedup is set;
+//   - the first locations of each word, when p.provenance is set.
+//
+// When p.db is set it writes the matching database rows, including the
+// citation index when p.index is set, and when p.history is set it records
+// the run in the history database.
+func (p *pipeline) makeFreq(c corpora.Corpus) (map[string]int, error) {
+	cc, err := p.countCorpus(c)
+	if err != nil {
+		return nil, err
+	}
+	defer cc.Close()
+	books := cc.Books
+	counts := books.Total()
+	name := p.label(c)
+	p.addProblems(name, cc.Problems)
+	p.summary.counted(corpusRun{Name: name, Files: len(cc.Files), Tokens: freq.TokenTotal(counts), Types: len(counts), FileProblems: len(cc.Problems)})
+	tools.Infof("%s: %d words in %d books", name, len(counts), len(books))
+	if p.cleaningReport {
+		logCleaning(name, corpora.CleanerOf(c))
+	}
+	if cc.Dedup != nil {
+		st := cc.DedupStats
+		tools.Infof("%s: %d of %d paragraphs repeat an earlier one, %.1f%% of the tokens; %d words counted without them", name, st.Repeats, st.Paragraphs, 100*float64(st.Repeated)/float64(max(st.Tokens, 1)), len(cc.Dedup))
+	}
+	if p.verse && cc.Verse == nil {
+		tools.Infof("%s: verse is not marked in this edition; no verse and prose counts", name)
+	}
+	var lemmas []freq.LemmaCount
+	if p.lem != nil {
+		lemmas = p.lem.Counts(counts)
+	}
+	// an interrupt between counting and writing leaves the outputs alone
+	if err := p.ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer stageWrite.Start()()
+	if p.files {
+		if err := p.saveFiles(name, c, cc, counts, lemmas); err != nil {
+			return nil, err
+		}
+	}
+	if p.db != nil {
+		writes := []func() error{
+			func() error { return export.WordFrequency(p.db, name, romanizeCounts(p.roman, counts)) },
+			func() error { return export.BookFrequency(p.db, name, romanizeBooks(p.roman, books)) },
+		}
+		if lemmas != nil {
+			writes = append(writes, func() error { return export.LemmaFrequency(p.db, name, romanizeLemmas(p.roman, lemmas)) })
+		}
+		if p.index {
+			writes = append(writes, func() error {
+				return export.Citations(p.db, name, corpora.RootOf(c), romanizeBooks(p.roman, cc.Files))
+			})
+		}
+		if cc.Verse != nil {
+			writes = append(writes,
+				func() error { return export.WordFrequency(p.db, name+"_verse", romanizeCounts(p.roman, cc.Verse)) },
+				func() error { return export.WordFrequency(p.db, name+"_prose", romanizeCounts(p.roman, cc.Prose)) })
+		}
+		if err := writeAll(writes); err != nil {
+			return nil, err
+		}
+	}
+	if p.history != nil {
+		if err := p.recordHistory(name, cc, counts); err != nil {
+			return nil, fmt.Errorf("-history: %w", err)
+		}
+	}
+	return counts, nil
+}
+
+// writeAll runs writes at once and returns the first of their errors, in
+// their order. Each writer of package export romanizes and sorts its rows
+// before it takes the single connection of the database, so the tables of
+// a corpus are made ready side by side while one of them is written.
+func writeAll(writes []func() error) error {
+	errs := make([]error, len(writes))
+	var wg sync.WaitGroup
+	for i, w := range writes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = w()
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unlisted reports whether the word lists leave w out: a word of -exclude
+// or, under -drop-numbers, a numeral or number word.
+func (p *pipeline) unlisted(w string) bool {
+	return p.exclude[w] || p.numbers != nil && numberKind(p.numbers, w) != ""
+}
+
+// saveFiles writes the file outputs of makeFreq for the corpus c.
+func (p *pipeline) saveFiles(name string, c corpora.Corpus, cc *freq.Table, counts map[string]int, lemmas []freq.LemmaCount) error {
+	list := freq.Sorted(counts)
+	if err := saveFreq(p.sink, name, list, freq.DispersionStats(cc.Files)); err != nil {
+		return err
+	}
+	// the extraction scripts read the word lists from the output
+	// directory, whatever the sink
+	wordlist := filepath.Join(freqDir, name+"_wordlist.json")
+	if err := saveWordlist(wordlist, list, p.unlisted); err != nil {
+		return err
+	}
+	p.meta.add(wordlist)
+	if p.numbers != nil {
+		if err := p.sink.Write(name+"_numbers", numberTable(list, p.numbers)); err != nil {
+			return err
+		}
+	}
+	if err := saveCoverage(p.sink, name, list); err != nil {
+		return err
+	}
+	if err := p.sink.Write(name+"_qa", qaTable(cc.Problems)); err != nil {
+		return err
+	}
+	if cc.Locations != nil {
+		if err := p.sink.Write(name+"_provenance", provenanceTable(list, cc.Locations)); err != nil {
+			return err
+		}
+	}
+	if err := saveBookFreq(p.sink, name, cc.Books); err != nil {
+		return err
+	}
+	if p.genres {
+		if err := saveGenreFreq(p.sink, name, c, cc.Files); err != nil {
+			return err
+		}
+	}
+	if cc.Dedup != nil {
+		if err := p.sink.Write(name+"_dedup_freq", freqTable(freq.Sorted(cc.Dedup))); err != nil {
+			return err
+		}
+	}
+	if p.cleaningReport {
+		if err := p.sink.Write(name+"_cleaning", cleaningTable(corpora.CleanerOf(c))); err != nil {
+			return err
+		}
+	}
+	if p.variants != nil {
+		if err := saveVariantReport(p.sink, name, p.variants, cc.Collapsed); err != nil {
+			return err
+		}
+	}
+	if p.split != nil {
+		split := freqTable(freq.Sorted(p.split.splitCounts(counts)))
+		if err := p.sink.Write(name+"_split_freq", split); err != nil {
+			return err
+		}
+	}
+	for _, n := range p.ngramSizes {
+		ranked, total, err := cc.Ngrams[n].Merge(p.ngramMin)
+		if err != nil {
+			return err
+		}
+		if err := saveNgramFreq(p.sink, name, n, ranked, total); err != nil {
+			return err
+		}
+	}
+	if cc.Verse != nil {
+		if err := p.sink.Write(name+"_verse_freq", freqTable(freq.Sorted(cc.Verse))); err != nil {
+			return err
+		}
+		if err := p.sink.Write(name+"_prose_freq", freqTable(freq.Sorted(cc.Prose))); err != nil {
+			return err
+		}
+	}
+	if lemmas != nil {
+		return saveLemmaFreq(p.sink, name, lemmas, freq.TokenTotal(counts))
+	}
+	return nil
+}
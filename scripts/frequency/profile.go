@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+
+	"dpd/go_modules/tools"
+)
+
+// profiles writes the CPU profile of -cpuprofile while a run counts, and
+// the heap profile of -memprofile when it is done, for go tool pprof.
+type profiles struct {
+	cpu     *os.File // nil unless -cpuprofile is given
+	memPath string   // "" unless -memprofile is given
+	once    sync.Once
+}
+
+// startProfiles starts the CPU profile into cpuPath, unless it is empty,
+// and notes memPath for stop.
+func startProfiles(cpuPath, memPath string) (*profiles, error) {
+	pr := &profiles{memPath: memPath}
+	if cpuPath == "" {
+		return pr, nil
+	}
+	f, err := os.Create(cpuPath)
+	if err != nil {
+		return nil, fmt.Errorf("-cpuprofile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("-cpuprofile: %w", err)
+	}
+	pr.cpu = f
+	return pr, nil
+}
+
+// stop ends the CPU profile and writes the heap profile. Only the first
+// call does anything, so it can be both deferred and called before exiting.
+func (pr *profiles) stop() {
+	if pr == nil {
+		return
+	}
+	pr.once.Do(func() {
+		if pr.cpu != nil {
+			pprof.StopCPUProfile()
+			if err := pr.cpu.Close(); err != nil {
+				tools.Errorf("-cpuprofile: %v", err)
+			}
+		}
+		if pr.memPath == "" {
+			return
+		}
+		f, err := os.Create(pr.memPath)
+		if err != nil {
+			tools.Errorf("-memprofile: %v", err)
+			return
+		}
+		defer f.Close()
+		// the live heap as of the last collection, so collect first
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			tools.Errorf("-memprofile: %v", err)
+		}
+	})
+}
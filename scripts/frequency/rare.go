@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/tools"
+)
+
+// rareWord is a word of a corpus seen at most the threshold of rareWords,
+// with the files it occurs in.
+type rareWord struct {
+	word  string
+	count int
+	files []string // sorted, with the count in a file when above 1
+}
+
+// rareWords returns the words of the per-file counts files seen at most
+// maxCount times in all, ordered by their first file, then word, so the
+// report reads file by file.
+func rareWords(files map[string]map[string]int, maxCount int) []rareWord {
+	total := make(map[string]int)
+	for _, counts := range files {
+		for w, n := range counts {
+			total[w] += n
+		}
+	}
+	byWord := make(map[string]*rareWord)
+	for _, path := range slices.Sorted(maps.Keys(files)) {
+		for w, n := range files[path] {
+			if total[w] > maxCount {
+				continue
+			}
+			r := byWord[w]
+			if r == nil {
+				r = &rareWord{word: w, count: total[w]}
+				byWord[w] = r
+			}
+			loc := path
+			if n > 1 {
+				loc = fmt.Sprintf("%s (%d)", path, n)
+			}
+			r.files = append(r.files, loc)
+		}
+	}
+	list := make([]rareWord, 0, len(byWord))
+	for _, r := range byWord {
+		list = append(list, *r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].files[0] != list[j].files[0] {
+			return list[i].files[0] < list[j].files[0]
+		}
+		return tools.ComparePali(list[i].word, list[j].word) < 0
+	})
+	return list
+}
+
+// rareTable is the report of the rare words list of one corpus: each word
+// with its count, its files, its count in the other corpora of elsewhere,
+// and, when lookup is not nil, whether DPD knows the form. Words other
+// editions have or DPD knows are less likely to be typing errors.
+func rareTable(list []rareWord, elsewhere map[string]int, lookup map[string][]int) table {
+	t := table{columns: []string{"word", "count", "files", "other_corpora"}}
+	if lookup != nil {
+		t.columns = append(t.columns, "in_dpd")
+	}
+	for _, r := range list {
+		row := []any{r.word, r.count, strings.Join(r.files, "; "), elsewhere[r.word]}
+		if lookup != nil {
+			inDpd := 0
+			if _, ok := lookup[r.word]; ok {
+				inDpd = 1
+			}
+			row = append(row, inDpd)
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+// runRare implements the rare subcommand: it lists the hapaxes and other
+// rare words of each corpus with the files they occur in, from the counts
+// of the last run.
+func runRare(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("rare", flag.ExitOnError)
+	commandUsage(fs, "Lists the hapaxes and other rare words of each corpus with the files they occur in, from the counts of the last run, to find typing and OCR errors.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora whose counts of the last run are reported")
+	maxCount := fs.Int("max-count", 1, "report words seen at most this many times in a corpus (1: hapaxes only)")
+	dpdPath := fs.String("dpd", "", "DPD database whose lookup table tells known forms (default: not checked)")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("listing rare words")
+	tic := tools.Tic()
+
+	list := strings.Split(*names, ",")
+	files := make(map[string]map[string]map[string]int, len(list))
+	totals := make(map[string]map[string]int, len(list))
+	for _, name := range list {
+		if files[name], err = loadFileCounts(name); err != nil {
+			tools.Errorf("%s: %v (count it first)", name, err)
+			return
+		}
+		totals[name] = make(map[string]int)
+		for _, counts := range files[name] {
+			for w, n := range counts {
+				totals[name][w] += n
+			}
+		}
+	}
+	var lookup map[string][]int
+	if *dpdPath != "" {
+		db, err := dpd.Open(*dpdPath)
+		if err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+		lookup, err = db.Lookup()
+		db.Close()
+		if err != nil {
+			tools.Errorf("%s: %v", *dpdPath, err)
+			return
+		}
+	}
+
+	for _, name := range list {
+		elsewhere := make(map[string]int)
+		for _, other := range list {
+			if other == name {
+				continue
+			}
+			for w, n := range totals[other] {
+				elsewhere[w] += n
+			}
+		}
+		rare := rareWords(files[name], *maxCount)
+		hapaxes := 0
+		for _, r := range rare {
+			if r.count == 1 {
+				hapaxes++
+			}
+		}
+		if err := sink.Write(name+"_rare_words", rareTable(rare, elsewhere, lookup)); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+		tools.Infof("%s: %d of %d words seen at most %d times, %d of them once", name, len(rare), len(totals[name]), *maxCount, hapaxes)
+	}
+
+	tic.Toc()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/translit"
+)
+
+// romanColumns are the columns of Pāḷi words that -romanization converts;
+// the title_ and text_ columns of the aligned paragraphs, and the form_
+// columns of their variants, are converted too.
+var romanColumns = map[string]bool{"word": true, "form": true, "ngram": true, "lemma": true, "ending": true, "forms": true, "collocate": true, "spellings": true, "root": true, "lemmas": true, "word_a": true, "word_b": true}
+
+// romanColumn reports whether -romanization converts column col.
+func romanColumn(col string) bool {
+	return romanColumns[col] || strings.HasPrefix(col, "title_") || strings.HasPrefix(col, "text_") || strings.HasPrefix(col, "form_")
+}
+
+// romanizeTable returns t with the text of its word columns converted from
+// IAST to r.
+func romanizeTable(r translit.Romanization, t table) table {
+	if r == translit.IAST {
+		return t
+	}
+	var cols []int
+	for i, c := range t.columns {
+		if romanColumn(c) {
+			cols = append(cols, i)
+		}
+	}
+	if len(cols) == 0 {
+		return t
+	}
+	convert := func(row []any) []any {
+		out := slices.Clone(row)
+		for _, i := range cols {
+			if s, ok := out[i].(string); ok {
+				out[i] = r.FromIAST(s)
+			}
+		}
+		return out
+	}
+	out := table{columns: t.columns}
+	if t.stream != nil {
+		stream := t.stream
+		out.stream = func(yield func(row []any) error) error {
+			return stream(func(row []any) error { return yield(convert(row)) })
+		}
+		return out
+	}
+	out.rows = make([][]any, len(t.rows))
+	for i, row := range t.rows {
+		out.rows[i] = convert(row)
+	}
+	return out
+}
+
+// romanSink writes the tables of another sink in a romanization other than
+// IAST.
+type romanSink struct {
+	Sink
+	r translit.Romanization
+}
+
+func (s romanSink) Write(name string, t table) error {
+	return s.Sink.Write(name, romanizeTable(s.r, t))
+}
+
+// romanizeCounts returns counts keyed by their words in r.
+func romanizeCounts(r translit.Romanization, counts map[string]int) map[string]int {
+	if r == translit.IAST {
+		return counts
+	}
+	out := make(map[string]int, len(counts))
+	for w, n := range counts {
+		out[r.FromIAST(w)] += n
+	}
+	return out
+}
+
+// romanizeBooks returns the counts of each book, or file, keyed by their
+// words in r.
+func romanizeBooks(r translit.Romanization, books freq.Books) freq.Books {
+	if r == translit.IAST {
+		return books
+	}
+	out := make(freq.Books, len(books))
+	for book, counts := range books {
+		out[book] = romanizeCounts(r, counts)
+	}
+	return out
+}
+
+// romanizeLemmas returns list with its lemmas in r.
+func romanizeLemmas(r translit.Romanization, list []freq.LemmaCount) []freq.LemmaCount {
+	if r == translit.IAST || list == nil {
+		return list
+	}
+	out := make([]freq.LemmaCount, len(list))
+	for i, lc := range list {
+		lc.Headword.Lemma1 = r.FromIAST(lc.Headword.Lemma1)
+		out[i] = lc
+	}
+	return out
+}
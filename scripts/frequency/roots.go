@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// verbalPos are the DPD parts of speech of the verbs and the verbal
+// derivatives, the headwords roots counts by default: the tenses and
+// moods, the participles, the absolutives and the infinitive.
+const verbalPos = "pr,aor,fut,opt,imp,cond,perf,imperf,pp,prp,app,ptp,abs,ger,inf"
+
+// rootTables returns the root_freq table of list, the roots with their
+// counts, and its root_forms table, the forms under each root, keeping the
+// top n forms and headwords of each. tokens is the token total the per
+// million counts are of.
+func rootTables(list []freq.RootCount, n, tokens int) (roots, forms table) {
+	roots.columns = []string{"rank", "root", "meaning", "count", "per_million", "headword_count", "form_count", "lemmas", "forms"}
+	forms.columns = []string{"root", "rank", "form", "count", "share"}
+	for i, rc := range list {
+		var heads, top []string
+		for _, lc := range rc.Headwords[:min(n, len(rc.Headwords))] {
+			heads = append(heads, fmt.Sprintf("%s %d", lc.Headword.Lemma1, lc.Count))
+		}
+		for j, wc := range rc.Forms[:min(n, len(rc.Forms))] {
+			top = append(top, fmt.Sprintf("%s %d", wc.Word, wc.Count))
+			forms.rows = append(forms.rows, []any{rc.Root.Key, j + 1, wc.Word, wc.Count, float64(wc.Count) / float64(rc.Count)})
+		}
+		roots.rows = append(roots.rows, []any{i + 1, rc.Root.Key, rc.Root.Meaning, rc.Count, freq.PerMillion(rc.Count, tokens),
+			len(rc.Headwords), len(rc.Forms), strings.Join(heads, ", "), strings.Join(top, ", ")})
+	}
+	return roots, forms
+}
+
+// prefixTable returns the root_prefixes table: a row for each root of
+// list, in its order, with its count and its counts with each of the n
+// prefix combinations most frequent over them, "none" for the bare root,
+// and the rest summed as "other". byRoot holds the counts by root and
+// prefixes, as PrefixCounts gives them.
+func prefixTable(list []freq.RootCount, byRoot map[string]map[string]int, n int) table {
+	total := make(map[string]int)
+	for _, rc := range list {
+		for prefix, c := range byRoot[rc.Root.Key] {
+			total[prefix] += c
+		}
+	}
+	combos := freq.Sorted(total)
+	if len(combos) > n {
+		combos = combos[:n]
+	}
+	t := table{columns: []string{"root", "count"}}
+	for _, wc := range combos {
+		name := strings.ReplaceAll(wc.Word, " ", "+")
+		if name == "" {
+			name = "none"
+		}
+		t.columns = append(t.columns, name)
+	}
+	t.columns = append(t.columns, "other")
+	for _, rc := range list {
+		row := []any{rc.Root.Key, rc.Count}
+		other := freq.TokenTotal(byRoot[rc.Root.Key])
+		for _, wc := range combos {
+			c := byRoot[rc.Root.Key][wc.Word]
+			row = append(row, c)
+			other -= c
+		}
+		t.rows = append(t.rows, append(row, other))
+	}
+	return t
+}
+
+// runRoots implements the roots subcommand.
+func runRoots(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("roots", flag.ExitOnError)
+	commandUsage(fs, "Writes the DPD roots (dhātu) ranked by the counts of their verb headwords, with the most frequent forms and headwords under each and a matrix of the prefixes (upasagga) each root is met with.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora whose counts of the last run rank the roots")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database")
+	pos := fs.String("pos", verbalPos, `comma-separated DPD parts of speech of the headwords counted for their roots, or "all" for every headword with a root`)
+	top := fs.Int("top", 0, "number of roots in the list (0: all)")
+	n := fs.Int("forms", 10, "number of forms and headwords listed under each root")
+	prefixes := fs.Int("prefixes", 20, "number of prefix combinations given a column of the root_prefixes matrix, the rest summed as other (0: no matrix)")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("counting the roots")
+	tic := tools.Tic()
+
+	total, err := corpusTotals(strings.Split(*names, ","))
+	if err != nil {
+		tools.Errorf("%v (count the corpora first)", err)
+		return
+	}
+	lem, err := freq.LoadLemmatizer(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	db, err := dpd.Open(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	roots, err := db.Roots()
+	var prefixOf map[int]string
+	if err == nil && *prefixes > 0 {
+		prefixOf, err = db.Prefixes()
+	}
+	db.Close()
+	if err != nil {
+		tools.Errorf("%s: %v", *dpdPath, err)
+		return
+	}
+	keep := func(dpd.Headword) bool { return true }
+	if *pos != "all" {
+		want := make(map[string]bool)
+		for _, p := range strings.Split(*pos, ",") {
+			want[strings.TrimSpace(p)] = true
+		}
+		keep = func(h dpd.Headword) bool { return want[h.Pos] }
+	}
+
+	list := lem.RootCounts(total, roots, keep)
+	if *top > 0 && len(list) > *top {
+		list = list[:*top]
+	}
+	rootTable, formTable := rootTables(list, *n, freq.TokenTotal(total))
+	if err := sink.Write("root_freq", rootTable); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if err := sink.Write("root_forms", formTable); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if *prefixes > 0 {
+		byRoot := lem.PrefixCounts(total, roots, prefixOf, keep)
+		if err := sink.Write("root_prefixes", prefixTable(list, byRoot, *prefixes)); err != nil {
+			tools.Errorf("%v", err)
+			return
+		}
+	}
+	tools.Infof("%d roots", len(list))
+
+	tic.Toc()
+}
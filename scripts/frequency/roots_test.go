@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+)
+
+func TestRunRoots(t *testing.T) {
+	freqDir = t.TempDir()
+	cache := freq.LoadCache(filepath.Join(freqDir, ".cache", "x.gob"), pali.Default, nil)
+	cache.Put("a.txt", "sum", map[string]int{"gacchati": 3, "āgacchati": 2, "dhammo": 5}, "")
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the tables of a release of each schema
+	for _, names := range [][3]string{
+		{"dpd_headwords", "lemma_1", "dpd_roots"},
+		{"pali_words", "pali_1", "pali_roots"},
+	} {
+		path := filepath.Join(t.TempDir(), "dpd.db")
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, q := range []string{
+			`CREATE TABLE ` + names[0] + ` (id INTEGER PRIMARY KEY, ` + names[1] + ` TEXT, pos TEXT, stem TEXT, pattern TEXT,
+				meaning_1 TEXT, meaning_2 TEXT, construction TEXT, grammar TEXT, root_key TEXT, family_root TEXT)`,
+			`INSERT INTO ` + names[0] + ` VALUES
+				(1, 'gacchati 1', 'pr', 'gacch', 'ati pr', 'goes', '', '', 'pr', '√gam', '√gam'),
+				(2, 'āgacchati 1', 'pr', 'āgacch', 'ati pr', 'comes', '', '', 'pr', '√gam', 'ā √gam'),
+				(3, 'dhamma 1', 'masc', 'dhamm', 'a masc', 'nature', '', '', 'masc', '', '')`,
+			`CREATE TABLE lookup (lookup_key TEXT PRIMARY KEY, headwords TEXT, deconstructor TEXT)`,
+			`INSERT INTO lookup VALUES ('gacchati', '[1]', ''), ('āgacchati', '[2]', ''), ('dhammo', '[3]', '')`,
+			`CREATE TABLE inflection_templates (pattern TEXT PRIMARY KEY, data TEXT)`,
+			`CREATE TABLE ` + names[2] + ` (root TEXT PRIMARY KEY, root_meaning TEXT)`,
+			`INSERT INTO ` + names[2] + ` VALUES ('√gam', 'going')`,
+		} {
+			if _, err := db.Exec(q); err != nil {
+				t.Fatalf("%s: %v", q, err)
+			}
+		}
+		db.Close()
+
+		runRoots(context.Background(), []string{"-corpora", "x", "-dpd", path, "-prefixes", "2"})
+		data, err := os.ReadFile(filepath.Join(freqDir, "root_freq.tsv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); !strings.Contains(got, "1\t√gam\tgoing\t5\t") || strings.Contains(got, "dhamma") {
+			t.Errorf("%s: root_freq.tsv\n%s", names[0], got)
+		}
+		data, err = os.ReadFile(filepath.Join(freqDir, "root_prefixes.tsv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); !strings.Contains(got, "√gam\t5\t3\t2\t0") {
+			t.Errorf("%s: root_prefixes.tsv\n%s", names[0], got)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"dpd/go_modules/tools"
+)
+
+// summarySchema is the version of the layout of summary.json. Fields may be
+// added without changing it; it changes when one is renamed, removed or
+// changes meaning.
+const summarySchema = 1
+
+// runSummary is summary.json, the machine-readable report of a run of a
+// counting command, for automation to read instead of the console: what
+// was counted, how long each stage took, what went wrong and which
+// outputs were written. It is written when counting starts, with status
+// running, and again when the run ends.
+type runSummary struct {
+	Schema   int      `json:"schema"`
+	Tool     string   `json:"tool"`
+	Command  string   `json:"command"`
+	Args     []string `json:"args"` // the flags set, as -name=value
+	Version  string   `json:"version"`
+	Commit   string   `json:"commit,omitempty"`
+	Dirty    bool     `json:"dirty,omitempty"`
+	Started  string   `json:"started"`            // RFC 3339, UTC
+	Finished string   `json:"finished,omitempty"` // RFC 3339, UTC
+	Seconds  float64  `json:"seconds"`
+	Status   string   `json:"status"` // running, ok, failed or interrupted
+	ExitCode int      `json:"exit_code"`
+
+	Corpora      []corpusRun  `json:"corpora"`
+	Stages       []stageRun   `json:"stages"`
+	FileProblems []problemRun `json:"file_problems"`
+
+	Warnings        int      `json:"warnings"`
+	Errors          int      `json:"errors"`
+	WarningMessages []string `json:"warning_messages"` // the first thousand
+	ErrorMessages   []string `json:"error_messages"`   // the first thousand
+
+	Outputs []jobFile `json:"outputs"` // sorted by path
+}
+
+// corpusRun is one corpus of a run summary, by the label its outputs are
+// saved under.
+type corpusRun struct {
+	Name         string  `json:"name"`
+	Status       string  `json:"status"` // ok, skipped, failed or interrupted
+	Error        string  `json:"error,omitempty"`
+	Files        int     `json:"files"`
+	Tokens       int     `json:"tokens"`
+	Types        int     `json:"types"`
+	FileProblems int     `json:"file_problems"`
+	Seconds      float64 `json:"seconds"`
+}
+
+// stageRun is the time of one stage of a run summary, summed over the
+// files counted at once.
+type stageRun struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+	Calls   int     `json:"calls"`
+}
+
+// problemRun is a file of a corpus that could not be read or looks wrong.
+type problemRun struct {
+	Corpus  string `json:"corpus"`
+	Path    string `json:"path"`
+	Reason  string `json:"reason"`
+	Skipped bool   `json:"skipped"`
+}
+
+// summaryLog is the run summary a pipeline keeps, with what was logged
+// and written before the run, which it leaves out.
+type summaryLog struct {
+	mu      sync.Mutex
+	path    string
+	started time.Time
+	s       runSummary
+	corpora map[string]corpusRun
+
+	warnings, errors, outputs int
+}
+
+// newSummaryLog starts the summary of a run of the command of fs, its
+// flags parsed, to be written to path.
+func newSummaryLog(fs *flag.FlagSet, path string) *summaryLog {
+	started := time.Now()
+	l := &summaryLog{path: path, started: started, corpora: make(map[string]corpusRun),
+		warnings: tools.Warnings(), errors: tools.Errors(), outputs: len(outputsSince(0))}
+	l.s = runSummary{Schema: summarySchema, Tool: "palifreq", Command: fs.Name(), Args: []string{}, Started: started.UTC().Format(time.RFC3339)}
+	l.s.Version, l.s.Commit, l.s.Dirty = buildInfo()
+	fs.Visit(func(f *flag.Flag) { l.s.Args = append(l.s.Args, "-"+f.Name+"="+f.Value.String()) })
+	return l
+}
+
+// counted records the counts of a corpus, before its outputs are saved.
+// The nil *summaryLog records nothing.
+func (l *summaryLog) counted(c corpusRun) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.corpora[c.Name] = c
+}
+
+// ended records how a corpus ended: its status, the error it failed with,
+// if any, and the time it took.
+func (l *summaryLog) ended(name, status string, err error, d time.Duration) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := l.corpora[name]
+	c.Name, c.Status, c.Seconds = name, status, d.Seconds()
+	if err != nil {
+		c.Error = err.Error()
+	}
+	l.corpora[name] = c
+}
+
+// write writes the summary with status and exitCode, as of now, with the
+// file problems of problems.
+func (l *summaryLog) write(status string, exitCode int, problems []fileProblem) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.s
+	s.Status, s.ExitCode = status, exitCode
+	s.Seconds = time.Since(l.started).Seconds()
+	if status != "running" {
+		s.Finished = time.Now().UTC().Format(time.RFC3339)
+	}
+	s.Corpora = make([]corpusRun, 0, len(l.corpora))
+	for _, c := range l.corpora {
+		s.Corpora = append(s.Corpora, c)
+	}
+	slices.SortFunc(s.Corpora, func(a, b corpusRun) int { return strings.Compare(a.Name, b.Name) })
+	s.Stages = []stageRun{}
+	for _, st := range tools.Stages() {
+		s.Stages = append(s.Stages, stageRun{st.Name, st.Total.Seconds(), st.Calls})
+	}
+	s.FileProblems = []problemRun{}
+	for _, fp := range problems {
+		s.FileProblems = append(s.FileProblems, problemRun{fp.label, fp.Path, fp.Reason, fp.Skipped})
+	}
+	s.Warnings, s.Errors = tools.Warnings()-l.warnings, tools.Errors()-l.errors
+	warnings, errors := tools.Kept()
+	s.WarningMessages = append([]string{}, warnings[min(l.warnings, len(warnings)):]...)
+	s.ErrorMessages = append([]string{}, errors[min(l.errors, len(errors)):]...)
+	s.Outputs = append([]jobFile{}, outputsSince(l.outputs)...)
+	slices.SortFunc(s.Outputs, func(a, b jobFile) int { return strings.Compare(a.Path, b.Path) })
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(l.path), 0o755); err == nil {
+			err = tools.WriteFileAtomic(l.path, append(data, '\n'), 0o644)
+		}
+	}
+	if err != nil {
+		tools.Warnf("run summary: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+func TestRunSummary(t *testing.T) {
+	tools.SetLogOutput(io.Discard)
+	defer tools.SetLogOutput(os.Stderr)
+	tools.Warnf("before the run")
+
+	fs := flag.NewFlagSet("freq", flag.ContinueOnError)
+	fs.String("corpora", "", "")
+	fs.Bool("lemmas", false, "")
+	if err := fs.Parse([]string{"-corpora", "cst,sya", "-lemmas"}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "summary.json")
+	l := newSummaryLog(fs, path)
+	l.counted(corpusRun{Name: "cst", Files: 2, Tokens: 10, Types: 4})
+	l.ended("cst", "ok", nil, time.Second)
+	l.ended("sya", "skipped", errors.New("no input"), 0)
+	tools.Warnf("a warning of the run")
+	wroteOutput(jobFile{Path: "out/cst_freq.tsv", Bytes: 3, SHA256: "abc"})
+	l.write("ok", 0, []fileProblem{{"cst", freq.FileProblem{Path: "a.xml", Reason: "not Pāḷi"}}})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s runSummary
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Schema != summarySchema || s.Command != "freq" || s.Status != "ok" || s.Finished == "" {
+		t.Errorf("summary %+v", s)
+	}
+	if len(s.Args) != 2 || s.Args[0] != "-corpora=cst,sya" || s.Args[1] != "-lemmas=true" {
+		t.Errorf("args %q", s.Args)
+	}
+	if len(s.Corpora) != 2 || s.Corpora[0] != (corpusRun{Name: "cst", Status: "ok", Files: 2, Tokens: 10, Types: 4, Seconds: 1}) || s.Corpora[1].Error != "no input" {
+		t.Errorf("corpora %+v", s.Corpora)
+	}
+	if s.Warnings != 1 || len(s.WarningMessages) != 1 || s.WarningMessages[0] != "a warning of the run" || s.ErrorMessages == nil {
+		t.Errorf("warnings %d %q, errors %q; want only the warning of the run", s.Warnings, s.WarningMessages, s.ErrorMessages)
+	}
+	if len(s.Outputs) != 1 || s.Outputs[0].Path != "out/cst_freq.tsv" || len(s.FileProblems) != 1 || s.FileProblems[0].Corpus != "cst" {
+		t.Errorf("outputs %+v, file problems %+v", s.Outputs, s.FileProblems)
+	}
+}
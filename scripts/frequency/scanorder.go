@@ -0,0 +1,51 @@
+package main
+
+import "context"
+
+// scanInOrder calls scan on each of files, up to jobs at once, and use on
+// the results one at a time in the order of files, so what use keeps does
+// not depend on which scan finishes first. At most jobs results wait for
+// use, which bounds the memory of a slow consumer. It stops at the first
+// error of scan or use, or when ctx is done, and returns it.
+func scanInOrder[T any](ctx context.Context, files []string, jobs int, scan func(path string) (T, error), use func(T) error) error {
+	type result struct {
+		v   T
+		err error
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sem := make(chan struct{}, max(jobs, 1))
+	results := make([]chan result, len(files))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	go func() {
+		for i, path := range files {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func() {
+				v, err := scan(path)
+				results[i] <- result{v, err}
+			}()
+		}
+	}()
+	for i := range files {
+		var r result
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		<-sem
+		if r.err != nil {
+			return r.err
+		}
+		if err := use(r.v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
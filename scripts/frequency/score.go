@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// scoreColumns are the columns of the learning value list, in the file
+// and the database alike.
+var scoreColumns = []string{"rank", "headword_id", "lemma", "pos", "count", "per_million", "dp", "length", "irregular", "score"}
+
+// scoreTable is the learning value list of the top n of list, or all of
+// it when n is 0, less the headwords whose lemma is in exclude, when
+// dropNames is set those in names and when dropNumbers is set those in
+// numbers.
+func scoreTable(list []freq.HeadwordScore, n int, exclude map[string]bool, names map[int]bool, dropNames bool, numbers map[int]string, dropNumbers bool) table {
+	t := table{columns: scoreColumns}
+	for _, s := range list {
+		if n > 0 && len(t.rows) == n {
+			break
+		}
+		if exclude[lemmaWord(s.Headword.Lemma1)] || dropNames && names[s.Headword.ID] || dropNumbers && numbers[s.Headword.ID] != "" {
+			continue
+		}
+		irregular := 0
+		if s.Irregular {
+			irregular = 1
+		}
+		t.rows = append(t.rows, []any{len(t.rows) + 1, s.Headword.ID, s.Headword.Lemma1, s.Headword.Pos, s.Count, s.PerMillion, s.DP, s.Length, irregular, s.Score})
+	}
+	return t
+}
+
+// loadBooks reads the per-file counts of the last run over names and adds
+// them up by book, the books of the editions of one text together.
+func loadBooks(names []string) (freq.Books, error) {
+	books := make(freq.Books)
+	for _, name := range names {
+		c, ok := corpora.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown corpus %q", name)
+		}
+		files, err := loadFileCounts(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w (count it first)", name, err)
+		}
+		for path, counts := range files {
+			books.Add(corpora.BookOf(c, path), counts)
+		}
+	}
+	return books, nil
+}
+
+// saveScoreDb replaces the learning_value table with the rows of t.
+func saveScoreDb(db *sql.DB, t table) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM learning_value`); err != nil {
+		return err
+	}
+	insert := export.NewInserter(tx, `INSERT INTO learning_value (`+strings.Join(scoreColumns, ", ")+`) VALUES`, ``, len(scoreColumns))
+	for _, row := range t.rows {
+		if err := insert.Add(row...); err != nil {
+			return err
+		}
+	}
+	if err := insert.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runScore implements the score subcommand: it ranks the DPD headwords by
+// learning value, from the counts of the last run.
+func runScore(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("score", flag.ExitOnError)
+	commandUsage(fs, "Ranks the DPD headwords by learning value, a weighted mean of their frequency, dispersion over the books, shortness and regularity, from the counts of the last run.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora whose counts of the last run score the headwords")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database")
+	weights := fs.String("weights", "", "weights of the parts of the score, e.g. frequency=0.5,dispersion=0.3,length=0.1,regularity=0.1 (default: frequency=0.4,dispersion=0.3,length=0.15,regularity=0.15)")
+	top := fs.Int("top", 0, "number of headwords in the list; 0 lists every headword counted")
+	dbPath := fs.String("db", "", "also write a learning_value table into this SQLite database")
+	exclude := fs.String("exclude", "", "file of words whose headwords to leave out, one per line (see stopwords)")
+	dropNames := fs.Bool("drop-names", false, "leave out proper nouns (names of people, places and texts)")
+	dropNumbers := fs.Bool("drop-numbers", false, "leave out number words (DPD cardinals and ordinals)")
+	sf := addSinkFlags(fs, "csv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("scoring headwords by learning value")
+	tic := tools.Tic()
+
+	w, err := freq.ParseScoreWeights(*weights)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	excluded, err := loadExclusions(*exclude)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	books, err := loadBooks(strings.Split(*names, ","))
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	lem, err := freq.LoadLemmatizer(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	db, err := dpd.Open(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	irregular, err := db.Irregular()
+	var properNouns map[int]bool
+	if err == nil {
+		properNouns, err = db.ProperNouns()
+	}
+	var numbers map[int]string
+	if err == nil {
+		numbers, err = db.Numbers()
+	}
+	db.Close()
+	if err != nil {
+		tools.Errorf("%s: %v", *dpdPath, err)
+		return
+	}
+
+	t := scoreTable(freq.LearningScores(books, lem, irregular, w), *top, excluded, properNouns, *dropNames, numbers, *dropNumbers)
+	if err := sink.Write("learning_value", t); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if *dbPath != "" {
+		out, err := export.Open(*dbPath)
+		if err != nil {
+			tools.Errorf("%s: %v", *dbPath, err)
+			return
+		}
+		err = saveScoreDb(out, romanizeTable(sf.roman, t))
+		if cerr := export.Close(out); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			tools.Errorf("%s: %v", *dbPath, err)
+			return
+		}
+	}
+	tools.Infof("%d headwords from %d books, weights %+v", len(t.rows), len(books), w)
+
+	tic.Toc()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// searchPassages adds the paragraphs of the files of c to the search
+// table through add, as passageText gives them, so the app shows them as
+// they read. When ctx is done it stops.
+func searchPassages(ctx context.Context, c corpora.Corpus, files []string, add func(export.Passage) error) error {
+	prog := tools.NewProgress(c.Name(), len(files))
+	defer prog.Finish()
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p := export.Passage{
+			Book:    corpora.BookOf(c, path),
+			Section: corpora.SectionOf(c, path),
+			Source:  export.SourceID(corpora.RootOf(c), path),
+		}
+		err := c.ScanText(path, func(line string) error {
+			p.Text = passageText(c, line)
+			if p.Text == "" {
+				return nil
+			}
+			return add(p)
+		})
+		if err != nil {
+			return err
+		}
+		prog.Add(1)
+	}
+	return nil
+}
+
+// passageText is a paragraph of c as the app shows it: normalized as it
+// is counted, with its spaces collapsed, but not tokenized.
+func passageText(c corpora.Corpus, line string) string {
+	return strings.Join(strings.Fields(pali.Normalize(c.Normalize(line))), " ")
+}
+
+// runBuildSearch implements the build-search subcommand: it loads the
+// corpus texts into the full-text search table of a SQLite database.
+func runBuildSearch(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("build-search", flag.ExitOnError)
+	commandUsage(fs, "Loads the corpus texts, one row per paragraph, into the FTS5 search table of a SQLite database for the app's full-text search.")
+	dbPath := fs.String("db", "", "SQLite database to write the search table into (required)")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to load")
+	layers := fs.String("layers", "all", "text layers to load: all, mula, commentaries, or layer keys like mul,att,tik,nrf")
+	fs.Parse(args)
+
+	tools.PTitle("building the search index")
+	tic := tools.Tic()
+	if *dbPath == "" {
+		tools.Errorf("build-search needs -db")
+		return
+	}
+	list, err := selectCorpora(*names)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	// only the file selection of the pipeline is used
+	p := &pipeline{}
+	if p.layers, p.layerKey, err = parseLayers(*layers); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+
+	db, err := export.Open(*dbPath)
+	if err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+	defer export.Close(db)
+	for _, c := range list {
+		files, err := p.corpusFiles(c)
+		if err != nil {
+			tools.Warnf("%s: %v", c.Name(), err)
+			continue
+		}
+		passages := 0
+		err = export.Search(db, c.Name(), func(add func(export.Passage) error) error {
+			return searchPassages(ctx, c, files, func(ps export.Passage) error {
+				passages++
+				return add(ps)
+			})
+		})
+		if err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			return
+		}
+		tools.Infof("%s: %d passages from %d files", c.Name(), passages, len(files))
+	}
+	if err := export.OptimizeSearch(db); err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+	tic.Toc()
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"maps"
+	"runtime"
+	"slices"
+	"sort"
+	"unicode/utf8"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// fragment is a short token of a file DPD does not know, likely a piece of
+// a word a stray space split.
+type fragment struct {
+	count int
+	joins map[string]int // DPD words it forms with the token before or after it
+}
+
+// join returns the DPD word the fragment forms most often with a token
+// beside it and how often, or "" when it forms none.
+func (f *fragment) join() (string, int) {
+	best, n := "", 0
+	for _, w := range slices.Sorted(maps.Keys(f.joins)) {
+		if f.joins[w] > n {
+			best, n = w, f.joins[w]
+		}
+	}
+	return best, n
+}
+
+// fileSegments is what the segmentation audit finds in one file.
+type fileSegments struct {
+	path      string
+	breaks    map[pali.HyphenBreak]int
+	fragments map[string]*fragment
+}
+
+// checkSegments reads the words of one file of c as passageText gives them
+// and finds the words a line-break hyphen split, which rejoin-hyphens
+// rejoins, and then, in the text so rejoined, the tokens DPD's lookup does
+// not know shorter than minLen letters, with the DPD words each forms with
+// the token before or after it.
+func checkSegments(c corpora.Corpus, path string, lookup map[string][]int, minLen int) (fileSegments, error) {
+	seg := fileSegments{path: path, breaks: make(map[pali.HyphenBreak]int), fragments: make(map[string]*fragment)}
+	err := c.ScanText(path, func(line string) error {
+		text := passageText(c, line)
+		for _, hb := range pali.HyphenBreaks(text) {
+			seg.breaks[hb]++
+		}
+		var words []string
+		for _, tok := range pali.Tokenize(pali.RejoinHyphens(text)) {
+			if r, _ := utf8.DecodeRuneInString(tok); pali.IsLetter(r) {
+				words = append(words, tok)
+			}
+		}
+		for i, w := range words {
+			if utf8.RuneCountInString(w) >= minLen || lookup[w] != nil {
+				continue
+			}
+			f := seg.fragments[w]
+			if f == nil {
+				f = &fragment{joins: make(map[string]int)}
+				seg.fragments[w] = f
+			}
+			f.count++
+			if i > 0 && lookup[words[i-1]+w] != nil {
+				f.joins[words[i-1]+w]++
+			} else if i+1 < len(words) && lookup[w+words[i+1]] != nil {
+				f.joins[w+words[i+1]]++
+			}
+		}
+		return nil
+	})
+	return seg, err
+}
+
+// segmentationTables gives the line-break splits of the files, with the
+// words they rejoin to, and the fragments, most frequent first in each
+// file.
+func segmentationTables(files []fileSegments, lookup map[string][]int) (breaks, fragments table) {
+	breaks = table{columns: []string{"file", "broken", "joined", "count", "in_dpd"}}
+	fragments = table{columns: []string{"file", "fragment", "count", "join", "join_count"}}
+	for _, seg := range files {
+		list := slices.SortedFunc(maps.Keys(seg.breaks), func(a, b pali.HyphenBreak) int {
+			if seg.breaks[a] != seg.breaks[b] {
+				return seg.breaks[b] - seg.breaks[a]
+			}
+			return tools.ComparePali(a.Broken, b.Broken)
+		})
+		for _, hb := range list {
+			breaks.rows = append(breaks.rows, []any{seg.path, hb.Broken, hb.Joined, seg.breaks[hb], lookup[hb.Joined] != nil})
+		}
+		words := slices.SortedFunc(maps.Keys(seg.fragments), tools.ComparePali)
+		sort.SliceStable(words, func(i, j int) bool { return seg.fragments[words[i]].count > seg.fragments[words[j]].count })
+		for _, w := range words {
+			join, n := seg.fragments[w].join()
+			fragments.rows = append(fragments.rows, []any{seg.path, w, seg.fragments[w].count, join, n})
+		}
+	}
+	return breaks, fragments
+}
+
+// runSegmentation implements the segmentation subcommand: the words of the
+// corpora that line-break hyphens and stray spaces split, by file.
+func runSegmentation(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("segmentation", flag.ExitOnError)
+	commandUsage(fs, "Lists by file the words of the corpora split by a line-break hyphen, such as paṭi- ccasamuppāda, which -rejoin-hyphens rejoins, and the short fragments DPD does not know that a stray space may have split off, with the DPD word each forms with a neighbour.")
+	list := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to check")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database whose lookup table tells known words")
+	minLen := fs.Int("min-length", 3, "letters a word DPD does not know needs not to be a fragment")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "number of files checked concurrently")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("auditing the word segmentation of the corpora")
+	tic := tools.Tic()
+	selected, err := selectCorpora(*list)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	db, err := dpd.Open(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	lookup, err := db.Lookup()
+	db.Close()
+	if err != nil {
+		tools.Errorf("%s: %v", *dpdPath, err)
+		return
+	}
+	for _, c := range selected {
+		paths, err := c.Files()
+		if err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			continue
+		}
+		var files []fileSegments
+		prog := tools.NewProgress(c.Name(), len(paths))
+		err = scanInOrder(ctx, paths, *jobs, func(path string) (fileSegments, error) {
+			return checkSegments(c, path, lookup, *minLen)
+		}, func(seg fileSegments) error {
+			prog.Add(1)
+			files = append(files, seg)
+			return nil
+		})
+		if err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			return
+		}
+		prog.Finish()
+
+		breaks, fragments := segmentationTables(files, lookup)
+		for _, out := range []struct {
+			name string
+			t    table
+		}{
+			{c.Name() + "_hyphen_breaks", breaks},
+			{c.Name() + "_fragments", fragments},
+		} {
+			if err := sink.Write(out.name, out.t); err != nil {
+				tools.Errorf("%v", err)
+				return
+			}
+		}
+		rejoined, known, tokens, joinable := 0, 0, 0, 0
+		for _, row := range breaks.rows {
+			rejoined += row[3].(int)
+			if row[4].(bool) {
+				known += row[3].(int)
+			}
+		}
+		for _, row := range fragments.rows {
+			tokens += row[2].(int)
+			joinable += row[4].(int)
+		}
+		tools.Infof("%s: %d line-break splits rejoined (-rejoin-hyphens), %d of them to a DPD word; %d fragment tokens, %d of them forming a DPD word with a neighbour", c.Name(), rejoined, known, tokens, joinable)
+	}
+	tic.Toc()
+}
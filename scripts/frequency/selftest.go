@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// selftestFiles holds the sample corpus of the selftest command, the
+// openings of DN 1 and MN 1 in the format of each edition, under corpora/,
+// and the outputs expected of it under golden/.
+//
+//go:embed selftest
+var selftestFiles embed.FS
+
+// sampleCorpora returns the editions of the sample corpus unpacked at dir.
+func sampleCorpora(dir string) []corpora.Corpus {
+	return []corpora.Corpus{
+		corpora.NewCst(filepath.Join(dir, "cst")),
+		corpora.NewBjt(filepath.Join(dir, "bjt")),
+		corpora.NewBjtSinhala(filepath.Join(dir, "bjt_sinh")),
+		corpora.NewSya(filepath.Join(dir, "sya")),
+	}
+}
+
+// unpackSample writes the sample corpus into dir, an edition per
+// directory.
+func unpackSample(dir string) error {
+	root, err := fs.Sub(selftestFiles, "selftest/corpora")
+	if err != nil {
+		return err
+	}
+	return fs.WalkDir(root, ".", func(name string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(root, name)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, 0o644)
+	})
+}
+
+// countSample counts list into a fresh output directory out, as freq
+// -force -ngrams 2 -ngram-min-count 1 -genres does with the defaults of
+// every other setting, and returns the files written, by slash path
+// relative to out. It sets the output directory and config of the
+// process, so it runs alone.
+func countSample(ctx context.Context, list []corpora.Corpus, out string) (map[string][]byte, error) {
+	cfg = defaultConfig()
+	freqDir = out
+	p := &pipeline{
+		ctx:        ctx,
+		sem:        make(chan struct{}, 4),
+		tok:        pali.Default,
+		force:      true,
+		files:      true,
+		sink:       fileSink{dir: out, format: formatTsv},
+		ngramSizes: []int{2},
+		ngramMin:   1,
+		genres:     true,
+	}
+	totals := p.runAll(list)
+	if p.failed > 0 {
+		return nil, fmt.Errorf("%d corpora failed", p.failed)
+	}
+	weights := make(map[string]float64, len(list))
+	for _, c := range list {
+		weights[c.Name()] = 1
+	}
+	if err := saveMasterList(p.sink, "master", totals, weights, 0); err != nil {
+		return nil, err
+	}
+	if err := saveSummary(p.sink, "corpus_summary", list, totals); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(out, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() {
+			if e.Name() == ".cache" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(out, path)
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// runSample unpacks the sample corpus under work and counts it into
+// work/out. The file paths the outputs name are cut to start at the
+// edition, as in cst/s0101m.mul.xml, so they do not depend on work.
+func runSample(ctx context.Context, work string) (map[string][]byte, error) {
+	dir := filepath.Join(work, "corpora")
+	if err := unpackSample(dir); err != nil {
+		return nil, fmt.Errorf("unpacking the sample corpus: %w", err)
+	}
+	files, err := countSample(ctx, sampleCorpora(dir), filepath.Join(work, "out"))
+	if err != nil {
+		return nil, err
+	}
+	prefix := []byte(filepath.ToSlash(dir) + "/")
+	for name, data := range files {
+		files[name] = bytes.ReplaceAll(data, prefix, nil)
+	}
+	return files, nil
+}
+
+// sampleGolden returns the outputs expected of the sample corpus, by slash
+// path relative to the output directory.
+func sampleGolden() (map[string][]byte, error) {
+	root, err := fs.Sub(selftestFiles, "selftest/golden")
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	err = fs.WalkDir(root, ".", func(name string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(root, name)
+		files[name] = data
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// compareOutputs describes how got departs from want, one line per file
+// that differs, is missing or is new, in path order; none when they match.
+func compareOutputs(got, want map[string][]byte) []string {
+	var problems []string
+	for name, data := range want {
+		g, ok := got[name]
+		switch {
+		case !ok:
+			problems = append(problems, name+" is no longer written")
+		case !bytes.Equal(g, data):
+			problems = append(problems, name+" differs, at "+firstDiff(string(data), string(g)))
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			problems = append(problems, name+" is written but has no golden file")
+		}
+	}
+	slices.SortFunc(problems, func(a, b string) int {
+		return strings.Compare(strings.Fields(a)[0], strings.Fields(b)[0])
+	})
+	return problems
+}
+
+// firstDiff describes the first line where got departs from want.
+func firstDiff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := range max(len(w), len(g)) {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			return "line " + strconv.Itoa(i+1) + ":\n  want " + wl + "\n  got  " + gl
+		}
+	}
+	return ""
+}
+
+// checkSample counts the sample corpus under work and reports each output
+// that departs from the expected ones, returning whether all matched.
+func checkSample(ctx context.Context, work string) bool {
+	got, err := runSample(ctx, work)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return false
+	}
+	want, err := sampleGolden()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return false
+	}
+	problems := compareOutputs(got, want)
+	for _, p := range problems {
+		tools.Errorf("%s", p)
+	}
+	if len(problems) > 0 {
+		tools.Errorf("%d of %d outputs differ from the expected ones", len(problems), len(want))
+		return false
+	}
+	tools.Infof("all %d outputs as expected", len(want))
+	return true
+}
+
+// runSelftest implements the selftest subcommand: the sample corpus built
+// into the binary, counted and checked against the outputs it should give.
+func runSelftest(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	commandUsage(fs, "Counts a small sample corpus built into palifreq, the openings of DN 1 and MN 1 in the format of each edition, and checks the outputs against the expected ones. Needs no corpus, database or config; exits nonzero when an output differs.")
+	keep := fs.String("keep", "", "empty directory to leave the sample corpus and its outputs in; by default a temporary one is removed")
+	fs.Parse(args)
+
+	tools.PTitle("self-test")
+	tic := tools.Tic()
+	work := *keep
+	if work == "" {
+		dir, err := os.MkdirTemp("", "palifreq-selftest-")
+		if err != nil {
+			tools.Errorf("%v", err)
+			os.Exit(1)
+		}
+		work = dir
+	} else if entries, err := os.ReadDir(work); err == nil && len(entries) > 0 {
+		tools.Errorf("-keep %s: not empty", work)
+		os.Exit(1)
+	}
+	ok := checkSample(ctx, work)
+	if *keep == "" {
+		os.RemoveAll(work)
+	} else {
+		tools.Infof("sample corpus in %s, outputs in %s", filepath.Join(work, "corpora"), filepath.Join(work, "out"))
+	}
+	if !ok && ctx.Err() == nil {
+		os.Exit(1)
+	}
+	tic.Toc()
+}
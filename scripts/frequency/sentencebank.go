@@ -0,0 +1,335 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// sentenceBank collects the sentences of the cloze cards: for each
+// headword, a sample of its sentences of a bounded length with one of its
+// forms, drawn from every book of the corpora.
+type sentenceBank struct {
+	forms              map[string][]keyword         // surface form → headwords it belongs to
+	minWords, maxWords int                          // bounds of a sentence, in words
+	max                int                          // sentences kept per headword
+	perBook            int                          // of them from one book, 0 for no limit
+	seed               uint64                       // of the sample keys
+	jobs               int                          // files scanned at once
+	pools              map[int]map[string]*bankPool // sampled so far, by headword and book
+	found              map[int]int                  // sentences stored by headword
+	cites              *citations                   // of the corpus being read
+}
+
+// bankSentence is a sentence of a file for one headword, with the
+// character offsets of its form.
+type bankSentence struct {
+	headwordID  int
+	lemma, form string
+	sentence    string
+	start, end  int
+	paragraph   int
+	citation    string
+}
+
+// bankFile is the sentences a scan found in one file.
+type bankFile struct {
+	source, book string
+	sentences    []bankSentence
+}
+
+// scanFile returns the sentences of one file. Each sentence is taken once
+// per headword, with the character offsets of the first of its forms and,
+// in DN and MN, its SuttaCentral id: mn10:5.2 is the second sentence of
+// the paragraph mn10:5.
+func (sb *sentenceBank) scanFile(c corpora.Corpus, path string) (bankFile, error) {
+	bf := bankFile{source: export.SourceID(corpora.RootOf(c), path), book: corpora.BookOf(c, path)}
+	cite := sb.cites.file(c, path)
+	paragraph := 0
+	err := c.ScanText(path, func(line string) error {
+		paragraph++
+		text := passageText(c, line)
+		para := cite.next(text)
+		// ...pe... is stored as …pe…, the elision marker of CST
+		for i, sentence := range tools.SplitSentences(strings.ReplaceAll(text, "...pe...", "…pe…")) {
+			spans := pali.WordSpans(sentence)
+			if len(spans) < sb.minWords || len(spans) > sb.maxWords {
+				continue
+			}
+			done := make(map[int]bool)
+			for _, s := range spans {
+				form := sentence[s[0]:s[1]]
+				for _, k := range sb.forms[form] {
+					if done[k.headwordID] {
+						continue
+					}
+					done[k.headwordID] = true
+					start := utf8.RuneCountInString(sentence[:s[0]])
+					end := start + utf8.RuneCountInString(form)
+					citation := ""
+					if para != "" {
+						citation = fmt.Sprintf("%s.%d", para, i+1)
+					}
+					bf.sentences = append(bf.sentences, bankSentence{k.headwordID, k.word, form, sentence, start, end, paragraph, citation})
+				}
+			}
+		}
+		return nil
+	})
+	return bf, err
+}
+
+// bankCandidate is a sentence of the sample of a headword, with where it
+// was found and its sample key.
+type bankCandidate struct {
+	bankSentence
+	corpus, source, book string
+	key                  uint64
+}
+
+// bankPool is the reservoir of the sentences of a headword in one book:
+// the k with the lowest sample keys of those offered, lowest first, and
+// how many were offered.
+type bankPool struct {
+	held    []bankCandidate
+	offered int
+}
+
+// offer adds c to the reservoir if its key is among the k lowest. The
+// same sentence has the same key, so one already held is not held twice.
+func (p *bankPool) offer(c bankCandidate, k int) {
+	p.offered++
+	i, found := slices.BinarySearchFunc(p.held, c, func(a, b bankCandidate) int {
+		if a.key != b.key {
+			return cmp.Compare(a.key, b.key)
+		}
+		return strings.Compare(a.sentence, b.sentence)
+	})
+	if found || i >= k {
+		return
+	}
+	// not to keep the paragraph the sentence and form were cut from
+	c.sentence, c.form = strings.Clone(c.sentence), strings.Clone(c.form)
+	p.held = slices.Insert(p.held, i, c)
+	if len(p.held) > k {
+		p.held = p.held[:k]
+	}
+}
+
+// sampleKey is the random key of a sentence of a headword under seed. The
+// keys, not the order the files are read in, decide which sentences a
+// reservoir keeps, so a rebuild with the same seed keeps the same ones.
+func sampleKey(seed uint64, headwordID int, sentence string) uint64 {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, [2]uint64{seed, uint64(headwordID)})
+	h.Write([]byte(sentence))
+	// the finalizer of MurmurHash3, as FNV mixes its last bytes poorly
+	x := h.Sum64()
+	x = (x ^ x>>33) * 0xff51afd7ed558ccd
+	x = (x ^ x>>33) * 0xc4ceb9fe1a85ec53
+	return x ^ x>>33
+}
+
+// add offers the sentences of one file to the reservoirs of their
+// headwords in its book, each holding up to max sentences.
+func (sb *sentenceBank) add(corpus string, bf bankFile) {
+	for _, s := range bf.sentences {
+		books := sb.pools[s.headwordID]
+		if books == nil {
+			books = make(map[string]*bankPool)
+			sb.pools[s.headwordID] = books
+		}
+		p := books[bf.book]
+		if p == nil {
+			p = &bankPool{}
+			books[bf.book] = p
+		}
+		p.offer(bankCandidate{s, corpus, bf.source, bf.book, sampleKey(sb.seed, s.headwordID, s.sentence)}, sb.max)
+	}
+}
+
+// pickSentences draws up to max sentences from the reservoirs of a
+// headword by book, one at a time from the book whose share of the
+// sentences offered is least served so far (the D'Hondt method), at most
+// perBook from one book when it is not 0. A sentence is drawn once, when
+// several books have it.
+func pickSentences(pools map[string]*bankPool, max, perBook int) []bankCandidate {
+	var picked []bankCandidate
+	next := make(map[string]int)  // index of the next sentence held by book
+	quota := make(map[string]int) // sentences drawn by book
+	drawn := make(map[string]bool)
+	for len(picked) < max {
+		best := ""
+		for _, b := range corpora.Books {
+			p := pools[b]
+			if p == nil {
+				continue
+			}
+			for next[b] < len(p.held) && drawn[p.held[next[b]].sentence] {
+				next[b]++
+			}
+			if next[b] == len(p.held) || perBook > 0 && quota[b] == perBook {
+				continue
+			}
+			if best == "" || p.offered*(quota[best]+1) > pools[best].offered*(quota[b]+1) {
+				best = b
+			}
+		}
+		if best == "" {
+			break
+		}
+		c := pools[best].held[next[best]]
+		picked = append(picked, c)
+		drawn[c.sentence] = true
+		quota[best]++
+		next[best]++
+	}
+	return picked
+}
+
+// run rebuilds the sentence_bank table of the headwords of heads from the
+// corpora in list: it samples the sentences of every file, -jobs files at
+// a time, then draws those of each headword from its books. The sample
+// depends on the seed only, not on -jobs or the order of the files. When
+// ctx is done it stops, and the table keeps its previous rows.
+func (sb *sentenceBank) run(ctx context.Context, db *sql.DB, list []corpora.Corpus, heads []freq.LemmaCount) error {
+	sb.pools = make(map[int]map[string]*bankPool)
+	for _, c := range list {
+		files, err := c.Files()
+		if err != nil {
+			return err
+		}
+		if sb.cites, err = loadCitations(ctx, c); err != nil {
+			return err
+		}
+		prog := tools.NewProgress(c.Name(), len(files))
+		err = scanInOrder(ctx, files, sb.jobs, func(path string) (bankFile, error) {
+			return sb.scanFile(c, path)
+		}, func(bf bankFile) error {
+			prog.Add(1)
+			sb.add(c.Name(), bf)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		prog.Finish()
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM sentence_bank`); err != nil {
+		return err
+	}
+	ins := export.NewInserter(tx, `
+		INSERT INTO sentence_bank (headword_id, lemma, form, sentence, form_start, form_end, corpus, source, book, paragraph, citation)
+		VALUES`, ``, 11)
+	for _, lc := range heads {
+		id := lc.Headword.ID
+		for _, c := range pickSentences(sb.pools[id], sb.max, sb.perBook) {
+			if err := ins.Add(id, c.lemma, c.form, c.sentence, c.start, c.end, c.corpus, c.source, c.book, c.paragraph, c.citation); err != nil {
+				return err
+			}
+			sb.found[id]++
+		}
+	}
+	if err := ins.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runSentenceBank implements the sentence-bank subcommand: it stores short
+// example sentences of the top headwords for cloze cards.
+func runSentenceBank(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("sentence-bank", flag.ExitOnError)
+	commandUsage(fs, "Stores, for each of the top DPD headwords, a few short sentences with one of its forms and the form's offsets in the sentence_bank table, for cloze cards.")
+	dbPath := fs.String("db", "", "SQLite database to write the sentence_bank table into (required)")
+	top := fs.Int("top", 1000, "number of headwords, by their counts of the last run")
+	perHeadword := fs.Int("per-headword", 5, "maximum sentences stored per headword")
+	perBook := fs.Int("per-book", 0, "maximum sentences of a headword from one book (0: as many as its share of the headword's sentences gives)")
+	seed := fs.Uint64("seed", 1, "seed of the random sample of sentences; the same seed, corpora and settings store the same sentences")
+	minWords := fs.Int("min-words", 4, "minimum words in a sentence")
+	maxWords := fs.Int("max-words", 20, "maximum words in a sentence")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to sample from")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database")
+	exclude := fs.String("exclude", "", "file of words whose headwords to leave out, one per line (see stopwords)")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "number of files scanned concurrently")
+	fs.Parse(args)
+
+	tools.PTitle("building the sentence bank")
+	tic := tools.Tic()
+	if *dbPath == "" {
+		tools.Errorf("sentence-bank needs -db")
+		return
+	}
+	list, err := selectCorpora(*names)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	excluded, err := loadExclusions(*exclude)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	lem, err := freq.LoadLemmatizer(*dpdPath)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	total, err := corpusTotals(strings.Split(*names, ","))
+	if err != nil {
+		tools.Errorf("%v (count the corpora first)", err)
+		return
+	}
+	var heads []freq.LemmaCount
+	for _, lc := range lem.Counts(total) {
+		if len(heads) == *top {
+			break
+		}
+		if !excluded[lemmaWord(lc.Headword.Lemma1)] {
+			heads = append(heads, lc)
+		}
+	}
+	sb := &sentenceBank{forms: headwordForms(lem, heads), minWords: *minWords, maxWords: *maxWords, max: *perHeadword, perBook: *perBook, seed: *seed, jobs: *jobs, found: make(map[int]int)}
+	tools.Infof("%d headwords, %d forms", len(heads), len(sb.forms))
+
+	db, err := export.Open(*dbPath)
+	if err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+	if err := sb.run(ctx, db, list, heads); err != nil {
+		export.Close(db)
+		tools.Errorf("%v", err)
+		return
+	}
+	if err := export.Close(db); err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+	stored := 0
+	for _, n := range sb.found {
+		stored += n
+	}
+	tools.Infof("%d sentences for %d of %d headwords", stored, len(sb.found), len(heads))
+	tic.Toc()
+}
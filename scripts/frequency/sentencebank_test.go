@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"dpd/go_modules/frequency/corpora"
+)
+
+func TestBankPool(t *testing.T) {
+	// the same sentences offered in two orders, one of them twice, keep
+	// the same sentences
+	var sentences []string
+	for i := range 50 {
+		sentences = append(sentences, fmt.Sprint("sentence ", i))
+	}
+	fill := func(order []string) []string {
+		p := &bankPool{}
+		for _, s := range order {
+			p.offer(bankCandidate{bankSentence: bankSentence{headwordID: 7, sentence: s}, key: sampleKey(1, 7, s)}, 5)
+		}
+		var held []string
+		for _, c := range p.held {
+			held = append(held, c.sentence)
+		}
+		return held
+	}
+	forward := fill(sentences)
+	reversed := slices.Clone(sentences)
+	slices.Reverse(reversed)
+	backward := fill(append(reversed, sentences...))
+	if len(forward) != 5 || !slices.Equal(forward, backward) {
+		t.Errorf("held %v and %v, want the same 5", forward, backward)
+	}
+	if other := fill(sentences); !slices.Equal(forward, other) {
+		t.Errorf("refilled %v, want %v", other, forward)
+	}
+	if sampleKey(1, 7, "a") == sampleKey(2, 7, "a") || sampleKey(1, 7, "a") == sampleKey(1, 8, "a") {
+		t.Errorf("keys do not depend on the seed and headword")
+	}
+}
+
+func TestPickSentences(t *testing.T) {
+	pool := func(offered int, sentences ...string) *bankPool {
+		p := &bankPool{offered: offered}
+		for i, s := range sentences {
+			p.held = append(p.held, bankCandidate{bankSentence: bankSentence{sentence: s}, key: uint64(i)})
+		}
+		return p
+	}
+	pools := map[string]*bankPool{
+		corpora.DN: pool(60, "d1", "d2", "d3", "d4", "d5"),
+		corpora.SN: pool(30, "s1", "shared", "s3"),
+		corpora.AN: pool(10, "shared", "a2"),
+	}
+	texts := func(picked []bankCandidate) []string {
+		var s []string
+		for _, c := range picked {
+			s = append(s, c.sentence)
+		}
+		return s
+	}
+	if got, want := texts(pickSentences(pools, 5, 0)), []string{"d1", "d2", "s1", "d3", "d4"}; !slices.Equal(got, want) {
+		t.Errorf("picked %v, want %v", got, want)
+	}
+	if got, want := texts(pickSentences(pools, 5, 1)), []string{"d1", "s1", "shared"}; !slices.Equal(got, want) {
+		t.Errorf("one per book: picked %v, want %v", got, want)
+	}
+	if got, want := texts(pickSentences(pools, 12, 0)), []string{"d1", "d2", "s1", "d3", "d4", "shared", "d5", "s3", "a2"}; !slices.Equal(got, want) {
+		t.Errorf("all: picked %v, want %v", got, want)
+	}
+}
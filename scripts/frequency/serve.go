@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// serveDefaultTop is the number of entries /top and /occurrences list when
+// no n is given.
+const serveDefaultTop = 100
+
+// servedCorpus is what serve knows of one corpus from the last run.
+type servedCorpus struct {
+	ranked []freq.WordCount // by descending count, as in its table
+	rank   map[string]int   // 1-based
+	tokens int
+	lemmas map[int]int               // counts by headword ID, nil without a lemma table
+	files  map[string]map[string]int // word counts by file, nil without a file cache
+}
+
+// serveIndex answers the API of serve from the outputs of the last run.
+type serveIndex struct {
+	names   []string // of the corpora loaded, in the order given
+	corpora map[string]*servedCorpus
+	lem     *freq.Lemmatizer // nil without the DPD database
+}
+
+// loadServeIndex reads the frequency tables, lemma tables and file caches
+// of the corpora names from the output directory. A corpus without a
+// frequency table is left out with a warning; the lemma table and the
+// cache are optional.
+func loadServeIndex(names []string, lem *freq.Lemmatizer) (*serveIndex, error) {
+	tables, err := countTables(freqDir)
+	if err != nil {
+		return nil, err
+	}
+	idx := &serveIndex{corpora: make(map[string]*servedCorpus), lem: lem}
+	for _, name := range names {
+		path, ok := tables[name+"_freq"]
+		if !ok {
+			tools.Warnf("%s: no %s_freq table in %s; count it first", name, name, freqDir)
+			continue
+		}
+		counts, err := readCounts(path)
+		if err != nil {
+			return nil, err
+		}
+		c := &servedCorpus{ranked: freq.Sorted(counts), rank: make(map[string]int, len(counts)), tokens: freq.TokenTotal(counts)}
+		for i, wc := range c.ranked {
+			c.rank[wc.Word] = i + 1
+		}
+		if path, ok := tables[name+"_lemma_freq"]; ok {
+			byID, err := readCounts(path)
+			if err != nil {
+				return nil, err
+			}
+			c.lemmas = make(map[int]int, len(byID))
+			for id, n := range byID {
+				if i, err := strconv.Atoi(id); err == nil {
+					c.lemmas[i] = n
+				}
+			}
+		}
+		if c.files, err = loadFileCounts(name); err != nil {
+			tools.Warnf("%s: no file cache (%v); /occurrences will not list it", name, err)
+			c.files = nil
+		}
+		idx.names = append(idx.names, name)
+		idx.corpora[name] = c
+		tools.Infof("%s: %d words, %d files", name, len(c.ranked), len(c.files))
+	}
+	if len(idx.names) == 0 {
+		return nil, fmt.Errorf("no frequency tables of %s in %s", strings.Join(names, ", "), freqDir)
+	}
+	return idx, nil
+}
+
+// servedCount is the count of a word in one corpus.
+type servedCount struct {
+	Corpus     string  `json:"corpus,omitempty"`
+	Word       string  `json:"word,omitempty"`
+	Count      int     `json:"count"`
+	Rank       int     `json:"rank"`
+	PerMillion float64 `json:"per_million"`
+}
+
+// servedHeadword is a DPD headword a form may belong to, with its counts
+// by corpus.
+type servedHeadword struct {
+	HeadwordID int            `json:"headword_id"`
+	Lemma      string         `json:"lemma"`
+	Pos        string         `json:"pos"`
+	Counts     map[string]int `json:"counts"`
+}
+
+// servedOccurrence is the count of a word in one file.
+type servedOccurrence struct {
+	Corpus string `json:"corpus"`
+	File   string `json:"file"`
+	Count  int    `json:"count"`
+}
+
+// handler routes the API:
+//
+//	GET /freq/{word}                    counts of the word in each corpus, and its headwords
+//	GET /top?n=&corpus=                 the n most frequent words of a corpus
+//	GET /occurrences/{word}?n=&corpus=  the files with the word, most occurrences first
+func (idx *serveIndex) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /freq/{word}", idx.serveFreq)
+	mux.HandleFunc("GET /top", idx.serveTop)
+	mux.HandleFunc("GET /occurrences/{word}", idx.serveOccurrences)
+	return mux
+}
+
+// serveFreq answers /freq/{word}. A word no corpus has is not found.
+func (idx *serveIndex) serveFreq(w http.ResponseWriter, r *http.Request) {
+	word := servedWord(r)
+	counts := []servedCount{}
+	for _, name := range idx.names {
+		c := idx.corpora[name]
+		if rank, ok := c.rank[word]; ok {
+			n := c.ranked[rank-1].Count
+			counts = append(counts, servedCount{Corpus: name, Count: n, Rank: rank, PerMillion: freq.PerMillion(n, c.tokens)})
+		}
+	}
+	if len(counts) == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("%q is in none of %s", word, strings.Join(idx.names, ", ")))
+		return
+	}
+	heads := []servedHeadword{}
+	if idx.lem != nil {
+		for _, id := range idx.lem.Lookup[word] {
+			h := idx.lem.Headwords[id]
+			sh := servedHeadword{HeadwordID: id, Lemma: h.Lemma1, Pos: h.Pos, Counts: make(map[string]int)}
+			for _, name := range idx.names {
+				if n, ok := idx.corpora[name].lemmas[id]; ok {
+					sh.Counts[name] = n
+				}
+			}
+			heads = append(heads, sh)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"word": word, "corpora": counts, "headwords": heads})
+}
+
+// serveTop answers /top, of the first corpus when none is given.
+func (idx *serveIndex) serveTop(w http.ResponseWriter, r *http.Request) {
+	name, c, ok := idx.servedCorpus(w, r)
+	if !ok {
+		return
+	}
+	n, ok := servedTop(w, r)
+	if !ok {
+		return
+	}
+	list := c.ranked
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	words := make([]servedCount, len(list))
+	for i, wc := range list {
+		words[i] = servedCount{Word: wc.Word, Count: wc.Count, Rank: i + 1, PerMillion: freq.PerMillion(wc.Count, c.tokens)}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"corpus": name, "tokens": c.tokens, "types": len(c.ranked), "words": words})
+}
+
+// serveOccurrences answers /occurrences/{word}, from every corpus with a
+// file cache unless one is given.
+func (idx *serveIndex) serveOccurrences(w http.ResponseWriter, r *http.Request) {
+	word := servedWord(r)
+	names := idx.names
+	if r.URL.Query().Get("corpus") != "" {
+		name, _, ok := idx.servedCorpus(w, r)
+		if !ok {
+			return
+		}
+		names = []string{name}
+	}
+	n, ok := servedTop(w, r)
+	if !ok {
+		return
+	}
+	list := []servedOccurrence{}
+	for _, name := range names {
+		for file, counts := range idx.corpora[name].files {
+			if k := counts[word]; k > 0 {
+				list = append(list, servedOccurrence{name, file, k})
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		if a, b := slices.Index(idx.names, list[i].Corpus), slices.Index(idx.names, list[j].Corpus); a != b {
+			return a < b
+		}
+		return naturalCompare(list[i].File, list[j].File) < 0
+	})
+	total := len(list)
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"word": word, "files": total, "occurrences": list})
+}
+
+// servedWord is the {word} of the path, lower-cased and normalized as the
+// corpora are.
+func servedWord(r *http.Request) string {
+	return pali.Normalize(strings.ToLower(r.PathValue("word")))
+}
+
+// servedCorpus returns the corpus of the corpus parameter, or the first
+// corpus loaded without one. It answers 400 itself for a corpus not loaded.
+func (idx *serveIndex) servedCorpus(w http.ResponseWriter, r *http.Request) (string, *servedCorpus, bool) {
+	name := r.URL.Query().Get("corpus")
+	if name == "" {
+		name = idx.names[0]
+	}
+	c, ok := idx.corpora[name]
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown corpus %q (have %s)", name, strings.Join(idx.names, ", ")))
+	}
+	return name, c, ok
+}
+
+// servedTop returns the n parameter, serveDefaultTop without one; 0 asks
+// for every entry. It answers 400 itself for an n that is not a number of
+// 0 or more.
+func servedTop(w http.ResponseWriter, r *http.Request) (int, bool) {
+	s := r.URL.Query().Get("n")
+	if s == "" {
+		return serveDefaultTop, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("n %q: want a number of 0 or more", s))
+		return 0, false
+	}
+	return n, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// the mobile app and web tools run on other origins during development
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		tools.Debugf("writing response: %v", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// runServe implements the serve subcommand: it answers frequency queries
+// over HTTP from the outputs of the last run, until interrupted.
+func runServe(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	commandUsage(fs, "Serves the frequency and lemma tables and the file counts of the last run as a JSON HTTP API: /freq/{word}, /top?n=&corpus= and /occurrences/{word}?n=&corpus=.")
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to serve; the first is the default of corpus=")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database giving the headwords of /freq (left out when missing)")
+	fs.Parse(args)
+
+	tools.PTitle("serving frequencies")
+	var lem *freq.Lemmatizer
+	if l, err := freq.LoadLemmatizer(*dpdPath); err != nil {
+		tools.Warnf("%v; /freq lists no headwords", err)
+	} else {
+		lem = l
+	}
+	idx, err := loadServeIndex(strings.Split(*names, ","), lem)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+
+	srv := &http.Server{Addr: *addr, Handler: idx.handler()}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+	tools.Infof("listening on http://%s", *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		tools.Errorf("%v", err)
+	}
+}
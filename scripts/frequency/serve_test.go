@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/freq"
+)
+
+func testServeIndex() *serveIndex {
+	cst := &servedCorpus{
+		ranked: freq.Sorted(map[string]int{"evaṃ": 3, "me": 2, "sutaṃ": 1}),
+		tokens: 6,
+		lemmas: map[int]int{20: 1},
+		files: map[string]map[string]int{
+			"s0101m.mul.xml": {"evaṃ": 1, "sutaṃ": 1},
+			"s0102m.mul.xml": {"evaṃ": 2, "me": 2},
+		},
+	}
+	cst.rank = map[string]int{"evaṃ": 1, "me": 2, "sutaṃ": 3}
+	return &serveIndex{
+		names:   []string{"cst"},
+		corpora: map[string]*servedCorpus{"cst": cst},
+		lem: &freq.Lemmatizer{
+			Lookup:    map[string][]int{"sutaṃ": {20}},
+			Headwords: map[int]dpd.Headword{20: {ID: 20, Lemma1: "suta 1", Pos: "pp"}},
+		},
+	}
+}
+
+func serveGet(t *testing.T, h http.Handler, target string, status int) map[string]any {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+	if rec.Code != status {
+		t.Fatalf("GET %s: status %d, want %d: %s", target, rec.Code, status, rec.Body)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	return got
+}
+
+func TestServe(t *testing.T) {
+	h := testServeIndex().handler()
+
+	got := serveGet(t, h, "/freq/Sutaṃ", http.StatusOK)
+	counts := got["corpora"].([]any)
+	heads := got["headwords"].([]any)
+	if len(counts) != 1 || counts[0].(map[string]any)["rank"] != 3.0 || len(heads) != 1 || heads[0].(map[string]any)["lemma"] != "suta 1" {
+		t.Errorf("/freq/Sutaṃ = %v", got)
+	}
+	serveGet(t, h, "/freq/dhamma", http.StatusNotFound)
+
+	got = serveGet(t, h, "/top?n=2&corpus=cst", http.StatusOK)
+	if words := got["words"].([]any); len(words) != 2 || words[1].(map[string]any)["word"] != "me" {
+		t.Errorf("/top?n=2 = %v", got)
+	}
+	serveGet(t, h, "/top?corpus=bjt", http.StatusBadRequest)
+	serveGet(t, h, "/top?n=-1", http.StatusBadRequest)
+
+	got = serveGet(t, h, "/occurrences/evaṃ?n=1", http.StatusOK)
+	occ := got["occurrences"].([]any)
+	if got["files"] != 2.0 || len(occ) != 1 || occ[0].(map[string]any)["file"] != "s0102m.mul.xml" {
+		t.Errorf("/occurrences/evaṃ?n=1 = %v", got)
+	}
+}
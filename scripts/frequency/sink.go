@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/frequency/translit"
+	"dpd/go_modules/tools"
+)
+
+// Sink receives the tables a command writes. A table's name reads like a
+// path without extension, relative to the output: "cst_freq",
+// "books/cst_dn_freq". Write may be called from several goroutines.
+type Sink interface {
+	Write(name string, t table) error
+	Close() error
+}
+
+// fileSink writes each table to a file of its format under dir, compressed
+// as compress says. With meta set, Close writes the sidecar of each.
+type fileSink struct {
+	dir      string
+	format   outputFormat
+	compress compression
+	meta     *sidecars
+}
+
+func (s fileSink) Write(name string, t table) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(name))
+	if err := writeTable(path, s.format, s.compress, t); err != nil {
+		return err
+	}
+	s.meta.add(tableFile(path, s.format, s.compress))
+	return nil
+}
+
+func (s fileSink) Close() error { return s.meta.write() }
+
+// streamSink writes every table to one stream, for piping into other
+// tools. Delimited tables follow a "# <name>" line and end with a blank
+// line; in the JSON formats each row is a line of its own with the table
+// name under "table" first, as a stream of arrays is no JSON document.
+type streamSink struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	format outputFormat
+}
+
+// newStdoutSink returns a sink writing to standard output and points
+// os.Stdout at stderr, so titles and timings printed later do not mix
+// with the tables.
+func newStdoutSink(format outputFormat) *streamSink {
+	s := &streamSink{w: bufio.NewWriter(os.Stdout), format: format}
+	os.Stdout = os.Stderr
+	return s
+}
+
+func (s *streamSink) Write(name string, t table) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch s.format {
+	case formatJson, formatJsonl:
+		named := table{columns: append([]string{"table"}, t.columns...)}
+		named.stream = func(yield func([]any) error) error {
+			return t.each(func(row []any) error { return yield(append([]any{name}, row...)) })
+		}
+		if err := writeJsonRows(s.w, named, false); err != nil {
+			return err
+		}
+	default:
+		fmt.Fprintf(s.w, "# %s\n", name)
+		if err := encodeTable(s.w, s.format, t); err != nil {
+			return err
+		}
+		s.w.WriteByte('\n')
+	}
+	return s.w.Flush()
+}
+
+func (s *streamSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Flush()
+}
+
+// sqliteSink stores each table as a database table of the same name, with
+// "/" and other characters SQLite names should not hold turned into "_".
+// Writing a table replaces the one of the previous run.
+type sqliteSink struct {
+	db *sql.DB
+}
+
+func newSqliteSink(path string) (*sqliteSink, error) {
+	db, err := export.OpenBulk(path)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteSink{db: db}, nil
+}
+
+func (s *sqliteSink) Write(name string, t table) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ident := quoteIdent(sqlName(name))
+	if _, err := tx.Exec(`DROP TABLE IF EXISTS ` + ident); err != nil {
+		return err
+	}
+	// the column types are taken from the first row, so the table is made
+	// once that row is there
+	var insert *export.Inserter
+	create := func(first []any) error {
+		cols := make([]string, len(t.columns))
+		for i, c := range t.columns {
+			var v any
+			if first != nil {
+				v = first[i]
+			}
+			cols[i] = quoteIdent(c) + " " + sqlType(v)
+		}
+		if _, err := tx.Exec(`CREATE TABLE ` + ident + ` (` + strings.Join(cols, ", ") + `)`); err != nil {
+			return err
+		}
+		insert = export.NewInserter(tx, `INSERT INTO `+ident+` VALUES`, ``, len(t.columns))
+		return nil
+	}
+	err = t.each(func(row []any) error {
+		if insert == nil {
+			if err := create(row); err != nil {
+				return err
+			}
+		}
+		return insert.Add(row...)
+	})
+	if err == nil && insert == nil {
+		err = create(nil)
+	}
+	if insert != nil {
+		if cerr := insert.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteSink) Close() error { return export.Close(s.db) }
+
+// sqlName turns a table name like "books/cst_dn_freq" into an SQL name.
+func sqlName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// sqlType is the column type for values like v.
+func sqlType(v any) string {
+	switch v.(type) {
+	case int, int64:
+		return "INTEGER"
+	case float64:
+		return "REAL"
+	}
+	return "TEXT"
+}
+
+// httpSink POSTs each table as one JSON document to an endpoint:
+//
+//	{"table": "cst_freq", "rows": [{"word": "ca", "count": 51063, ...}, ...]}
+//
+// Network errors and 429 or 5xx replies are retried with a doubling
+// wait; other replies outside 2xx fail at once.
+type httpSink struct {
+	url     string
+	header  http.Header
+	client  *http.Client
+	retries int
+	wait    time.Duration // before the first retry
+}
+
+func newHttpSink(c httpSinkConfig) (*httpSink, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("the http sink needs [sink.http] url in palifreq.toml, or -sink with the URL")
+	}
+	timeout, err := time.ParseDuration(c.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("[sink.http] timeout: %w", err)
+	}
+	wait, err := time.ParseDuration(c.RetryWait)
+	if err != nil {
+		return nil, fmt.Errorf("[sink.http] retry_wait: %w", err)
+	}
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	for k, v := range c.Headers {
+		header.Set(k, os.ExpandEnv(v))
+	}
+	return &httpSink{
+		url:     c.URL,
+		header:  header,
+		client:  &http.Client{Timeout: timeout},
+		retries: max(c.Retries, 0),
+		wait:    wait,
+	}, nil
+}
+
+func (s *httpSink) Write(name string, t table) error {
+	var body bytes.Buffer
+	w := bufio.NewWriter(&body)
+	k, _ := json.Marshal(name)
+	fmt.Fprintf(w, `{"table":%s,"rows":`, k)
+	if err := writeJsonRows(w, t, true); err != nil {
+		return err
+	}
+	w.WriteString("}\n")
+	w.Flush()
+
+	wait := s.wait
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(body.Bytes())
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == s.retries {
+			return fmt.Errorf("%s: POST %s: %w", name, s.url, err)
+		}
+		tools.Warnf("%s: POST %s: %v; retrying in %v", name, s.url, err, wait)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// post sends one request and tells whether a failure is worth retrying.
+func (s *httpSink) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header = s.header.Clone()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+func (*httpSink) Close() error { return nil }
+
+// sinkFlags are the output flags of the subcommands that write tables.
+type sinkFlags struct {
+	format       *string
+	compress     *string
+	sink         *string
+	romanization *string
+
+	// roman is the parsed -romanization, set by open
+	roman translit.Romanization
+}
+
+func addSinkFlags(fs *flag.FlagSet, defaultFormat string) *sinkFlags {
+	return &sinkFlags{
+		format:       fs.String("output-format", defaultFormat, "table format: tsv, csv, json, jsonl or parquet, or one of them compressed, as tsv.gz or csv.zst (parquet compresses its pages and keeps the .parquet name)"),
+		compress:     fs.String("compress", "none", "compression of the table files of -sink file: none, gzip (.gz) or zstd (.zst); of the pages of parquet files"),
+		sink:         fs.String("sink", "file", "where tables go: file (the output directory), stdout, sqlite:PATH, http (the [sink.http] endpoint) or an http(s) URL"),
+		romanization: fs.String("romanization", "iast", "romanization of the word columns: iast, iso15919 (ṁ) or velthuis (ASCII, e.g. aa and .m)"),
+	}
+}
+
+// open returns the sink chosen by the parsed flags, writing the word
+// columns in the chosen romanization. Call it before any output: the
+// stdout sink points os.Stdout at stderr from then on.
+func (sf *sinkFlags) open() (Sink, error) {
+	r, err := translit.ParseRomanization(*sf.romanization)
+	if err != nil {
+		return nil, err
+	}
+	sf.roman = r
+	s, err := sf.openSink()
+	if err != nil || r == translit.IAST {
+		return s, err
+	}
+	return romanSink{Sink: s, r: r}, nil
+}
+
+// formats returns the table format and compression of -output-format and
+// -compress. The format may carry the extension of a compression itself,
+// as tsv.gz does.
+func (sf *sinkFlags) formats() (outputFormat, compression, error) {
+	name, comp := splitCompression(strings.ToLower(*sf.format))
+	format, err := parseOutputFormat(name)
+	if err != nil {
+		return "", "", err
+	}
+	flagged, err := parseCompression(*sf.compress)
+	if err != nil {
+		return "", "", err
+	}
+	if flagged != compressNone {
+		if comp != compressNone && comp != flagged {
+			return "", "", fmt.Errorf("-output-format %s and -compress %s disagree", *sf.format, *sf.compress)
+		}
+		comp = flagged
+	}
+	return format, comp, nil
+}
+
+// openSink returns the sink chosen by -output-format, -compress and -sink.
+func (sf *sinkFlags) openSink() (Sink, error) {
+	format, comp, err := sf.formats()
+	if err != nil {
+		return nil, err
+	}
+	spec := *sf.sink
+	if comp != compressNone && spec != "file" {
+		return nil, fmt.Errorf("-sink %s: only the file sink compresses", spec)
+	}
+	if format == formatParquet && spec != "file" {
+		return nil, fmt.Errorf("-sink %s: only the file sink writes parquet", spec)
+	}
+	switch {
+	case spec == "file":
+		return fileSink{dir: freqDir, format: format, compress: comp, meta: &sidecars{}}, nil
+	case spec == "stdout":
+		return newStdoutSink(format), nil
+	case strings.HasPrefix(spec, "sqlite:"):
+		return newSqliteSink(strings.TrimPrefix(spec, "sqlite:"))
+	case spec == "http":
+		return newHttpSink(cfg.Sink.HTTP)
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		c := cfg.Sink.HTTP
+		c.URL = spec
+		return newHttpSink(c)
+	}
+	return nil, fmt.Errorf("unknown sink %q (want file, stdout, sqlite:PATH, http or a URL)", spec)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dpd/go_modules/frequency/translit"
+)
+
+func testHttpSink(t *testing.T, h http.HandlerFunc, retries int) *httpSink {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	s, err := newHttpSink(httpSinkConfig{
+		URL:       srv.URL,
+		Headers:   map[string]string{"Authorization": "Bearer ${PALIFREQ_TEST_TOKEN}"},
+		Retries:   retries,
+		RetryWait: "1ms",
+		Timeout:   "5s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestHttpSinkRetries(t *testing.T) {
+	t.Setenv("PALIFREQ_TEST_TOKEN", "secret")
+	var got struct {
+		Table string           `json:"table"`
+		Rows  []map[string]any `json:"rows"`
+	}
+	calls := 0
+	s := testHttpSink(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}, 3)
+
+	tab := table{columns: []string{"word", "count"}, rows: [][]any{{"ca", 3}, {"dhamma", 2}}}
+	if err := s.Write("books/cst_dn_freq", tab); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("%d requests, want 3", calls)
+	}
+	if got.Table != "books/cst_dn_freq" || len(got.Rows) != 2 || got.Rows[1]["word"] != "dhamma" || got.Rows[1]["count"] != 2.0 {
+		t.Errorf("server got %+v", got)
+	}
+}
+
+func TestHttpSinkGivesUp(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		calls  int
+	}{
+		{http.StatusInternalServerError, 3}, // retried until retries run out
+		{http.StatusBadRequest, 1},          // not retried
+	} {
+		calls := 0
+		s := testHttpSink(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(tc.status)
+		}, 2)
+		if err := s.Write("cst_freq", table{columns: []string{"word"}}); err == nil {
+			t.Errorf("status %d: no error", tc.status)
+		}
+		if calls != tc.calls {
+			t.Errorf("status %d: %d requests, want %d", tc.status, calls, tc.calls)
+		}
+	}
+}
+
+// tableSink keeps the tables written to it.
+type tableSink map[string]table
+
+func (s tableSink) Write(name string, t table) error { s[name] = t; return nil }
+func (s tableSink) Close() error                     { return nil }
+
+func TestRomanSink(t *testing.T) {
+	got := make(tableSink)
+	s := romanSink{Sink: got, r: translit.Velthuis}
+	tab := table{columns: []string{"word", "count", "example_file"}, rows: [][]any{{"evaṃ", 3, "ñāṇa.txt"}}}
+	if err := s.Write("cst_freq", tab); err != nil {
+		t.Fatal(err)
+	}
+	row := got["cst_freq"].rows[0]
+	if row[0] != "eva.m" || row[1] != 3 || row[2] != "ñāṇa.txt" {
+		t.Errorf("row = %v, want the word alone in Velthuis", row)
+	}
+	if tab.rows[0][0] != "evaṃ" {
+		t.Errorf("the table written was changed: %v", tab.rows[0])
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"dpd/go_modules/frequency/dpd"
+)
+
+// splitter credits sandhi forms and compounds to their constituents using
+// the splits precomputed by DPD's deconstructor.
+type splitter struct {
+	known  map[string]bool     // forms DPD knows as inflections of a headword
+	splits map[string][]string // form -> deconstructions, best first
+}
+
+func loadSplitter(path string) (*splitter, error) {
+	db, err := dpd.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	lookup, err := db.Lookup()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	splits, err := db.Deconstructions()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	known := make(map[string]bool, len(lookup))
+	for w := range lookup {
+		known[w] = true
+	}
+	return &splitter{known, splits}, nil
+}
+
+// parts returns the constituents of w: w itself when DPD knows it as a
+// word or has no split for it, otherwise the parts of its best split.
+func (s *splitter) parts(w string) []string {
+	if s.known[w] {
+		return []string{w}
+	}
+	splits := s.splits[w]
+	if len(splits) == 0 {
+		return []string{w}
+	}
+	var parts []string
+	for _, p := range strings.Split(splits[0], "+") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// splitCounts applies parts to every word of counts, so each constituent
+// gets the full count of the form it was split from.
+func (s *splitter) splitCounts(counts map[string]int) map[string]int {
+	out := make(map[string]int, len(counts))
+	for w, n := range counts {
+		for _, p := range s.parts(w) {
+			out[p] += n
+		}
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// wordStats are the distributions of one corpus's words: by length in
+// characters and by number of syllables, each as the distinct words
+// (types) and their occurrences (tokens), and the characters counted over
+// all tokens.
+type wordStats struct {
+	tokens         int
+	lengthTypes    map[int]int
+	lengthTokens   map[int]int
+	syllableTypes  map[int]int
+	syllableTokens map[int]int
+	chars          map[string]int
+}
+
+// collectStats computes the word statistics of counts. Tokens that are not
+// words, such as digits and daṇḍas kept by the tokenizer, are left out.
+func collectStats(counts map[string]int) wordStats {
+	s := wordStats{
+		lengthTypes:    make(map[int]int),
+		lengthTokens:   make(map[int]int),
+		syllableTypes:  make(map[int]int),
+		syllableTokens: make(map[int]int),
+		chars:          make(map[string]int),
+	}
+	for w, n := range counts {
+		if strings.IndexFunc(w, func(r rune) bool { return !pali.IsLetter(r) }) >= 0 {
+			continue
+		}
+		s.tokens += n
+		length := utf8.RuneCountInString(w)
+		s.lengthTypes[length]++
+		s.lengthTokens[length] += n
+		syllables := len(pali.Syllables(w))
+		s.syllableTypes[syllables]++
+		s.syllableTokens[syllables] += n
+		for _, r := range w {
+			s.chars[string(r)] += n
+		}
+	}
+	return s
+}
+
+// distributionTable is a table of key (length or syllables), types,
+// tokens and per_million, the tokens per million tokens, by ascending key.
+func distributionTable(key string, types, tokens map[int]int, total int) table {
+	keys := make([]int, 0, len(types))
+	for k := range types {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	t := table{columns: []string{key, "types", "tokens", "per_million"}}
+	for _, k := range keys {
+		t.rows = append(t.rows, []any{k, types[k], tokens[k], freq.PerMillion(tokens[k], total)})
+	}
+	return t
+}
+
+// mean is the average key of a distribution weighted by tokens.
+func mean(tokens map[int]int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	sum := 0
+	for k, n := range tokens {
+		sum += k * n
+	}
+	return float64(sum) / float64(total)
+}
+
+// saveStats writes the tables <name>_length_stats, <name>_syllable_stats
+// (columns length or syllables, types, tokens, per_million) and
+// <name>_char_freq (char, count, rank, per_million: per million
+// characters) to s.
+func saveStats(s Sink, name string, st wordStats) error {
+	if err := s.Write(name+"_length_stats", distributionTable("length", st.lengthTypes, st.lengthTokens, st.tokens)); err != nil {
+		return err
+	}
+	if err := s.Write(name+"_syllable_stats", distributionTable("syllables", st.syllableTypes, st.syllableTokens, st.tokens)); err != nil {
+		return err
+	}
+	chars := freqTable(freq.Sorted(st.chars))
+	chars.columns[0] = "char"
+	return s.Write(name+"_char_freq", chars)
+}
+
+// zipfPerDecade is the number of ranks in each power of ten the Zipf curves
+// are sampled and fitted at.
+const zipfPerDecade = 10
+
+// curveFitColumns are the columns of the curve_fit table.
+var curveFitColumns = []string{"corpus", "tokens", "types", "zipf_c", "zipf_s", "zipf_r2", "heaps_k", "heaps_beta", "heaps_r2"}
+
+// saveCurves fits Zipf's and Heaps' laws to the per-file counts files of the
+// corpus name and writes their points, <name>_zipf (rank, word, count and
+// the fitted count) and <name>_heaps (files, tokens, types and the fitted
+// types), to s. It returns the row of the corpus in curve_fit.
+func saveCurves(s Sink, name string, files map[string]map[string]int, counts map[string]int) ([]any, error) {
+	zipf, ranks := freq.Zipf(counts, zipfPerDecade)
+	zt := table{columns: []string{"rank", "word", "count", "fitted"}}
+	for _, p := range ranks {
+		zt.rows = append(zt.rows, []any{p.Rank, p.Word, p.Count, zipf.At(p.Rank)})
+	}
+	if err := s.Write(name+"_zipf", zt); err != nil {
+		return nil, err
+	}
+	heaps, growth := freq.Heaps(files)
+	ht := table{columns: []string{"files", "tokens", "types", "fitted"}}
+	for _, p := range growth {
+		ht.rows = append(ht.rows, []any{p.Files, p.Tokens, p.Types, heaps.At(p.Tokens)})
+	}
+	if err := s.Write(name+"_heaps", ht); err != nil {
+		return nil, err
+	}
+	tools.Infof("%s: Zipf s %.4f (R² %.4f), Heaps β %.4f (R² %.4f)", name, zipf.S, zipf.R2, heaps.Beta, heaps.R2)
+	return []any{name, freq.TokenTotal(counts), len(counts), zipf.C, zipf.S, zipf.R2, heaps.K, heaps.Beta, heaps.R2}, nil
+}
+
+// runStats implements the stats subcommand: word length, syllable and
+// character statistics of each corpus, from the counts of the last run.
+func runStats(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	commandUsage(fs, "Writes word length, syllable count and character frequency tables per corpus, and the Zipf and Heaps curves fitted to its counts, from the counts of the last run.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora to analyse")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("saving word statistics")
+	tic := tools.Tic()
+
+	fits := table{columns: curveFitColumns}
+	for _, name := range strings.Split(*names, ",") {
+		files, err := loadFileCounts(name)
+		if err != nil {
+			tools.Errorf("%s: %v (count it first)", name, err)
+			return
+		}
+		counts := make(map[string]int)
+		for _, c := range files {
+			for w, n := range c {
+				counts[w] += n
+			}
+		}
+		st := collectStats(counts)
+		if err := saveStats(sink, name, st); err != nil {
+			tools.Errorf("%s: %v", name, err)
+			return
+		}
+		tools.Infof("%s: %d tokens, %.2f characters and %.2f syllables per token on average",
+			name, st.tokens, mean(st.lengthTokens, st.tokens), mean(st.syllableTokens, st.tokens))
+		row, err := saveCurves(sink, name, files, counts)
+		if err != nil {
+			tools.Errorf("%s: %v", name, err)
+			return
+		}
+		fits.rows = append(fits.rows, row)
+	}
+	if err := sink.Write("curve_fit", fits); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+
+	tic.Toc()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// functionWordLimits are the thresholds a word must meet to be proposed as
+// a function word: frequent, spread evenly over the texts and short, like
+// ca, vā, hi and kho.
+type functionWordLimits struct {
+	minPerMillion float64
+	maxDp         float64
+	maxLength     int // in letters
+}
+
+// functionWords returns the rows of the function_words table for the
+// per-file counts files: the words within limits, most frequent first,
+// with their rank among all words, count, occurrences per million tokens,
+// document frequency, DP and length.
+func functionWords(files map[string]map[string]int, limits functionWordLimits) table {
+	total := make(map[string]int)
+	for _, counts := range files {
+		for w, n := range counts {
+			total[w] += n
+		}
+	}
+	tokens := freq.TokenTotal(total)
+	disp := freq.DispersionStats(files)
+
+	t := table{columns: []string{"word", "rank", "count", "per_million", "doc_freq", "dp", "length"}}
+	for i, wc := range freq.Sorted(total) {
+		pm := freq.PerMillion(wc.Count, tokens)
+		if pm < limits.minPerMillion {
+			// the list is ordered by count, so no later word qualifies
+			break
+		}
+		d := disp[wc.Word]
+		n := utf8.RuneCountInString(wc.Word)
+		if d.DP > limits.maxDp || n > limits.maxLength {
+			continue
+		}
+		t.rows = append(t.rows, []any{wc.Word, i + 1, wc.Count, pm, d.Docs, d.DP, n})
+	}
+	return t
+}
+
+// saveExclusions writes the words of the function_words table t to path,
+// one per line after a comment saying where they come from, in the format
+// loadExclusions reads.
+func saveExclusions(path, source string, t table) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# function-word candidates of %s; review before using with -exclude\n", source)
+	for _, row := range t.rows {
+		b.WriteString(row[0].(string))
+		b.WriteByte('\n')
+	}
+	return tools.WriteFileAtomic(path, []byte(b.String()), 0o644)
+}
+
+// loadExclusions reads a word exclusion file: one word per line, blank
+// lines and lines starting with "#" ignored, anything after the first
+// field ignored too, so annotated lists work. An empty path excludes
+// nothing and returns a nil set.
+func loadExclusions(path string) (map[string]bool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := openDecompressed(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	set := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		set[fields[0]] = true
+	}
+	return set, sc.Err()
+}
+
+// runStopwords implements the stopwords subcommand: it proposes the
+// function words of the corpora, from the counts of the last run.
+func runStopwords(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("stopwords", flag.ExitOnError)
+	commandUsage(fs, "Proposes function words (frequent, evenly spread and short) to leave out of learner word lists, from the counts of the last run.")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora whose counts of the last run are analysed together")
+	minPm := fs.Float64("min-per-million", 500, "minimum occurrences per million tokens")
+	maxDp := fs.Float64("max-dp", 0.3, "maximum DP (0: spread like the text, 1: in one file)")
+	maxLen := fs.Int("max-length", 5, "maximum length in letters")
+	listPath := fs.String("list", filepath.Join(freqDir, "function_words.txt"), "exclusion file to write, for -exclude")
+	sf := addSinkFlags(fs, "tsv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("proposing function words")
+	tic := tools.Tic()
+
+	// the corpora are analysed as one text, their files kept apart for DP
+	files := make(map[string]map[string]int)
+	for _, name := range strings.Split(*names, ",") {
+		counts, err := loadFileCounts(name)
+		if err != nil {
+			tools.Errorf("%s: %v (count it first)", name, err)
+			return
+		}
+		for path, c := range counts {
+			files[name+":"+path] = c
+		}
+	}
+
+	t := functionWords(files, functionWordLimits{minPerMillion: *minPm, maxDp: *maxDp, maxLength: *maxLen})
+	if err := sink.Write("function_words", t); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if err := saveExclusions(*listPath, *names, t); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	tools.Infof("%d candidates in %s", len(t.rows), *listPath)
+
+	tic.Toc()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/cstxml"
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/tools"
+)
+
+// structureDivisions reads the paragraphs of files of c, which has their
+// markup, in order and passes each to add with the headings it stands
+// under, the texts as passageText gives them.
+func structureDivisions(ctx context.Context, c corpora.Corpus, files []string, add func(export.Division) error) error {
+	ps := c.(corpora.ParagraphScanner)
+	prog := tools.NewProgress(c.Name(), len(files))
+	defer prog.Finish()
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var st cstxml.Structure
+		d := export.Division{
+			Source:  export.SourceID(corpora.RootOf(c), path),
+			Book:    corpora.BookOf(c, path),
+			Section: corpora.SectionOf(c, path),
+		}
+		err := ps.ScanParagraphs(path, func(p cstxml.Paragraph) error {
+			p.Text = passageText(c, p.Text)
+			if p.Text == "" {
+				return nil
+			}
+			d.Heading = st.Next(p)
+			d.Seq++
+			d.Rend, d.ParaNum = p.Rend, st.ParaNum
+			d.Nikaya, d.BookTitle, d.Chapter, d.Vagga, d.Sutta = st.Nikaya, st.Book, st.Chapter, st.Vagga, st.Sutta
+			return add(d)
+		})
+		if err != nil {
+			return err
+		}
+		prog.Add(1)
+	}
+	return nil
+}
+
+// runStructure implements the structure subcommand: the structural markup
+// of the CST XML sources, each paragraph with the nikāya, book, vagga and
+// sutta it belongs to and its paragraph number, in the structure table of
+// a SQLite database.
+func runStructure(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("structure", flag.ExitOnError)
+	commandUsage(fs, "Loads the structure of the CST XML sources, each paragraph with the nikāya, book, vagga and sutta headings above it and its paragraph number, into the structure table of a SQLite database.")
+	dbPath := fs.String("db", "", "SQLite database to write the structure table into (required)")
+	names := fs.String("corpora", "cst", "comma-separated corpora to load, of those read from CST XML: cst, cst_mymr, cst_deva and vri")
+	layers := fs.String("layers", "all", "text layers to load: all, mula, commentaries, or layer keys like mul,att,tik,nrf")
+	fs.Parse(args)
+
+	tools.PTitle("loading the structure of the texts")
+	tic := tools.Tic()
+	if *dbPath == "" {
+		tools.Errorf("structure needs -db")
+		return
+	}
+	list, err := selectCorpora(*names)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	// only the file selection of the pipeline is used
+	p := &pipeline{}
+	if p.layers, p.layerKey, err = parseLayers(*layers); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+
+	db, err := export.Open(*dbPath)
+	if err != nil {
+		tools.Errorf("%s: %v", *dbPath, err)
+		return
+	}
+	defer export.Close(db)
+	for _, c := range list {
+		if _, ok := c.(corpora.ParagraphScanner); !ok {
+			tools.Warnf("%s: the edition has no structural markup; skipped", c.Name())
+			continue
+		}
+		files, err := p.corpusFiles(c)
+		if err != nil {
+			tools.Warnf("%s: %v", c.Name(), err)
+			continue
+		}
+		paras, headings, suttas := 0, 0, 0
+		err = export.Structure(db, c.Name(), func(add func(export.Division) error) error {
+			var last export.Division
+			return structureDivisions(ctx, c, files, func(d export.Division) error {
+				paras++
+				if d.Heading {
+					headings++
+				}
+				if d.Heading && d.Sutta != "" && d.Sutta != last.Sutta {
+					suttas++
+				}
+				last = d
+				return add(d)
+			})
+		})
+		if err != nil {
+			tools.Errorf("%s: %v", c.Name(), err)
+			return
+		}
+		tools.Infof("%s: %d paragraphs, %d of them headings, %d sutta titles, from %d files", c.Name(), paras, headings, suttas, len(files))
+	}
+	tic.Toc()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"strings"
+
+	"dpd/go_modules/frequency/dpd"
+	"dpd/go_modules/frequency/export"
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// studyColumns are the columns of the study list, in the file and the
+// database alike.
+var studyColumns = []string{"rank", "headword_id", "lemma", "pos", "count", "meaning", "construction", "proper_noun", "number"}
+
+// studyTable joins the top n headwords of list with their DPD glosses,
+// leaving out the headwords whose lemma, without its homonym number, is in
+// exclude. Proper nouns, the headwords in names, are marked with a
+// proper_noun of 1, or left out too when dropNames is set; number words,
+// those in numbers, with their kind as number, or left out when
+// dropNumbers is set.
+func studyTable(list []freq.LemmaCount, glosses map[int]dpd.Gloss, n int, exclude map[string]bool, names map[int]bool, dropNames bool, numbers map[int]string, dropNumbers bool) table {
+	if exclude != nil || dropNames || dropNumbers {
+		kept := make([]freq.LemmaCount, 0, len(list))
+		for _, lc := range list {
+			if !exclude[lemmaWord(lc.Headword.Lemma1)] && !(dropNames && names[lc.Headword.ID]) && !(dropNumbers && numbers[lc.Headword.ID] != "") {
+				kept = append(kept, lc)
+			}
+		}
+		list = kept
+	}
+	if len(list) > n {
+		list = list[:n]
+	}
+	t := table{columns: studyColumns}
+	for i, lc := range list {
+		g := glosses[lc.Headword.ID]
+		name := 0
+		if names[lc.Headword.ID] {
+			name = 1
+		}
+		t.rows = append(t.rows, []any{i + 1, lc.Headword.ID, lc.Headword.Lemma1, lc.Headword.Pos, lc.Count, g.Meaning, g.Construction, name, numbers[lc.Headword.ID]})
+	}
+	return t
+}
+
+// lemmaWord strips the homonym number from a DPD lemma_1: "ca 1" is "ca".
+func lemmaWord(lemma string) string {
+	if i := strings.IndexByte(lemma, ' '); i >= 0 {
+		return lemma[:i]
+	}
+	return lemma
+}
+
+// loadStudyTable ranks the DPD headwords of dpdPath by their counts of
+// the last run over names and returns the study list of the top n, less
+// the headwords of the exclusion file excludePath, if any, less the proper
+// nouns when dropNames is set and less the number words when dropNumbers
+// is.
+func loadStudyTable(names []string, dpdPath string, n int, excludePath string, dropNames, dropNumbers bool) (table, error) {
+	total, err := corpusTotals(names)
+	if err != nil {
+		return table{}, fmt.Errorf("%w (count the corpora first)", err)
+	}
+	exclude, err := loadExclusions(excludePath)
+	if err != nil {
+		return table{}, err
+	}
+	lem, err := freq.LoadLemmatizer(dpdPath)
+	if err != nil {
+		return table{}, err
+	}
+	db, err := dpd.Open(dpdPath)
+	if err != nil {
+		return table{}, err
+	}
+	defer db.Close()
+	glosses, err := db.Glosses()
+	if err != nil {
+		return table{}, fmt.Errorf("%s: %w", dpdPath, err)
+	}
+	properNouns, err := db.ProperNouns()
+	if err != nil {
+		return table{}, fmt.Errorf("%s: %w", dpdPath, err)
+	}
+	numbers, err := db.Numbers()
+	if err != nil {
+		return table{}, fmt.Errorf("%s: %w", dpdPath, err)
+	}
+	return studyTable(lem.Counts(total), glosses, n, exclude, properNouns, dropNames, numbers, dropNumbers), nil
+}
+
+// saveStudyDb replaces the study_list table with the rows of t.
+func saveStudyDb(db *sql.DB, t table) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM study_list`); err != nil {
+		return err
+	}
+	insert := export.NewInserter(tx, `INSERT INTO study_list (`+strings.Join(studyColumns, ", ")+`) VALUES`, ``, len(studyColumns))
+	for _, row := range t.rows {
+		if err := insert.Add(row...); err != nil {
+			return err
+		}
+	}
+	if err := insert.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runStudy implements the study subcommand.
+func runStudy(_ context.Context, args []string) {
+	fs := flag.NewFlagSet("study", flag.ExitOnError)
+	commandUsage(fs, "Writes the most frequent DPD headwords with their part of speech, meaning and construction.")
+	top := fs.Int("top", 1000, "number of headwords in the list")
+	names := fs.String("corpora", "cst,bjt,sya", "comma-separated corpora whose counts of the last run rank the headwords")
+	dpdPath := fs.String("dpd", "dpd.db", "DPD database")
+	dbPath := fs.String("db", "", "also write a study_list table into this SQLite database")
+	exclude := fs.String("exclude", "", "file of words whose headwords to leave out, one per line (see stopwords)")
+	dropNames := fs.Bool("drop-names", false, "leave out proper nouns (names of people, places and texts) instead of marking them")
+	dropNumbers := fs.Bool("drop-numbers", false, "leave out number words (DPD cardinals and ordinals) instead of marking them")
+	sf := addSinkFlags(fs, "csv")
+	fs.Parse(args)
+
+	sink, err := sf.open()
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	defer sink.Close()
+
+	tools.PTitle("saving the study list")
+	tic := tools.Tic()
+
+	t, err := loadStudyTable(strings.Split(*names, ","), *dpdPath, *top, *exclude, *dropNames, *dropNumbers)
+	if err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if err := sink.Write("study_list", t); err != nil {
+		tools.Errorf("%v", err)
+		return
+	}
+	if *dbPath != "" {
+		out, err := export.Open(*dbPath)
+		if err != nil {
+			tools.Errorf("%s: %v", *dbPath, err)
+			return
+		}
+		err = saveStudyDb(out, romanizeTable(sf.roman, t))
+		if cerr := export.Close(out); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			tools.Errorf("%s: %v", *dbPath, err)
+			return
+		}
+	}
+	tools.Infof("%d headwords", len(t.rows))
+
+	tic.Toc()
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dpd/go_modules/frequency/freq"
+	"dpd/go_modules/tools"
+)
+
+// outputFormat selects how tables are written. Every format carries the
+// same columns in the same order:
+//
+//	tsv, csv  header row, then one row per record
+//	json      an array of objects keyed by column name
+//	jsonl     one object per line
+//	parquet   Parquet columns typed by their values (see writeParquet)
+type outputFormat string
+
+const (
+	formatTsv     outputFormat = "tsv"
+	formatCsv     outputFormat = "csv"
+	formatJson    outputFormat = "json"
+	formatJsonl   outputFormat = "jsonl"
+	formatParquet outputFormat = "parquet"
+)
+
+func parseOutputFormat(s string) (outputFormat, error) {
+	switch f := outputFormat(strings.ToLower(s)); f {
+	case formatTsv, formatCsv, formatJson, formatJsonl, formatParquet:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (want tsv, csv, json, jsonl or parquet)", s)
+}
+
+// table is a list of records with named columns.
+type table struct {
+	columns []string
+	rows    [][]any
+	// stream, when set, yields the rows in place of rows, for tables too
+	// large to hold in memory. It may be called more than once.
+	stream func(yield func(row []any) error) error
+}
+
+// each calls fn with every row of t in order, stopping at the first error.
+func (t table) each(fn func(row []any) error) error {
+	if t.stream != nil {
+		return t.stream(fn)
+	}
+	for _, row := range t.rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// freqTable is the schema of word frequency outputs: word, count, rank
+// (1-based, by descending count) and per_million, the count per million
+// tokens of the table.
+func freqTable(list []freq.WordCount) table {
+	total := 0
+	for _, wc := range list {
+		total += wc.Count
+	}
+	t := table{columns: []string{"word", "count", "rank", "per_million"}}
+	for i, wc := range list {
+		t.rows = append(t.rows, []any{wc.Word, wc.Count, i + 1, freq.PerMillion(wc.Count, total)})
+	}
+	return t
+}
+
+// writeTable writes t to path, which is given without extension; the
+// format's extension is appended, and that of comp when it compresses. A
+// Parquet file compresses its pages instead and keeps the plain .parquet.
+// The file is replaced atomically, so an interrupted run leaves the
+// previous table in place.
+func writeTable(path string, format outputFormat, comp compression, t table) error {
+	path = tableFile(path, format, comp)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := tools.CreateAtomic(path, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if format == formatParquet {
+		if err := writeParquet(f, comp, t); err != nil {
+			return err
+		}
+		return f.Commit()
+	}
+	zw, err := compressWriter(f, comp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(zw)
+	if err := encodeTable(w, format, t); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// tableFile is the file writeTable writes for path: path with the
+// extensions of format and comp. Parquet compresses its pages and keeps
+// the .parquet name.
+func tableFile(path string, format outputFormat, comp compression) string {
+	path += "." + string(format)
+	if format != formatParquet {
+		path += comp.ext()
+	}
+	return path
+}
+
+// encodeTable writes t to w in format.
+func encodeTable(w *bufio.Writer, format outputFormat, t table) error {
+	switch format {
+	case formatTsv:
+		return writeDelimited(w, '\t', t)
+	case formatCsv:
+		return writeDelimited(w, ',', t)
+	case formatJson:
+		return writeJsonRows(w, t, true)
+	case formatJsonl:
+		return writeJsonRows(w, t, false)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+func writeDelimited(w *bufio.Writer, comma rune, t table) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(t.columns); err != nil {
+		return err
+	}
+	rec := make([]string, len(t.columns))
+	err := t.each(func(row []any) error {
+		for i, v := range row {
+			rec[i] = formatValue(v)
+		}
+		return cw.Write(rec)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return newlines.Replace(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// newlines makes line endings inside values "\n", whatever platform or
+// source file they came from, as the output itself uses.
+var newlines = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// writeJsonRows writes each row as an object with keys in column order,
+// as a JSON array when array is set and as JSON lines otherwise.
+func writeJsonRows(w *bufio.Writer, t table, array bool) error {
+	if array {
+		w.WriteString("[\n")
+	}
+	first := true
+	err := t.each(func(row []any) error {
+		if array && !first {
+			w.WriteString(",\n")
+		}
+		first = false
+		w.WriteByte('{')
+		for i, v := range row {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			k, _ := json.Marshal(t.columns[i])
+			if s, ok := v.(string); ok {
+				v = newlines.Replace(s)
+			}
+			val, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			w.Write(k)
+			w.WriteByte(':')
+			w.Write(val)
+		}
+		w.WriteByte('}')
+		if !array {
+			w.WriteByte('\n')
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if array {
+		if !first {
+			w.WriteByte('\n')
+		}
+		w.WriteString("]\n")
+	}
+	return nil
+}
+
+// keyColumns are the columns that name the entry a row counts, in the
+// tables readCounts reads: word tables, n-gram tables and lemma tables.
+var keyColumns = []string{"word", "ngram", "headword_id"}
+
+// errNoCounts is returned by readCounts for tables without a key and a
+// count column, such as the master list.
+var errNoCounts = errors.New("no count column")
+
+// readCounts reads the count column of a table written by writeTable, in
+// the format of its extension, keyed by its word, n-gram or headword_id
+// column.
+func readCounts(path string) (map[string]int, error) {
+	name, _ := splitCompression(path)
+	format, err := parseOutputFormat(strings.TrimPrefix(filepath.Ext(name), "."))
+	if err != nil {
+		return nil, err
+	}
+	if format == formatParquet {
+		return nil, fmt.Errorf("%s: Parquet tables are not read back; write them as tsv, csv or json for this", path)
+	}
+	f, err := openDecompressed(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	counts := make(map[string]int)
+	switch format {
+	case formatTsv, formatCsv:
+		err = readDelimitedCounts(bufio.NewReader(f), format, counts)
+	default:
+		err = readJsonCounts(bufio.NewReader(f), format, counts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return counts, nil
+}
+
+func readDelimitedCounts(r io.Reader, format outputFormat, counts map[string]int) error {
+	cr := csv.NewReader(r)
+	if format == formatTsv {
+		cr.Comma = '\t'
+	}
+	header, err := cr.Read()
+	if err != nil {
+		return err
+	}
+	key, count := -1, -1
+	for i, c := range header {
+		if c == "count" {
+			count = i
+		} else if key < 0 && slices.Contains(keyColumns, c) {
+			key = i
+		}
+	}
+	if key < 0 || count < 0 {
+		return errNoCounts
+	}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		n, err := strconv.Atoi(rec[count])
+		if err != nil {
+			return err
+		}
+		counts[rec[key]] += n
+	}
+}
+
+func readJsonCounts(r io.Reader, format outputFormat, counts map[string]int) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if format == formatJson {
+		if _, err := dec.Token(); err != nil { // [
+			return err
+		}
+	}
+	for dec.More() {
+		var row map[string]any
+		if err := dec.Decode(&row); err != nil {
+			return err
+		}
+		n, ok := row["count"].(json.Number)
+		if !ok {
+			return errNoCounts
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return err
+		}
+		key, ok := "", false
+		for _, c := range keyColumns {
+			if v, found := row[c]; found {
+				key, ok = fmt.Sprint(v), true
+				break
+			}
+		}
+		if !ok {
+			return errNoCounts
+		}
+		counts[key] += int(i)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package translit
+
+var devanagari = &brahmic{
+	consonants: map[rune]string{
+		'क': "k", 'ख': "kh", 'ग': "g", 'घ': "gh", 'ङ': "ṅ",
+		'च': "c", 'छ': "ch", 'ज': "j", 'झ': "jh", 'ञ': "ñ",
+		'ट': "ṭ", 'ठ': "ṭh", 'ड': "ḍ", 'ढ': "ḍh", 'ण': "ṇ",
+		'त': "t", 'थ': "th", 'द': "d", 'ध': "dh", 'न': "n",
+		'प': "p", 'फ': "ph", 'ब': "b", 'भ': "bh", 'म': "m",
+		'य': "y", 'र': "r", 'ल': "l", 'व': "v", 'स': "s", 'ह': "h", 'ळ': "ḷ",
+	},
+	vowels: map[rune]string{
+		'अ': "a", 'आ': "ā", 'इ': "i", 'ई': "ī", 'उ': "u", 'ऊ': "ū",
+		'ए': "e", 'ओ': "o",
+	},
+	signs: map[rune]string{
+		'ा': "ā", 'ि': "i", 'ी': "ī", 'ु': "u", 'ू': "ū",
+		'े': "e", 'ो': "o",
+	},
+	viramas:   "्",
+	niggahita: "ंँ", // the anusvāra, and the candrabindu some printings use
+	digitZero: '०',
+	// the daṇḍas stand where the Roman CST has full stops
+	other:  map[rune]string{'।': ".", '॥': "."},
+	ignore: "\u200c\u200d", // joiners select conjunct shapes only
+}
+
+// Devanagari transliterates Devanagari-script Pāḷi to Roman script.
+func Devanagari(text string) string {
+	return devanagari.translit(text)
+}
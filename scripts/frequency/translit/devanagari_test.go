@@ -0,0 +1,24 @@
+package translit
+
+import "testing"
+
+func TestDevanagari(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"एवं मे सुतं", "evaṃ me sutaṃ"},
+		{"भगवा", "bhagavā"},
+		{"धम्मो", "dhammo"},
+		{"बुद्धस्स", "buddhassa"},
+		{"आनन्द", "ānanda"},
+		{"भिक्खवे", "bhikkhave"},
+		{"पञ्ञा", "paññā"},
+		{"ब्रह्मजालसुत्तं", "brahmajālasuttaṃ"},
+		{"नाळन्दं", "nāḷandaṃ"},
+		{"इति।", "iti."},
+		{"१२", "12"},
+	}
+	for _, tt := range tests {
+		if got := Devanagari(tt.in); got != tt.want {
+			t.Errorf("Devanagari(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
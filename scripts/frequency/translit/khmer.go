@@ -0,0 +1,33 @@
+package translit
+
+var khmer = &brahmic{
+	consonants: map[rune]string{
+		'ក': "k", 'ខ': "kh", 'គ': "g", 'ឃ': "gh", 'ង': "ṅ",
+		'ច': "c", 'ឆ': "ch", 'ជ': "j", 'ឈ': "jh", 'ញ': "ñ",
+		'ដ': "ṭ", 'ឋ': "ṭh", 'ឌ': "ḍ", 'ឍ': "ḍh", 'ណ': "ṇ",
+		'ត': "t", 'ថ': "th", 'ទ': "d", 'ធ': "dh", 'ន': "n",
+		'ប': "p", 'ផ': "ph", 'ព': "b", 'ភ': "bh", 'ម': "m",
+		'យ': "y", 'រ': "r", 'ល': "l", 'វ': "v", 'ស': "s", 'ហ': "h", 'ឡ': "ḷ",
+		'អ': "", // vowel carrier: អ alone is a, អា is ā, and so on
+	},
+	vowels: map[rune]string{
+		'ឥ': "i", 'ឦ': "ī", 'ឧ': "u", 'ឩ': "ū", 'ឯ': "e", 'ឱ': "o",
+	},
+	signs: map[rune]string{
+		'ា': "ā", 'ិ': "i", 'ី': "ī", 'ុ': "u", 'ូ': "ū", 'េ': "e", 'ោ': "o",
+	},
+	// the coeng writes the next consonant as a subscript, the viriam and
+	// bantoc end a syllable on a bare consonant
+	viramas:   "្៑់",
+	niggahita: "ំ",
+	digitZero: '០',
+	// Khmer leaves no spaces between words; a zero-width space marks the
+	// break where one is marked at all
+	other:  map[rune]string{'។': ".", '៕': ".", '\u200b': " "},
+	ignore: "\u200c\u200d", // joiners select conjunct shapes only
+}
+
+// Khmer transliterates Khmer-script Pāḷi to Roman script.
+func Khmer(text string) string {
+	return khmer.translit(text)
+}
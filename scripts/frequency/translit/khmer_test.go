@@ -0,0 +1,24 @@
+package translit
+
+import "testing"
+
+func TestKhmer(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"ឯវំ មេ សុតំ", "evaṃ me sutaṃ"},
+		{"ភគវា", "bhagavā"},
+		{"ធម្មោ", "dhammo"},
+		{"ពុទ្ធស្ស", "buddhassa"},
+		{"អានន្ទ", "ānanda"},
+		{"ភិក្ខវេ", "bhikkhave"},
+		{"បញ្ញា", "paññā"},
+		{"ត្វំ", "tvaṃ"},
+		{"សង្ឃោ", "saṅgho"},
+		{"ឯវំ\u200bមេ\u200bសុតំ", "evaṃ me sutaṃ"}, // zero-width word breaks
+		{"១២។", "12."},
+	}
+	for _, tt := range tests {
+		if got := Khmer(tt.in); got != tt.want {
+			t.Errorf("Khmer(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package translit
+
+import "strings"
+
+// oSign stands for the vowel sign o, which Myanmar writes as e + ā and has
+// no code point of its own; compose folds the pair into it.
+const oSign = '\ue000'
+
+var myanmar = &brahmic{
+	consonants: map[rune]string{
+		'က': "k", 'ခ': "kh", 'ဂ': "g", 'ဃ': "gh", 'င': "ṅ",
+		'စ': "c", 'ဆ': "ch", 'ဇ': "j", 'ဈ': "jh", 'ဉ': "ñ", 'ည': "ññ",
+		'ဋ': "ṭ", 'ဌ': "ṭh", 'ဍ': "ḍ", 'ဎ': "ḍh", 'ဏ': "ṇ",
+		'တ': "t", 'ထ': "th", 'ဒ': "d", 'ဓ': "dh", 'န': "n",
+		'ပ': "p", 'ဖ': "ph", 'ဗ': "b", 'ဘ': "bh", 'မ': "m",
+		'ယ': "y", 'ရ': "r", 'လ': "l", 'ဝ': "v", 'သ': "s", 'ဟ': "h", 'ဠ': "ḷ",
+		'ဿ': "ss", // great sa, the ligature of s + s
+		'အ': "",   // vowel carrier: အ alone is a, အာ is ā, and so on
+	},
+	vowels: map[rune]string{
+		'ဣ': "i", 'ဤ': "ī", 'ဥ': "u", 'ဦ': "ū", 'ဧ': "e", 'ဩ': "o",
+	},
+	signs: map[rune]string{
+		'ာ': "ā", 'ါ': "ā", 'ိ': "i", 'ီ': "ī", 'ု': "u", 'ူ': "ū",
+		'ေ': "e", oSign: "o",
+	},
+	// medial y, r, v and h write the second consonant of a cluster
+	medials:   map[rune]string{'ျ': "y", 'ြ': "r", 'ွ': "v", 'ှ': "h"},
+	viramas:   "္်", // the stacking virama and the visible asat
+	niggahita: "ံ",
+	digitZero: '၀',
+	other:     map[rune]string{'။': ".", '၊': ","},
+	ignore:    "\u1037\u1038\u200c\u200d", // tone marks and joiners
+	compose: strings.NewReplacer(
+		"\u1031\u102c", string(oSign), // e + ā = o
+		"\u1031\u102b", string(oSign), // e + tall ā = o
+		"\u1025\u102e", "\u1026", // u + ī sign = ū
+	),
+}
+
+// Myanmar transliterates Myanmar-script Pāḷi to Roman script.
+func Myanmar(text string) string {
+	return myanmar.translit(text)
+}
@@ -0,0 +1,27 @@
+package translit
+
+import "testing"
+
+func TestMyanmar(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"ဧဝံ မေ သုတံ", "evaṃ me sutaṃ"},
+		{"ဘဂဝါ", "bhagavā"},
+		{"ဓမ္မော", "dhammo"},
+		{"ဗုဒ္ဓဿ", "buddhassa"},
+		{"အာနန္ဒ", "ānanda"},
+		{"ဘိက္ခဝေ", "bhikkhave"},
+		{"ပညာ", "paññā"},
+		{"ဉာဏ", "ñāṇa"},
+		{"ဥ\u102eန", "ūna"}, // ū written as u + ī sign
+		{"တွံ", "tvaṃ"},
+		{"ဗြဟ္မ", "brahma"},
+		{"ကဏှ", "kaṇha"},
+		{"သင်္ဃော", "saṅgho"}, // kinzi: ṅ + asat + virama
+		{"၁၂။", "12."},
+	}
+	for _, tt := range tests {
+		if got := Myanmar(tt.in); got != tt.want {
+			t.Errorf("Myanmar(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
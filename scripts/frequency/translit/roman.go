@@ -0,0 +1,126 @@
+package translit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Romanization is a way of writing Pāḷi in Latin letters. Text in IAST, the
+// romanization of the corpora and of palifreq's outputs, converts to each
+// of them and back unchanged.
+type Romanization string
+
+const (
+	// IAST writes the niggahīta ṃ, as CST and DPD do.
+	IAST Romanization = "iast"
+	// ISO15919 writes the niggahīta ṁ; the other letters are those of IAST.
+	ISO15919 Romanization = "iso15919"
+	// Velthuis writes in ASCII: long vowels doubled, as aa, and the dotted
+	// letters with a mark before them, as .m, .t, "n and ~n.
+	Velthuis Romanization = "velthuis"
+)
+
+// Romanizations lists the romanizations ParseRomanization accepts.
+var Romanizations = []Romanization{IAST, ISO15919, Velthuis}
+
+// ParseRomanization returns the romanization named s, in any case.
+func ParseRomanization(s string) (Romanization, error) {
+	r := Romanization(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range Romanizations {
+		if r == known {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("unknown romanization %q (want iast, iso15919 or velthuis)", s)
+}
+
+// velthuisLetters are the Velthuis spellings of the IAST letters that
+// differ, upper case included.
+var velthuisLetters = map[rune]string{
+	'ā': "aa", 'ī': "ii", 'ū': "uu", 'ṃ': ".m", 'ṅ': `"n`, 'ñ': "~n",
+	'ṭ': ".t", 'ḍ': ".d", 'ṇ': ".n", 'ḷ': ".l",
+	'Ā': "AA", 'Ī': "II", 'Ū': "UU", 'Ṃ': ".M", 'Ṅ': `"N`, 'Ñ': "~N",
+	'Ṭ': ".T", 'Ḍ': ".D", 'Ṇ': ".N", 'Ḷ': ".L",
+}
+
+// fromVelthuis reads Velthuis back into IAST. Its pairs are tried in order
+// at each position, so {} between two letters keeps them apart.
+var fromVelthuis = func() *strings.Replacer {
+	var pairs []string
+	for r, v := range velthuisLetters {
+		pairs = append(pairs, v, string(r))
+	}
+	return strings.NewReplacer(append(pairs, "{}", "")...)
+}()
+
+// toISO and fromISO convert the niggahīta between IAST and ISO 15919.
+var (
+	toISO   = strings.NewReplacer("ṃ", "ṁ", "Ṃ", "Ṁ")
+	fromISO = strings.NewReplacer("ṁ", "ṃ", "Ṁ", "Ṃ")
+)
+
+// FromIAST converts IAST text to r.
+func (r Romanization) FromIAST(text string) string {
+	switch r {
+	case ISO15919:
+		return toISO.Replace(text)
+	case Velthuis:
+		return toVelthuis(text)
+	}
+	return text
+}
+
+// ToIAST converts text in r to IAST.
+func (r Romanization) ToIAST(text string) string {
+	switch r {
+	case ISO15919:
+		return fromISO.Replace(text)
+	case Velthuis:
+		return fromVelthuis.Replace(text)
+	}
+	return text
+}
+
+// toVelthuis spells text in Velthuis. Where IAST letters would read as one
+// Velthuis letter, as the two vowels of "aa" or the dot of "iti.mayaṃ",
+// {} is put between them, as Velthuis does, so the text reads back the
+// same.
+func toVelthuis(text string) string {
+	var b strings.Builder
+	b.Grow(len(text) + len(text)/4)
+	prev := rune(0)
+	for _, c := range text {
+		v, ok := velthuisLetters[c]
+		if !ok {
+			v = string(c)
+		}
+		if prev != 0 && joinsVelthuis(prev, rune(v[0])) {
+			b.WriteString("{}")
+		}
+		b.WriteString(v)
+		prev = c
+		if ok {
+			// a letter spelled as two ends in a plain letter that no
+			// following letter joins
+			prev = 0
+		}
+	}
+	return b.String()
+}
+
+// joinsVelthuis reports whether the IAST letter prev, written as is, and
+// first, the first letter of the spelling of the next, read as one Velthuis
+// letter.
+func joinsVelthuis(prev, first rune) bool {
+	switch prev {
+	case 'a', 'i', 'u', 'A', 'I', 'U':
+		return first == prev
+	case '.':
+		return strings.ContainsRune("mtdnlMTDNL", first)
+	case '"', '~':
+		return first == 'n' || first == 'N'
+	case '{':
+		return first == '}'
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package translit
+
+import "testing"
+
+func TestRomanization(t *testing.T) {
+	tests := []struct {
+		in, iso, velthuis string
+	}{
+		{"evaṃ me sutaṃ", "evaṁ me sutaṁ", "eva.m me suta.m"},
+		{"bhagavā", "bhagavā", "bhagavaa"},
+		{"paññā", "paññā", "pa~n~naa"},
+		{"saṅgha", "saṅgha", `sa"ngha`},
+		{"nāḷandaṃ", "nāḷandaṁ", "naa.landa.m"},
+		{"kuṭṭhaṃ ḍaṃsa", "kuṭṭhaṁ ḍaṁsa", "ku.t.tha.m .da.msa"},
+		{"pīti dūta", "pīti dūta", "piiti duuta"},
+		{"Ānanda", "Ānanda", "AAnanda"},
+		// letters that would read as one Velthuis letter are kept apart
+		{"aa āa aā ii", "aa āa aā ii", "a{}a aaa a{}aa i{}i"},
+		{"iti.mayaṃ", "iti.mayaṁ", "iti.{}maya.m"},
+		{`"n ~n {}`, `"n ~n {}`, `"{}n ~{}n {{}}`},
+	}
+	for _, tt := range tests {
+		if got := ISO15919.FromIAST(tt.in); got != tt.iso {
+			t.Errorf("ISO15919.FromIAST(%q) = %q, want %q", tt.in, got, tt.iso)
+		}
+		if got := Velthuis.FromIAST(tt.in); got != tt.velthuis {
+			t.Errorf("Velthuis.FromIAST(%q) = %q, want %q", tt.in, got, tt.velthuis)
+		}
+		for _, r := range Romanizations {
+			if got := r.ToIAST(r.FromIAST(tt.in)); got != tt.in {
+				t.Errorf("%s round trip of %q = %q", r, tt.in, got)
+			}
+		}
+	}
+}
+
+func TestParseRomanization(t *testing.T) {
+	for in, want := range map[string]Romanization{"iast": IAST, "ISO15919": ISO15919, " velthuis": Velthuis} {
+		if got, err := ParseRomanization(in); err != nil || got != want {
+			t.Errorf("ParseRomanization(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseRomanization("harvard-kyoto"); err == nil {
+		t.Error("ParseRomanization(harvard-kyoto) gave no error")
+	}
+}
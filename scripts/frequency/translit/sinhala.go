@@ -0,0 +1,39 @@
+package translit
+
+import "strings"
+
+var sinhala = &brahmic{
+	consonants: map[rune]string{
+		'ක': "k", 'ඛ': "kh", 'ග': "g", 'ඝ': "gh", 'ඞ': "ṅ",
+		'ච': "c", 'ඡ': "ch", 'ජ': "j", 'ඣ': "jh", 'ඤ': "ñ",
+		'ට': "ṭ", 'ඨ': "ṭh", 'ඩ': "ḍ", 'ඪ': "ḍh", 'ණ': "ṇ",
+		'ත': "t", 'ථ': "th", 'ද': "d", 'ධ': "dh", 'න': "n",
+		'ප': "p", 'ඵ': "ph", 'බ': "b", 'භ': "bh", 'ම': "m",
+		'ය': "y", 'ර': "r", 'ල': "l", 'ව': "v", 'ස': "s", 'හ': "h", 'ළ': "ḷ",
+		// prenasalized letters, used by some printings for nasal clusters
+		'ඦ': "ñj", 'ඬ': "ṇḍ", 'ඳ': "nd", 'ඹ': "mb",
+	},
+	vowels: map[rune]string{
+		'අ': "a", 'ආ': "ā", 'ඉ': "i", 'ඊ': "ī", 'උ': "u", 'ඌ': "ū",
+		'එ': "e", 'ඒ': "e", 'ඔ': "o", 'ඕ': "o",
+	},
+	signs: map[rune]string{
+		'ා': "ā", 'ි': "i", 'ී': "ī", 'ු': "u", 'ූ': "ū",
+		'ෙ': "e", 'ේ': "e", 'ො': "o", 'ෝ': "o",
+	},
+	viramas:   "්",
+	niggahita: "ං",
+	digitZero: '෦',
+	other:     map[rune]string{'෴': "."},
+	ignore:    "\u200c\u200d", // joiners select conjunct shapes only
+	compose: strings.NewReplacer(
+		"\u0dd9\u0dcf", "\u0ddc", // e + ā = o
+		"\u0dd9\u0dca", "\u0dda", // e + virama = ē
+		"\u0ddc\u0dca", "\u0ddd", // o + virama = ō
+	),
+}
+
+// Sinhala transliterates Sinhala-script Pāḷi to Roman script.
+func Sinhala(text string) string {
+	return sinhala.translit(text)
+}
@@ -0,0 +1,22 @@
+package translit
+
+import "testing"
+
+func TestSinhala(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"එවං මෙ සුතං", "evaṃ me sutaṃ"},
+		{"භගවා", "bhagavā"},
+		{"ධම්මො", "dhammo"},
+		{"ධම්ම\u0dd9\u0dcf", "dhammo"}, // o written as e + ā
+		{"බුද්ධස්ස", "buddhassa"},
+		{"ආනන්ද", "ānanda"},
+		{"භික්\u200dඛවෙ", "bhikkhave"},
+		{"පඤ්ඤා", "paññā"},
+		{"ඉති ෴", "iti ."},
+	}
+	for _, tt := range tests {
+		if got := Sinhala(tt.in); got != tt.want {
+			t.Errorf("Sinhala(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package translit
+
+import "strings"
+
+var thaiConsonants = map[rune]string{
+	'ก': "k", 'ข': "kh", 'ค': "g", 'ฆ': "gh", 'ง': "ṅ",
+	'จ': "c", 'ฉ': "ch", 'ช': "j", 'ฌ': "jh", 'ญ': "ñ",
+	'ฏ': "ṭ", 'ฐ': "ṭh", 'ฑ': "ḍ", 'ฎ': "ḍ", 'ฒ': "ḍh", 'ณ': "ṇ",
+	'ต': "t", 'ถ': "th", 'ท': "d", 'ธ': "dh", 'น': "n",
+	'ป': "p", 'ผ': "ph", 'พ': "b", 'ภ': "bh", 'ม': "m",
+	'ย': "y", 'ร': "r", 'ล': "l", 'ว': "v", 'ส': "s", 'ห': "h", 'ฬ': "ḷ",
+	'อ': "", // vowel carrier: อ alone is a, อา is ā, and so on
+}
+
+var thaiVowels = map[rune]string{
+	'า': "ā", 'ิ': "i", 'ี': "ī", 'ุ': "u", 'ู': "ū",
+}
+
+// prefix vowels are written before the consonant they follow in speech
+var thaiPrefixVowels = map[rune]string{
+	'เ': "e", 'โ': "o",
+}
+
+const (
+	thaiPinthu    = 'ฺ' // U+0E3A, suppresses the inherent vowel
+	thaiYamakkan  = '๎' // U+0E4E, used as pinthu in some editions
+	thaiNiggahita = 'ํ' // U+0E4D
+	thaiPaiyannoi = 'ฯ' // abbreviation mark, written as "."
+	thaiDigitZero = '๐'
+	thaiDigitNine = '๙'
+)
+
+// Thai transliterates Thai-script Pāḷi to Roman script. Characters outside
+// the Thai Pāḷi alphabet are copied unchanged.
+func Thai(text string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	b.Grow(len(text))
+	pending := "" // prefix vowel waiting for its consonant
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if v, ok := thaiPrefixVowels[r]; ok {
+			pending = v
+			continue
+		}
+		c, ok := thaiConsonants[r]
+		if !ok {
+			switch {
+			case r == thaiNiggahita:
+				b.WriteString("ṃ")
+			case r == thaiPaiyannoi:
+				b.WriteByte('.')
+			case r >= thaiDigitZero && r <= thaiDigitNine:
+				b.WriteRune('0' + r - thaiDigitZero)
+			default:
+				if pending != "" {
+					// a prefix vowel without a consonant; keep it
+					b.WriteString(pending)
+					pending = ""
+				}
+				b.WriteRune(r)
+			}
+			continue
+		}
+		b.WriteString(c)
+		var next rune
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		switch {
+		case next == thaiPinthu || next == thaiYamakkan:
+			// conjunct: the prefix vowel, if any, belongs to a later consonant
+			i++
+		case thaiVowels[next] != "":
+			b.WriteString(thaiVowels[next])
+			i++
+		case pending != "":
+			b.WriteString(pending)
+			pending = ""
+		default:
+			b.WriteString("a")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,23 @@
+package translit
+
+import "testing"
+
+func TestThai(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"เอวํ เม สุตํ", "evaṃ me sutaṃ"},
+		{"ภควา", "bhagavā"},
+		{"ธมฺโม", "dhammo"},
+		{"พุทฺธสฺส", "buddhassa"},
+		{"อานนฺท", "ānanda"},
+		{"ตฺวํ", "tvaṃ"},
+		{"สฺเวว", "sveva"},
+		{"ภิกฺขเว", "bhikkhave"},
+		{"ปญฺญา", "paññā"},
+		{"๑๒.", "12."},
+	}
+	for _, tt := range tests {
+		if got := Thai(tt.in); got != tt.want {
+			t.Errorf("Thai(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
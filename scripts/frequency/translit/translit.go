@@ -0,0 +1,89 @@
+// Package translit converts Pāḷi written in Asian scripts to Roman script
+// (IAST with ṃ for the niggahīta, as CST and DPD use).
+//
+// Most Indic scripts share one structure: consonants carry an inherent a
+// that a following vowel sign replaces and a virama removes. They are
+// described by a brahmic table; Thai, which writes some vowels before
+// their consonant, has its own converter.
+package translit
+
+import "strings"
+
+// brahmic describes a Brahmi-derived script.
+type brahmic struct {
+	consonants map[rune]string
+	vowels     map[rune]string // independent vowels
+	signs      map[rune]string // dependent vowel signs
+	medials    map[rune]string // consonant signs between a consonant and its vowel
+	viramas    string          // characters that remove the inherent vowel
+	niggahita  string          // characters written as ṃ
+	digitZero  rune            // first of ten consecutive digits, 0 if none
+	other      map[rune]string // punctuation and the like
+	ignore     string          // characters dropped, e.g. joiners
+	// compose rewrites multi-rune spellings of a sign into one rune before
+	// conversion, e.g. Sinhala e + ā into o
+	compose *strings.Replacer
+}
+
+func (s *brahmic) translit(text string) string {
+	if s.compose != nil {
+		text = s.compose.Replace(text)
+	}
+	runes := []rune(text)
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if strings.ContainsRune(s.ignore, r) {
+			continue
+		}
+		if c, ok := s.consonants[r]; ok {
+			b.WriteString(c)
+			// skip joiners and take medials between the consonant and its
+			// sign
+			j := i + 1
+			for j < len(runes) {
+				if m, ok := s.medials[runes[j]]; ok {
+					b.WriteString(m)
+				} else if !strings.ContainsRune(s.ignore, runes[j]) {
+					break
+				}
+				j++
+			}
+			var next rune
+			if j < len(runes) {
+				next = runes[j]
+			}
+			switch {
+			case next != 0 && strings.ContainsRune(s.viramas, next):
+				i = j
+			case s.signs[next] != "":
+				b.WriteString(s.signs[next])
+				i = j
+			default:
+				b.WriteString("a")
+				i = j - 1
+			}
+			continue
+		}
+		switch {
+		case strings.ContainsRune(s.viramas, r):
+			// a virama after another, as in a Myanmar kinzi: nothing left
+			// to remove
+		case s.vowels[r] != "":
+			b.WriteString(s.vowels[r])
+		case strings.ContainsRune(s.niggahita, r):
+			b.WriteString("ṃ")
+		case s.digitZero != 0 && r >= s.digitZero && r <= s.digitZero+9:
+			b.WriteRune('0' + r - s.digitZero)
+		case s.other[r] != "":
+			b.WriteString(s.other[r])
+		case s.signs[r] != "":
+			// a stray sign without a consonant; keep its value
+			b.WriteString(s.signs[r])
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"dpd/go_modules/frequency/corpora"
+	"dpd/go_modules/frequency/pali"
+	"dpd/go_modules/tools"
+)
+
+// variantSim is the least similarity, Dice over their letter pairs, of two
+// words of aligned paragraphs to stand at the same position: dhammaṃ and
+// dhamman pair, dhammaṃ and bhikkhave do not.
+const variantSim = 0.5
+
+// formBag counts the letter pairs of a word with its ends marked, so that
+// one-letter words and the first and last letters count too.
+func formBag(word string) map[string]int {
+	r := []rune("^" + word + "$")
+	bag := make(map[string]int)
+	for i := 0; i+1 < len(r); i++ {
+		bag[string(r[i:i+2])]++
+	}
+	return bag
+}
+
+// alignTokens pairs the words of a with the words of b, in order, identical
+// words first and like spellings, at least variantSim, where they differ.
+// It returns the word of b at each position of a, "" where b has none.
+func alignTokens(a, b []string) []string {
+	bagsA := make([]map[string]int, len(a))
+	for i, w := range a {
+		bagsA[i] = formBag(w)
+	}
+	bagsB := make([]map[string]int, len(b))
+	for j, w := range b {
+		bagsB[j] = formBag(w)
+	}
+	sim := func(i, j int) float64 {
+		if a[i] == b[j] {
+			return 1
+		}
+		return dice(bagsA[i], bagsB[j])
+	}
+	at := make([]string, len(a))
+	for _, p := range alignPairs(len(a), len(b), sim, variantSim) {
+		at[p.i] = b[p.j]
+	}
+	return at
+}
+
+// variantCount is a tuple of the forms of the editions at one position,
+// with how often it occurs and where first.
+type variantCount struct {
+	forms   []string
+	count   int
+	example string // book sutta.paragraph of the first edition
+}
+
+// variantTable aligns the words of the paragraphs of the first of
+// editions, by names, with the paragraphs the others pair them with, as
+// align pairs them, and counts the tuples of forms standing at the same
+// position where the editions spell the word differently. An edition with
+// no paragraph or no word at a position has an empty form there.
+func variantTable(names []string, editions []map[string][]sutta, minSim float64) table {
+	t := table{columns: []string{"count"}}
+	for _, name := range names {
+		t.columns = append(t.columns, "form_"+name)
+	}
+	t.columns = append(t.columns, "example")
+
+	byForms := make(map[string]*variantCount)
+	var order []*variantCount
+	for _, book := range corpora.Books {
+		pivot := editions[0][book]
+		// the sutta and the paragraphs of each edition paired with each
+		// sutta of the first, as align pairs them
+		paired := make([][]map[int]int, len(pivot))
+		matched := make([][]int, len(pivot))
+		for i := range pivot {
+			paired[i] = make([]map[int]int, len(editions))
+			matched[i] = make([]int, len(editions))
+		}
+		for e := 1; e < len(editions); e++ {
+			alignSuttas(pivot, editions[e][book], minSim, func(i, j int, pairs []alignedPair) {
+				paras := make(map[int]int, len(pairs))
+				for _, pp := range pairs {
+					paras[pp.i] = pp.j
+				}
+				paired[i][e] = paras
+				matched[i][e] = j
+			})
+		}
+
+		for i, x := range pivot {
+			for k, text := range x.paras {
+				words := pali.Tokenize(text)
+				at := make([][]string, len(editions))
+				for e := 1; e < len(editions); e++ {
+					l, ok := paired[i][e][k]
+					if !ok {
+						continue
+					}
+					at[e] = alignTokens(words, pali.Tokenize(editions[e][book][matched[i][e]].paras[l]))
+				}
+				for n, w := range words {
+					forms := make([]string, len(editions))
+					forms[0] = w
+					differ := false
+					for e := 1; e < len(editions); e++ {
+						if at[e] != nil {
+							forms[e] = at[e][n]
+						}
+						differ = differ || forms[e] != "" && forms[e] != w
+					}
+					if !differ {
+						continue
+					}
+					key := strings.Join(forms, "\x00")
+					vc := byForms[key]
+					if vc == nil {
+						vc = &variantCount{forms: forms, example: fmt.Sprintf("%s %d.%d", book, i+1, k+1)}
+						byForms[key] = vc
+						order = append(order, vc)
+					}
+					vc.count++
+				}
+			}
+		}
+	}
+
+	// most frequent first, ties in the Pāḷi order of the forms
+	slices.SortStableFunc(order, func(a, b *variantCount) int {
+		if a.count != b.count {
+			return b.count - a.count
+		}
+		for e := range a.forms {
+			if c := tools.ComparePali(a.forms[e], b.forms[e]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	for _, vc := range order {
+		row := []any{vc.count}
+		for _, f := range vc.forms {
+			row = append(row, f)
+		}
+		t.rows = append(t.rows, append(row, vc.example))
+	}
+	return t
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// AtomicFile is a file written under a temporary name in the directory of
+// its destination and renamed into place by Commit, so readers, and a run
+// interrupted halfway, never see it partly written: the destination holds
+// either its old contents or all of the new ones.
+type AtomicFile struct {
+	*os.File
+	path string
+	perm os.FileMode
+	done bool
+}
+
+// CreateAtomic starts writing the file at path, which gets the permissions
+// perm on Commit.
+func CreateAtomic(path string, perm os.FileMode) (*AtomicFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{File: f, path: path, perm: perm}, nil
+}
+
+// Commit closes the file and renames it to its destination.
+func (f *AtomicFile) Commit() error {
+	if f.done {
+		return os.ErrClosed
+	}
+	f.done = true
+	err := f.Chmod(f.perm)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Abort closes and removes the temporary file unless Commit was called,
+// leaving the destination as it was; deferring it right after CreateAtomic
+// cleans up after every error.
+func (f *AtomicFile) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// WriteFileAtomic is os.WriteFile through an AtomicFile.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := CreateAtomic(path, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
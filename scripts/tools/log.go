@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// LogLevel orders log messages by importance.
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = [...]string{"debug", "info", "warn", "error"}
+
+func (l LogLevel) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// console serializes everything written to the terminal by the logger and
+// the progress bar, so log lines never land in the middle of a bar.
+var console = struct {
+	sync.Mutex
+	w     io.Writer
+	level LogLevel
+	bar   *Progress // bar currently drawn on the last line, if any
+}{w: os.Stderr, level: LevelInfo}
+
+// SetLogLevel sets the lowest level that is printed. The default is
+// LevelInfo.
+func SetLogLevel(l LogLevel) {
+	console.Lock()
+	console.level = l
+	console.Unlock()
+}
+
+// SetLogOutput redirects log and progress output, os.Stderr by default.
+func SetLogOutput(w io.Writer) {
+	console.Lock()
+	console.w = w
+	console.Unlock()
+}
+
+func logf(l LogLevel, format string, args ...any) {
+	console.Lock()
+	defer console.Unlock()
+	if l < console.level {
+		return
+	}
+	if console.bar != nil {
+		fmt.Fprint(console.w, "\r\033[K")
+	}
+	fmt.Fprintf(console.w, "%-5s ", l)
+	fmt.Fprintf(console.w, format, args...)
+	fmt.Fprintln(console.w)
+	if console.bar != nil {
+		console.bar.draw()
+	}
+}
+
+func Debugf(format string, args ...any) { logf(LevelDebug, format, args...) }
+func Infof(format string, args ...any)  { logf(LevelInfo, format, args...) }
+
+func Warnf(format string, args ...any) {
+	warningCount.Add(1)
+	keep(&kept.warnings, format, args)
+	logf(LevelWarn, format, args...)
+}
+
+// errorCount and warningCount are the numbers of errors and warnings
+// logged so far, printed or not.
+var errorCount, warningCount atomic.Int64
+
+func Errorf(format string, args ...any) {
+	errorCount.Add(1)
+	keep(&kept.errors, format, args)
+	logf(LevelError, format, args...)
+}
+
+// Errors returns the number of errors logged so far, so a command running
+// others can tell whether they failed.
+func Errors() int { return int(errorCount.Load()) }
+
+// Warnings returns the number of warnings logged so far.
+func Warnings() int { return int(warningCount.Load()) }
+
+// maxKept bounds the warnings and errors Kept returns; the counts go on.
+const maxKept = 1000
+
+// kept holds the first maxKept warnings and errors logged.
+var kept struct {
+	sync.Mutex
+	warnings, errors []string
+}
+
+func keep(list *[]string, format string, args []any) {
+	kept.Lock()
+	defer kept.Unlock()
+	if len(*list) < maxKept {
+		*list = append(*list, fmt.Sprintf(format, args...))
+	}
+}
+
+// Kept returns the first warnings and errors logged, printed or not, up to
+// a thousand of each, for the reports of a run.
+func Kept() (warnings, errors []string) {
+	kept.Lock()
+	defer kept.Unlock()
+	return append([]string(nil), kept.warnings...), append([]string(nil), kept.errors...)
+}
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PaliAlphabet is the Pāḷi alphabet in its traditional order, as the
+// dictionaries sort their entries: the vowels, the niggahīta, then the
+// consonants by place of articulation. Aspirated consonants are letters of
+// their own, so all words in kh follow all words in k.
+var PaliAlphabet = []string{
+	"a", "ā", "i", "ī", "u", "ū", "e", "o", "ṃ",
+	"k", "kh", "g", "gh", "ṅ",
+	"c", "ch", "j", "jh", "ñ",
+	"ṭ", "ṭh", "ḍ", "ḍh", "ṇ",
+	"t", "th", "d", "dh", "n",
+	"p", "ph", "b", "bh", "m",
+	"y", "r", "l", "ḷ", "ḷh", "v", "s", "h",
+}
+
+// letterBase puts the letters of the alphabet after every other character,
+// which sort by code point: spaces, digits and punctuation come first, so
+// "ca" sorts before "ca ca" and before "caka".
+const letterBase = unicode.MaxRune + 1
+
+var (
+	letterRanks    = make(map[rune]int) // single-rune letters
+	aspiratedRanks = make(map[rune]int) // by the consonant before the h
+
+	// the ranks of the ASCII characters, folded to lower case, and of the
+	// aspirated consonants by their first letter, 0 for none, so most
+	// letters are ranked without a map lookup
+	asciiRanks, asciiAspirated [utf8.RuneSelf]int
+)
+
+func init() {
+	for i, l := range PaliAlphabet {
+		r, n := utf8.DecodeRuneInString(l)
+		if n < len(l) {
+			aspiratedRanks[r] = letterBase + i
+		} else {
+			letterRanks[r] = letterBase + i
+		}
+	}
+	// the niggahīta as BJT and older romanizations write it
+	letterRanks['ṁ'] = letterRanks['ṃ']
+	for c := range utf8.RuneSelf {
+		r := unicode.ToLower(rune(c))
+		asciiRanks[c] = int(r)
+		if rank, ok := letterRanks[r]; ok {
+			asciiRanks[c] = rank
+		}
+		asciiAspirated[c] = aspiratedRanks[r]
+	}
+}
+
+// paliLetter returns the rank of the letter s starts with and its length
+// in bytes. Case is ignored.
+func paliLetter(s string) (rank, size int) {
+	if c := s[0]; c < utf8.RuneSelf {
+		if rank := asciiAspirated[c]; rank != 0 && len(s) > 1 && s[1]|0x20 == 'h' {
+			return rank, 2
+		}
+		return asciiRanks[c], 1
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	r = unicode.ToLower(r)
+	if rank, ok := aspiratedRanks[r]; ok && n < len(s) {
+		if h, m := utf8.DecodeRuneInString(s[n:]); unicode.ToLower(h) == 'h' {
+			return rank, n + m
+		}
+	}
+	if rank, ok := letterRanks[r]; ok {
+		return rank, n
+	}
+	return int(r), n
+}
+
+// ComparePali orders a and b in Pāḷi alphabetical order (PaliAlphabet),
+// returning -1, 0 or +1 like strings.Compare. Letters are compared without
+// regard to case and ṁ as ṃ; strings equal that way fall back to byte
+// order, so the order is total.
+func ComparePali(a, b string) int {
+	// skip the bytes a and b share, back to the end of a letter: after an
+	// ASCII character, which no h can follow as part of it
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	for i > 0 && (a[i-1] >= utf8.RuneSelf || asciiAspirated[a[i-1]] != 0) {
+		i--
+	}
+	x, y := a[i:], b[i:]
+	for x != "" && y != "" {
+		rx, nx := paliLetter(x)
+		ry, ny := paliLetter(y)
+		if rx != ry {
+			if rx < ry {
+				return -1
+			}
+			return +1
+		}
+		x, y = x[nx:], y[ny:]
+	}
+	switch {
+	case x == "" && y != "":
+		return -1
+	case x != "" && y == "":
+		return +1
+	}
+	return strings.Compare(a, b)
+}
+
+// PaliSort sorts words in Pāḷi alphabetical order.
+func PaliSort(words []string) {
+	slices.SortFunc(words, ComparePali)
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Progress is a single-line progress bar with an ETA, safe for concurrent
+// use. The total may grow while work is under way, e.g. as each corpus
+// lists its files. When the output is not a terminal only the final line
+// is printed.
+type Progress struct {
+	label string
+	start time.Time
+	tty   bool
+
+	// guarded by console
+	done, total int
+	drawn       time.Time
+}
+
+// NewProgress starts a progress bar for total items and draws it.
+func NewProgress(label string, total int) *Progress {
+	p := &Progress{label: label, start: time.Now(), total: total}
+	console.Lock()
+	p.tty = isTerminal(console.w)
+	if p.tty {
+		console.bar = p
+		p.draw()
+	}
+	console.Unlock()
+	return p
+}
+
+// AddTotal raises the number of items expected.
+func (p *Progress) AddTotal(n int) {
+	console.Lock()
+	p.total += n
+	p.redraw()
+	console.Unlock()
+}
+
+// Add marks n more items as done.
+func (p *Progress) Add(n int) {
+	console.Lock()
+	p.done += n
+	p.redraw()
+	console.Unlock()
+}
+
+// Finish draws the final state and releases the terminal line.
+func (p *Progress) Finish() {
+	console.Lock()
+	p.draw()
+	fmt.Fprintln(console.w)
+	if console.bar == p {
+		console.bar = nil
+	}
+	console.Unlock()
+}
+
+// redraw draws at most ten times a second, and always at the end.
+func (p *Progress) redraw() {
+	if !p.tty || p.done < p.total && time.Since(p.drawn) < 100*time.Millisecond {
+		return
+	}
+	p.draw()
+}
+
+const barWidth = 30
+
+func (p *Progress) draw() {
+	p.drawn = time.Now()
+	frac := 0.0
+	if p.total > 0 {
+		frac = float64(p.done) / float64(p.total)
+	}
+	filled := int(frac * barWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	elapsed := time.Since(p.start)
+	var tail string
+	switch {
+	case p.total > 0 && p.done >= p.total:
+		tail = "in " + elapsed.Round(time.Millisecond).String()
+	case p.done > 0:
+		left := time.Duration(float64(elapsed) / float64(p.done) * float64(p.total-p.done))
+		tail = "ETA " + left.Round(time.Second).String()
+	default:
+		tail = "ETA --"
+	}
+	if p.tty {
+		fmt.Fprint(console.w, "\r\033[K")
+	}
+	fmt.Fprintf(console.w, "%s [%s] %d/%d %3.0f%% %s", p.label, bar, p.done, p.total, frac*100, tail)
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
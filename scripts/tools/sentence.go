@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sentenceEnds are the marks that may end a sentence: the full stop, the
+// question and exclamation marks, the semicolon, which the editions use
+// between sentences of one period, and the daṇḍas of the Indic scripts.
+const sentenceEnds = ".?!;।॥"
+
+// closingMarks are the closing quotes and brackets an end mark may be
+// followed by within its sentence, as in ‘sādhu, bhante.’
+const closingMarks = "’”'\"»)]"
+
+// abbreviations are the words the editions write with a dot that does not
+// end a sentence: elisions (pe., la., pa.), the sigla of the editions in
+// variant notes (sī., syā., kaṃ., pī.) and the short titles of citations
+// (dī. ni., ma. ni., saṃ. ni., a. ni., pārā.), lower-cased.
+var abbreviations = make(map[string]bool)
+
+func init() {
+	for _, w := range strings.Fields(`
+		pe la pa
+		sī syā kaṃ pī ka ṭī aṭṭha mū
+		dī ma saṃ a khu ni pā dha su vi bu cari apa jā itivu udā netti mi
+		pārā pāci mahāva cūḷava pari abhi vibha paṭṭhā
+		visuddhi sārattha paṭi theragā therīgā va
+	`) {
+		abbreviations[w] = true
+	}
+}
+
+// SentenceSpans returns the byte offsets of the sentences of a paragraph of
+// Pāḷi, each as a start and end pair with the spaces around it left out.
+// A sentence ends after an end mark (.?!; and the daṇḍas) and any closing
+// quotes or brackets after it, when a space or the end of the text
+// follows, except:
+//   - inside parentheses or square brackets, where the editions put
+//     citations like (dī. ni. 1.1);
+//   - when ti closes a quotation after it, as in ‘kiṃ nu kho?’ti or
+//     evaṃ, bhante.'ti, so the quotation stays in the sentence of its
+//     speaker;
+//   - for the dot of an abbreviation, such as the elision pe. or the
+//     sigla syā. and dī. ni., which are listed, of a number, as in 12. or
+//     1.2, or of a run of dots, such as the ellipsis of ...pe....
+func SentenceSpans(text string) [][2]int {
+	var spans [][2]int
+	add := func(start, end int) {
+		s := strings.TrimSpace(text[start:end])
+		if s == "" {
+			return
+		}
+		start += strings.Index(text[start:end], s)
+		spans = append(spans, [2]int{start, start + len(s)})
+	}
+	start, depth := 0, 0
+	for i := 0; i < len(text); {
+		r, n := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '(' || r == '[':
+			depth++
+		case (r == ')' || r == ']') && depth > 0:
+			depth--
+		}
+		if depth > 0 || !strings.ContainsRune(sentenceEnds, r) || r == '.' && !fullStop(text, i) {
+			i += n
+			continue
+		}
+		end := i + n
+		for end < len(text) {
+			c, m := utf8.DecodeRuneInString(text[end:])
+			if !strings.ContainsRune(closingMarks, c) && !strings.ContainsRune(sentenceEnds, c) {
+				break
+			}
+			end += m
+		}
+		i = end
+		if end < len(text) && text[end] != ' ' || quotativeTi(text[end:]) {
+			continue
+		}
+		add(start, end)
+		start = end
+	}
+	add(start, len(text))
+	return spans
+}
+
+// SplitSentences returns the sentences of a paragraph of Pāḷi as
+// SentenceSpans finds them.
+func SplitSentences(text string) []string {
+	spans := SentenceSpans(text)
+	sentences := make([]string, len(spans))
+	for i, s := range spans {
+		sentences[i] = text[s[0]:s[1]]
+	}
+	return sentences
+}
+
+// fullStop reports whether the dot at text[i] may end a sentence: it is
+// not one of a run of dots and does not follow a number or an
+// abbreviation.
+func fullStop(text string, i int) bool {
+	if i > 0 && text[i-1] == '.' || i+1 < len(text) && text[i+1] == '.' {
+		return false
+	}
+	j := i
+	for j > 0 {
+		r, n := utf8.DecodeLastRuneInString(text[:j])
+		if !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r) {
+			break
+		}
+		j -= n
+	}
+	word := text[j:i]
+	if word == "" {
+		return true
+	}
+	if r, _ := utf8.DecodeRuneInString(word); unicode.IsDigit(r) {
+		return false
+	}
+	return !abbreviations[strings.ToLower(word)]
+}
+
+// quotativeTi reports whether rest, the text after an end mark and its
+// closing quotes, starts with the particle ti that closes a quotation,
+// written on its own or after an apostrophe.
+func quotativeTi(rest string) bool {
+	rest = strings.TrimLeft(rest, " ")
+	rest = strings.TrimLeft(rest, "’'")
+	if !strings.HasPrefix(rest, "ti") {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(rest[2:])
+	return !unicode.IsLetter(r) && !unicode.IsMark(r)
+}
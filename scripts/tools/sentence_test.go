@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name, text string
+		want       []string
+	}{
+		{"cst quotation", "‘‘kiṃ nu kho, bhante?’’ti. ‘‘evaṃ, āvuso’’ti kho āyasmā sāriputto paccassosi.",
+			[]string{"‘‘kiṃ nu kho, bhante?’’ti.", "‘‘evaṃ, āvuso’’ti kho āyasmā sāriputto paccassosi."}},
+		{"cst quotation ending in a stop", "‘‘sādhu, bhante.’’ti bhagavā avoca. te bhikkhū attamanā ahesuṃ.",
+			[]string{"‘‘sādhu, bhante.’’ti bhagavā avoca.", "te bhikkhū attamanā ahesuṃ."}},
+		{"cst citation", "vuttañhetaṃ (dī. ni. 1.2; ma. ni. 1.10) bhagavatā. tattha evanti nipātapadaṃ.",
+			[]string{"vuttañhetaṃ (dī. ni. 1.2; ma. ni. 1.10) bhagavatā.", "tattha evanti nipātapadaṃ."}},
+		{"cst citation outside brackets", "yathāha saṃ. ni. 2.1 idha, bhikkhave. taṃ suṇātha.",
+			[]string{"yathāha saṃ. ni. 2.1 idha, bhikkhave.", "taṃ suṇātha."}},
+		{"cst elision", "rūpaṃ aniccaṃ …pe… viññāṇaṃ aniccaṃ. sabbe saṅkhārā aniccā.",
+			[]string{"rūpaṃ aniccaṃ …pe… viññāṇaṃ aniccaṃ.", "sabbe saṅkhārā aniccā."}},
+		{"cst deva dandas", "एवं मे सुतं । एकं समयं भगवा सावत्थियं विहरति । किं नु खो’ति ॥",
+			[]string{"एवं मे सुतं ।", "एकं समयं भगवा सावत्थियं विहरति ।", "किं नु खो’ति ॥"}},
+		{"bjt apostrophe ti", "evaṃ bhante'ti kho te bhikkhū bhagavato paccassosuṃ. bhagavā etadavoca: sādhu bhikkhave. 'ti",
+			[]string{"evaṃ bhante'ti kho te bhikkhū bhagavato paccassosuṃ.", "bhagavā etadavoca: sādhu bhikkhave. 'ti"}},
+		{"bjt elision", "rūpaṃ bhikkhave anattā. pe. viññāṇaṃ anattā. yadaniccaṃ taṃ dukkhaṃ.",
+			[]string{"rūpaṃ bhikkhave anattā.", "pe. viññāṇaṃ anattā.", "yadaniccaṃ taṃ dukkhaṃ."}},
+		{"sya number and sigla", "12. tena kho pana samayena (syā. kaṃ. pī. tena samayena) bhagavā rājagahe viharati. so ...pe... nisīdi.",
+			[]string{"12. tena kho pana samayena (syā. kaṃ. pī. tena samayena) bhagavā rājagahe viharati.", "so ...pe... nisīdi."}},
+		{"sya la elision", "dasa kasiṇāni la. dasa asubhā. iti imāni cattālīsa kammaṭṭhānāni!",
+			[]string{"dasa kasiṇāni la. dasa asubhā.", "iti imāni cattālīsa kammaṭṭhānāni!"}},
+		{"no end mark", "  evaṃ me sutaṃ  ", []string{"evaṃ me sutaṃ"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitSentences(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("SplitSentences(%q)\n got %q\nwant %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSentenceSpans(t *testing.T) {
+	text := " evaṃ me sutaṃ.  ekaṃ samayaṃ "
+	want := [][2]int{{1, 19}, {21, 37}}
+	if got := SentenceSpans(text); !slices.Equal(got, want) {
+		t.Errorf("SentenceSpans(%q) = %v, want %v", text, got, want)
+	}
+}
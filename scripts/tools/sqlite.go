@@ -0,0 +1,15 @@
+package tools
+
+import (
+	"net/url"
+	"path/filepath"
+)
+
+// SQLiteURI returns the file: URI of the SQLite database at path with the
+// URI parameters query, such as "mode=ro". The path is escaped, so that a
+// ? or # in it is not read as the query or a fragment.
+func SQLiteURI(path, query string) string {
+	name := (&url.URL{Path: filepath.ToSlash(path)}).EscapedPath()
+	u := url.URL{Scheme: "file", Opaque: name, RawQuery: query}
+	return u.String()
+}
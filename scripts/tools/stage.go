@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StageTimer accumulates the time spent in one named stage of a run, such
+// as reading or tokenizing, across calls and goroutines. Stages that run
+// concurrently add up their time, so the totals may exceed the wall time.
+type StageTimer struct {
+	name  string
+	total atomic.Int64 // nanoseconds
+	calls atomic.Int64
+}
+
+var stages struct {
+	sync.Mutex
+	list []*StageTimer // in order of creation
+}
+
+// Stage returns the timer of the named stage, creating it on first use.
+func Stage(name string) *StageTimer {
+	stages.Lock()
+	defer stages.Unlock()
+	for _, s := range stages.list {
+		if s.name == name {
+			return s
+		}
+	}
+	s := &StageTimer{name: name}
+	stages.list = append(stages.list, s)
+	return s
+}
+
+// Add records one call of the stage that took d.
+func (s *StageTimer) Add(d time.Duration) {
+	s.total.Add(int64(d))
+	s.calls.Add(1)
+}
+
+// Start begins one call of the stage and returns the function that ends
+// it, as in defer timer.Start()().
+func (s *StageTimer) Start() func() {
+	start := time.Now()
+	return func() { s.Add(time.Since(start)) }
+}
+
+// Total is the time recorded for the stage so far.
+func (s *StageTimer) Total() time.Duration { return time.Duration(s.total.Load()) }
+
+// StageTotal is the time recorded for one stage and its number of calls.
+type StageTotal struct {
+	Name  string
+	Total time.Duration
+	Calls int
+}
+
+// Stages returns the totals of the stages that were used, in order of
+// creation.
+func Stages() []StageTotal {
+	stages.Lock()
+	defer stages.Unlock()
+	var list []StageTotal
+	for _, s := range stages.list {
+		if calls := s.calls.Load(); calls > 0 {
+			list = append(list, StageTotal{s.name, s.Total(), int(calls)})
+		}
+	}
+	return list
+}
+
+// PrintStages writes a table of the stages that were used, in order of
+// creation, with their total time, share of all stage time and number of
+// calls.
+func PrintStages() {
+	list := Stages()
+	var sum time.Duration
+	for _, s := range list {
+		sum += s.Total
+	}
+	console.Lock()
+	defer console.Unlock()
+	fmt.Fprintf(console.w, "%-12s %12s %7s %9s\n", "stage", "time", "share", "calls")
+	for _, s := range list {
+		share := 0.0
+		if sum > 0 {
+			share = 100 * float64(s.Total) / float64(sum)
+		}
+		fmt.Fprintf(console.w, "%-12s %12s %6.1f%% %9d\n", s.Name, s.Total.Round(time.Microsecond), share, s.Calls)
+	}
+}